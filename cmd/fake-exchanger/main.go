@@ -0,0 +1,112 @@
+// Command fake-exchanger serves the exchanger gRPC API from quotes held in memory, so that
+// transferer can run without grpcexchanger. Quotes can drift in a random walk, and calls can be
+// slowed down or failed on purpose to try the client's timeouts, retries and circuit breaker.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
+	"github.com/Foreground-Eclipse/transferer/internal/exchanger/fake"
+	"github.com/Foreground-Eclipse/transferer/internal/tlsconfig"
+	"github.com/Foreground-Eclipse/transferer/pkg/logger"
+	"github.com/ilyakaznacheev/cleanenv"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+)
+
+type Config struct {
+	Address string            `env:"FAKE_EXCHANGER_ADDRESS" env-default:":8089"`
+	Rates   map[string]string `env:"FAKE_EXCHANGER_RATES" env-default:"USD_EUR:0.92,USD_RUB:90,EUR_RUB:98"`
+	// WalkStep is the largest relative change of every quote per WalkInterval, 0 keeps them static.
+	WalkStep     float64       `env:"FAKE_EXCHANGER_WALK_STEP" env-default:"0"`
+	WalkInterval time.Duration `env:"FAKE_EXCHANGER_WALK_INTERVAL" env-default:"1s"`
+	Latency      time.Duration `env:"FAKE_EXCHANGER_LATENCY" env-default:"0s"`
+	Jitter       time.Duration `env:"FAKE_EXCHANGER_JITTER" env-default:"0s"`
+	ErrorRate    float64       `env:"FAKE_EXCHANGER_ERROR_RATE" env-default:"0"`
+	// ErrorCode is the gRPC code of injected failures, e.g. UNAVAILABLE or INTERNAL.
+	ErrorCode   string `env:"FAKE_EXCHANGER_ERROR_CODE" env-default:"UNAVAILABLE"`
+	Seed        int64  `env:"FAKE_EXCHANGER_SEED" env-default:"0"`
+	TLSCertFile string `env:"FAKE_EXCHANGER_TLS_CERT_FILE"`
+	TLSKeyFile  string `env:"FAKE_EXCHANGER_TLS_KEY_FILE"`
+}
+
+func main() {
+	var cfg Config
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		log.Fatalf("cant read config: %s", err)
+	}
+
+	zlog := logger.SetupLogger()
+
+	opts, err := options(cfg)
+	if err != nil {
+		zlog.Fatal("invalid config", zap.Error(err))
+	}
+	server, err := fake.New(opts)
+	if err != nil {
+		zlog.Fatal("failed to create fake exchanger", zap.Error(err))
+	}
+	if cfg.WalkStep > 0 {
+		go server.Run(context.Background(), cfg.WalkInterval)
+	}
+
+	var serverOpts []grpc.ServerOption
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		tlsConfig, err := tlsconfig.Server(zlog, cfg.TLSCertFile, cfg.TLSKeyFile, "")
+		if err != nil {
+			zlog.Fatal("failed to set up TLS", zap.Error(err))
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	grpcServer := grpc.NewServer(serverOpts...)
+	pb.RegisterExchangeServiceServer(grpcServer, server)
+
+	listener, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		zlog.Fatal("failed to listen", zap.Error(err))
+	}
+
+	zlog.Info("fake exchanger is listening", zap.String("address", cfg.Address))
+	if err := grpcServer.Serve(listener); err != nil {
+		zlog.Fatal("failed to serve", zap.Error(err))
+	}
+}
+
+func options(cfg Config) (fake.Options, error) {
+	quotes := make(map[string]float32, len(cfg.Rates))
+	for pair, value := range cfg.Rates {
+		rate, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return fake.Options{}, fmt.Errorf("invalid rate for %s: %w", pair, err)
+		}
+		quotes[pair] = float32(rate)
+	}
+
+	if cfg.WalkStep > 0 && cfg.WalkInterval <= 0 {
+		return fake.Options{}, fmt.Errorf("walk interval must be positive, got %s", cfg.WalkInterval)
+	}
+
+	var code codes.Code
+	if err := code.UnmarshalJSON([]byte(strconv.Quote(cfg.ErrorCode))); err != nil {
+		return fake.Options{}, fmt.Errorf("invalid error code: %w", err)
+	}
+
+	return fake.Options{
+		Rates:     quotes,
+		WalkStep:  cfg.WalkStep,
+		Latency:   cfg.Latency,
+		Jitter:    cfg.Jitter,
+		ErrorRate: cfg.ErrorRate,
+		ErrorCode: code,
+		Seed:      cfg.Seed,
+	}, nil
+}
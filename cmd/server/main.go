@@ -1,20 +1,36 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"net/http"
+	"strings"
 
 	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
 	"github.com/Foreground-Eclipse/transferer/config"
 	_ "github.com/Foreground-Eclipse/transferer/docs"
+	"github.com/Foreground-Eclipse/transferer/internal/exchanger"
 	"github.com/Foreground-Eclipse/transferer/internal/handlers"
+	"github.com/Foreground-Eclipse/transferer/internal/lockout"
+	"github.com/Foreground-Eclipse/transferer/internal/mail"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/passwordreset"
+	"github.com/Foreground-Eclipse/transferer/internal/rates"
+	"github.com/Foreground-Eclipse/transferer/internal/session"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
 	"github.com/Foreground-Eclipse/transferer/internal/storage/postgres"
+	"github.com/Foreground-Eclipse/transferer/internal/tlsconfig"
+	"github.com/Foreground-Eclipse/transferer/internal/twofactor"
+	"github.com/Foreground-Eclipse/transferer/internal/verification"
+	jwt "github.com/Foreground-Eclipse/transferer/pkg/auth"
 	"github.com/Foreground-Eclipse/transferer/pkg/logger"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
@@ -24,6 +40,10 @@ import (
 // @host localhost:8088
 // @BasePath /api/v1
 // @schemes http https
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @description Access token as "Bearer <token>"
 func main() {
 	cfg := config.MustLoad("config")
 
@@ -53,23 +73,304 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	exchangerHost := os.Getenv("EXCHANGER_HOST")
-	conn, err := grpc.Dial(exchangerHost, grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	err = storage.InitLedgerSchema()
+	if err != nil {
+		panic(err)
+	}
+
+	err = storage.PostOpeningBalances()
+	if err != nil {
+		panic(err)
+	}
+
+	err = storage.InitIdempotencySchema()
+	if err != nil {
+		panic(err)
+	}
+
+	err = storage.InitTransactionSchema()
+	if err != nil {
+		panic(err)
+	}
+	err = storage.InitQuoteSchema()
+	if err != nil {
+		panic(err)
+	}
+	err = storage.InitRateHistorySchema()
+	if err != nil {
+		panic(err)
+	}
+
+	err = storage.InitSessionSchema()
+	if err != nil {
+		panic(err)
+	}
+
+	err = storage.InitTwoFactorSchema()
+	if err != nil {
+		panic(err)
+	}
+
+	err = storage.InitLockoutSchema()
+	if err != nil {
+		panic(err)
+	}
+
+	err = storage.InitPasswordResetSchema()
+	if err != nil {
+		panic(err)
+	}
+
+	err = storage.InitEmailVerificationSchema()
+	if err != nil {
+		panic(err)
+	}
+
+	err = storage.InitAdminSchema()
+	if err != nil {
+		panic(err)
+	}
+
+	err = storage.InitAdjustmentApprovalSchema()
 	if err != nil {
 		panic(err)
 	}
-	defer conn.Close()
-	client := pb.NewExchangeServiceClient(conn)
+	grantBootstrapAdmins(log, storage, cfg.Admin.BootstrapAdmins)
+
+	keySet, err := jwt.NewKeySet(cfg.JWT, storage)
+	if err != nil {
+		panic(err)
+	}
+	sessions := session.NewManager(keySet, storage, cfg.JWT.RefreshTokenTTL)
+	twoFactor := twofactor.NewManager(storage, cfg.TwoFactor.Issuer, cfg.TwoFactor.ChallengeTTL)
+	mailer, err := newMailer(cfg.Mail)
+	if err != nil {
+		panic(err)
+	}
+	loginGuard := lockout.NewGuard(storage, mailer, lockout.Options{
+		FreeAttempts:    cfg.Lockout.FreeAttempts,
+		MaxFailures:     cfg.Lockout.MaxFailures,
+		IPMaxFailures:   cfg.Lockout.IPMaxFailures,
+		Backoff:         cfg.Lockout.Backoff,
+		MaxBackoff:      cfg.Lockout.MaxBackoff,
+		LockoutDuration: cfg.Lockout.LockoutDuration,
+		Window:          cfg.Lockout.Window,
+		UnlockTokenTTL:  cfg.Lockout.UnlockTokenTTL,
+		UnlockURL:       cfg.Lockout.UnlockURL,
+	})
+	passwordReset := passwordreset.NewManager(storage, mailer, passwordreset.Options{
+		TokenTTL:       cfg.Reset.TokenTTL,
+		ResendInterval: cfg.Reset.ResendInterval,
+		URL:            cfg.Reset.URL,
+	})
+	verifications := verification.NewManager(storage, mailer, verification.Options{
+		TokenTTL:       cfg.Verify.TokenTTL,
+		ResendInterval: cfg.Verify.ResendInterval,
+		URL:            cfg.Verify.URL,
+	})
+	depositLimits, err := newUnverifiedDepositLimits(cfg.Verify)
+	if err != nil {
+		panic(err)
+	}
+	stepUp, err := newStepUpPolicy(cfg.StepUp)
+	if err != nil {
+		panic(err)
+	}
+
+	rateProvider, closeRateProvider, err := newRateProvider(log, cfg, storage)
+	if err != nil {
+		panic(err)
+	}
+	defer closeRateProvider()
+	rateRecorder := rates.NewRecorder(log, rateProvider, cfg.Rates.Provider, storage)
+	rateCache := rates.NewCache(rateRecorder, cfg.Rates.CacheTTL, cfg.Rates.MaxStale)
 
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		panic(err)
+	}
+
+	public := router.Group("/api/v1")
+	public.POST("/register", handlers.HandleRegisterUser(log, storage, verifications))
+	public.POST("/login", handlers.HandleLoginUser(log, storage, sessions, twoFactor, loginGuard))
+	public.POST("/login/unlock", handlers.HandleUnlockAccount(log, loginGuard))
+	public.POST("/login/2fa", handlers.HandleLoginTwoFactor(log, twoFactor, sessions, loginGuard))
+	public.POST("/token/refresh", handlers.HandleRefreshToken(log, sessions))
+	public.POST("/password/forgot", handlers.HandleForgotPassword(log, passwordReset))
+	public.POST("/password/reset", handlers.HandleResetPassword(log, passwordReset))
+	public.POST("/email/verify", handlers.HandleVerifyEmail(log, verifications))
+
+	authenticated := router.Group("/api/v1", middleware.Authenticate(log, keySet))
+	authenticated.POST("/logout", handlers.HandleLogout(log, sessions))
+	authenticated.POST("/reauth", handlers.HandleReauthenticate(log, storage, twoFactor, sessions, loginGuard))
+	authenticated.POST("/account/password", middleware.RequireRecentAuth(stepUp.MaxAge), handlers.HandleChangePassword(log, storage))
+	authenticated.POST("/account/email", middleware.RequireRecentAuth(stepUp.MaxAge), handlers.HandleChangeEmail(log, storage, verifications))
+	authenticated.POST("/email/verify/resend", handlers.HandleResendVerification(log, verifications))
+	authenticated.GET("/profile", handlers.HandleProfile(log, storage))
+	authenticated.POST("/2fa/setup", handlers.HandleTwoFactorSetup(log, twoFactor))
+	authenticated.POST("/2fa/confirm", handlers.HandleTwoFactorConfirm(log, twoFactor))
+	authenticated.GET("/balance", handlers.HandleBalance(log, storage))
+	authenticated.POST("/wallet/deposit", middleware.RequireActiveAccount(log, storage), handlers.HandleDeposit(log, storage, storage, storage, depositLimits))
+	authenticated.POST("/wallet/withdraw", middleware.RequireActiveAccount(log, storage), middleware.RequireVerifiedEmail(log, storage), handlers.HandleWithdraw(log, storage, storage, stepUp))
+	authenticated.GET("/transactions", handlers.HandleTransactions(log, storage))
+	authenticated.POST("/transfers", middleware.RequireActiveAccount(log, storage), middleware.RequireVerifiedEmail(log, storage), handlers.HandleTransfer(log, rateCache, storage, storage, stepUp))
+	authenticated.GET("/exchange/rates", handlers.HandleRates(log, rateCache))
+	authenticated.GET("/exchange/rates/history", handlers.HandleRatesHistory(log, storage))
+	authenticated.POST("/exchange/quote", handlers.HandleExchangeQuote(log, rateCache, storage))
+	authenticated.POST("/exchange", middleware.RequireActiveAccount(log, storage), handlers.HandleExchange(log, rateCache, storage, storage))
 
-	router.POST("/api/v1/register", handlers.HandleRegisterUser(log, storage))
-	router.POST("/api/v1/login", handlers.HandleLoginUser(log, storage, cfg))
-	router.GET("/api/v1/balance", handlers.HandleBalance(log, storage))
-	router.POST("/api/v1/wallet/deposit", handlers.HandleDeposit(log, storage))
-	router.GET("/api/v1/exchange/rates", handlers.HandleRates(log, client))
-	router.POST("/api/v1/exchange", handlers.HandleExchange(log, client, storage))
+	admin := router.Group("/api/v1/admin", middleware.Authenticate(log, keySet),
+		middleware.RequireRole(jwt.RoleSupport, jwt.RoleAdmin, jwt.RoleAuditor), middleware.RequireActiveAccount(log, storage))
+	admin.GET("/users", handlers.HandleAdminListUsers(log, storage))
+	admin.GET("/users/:username", handlers.HandleAdminGetUser(log, storage))
+	admin.GET("/users/:username/balance", handlers.HandleAdminBalance(log, storage, storage))
+	admin.GET("/users/:username/transactions", handlers.HandleAdminTransactions(log, storage, storage))
+	admin.POST("/users/:username/freeze", middleware.RequireRole(jwt.RoleSupport, jwt.RoleAdmin), handlers.HandleAdminFreeze(log, storage, storage))
+	admin.POST("/users/:username/unfreeze", middleware.RequireRole(jwt.RoleSupport, jwt.RoleAdmin), handlers.HandleAdminUnfreeze(log, storage))
+	admin.POST("/users/:username/adjustments", middleware.RequireRole(jwt.RoleAdmin), middleware.RequireRecentAuth(stepUp.MaxAge),
+		handlers.HandleAdminProposeAdjustment(log, storage, cfg.Admin.AdjustmentTTL))
+	admin.GET("/adjustments", handlers.HandleAdminListAdjustments(log, storage))
+	admin.GET("/adjustments/:id", handlers.HandleAdminGetAdjustment(log, storage))
+	admin.POST("/adjustments/:id/approve", middleware.RequireRole(jwt.RoleAdmin), middleware.RequireRecentAuth(stepUp.MaxAge),
+		handlers.HandleAdminApproveAdjustment(log, storage))
+	admin.POST("/adjustments/:id/reject", middleware.RequireRole(jwt.RoleAdmin), handlers.HandleAdminRejectAdjustment(log, storage))
+	admin.PUT("/users/:username/roles", middleware.RequireRole(jwt.RoleAdmin), middleware.RequireRecentAuth(stepUp.MaxAge),
+		handlers.HandleAdminSetRoles(log, storage))
+
+	router.GET("/.well-known/jwks.json", handlers.HandleJWKS(log, keySet))
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
-	router.Run(cfg.Server.Address)
+	if cfg.Server.TLSCertFile == "" && cfg.Server.TLSKeyFile == "" {
+		router.Run(cfg.Server.Address)
+		return
+	}
+
+	tlsConfig, err := tlsconfig.Server(log, cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile, cfg.Server.TLSMinVersion)
+	if err != nil {
+		panic(err)
+	}
+	server := &http.Server{
+		Addr:      cfg.Server.Address,
+		Handler:   router,
+		TLSConfig: tlsConfig,
+	}
+	// the certificate comes from TLSConfig, so it can be reloaded
+	if err := server.ListenAndServeTLS("", ""); err != nil {
+		panic(err)
+	}
+}
+
+// newRateProvider returns the rate provider selected by cfg.Rates.Provider
+// and a function releasing its resources.
+func newRateProvider(log *zap.Logger, cfg *config.Config, storage *postgres.Storage) (rates.Provider, func(), error) {
+	switch cfg.Rates.Provider {
+	case "grpc":
+		creds := insecure.NewCredentials()
+		if cfg.Exchanger.TLS {
+			tlsConfig, err := tlsconfig.Client(log, cfg.Exchanger.TLSCAFile, cfg.Exchanger.TLSCertFile,
+				cfg.Exchanger.TLSKeyFile, cfg.Exchanger.TLSServerName, cfg.Exchanger.TLSMinVersion)
+			if err != nil {
+				return nil, nil, err
+			}
+			creds = credentials.NewTLS(tlsConfig)
+		}
+
+		conn, err := grpc.Dial(cfg.Exchanger.Host, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, nil, err
+		}
+		client := exchanger.NewClient(pb.NewExchangeServiceClient(conn), exchanger.Options{
+			CallTimeout:        cfg.Exchanger.CallTimeout,
+			MaxRetries:         cfg.Exchanger.MaxRetries,
+			Backoff:            cfg.Exchanger.RetryBackoff,
+			BreakerFailures:    cfg.Exchanger.BreakerFailures,
+			BreakerOpenTimeout: cfg.Exchanger.BreakerOpenTimeout,
+		})
+		return rates.NewGRPCProvider(client), func() { conn.Close() }, nil
+	case "postgres":
+		return storage, func() {}, nil
+	case "file":
+		provider, err := rates.NewFileProvider(cfg.Rates.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		return provider, func() {}, nil
+	case "static":
+		quotes, err := rates.ParseStaticQuotes(cfg.Rates.Static)
+		if err != nil {
+			return nil, nil, err
+		}
+		provider, err := rates.NewStaticProvider(quotes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return provider, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown rates provider %q", cfg.Rates.Provider)
+	}
+}
+
+// newMailer returns the mail sender selected by cfg.Provider.
+func newMailer(cfg config.MailConfig) (mail.Mailer, error) {
+	switch cfg.Provider {
+	case "outbox":
+		return mail.NewOutbox(cfg.OutboxDir, cfg.From)
+	case "smtp":
+		return mail.NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mail provider %q", cfg.Provider)
+	}
+}
+
+// newStepUpPolicy parses the withdrawal thresholds of cfg, given as CURRENCY:amount.
+func newStepUpPolicy(cfg config.StepUpConfig) (handlers.StepUpPolicy, error) {
+	policy := handlers.StepUpPolicy{
+		MaxAge:             cfg.MaxAge,
+		WithdrawThresholds: make(map[string]money.Amount, len(cfg.WithdrawThresholds)),
+	}
+	for currency, value := range cfg.WithdrawThresholds {
+		threshold, err := money.Parse(value)
+		if err != nil {
+			return policy, fmt.Errorf("invalid withdraw threshold for %s: %w", currency, err)
+		}
+		policy.WithdrawThresholds[strings.ToUpper(currency)] = threshold
+	}
+	return policy, nil
+}
+
+// grantBootstrapAdmins grants the admin role to usernames once. Users who have not registered
+// yet are skipped for good, so whoever registers such a name later does not become an admin.
+func grantBootstrapAdmins(log *zap.Logger, store *postgres.Storage, usernames []string) {
+	for _, username := range usernames {
+		username = strings.TrimSpace(username)
+		if username == "" {
+			continue
+		}
+		granted, err := store.BootstrapRole(username, jwt.RoleAdmin)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("bootstrap admin is not registered, the admin role will not be granted to it",
+				zap.String("username", username))
+			continue
+		}
+		if err != nil {
+			panic(err)
+		}
+		if granted {
+			log.Info("granted the admin role to a bootstrap admin", zap.String("username", username))
+		}
+	}
+}
+
+// newUnverifiedDepositLimits parses the deposit limits of cfg, given as CURRENCY:amount.
+func newUnverifiedDepositLimits(cfg config.VerificationConfig) (handlers.UnverifiedDepositLimits, error) {
+	limits := make(handlers.UnverifiedDepositLimits, len(cfg.DepositLimits))
+	for currency, value := range cfg.DepositLimits {
+		limit, err := money.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unverified deposit limit for %s: %w", currency, err)
+		}
+		limits[strings.ToUpper(currency)] = limit
+	}
+	return limits, nil
 }
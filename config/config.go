@@ -4,19 +4,36 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type (
 	Config struct {
-		Server   ServerConfig
-		Database DatabaseConfig
-		JWT      JWTConfig
+		Server    ServerConfig
+		Database  DatabaseConfig
+		JWT       JWTConfig
+		Rates     RatesConfig
+		Exchanger ExchangerConfig
+		TwoFactor TwoFactorConfig
+		StepUp    StepUpConfig
+		Lockout   LockoutConfig
+		Mail      MailConfig
+		Reset     PasswordResetConfig
+		Verify    VerificationConfig
+		Admin     AdminConfig
 	}
 
+	// ServerConfig is the HTTP listener. It serves HTTPS when TLSCertFile and TLSKeyFile are set.
+	// The client IP is taken from X-Forwarded-For only for requests coming from TrustedProxies
+	// (addresses or CIDRs), with none the remote address is used.
 	ServerConfig struct {
-		Address string `env:"SERVER_ADDRESS"`
+		Address        string   `env:"SERVER_ADDRESS"`
+		TLSCertFile    string   `env:"SERVER_TLS_CERT_FILE"`
+		TLSKeyFile     string   `env:"SERVER_TLS_KEY_FILE"`
+		TLSMinVersion  string   `env:"SERVER_TLS_MIN_VERSION" env-default:"1.2"`
+		TrustedProxies []string `env:"SERVER_TRUSTED_PROXIES"`
 	}
 
 	DatabaseConfig struct {
@@ -28,9 +45,124 @@ type (
 		SSLMode  string `env:"DATABASE_SSLMODE"`
 	}
 
+	// RatesConfig selects where exchange rates come from: grpc (the exchanger service),
+	// postgres (the currency table), file (a JSON file of quotes) or static (quotes in the config).
+	RatesConfig struct {
+		Provider string            `env:"RATES_PROVIDER" env-default:"grpc"`
+		File     string            `env:"RATES_FILE"`
+		Static   map[string]string `env:"RATES_STATIC"`
+		// CacheTTL is how long fetched rates are reused, MaxStale how old they may get
+		// while the provider is failing.
+		CacheTTL time.Duration `env:"RATES_CACHE_TTL" env-default:"10s"`
+		MaxStale time.Duration `env:"RATES_MAX_STALE" env-default:"5m"`
+	}
+
+	// ExchangerConfig is the connection to the grpcexchanger service. Every call is bounded by
+	// CallTimeout and retried up to MaxRetries times on transient errors, BreakerFailures
+	// failures in a row stop calls for BreakerOpenTimeout.
+	// With TLS the server is verified against TLSCAFile and TLSServerName, TLSCertFile and
+	// TLSKeyFile are the client certificate for mutual TLS.
+	ExchangerConfig struct {
+		Host               string        `env:"EXCHANGER_HOST"`
+		TLS                bool          `env:"EXCHANGER_TLS"`
+		TLSCAFile          string        `env:"EXCHANGER_TLS_CA_FILE"`
+		TLSCertFile        string        `env:"EXCHANGER_TLS_CERT_FILE"`
+		TLSKeyFile         string        `env:"EXCHANGER_TLS_KEY_FILE"`
+		TLSServerName      string        `env:"EXCHANGER_TLS_SERVER_NAME"`
+		TLSMinVersion      string        `env:"EXCHANGER_TLS_MIN_VERSION" env-default:"1.2"`
+		CallTimeout        time.Duration `env:"EXCHANGER_CALL_TIMEOUT" env-default:"2s"`
+		MaxRetries         int           `env:"EXCHANGER_MAX_RETRIES" env-default:"2"`
+		RetryBackoff       time.Duration `env:"EXCHANGER_RETRY_BACKOFF" env-default:"100ms"`
+		BreakerFailures    int           `env:"EXCHANGER_BREAKER_FAILURES" env-default:"5"`
+		BreakerOpenTimeout time.Duration `env:"EXCHANGER_BREAKER_OPEN_TIMEOUT" env-default:"30s"`
+	}
+
+	// TwoFactorConfig is TOTP two-factor authentication. Issuer is the account label shown in
+	// authenticator apps, a login waiting for the code expires after ChallengeTTL.
+	TwoFactorConfig struct {
+		Issuer       string        `env:"TOTP_ISSUER" env-default:"Transferer"`
+		ChallengeTTL time.Duration `env:"TOTP_CHALLENGE_TTL" env-default:"5m"`
+	}
+
+	// StepUpConfig is when sensitive operations need the user to have authenticated within MaxAge:
+	// withdrawals above WithdrawThresholds of their currency, e.g. "USD:1000,EUR:1000",
+	// transfers to recipients the user never sent money to and password and email changes.
+	StepUpConfig struct {
+		MaxAge             time.Duration     `env:"STEP_UP_MAX_AGE" env-default:"5m"`
+		WithdrawThresholds map[string]string `env:"STEP_UP_WITHDRAW_THRESHOLDS"`
+	}
+
+	// LockoutConfig throttles password guessing. Failed logins are counted per username and per
+	// client IP, failures older than Window are forgotten. After FreeAttempts failures every
+	// further one delays the next login by Backoff, doubled each time up to MaxBackoff.
+	// MaxFailures failures of a username lock it for LockoutDuration and mail the user a link
+	// to unlock it, valid for UnlockTokenTTL. IPMaxFailures failures of an IP block it for
+	// LockoutDuration.
+	LockoutConfig struct {
+		FreeAttempts    int           `env:"LOGIN_FREE_ATTEMPTS" env-default:"3"`
+		MaxFailures     int           `env:"LOGIN_MAX_FAILURES" env-default:"10"`
+		IPMaxFailures   int           `env:"LOGIN_IP_MAX_FAILURES" env-default:"50"`
+		Backoff         time.Duration `env:"LOGIN_BACKOFF" env-default:"1s"`
+		MaxBackoff      time.Duration `env:"LOGIN_MAX_BACKOFF" env-default:"5m"`
+		LockoutDuration time.Duration `env:"LOGIN_LOCKOUT_DURATION" env-default:"30m"`
+		Window          time.Duration `env:"LOGIN_FAILURE_WINDOW" env-default:"15m"`
+		UnlockTokenTTL  time.Duration `env:"LOGIN_UNLOCK_TOKEN_TTL" env-default:"24h"`
+		UnlockURL       string        `env:"LOGIN_UNLOCK_URL"`
+	}
+
+	// MailConfig is outgoing mail. Provider "outbox" writes messages to OutboxDir as .eml files,
+	// "smtp" sends them through SMTPHost:SMTPPort, authenticating when SMTPUsername is set.
+	MailConfig struct {
+		Provider     string `env:"MAIL_PROVIDER" env-default:"outbox"`
+		From         string `env:"MAIL_FROM" env-default:"Transferer <no-reply@transferer.local>"`
+		OutboxDir    string `env:"MAIL_OUTBOX_DIR" env-default:"outbox"`
+		SMTPHost     string `env:"SMTP_HOST"`
+		SMTPPort     int    `env:"SMTP_PORT" env-default:"587"`
+		SMTPUsername string `env:"SMTP_USERNAME"`
+		SMTPPassword string `env:"SMTP_PASSWORD"`
+	}
+
+	// PasswordResetConfig is the forgotten password flow. The mailed link points to URL and
+	// is valid for TokenTTL, without URL the token itself is mailed. An email is mailed at most
+	// one link every ResendInterval.
+	PasswordResetConfig struct {
+		TokenTTL       time.Duration `env:"PASSWORD_RESET_TOKEN_TTL" env-default:"1h"`
+		ResendInterval time.Duration `env:"PASSWORD_RESET_RESEND_INTERVAL" env-default:"5m"`
+		URL            string        `env:"PASSWORD_RESET_URL"`
+	}
+
+	// VerificationConfig is email verification. The mailed link points to URL and is valid for
+	// TokenTTL, a new one can be requested every ResendInterval. Until the email is verified
+	// withdrawals and transfers are refused and the deposits of a currency may not add up to
+	// more than its DepositLimits, e.g. "USD:1000,EUR:1000". Other currencies cannot be deposited.
+	VerificationConfig struct {
+		TokenTTL       time.Duration     `env:"EMAIL_VERIFICATION_TOKEN_TTL" env-default:"48h"`
+		ResendInterval time.Duration     `env:"EMAIL_VERIFICATION_RESEND_INTERVAL" env-default:"1m"`
+		URL            string            `env:"EMAIL_VERIFICATION_URL"`
+		DepositLimits  map[string]string `env:"UNVERIFIED_DEPOSIT_LIMITS"`
+	}
+
+	// AdminConfig is the back office. BootstrapAdmins, e.g. "alice,bob", are granted the admin
+	// role at the first startup listing them if registered by then, so the first admin does not
+	// have to be set up in the database by hand.
+	// Manual balance adjustments expire unless another admin approves them within AdjustmentTTL.
+	AdminConfig struct {
+		BootstrapAdmins []string      `env:"ADMIN_USERNAMES"`
+		AdjustmentTTL   time.Duration `env:"ADJUSTMENT_APPROVAL_TTL" env-default:"24h"`
+	}
+
+	// JWTConfig holds the token signing keys. Keys are HS256 secrets by key id, e.g.
+	// "2025-01:secret1,2025-06:secret2", KeyFiles are PEM files of RSA or Ed25519 keys by key id,
+	// JWTSecret is added to them under the "default" id.
+	// Tokens are signed with SigningKeyID and validated with any key.
+	// Access tokens live for AccessTokenTTL, refresh tokens for RefreshTokenTTL.
 	JWTConfig struct {
-		JWTSecret              string `env:"JWT_SECRET"`
-		JWTExpirationTimeHours int    `env:"JWT_EXPIRATION_TIME_HOURS"`
+		JWTSecret       string            `env:"JWT_SECRET"`
+		Keys            map[string]string `env:"JWT_KEYS"`
+		KeyFiles        map[string]string `env:"JWT_KEY_FILES"`
+		SigningKeyID    string            `env:"JWT_SIGNING_KEY_ID"`
+		AccessTokenTTL  time.Duration     `env:"JWT_ACCESS_TOKEN_TTL" env-default:"15m"`
+		RefreshTokenTTL time.Duration     `env:"JWT_REFRESH_TOKEN_TTL" env-default:"720h"`
 	}
 )
 
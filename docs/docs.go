@@ -15,43 +15,34 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/balance": {
+        "/.well-known/jwks.json": {
             "get": {
-                "description": "Получает баланс пользователя на основе предоставленного токена.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает публичные ключи RS256 и EdDSA в формате JWKS для проверки токенов другими сервисами. Секреты HS256 не публикуются.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "balance"
+                    "auth"
                 ],
-                "summary": "Получение баланса пользователя",
+                "summary": "Публичные ключи JWT",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/requests.BalanceResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Не авторизован",
-                        "schema": {
-                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                            "$ref": "#/definitions/jwt.JWKS"
                         }
                     }
                 }
             }
         },
-        "/api/v1/deposit": {
+        "/api/v1/2fa/confirm": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Пополняет баланс пользователя на указанную сумму в указанной валюте.",
+                "description": "Включает двухфакторную аутентификацию по коду из приложения-аутентификатора и возвращает коды восстановления.",
                 "consumes": [
                     "application/json"
                 ],
@@ -59,29 +50,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "deposit"
+                    "2fa"
                 ],
-                "summary": "Пополнение баланса пользователя",
+                "summary": "Подтверждение двухфакторной аутентификации",
                 "parameters": [
                     {
-                        "description": "Данные для пополнения",
+                        "description": "Код из приложения",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/requests.DepositRequest"
+                            "$ref": "#/definitions/requests.TwoFactorConfirmRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Двухфакторная аутентификация включена",
                         "schema": {
-                            "$ref": "#/definitions/requests.DepositResponse"
+                            "$ref": "#/definitions/requests.TwoFactorConfirmResponse"
                         }
                     },
                     "400": {
-                        "description": "Некорректный запрос",
+                        "description": "Некорректный запрос или неверный код",
                         "schema": {
                             "$ref": "#/definitions/requests.BadRequestError"
                         }
@@ -91,18 +82,74 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/requests.NotAuthorizedError"
                         }
+                    },
+                    "409": {
+                        "description": "Двухфакторная аутентификация уже включена или не подключена",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TwoFactorConflictError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/exchange": {
+        "/api/v1/2fa/setup": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Создает секрет TOTP (RFC 6238) и возвращает otpauth URI и QR код для приложения-аутентификатора.\nС заголовком Accept: image/png возвращает только PNG изображение QR кода.\nДвухфакторная аутентификация включается после подтверждения кодом.",
+                "produces": [
+                    "application/json",
+                    "image/png"
+                ],
+                "tags": [
+                    "2fa"
+                ],
+                "summary": "Подключение двухфакторной аутентификации",
+                "responses": {
+                    "200": {
+                        "description": "Секрет создан",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TwoFactorSetupResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "409": {
+                        "description": "Двухфакторная аутентификация уже включена",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TwoFactorConflictError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/account/email": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Выполняет обмен валюты пользователя из одной валюты в другую.",
+                "description": "Меняет email пользователя. Требует повторной аутентификации через /api/v1/reauth.\nНовый email не подтвержден, на него отправляется письмо для подтверждения.",
                 "consumes": [
                     "application/json"
                 ],
@@ -110,25 +157,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "exchange"
+                    "account"
                 ],
-                "summary": "Обмен валюты пользователя",
+                "summary": "Смена email",
                 "parameters": [
                     {
-                        "description": "Данные для обмена",
+                        "description": "Новый email",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/requests.ExchangeRequest"
+                            "$ref": "#/definitions/requests.ChangeEmailRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Email изменен",
                         "schema": {
-                            "$ref": "#/definitions/requests.ExchangeResponse"
+                            "$ref": "#/definitions/requests.AccountUpdatedResponse"
                         }
                     },
                     "400": {
@@ -138,17 +185,34 @@ const docTemplate = `{
                         }
                     },
                     "401": {
-                        "description": "Не авторизован",
+                        "description": "Не авторизован или требуется повторная аутентификация",
                         "schema": {
-                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                            "$ref": "#/definitions/requests.StepUpRequiredError"
+                        }
+                    },
+                    "409": {
+                        "description": "Email уже используется",
+                        "schema": {
+                            "$ref": "#/definitions/requests.EmailTakenError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
                         }
                     }
                 }
             }
         },
-        "/api/v1/login": {
+        "/api/v1/account/password": {
             "post": {
-                "description": "Аутентифицирует пользователя и возвращает JWT токен.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Меняет пароль пользователя. Требует повторной аутентификации через /api/v1/reauth.\nВсе остальные сессии пользователя отзываются.",
                 "consumes": [
                     "application/json"
                 ],
@@ -156,26 +220,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "account"
                 ],
-                "summary": "Аутентификация пользователя",
+                "summary": "Смена пароля",
                 "parameters": [
                     {
-                        "description": "Логин пользователя",
+                        "description": "Новый пароль",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/requests.LoginRequest"
+                            "$ref": "#/definitions/requests.ChangePasswordRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешная аутентификация",
+                        "description": "Пароль изменен",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/requests.AccountUpdatedResponse"
                         }
                     },
                     "400": {
@@ -184,38 +247,139 @@ const docTemplate = `{
                             "$ref": "#/definitions/requests.BadRequestError"
                         }
                     },
+                    "401": {
+                        "description": "Не авторизован или требуется повторная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/requests.StepUpRequiredError"
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/requests.CantCreateJWTError"
+                            "$ref": "#/definitions/requests.BadRequestError"
                         }
                     }
                 }
             }
         },
-        "/api/v1/rates": {
+        "/api/v1/admin/adjustments": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Получает текущие курсы валют.",
-                "consumes": [
+                "description": "Возвращает заявки на корректировку баланса, начиная с самых новых, вместе с тем, кто их\nсоздал, одобрил или отклонил. Доступно ролям support, admin и auditor.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Заявки на корректировку баланса",
+                "parameters": [
+                    {
+                        "enum": [
+                            "pending",
+                            "approved",
+                            "rejected",
+                            "expired"
+                        ],
+                        "type": "string",
+                        "description": "Статус заявки",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пользователь, чей баланс корректируется",
+                        "name": "username",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Размер страницы, не больше 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "next_offset из предыдущего ответа",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Заявки",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BalanceAdjustmentsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForbiddenError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/adjustments/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает заявку на корректировку баланса. Доступно ролям support, admin и auditor.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "rates"
+                    "admin"
+                ],
+                "summary": "Заявка на корректировку баланса",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Номер заявки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Получение курсов валют",
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Заявка",
                         "schema": {
-                            "$ref": "#/definitions/requests.RatesResponse"
+                            "$ref": "#/definitions/requests.BalanceAdjustmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
                         }
                     },
                     "401": {
@@ -224,79 +388,111 @@ const docTemplate = `{
                             "$ref": "#/definitions/requests.NotAuthorizedError"
                         }
                     },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForbiddenError"
+                        }
+                    },
+                    "404": {
+                        "description": "Заявка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AdjustmentNotFoundError"
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/requests.RetrieveRatesError"
+                            "$ref": "#/definitions/requests.BadRequestError"
                         }
                     }
                 }
             }
         },
-        "/api/v1/register": {
+        "/api/v1/admin/adjustments/{id}/approve": {
             "post": {
-                "description": "Регистрирует нового пользователя в системе.",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Проводит заявку на корректировку баланса. Одобрить заявку может только другой администратор,\nне тот, кто ее создал, и не тот, чей баланс корректируется. Если списание больше баланса,\nзаявка остается ожидающей.\nДоступно роли admin, требует недавней аутентификации.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Регистрация нового пользователя",
+                "summary": "Одобрение заявки на корректировку баланса",
                 "parameters": [
                     {
-                        "description": "Данные для регистрации",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/requests.RegisterRequest"
-                        }
+                        "type": "integer",
+                        "description": "Номер заявки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешная регистрация",
+                        "description": "Баланс скорректирован",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/requests.BalanceAdjustmentResponse"
                         }
                     },
                     "400": {
                         "description": "Некорректный запрос",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован или требуется повторная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/requests.StepUpRequiredError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав, заявка создана этим же администратором или корректирует его баланс",
+                        "schema": {
+                            "$ref": "#/definitions/requests.SelfApprovalError"
+                        }
+                    },
+                    "404": {
+                        "description": "Заявка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AdjustmentNotFoundError"
                         }
                     },
                     "409": {
-                        "description": "Конфликт (имя пользователя или email уже существует)",
+                        "description": "Заявка уже рассмотрена или списание больше баланса",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/requests.AdjustmentNotPendingError"
+                        }
+                    },
+                    "410": {
+                        "description": "Заявка истекла",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AdjustmentExpiredError"
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/requests.BadRequestError"
                         }
                     }
                 }
             }
         },
-        "/api/v1/withdraw": {
+        "/api/v1/admin/adjustments/{id}/reject": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Снимает указанную сумму в указанной валюте с баланса пользователя.",
+                "description": "Отклоняет заявку на корректировку баланса, баланс не меняется. Создатель заявки может\nотклонить ее сам, чтобы отозвать. Доступно роли admin.",
                 "consumes": [
                     "application/json"
                 ],
@@ -304,25 +500,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "withdraw"
+                    "admin"
                 ],
-                "summary": "Снятие средств с баланса пользователя",
+                "summary": "Отклонение заявки на корректировку баланса",
                 "parameters": [
                     {
-                        "description": "Данные для снятия",
+                        "type": "integer",
+                        "description": "Номер заявки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Причина отклонения",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/requests.WithdrawRequest"
+                            "$ref": "#/definitions/requests.RejectAdjustmentRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Заявка отклонена",
                         "schema": {
-                            "$ref": "#/definitions/requests.DepositResponse"
+                            "$ref": "#/definitions/requests.BalanceAdjustmentResponse"
                         }
                     },
                     "400": {
@@ -338,46 +541,2840 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "Недостаточно средств",
+                        "description": "Недостаточно прав",
                         "schema": {
-                            "$ref": "#/definitions/requests.NotEnoughFundsError"
+                            "$ref": "#/definitions/requests.ForbiddenError"
+                        }
+                    },
+                    "404": {
+                        "description": "Заявка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AdjustmentNotFoundError"
+                        }
+                    },
+                    "409": {
+                        "description": "Заявка уже рассмотрена",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AdjustmentNotPendingError"
+                        }
+                    },
+                    "410": {
+                        "description": "Заявка истекла",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AdjustmentExpiredError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "requests.BadRequestError": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "invalid json data"
-                },
-                "status": {
+        },
+        "/api/v1/admin/users": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает пользователей, у которых имя или email содержат q, в порядке регистрации.\nДоступно ролям support, admin и auditor.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Поиск пользователей",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "john",
+                        "description": "Часть имени пользователя или email",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "user",
+                            "support",
+                            "admin",
+                            "auditor"
+                        ],
+                        "type": "string",
+                        "description": "Роль пользователя",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Размер страницы, не больше 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "next_offset из предыдущего ответа",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пользователи",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AdminUsersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForbiddenError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users/{username}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает пользователя с его ролями и состоянием заморозки. Доступно ролям support, admin и auditor.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Пользователь",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя пользователя",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пользователь",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AdminUser"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForbiddenError"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/requests.UserNotFoundError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users/{username}/adjustments": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Создает заявку на зачисление положительной или списание отрицательной суммы с кошелька пользователя.\nБаланс изменится только после одобрения заявки другим администратором, неодобренная заявка истекает.\nПричина обязательна. Корректировать свой баланс нельзя. Доступно роли admin, требует недавней аутентификации.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Заявка на ручную корректировку баланса",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя пользователя",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Валюта, сумма и причина корректировки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.BalanceAdjustmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Заявка создана",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BalanceAdjustmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован или требуется повторная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/requests.StepUpRequiredError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав или корректировка своего баланса",
+                        "schema": {
+                            "$ref": "#/definitions/requests.SelfAdjustmentError"
+                        }
+                    },
+                    "404": {
+                        "description": "У пользователя нет кошелька в валюте",
+                        "schema": {
+                            "$ref": "#/definitions/requests.WalletNotFoundError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users/{username}/balance": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает баланс кошельков любого пользователя. Доступно ролям support, admin и auditor.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Баланс пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя пользователя",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BalanceResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForbiddenError"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/requests.UserNotFoundError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users/{username}/freeze": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Запрещает пользователю пополнения, снятия, обмены и переводы до разморозки. Заморозка уже\nзамороженного аккаунта сохраняет исходную причину. Доступно ролям support и admin,\nаккаунт администратора может заморозить только admin. Заморозить свой аккаунт нельзя.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Заморозка аккаунта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя пользователя",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Причина заморозки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.FreezeAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Аккаунт заморожен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AccountUpdatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или попытка заморозить свой аккаунт",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForbiddenError"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/requests.UserNotFoundError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users/{username}/roles": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Заменяет роли пользователя. Все сессии пользователя отзываются, новые роли попадут в токены при следующем входе.\nИзменение сохраняется в журнале изменений ролей вместе с тем, кто его сделал.\nАдминистратор не может снять роль admin с самого себя. Доступно роли admin, требует недавней аутентификации.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Изменение ролей пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя пользователя",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Роли пользователя",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.SetRolesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Роли изменены",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AccountUpdatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован или требуется повторная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/requests.StepUpRequiredError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForbiddenError"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/requests.UserNotFoundError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users/{username}/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает историю операций любого пользователя с теми же фильтрами, что и /api/v1/transactions.\nДоступно ролям support, admin и auditor.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "История операций пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя пользователя",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "deposit",
+                            "withdrawal",
+                            "exchange",
+                            "transfer_in",
+                            "transfer_out",
+                            "adjustment_in",
+                            "adjustment_out"
+                        ],
+                        "type": "string",
+                        "description": "Тип операции",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "USD",
+                        "description": "Валюта операции",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-03-01T00:00:00Z",
+                        "description": "Начало периода, RFC3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-04-01T00:00:00Z",
+                        "description": "Конец периода (не включительно), RFC3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "10",
+                        "description": "Минимальная сумма",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "1000",
+                        "description": "Максимальная сумма",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Размер страницы, не больше 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor из предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TransactionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForbiddenError"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/requests.UserNotFoundError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/users/{username}/unfreeze": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Снимает заморозку с аккаунта пользователя. Доступно ролям support и admin.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Разморозка аккаунта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя пользователя",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Аккаунт разморожен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AccountUpdatedResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForbiddenError"
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "$ref": "#/definitions/requests.UserNotFoundError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/balance": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Получает баланс пользователя на основе предоставленного токена.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "balance"
+                ],
+                "summary": "Получение баланса пользователя",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BalanceResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/email/verify": {
+            "post": {
+                "description": "Подтверждает email пользователя по токену из письма. Токен действует один раз и только\nдля email, на который он был отправлен.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Подтверждение email",
+                "parameters": [
+                    {
+                        "description": "Токен из письма",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.VerifyEmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email подтвержден",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AccountUpdatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или недействительный токен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.InvalidVerificationTokenError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/email/verify/resend": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отправляет новое письмо со ссылкой для подтверждения email. Ранее отправленные ссылки\nпродолжают действовать. Новое письмо можно запросить не чаще одного раза в интервал,\nзаголовок Retry-After содержит время ожидания в секундах.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Повторная отправка письма для подтверждения email",
+                "responses": {
+                    "202": {
+                        "description": "Письмо отправлено",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AccountUpdatedResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "409": {
+                        "description": "Email уже подтвержден",
+                        "schema": {
+                            "$ref": "#/definitions/requests.EmailAlreadyVerifiedError"
+                        }
+                    },
+                    "429": {
+                        "description": "Письмо отправлено недавно, повторить через Retry-After секунд",
+                        "schema": {
+                            "$ref": "#/definitions/requests.VerificationResendTooSoonError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно запросить новое письмо"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/exchange": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Выполняет обмен валюты пользователя из одной валюты в другую.\nС quote_id обмен выполняется ровно по курсу котировки из /api/v1/exchange/quote или не выполняется вовсе,\nfrom_currency, to_currency и amount в этом случае передавать нельзя.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "exchange"
+                ],
+                "summary": "Обмен валюты пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные для обмена",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.ExchangeRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности, повтор запроса с тем же ключом вернет первый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ExchangeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или нет курса для пары валют",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно средств",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotEnoughFundsError"
+                        }
+                    },
+                    "404": {
+                        "description": "Котировка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "409": {
+                        "description": "Ключ идемпотентности уже использован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.IdempotencyConflictError"
+                        }
+                    },
+                    "410": {
+                        "description": "Котировка истекла или уже использована",
+                        "schema": {
+                            "$ref": "#/definitions/requests.QuoteExpiredError"
+                        }
+                    },
+                    "503": {
+                        "description": "Источник курсов недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.RatesUnavailableError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/exchange/quote": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Фиксирует текущий курс обмена на 30 секунд и возвращает сумму, которую получит пользователь.\nОбмен по котировке выполняется через /api/v1/exchange с quote_id.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "exchange"
+                ],
+                "summary": "Котировка обмена валюты",
+                "parameters": [
+                    {
+                        "description": "Данные для котировки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.QuoteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.QuoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или нет курса для пары валют",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "500": {
+                        "description": "Ошибка получения курсов",
+                        "schema": {
+                            "$ref": "#/definitions/requests.RetrieveRatesError"
+                        }
+                    },
+                    "503": {
+                        "description": "Источник курсов недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.RatesUnavailableError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/exchange/rates": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Получает текущие курсы валют и время, когда они были получены.\nЕсли источник курсов недоступен, возвращаются последние полученные курсы с признаком stale.\nС параметром base дополнительно возвращает лучший курс базовой валюты ко всем остальным, в том числе через промежуточные валюты.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rates"
+                ],
+                "summary": "Получение курсов валют",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "USD",
+                        "description": "Базовая валюта",
+                        "name": "base",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.RatesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Неизвестная базовая валюта",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.RetrieveRatesError"
+                        }
+                    },
+                    "503": {
+                        "description": "Источник курсов недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.RatesUnavailableError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/exchange/rates/history": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает курсы пары, полученные от источника курсов за период, или свечи OHLC, если указан interval.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rates"
+                ],
+                "summary": "История курса валютной пары",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "RUB_USD",
+                        "description": "Валютная пара",
+                        "name": "pair",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-03-01T00:00:00Z",
+                        "description": "Начало периода, RFC3339, по умолчанию сутки до конца периода",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-03-02T00:00:00Z",
+                        "description": "Конец периода (не включительно), RFC3339, по умолчанию текущее время",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "1h",
+                        "description": "Размер свечи, не меньше 1m",
+                        "name": "interval",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.RateHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/login": {
+            "post": {
+                "description": "Аутентифицирует пользователя и возвращает короткоживущий access токен и refresh токен.\nЕсли у пользователя включена двухфакторная аутентификация, возвращает challenge_token,\nтокены выдаются после подтверждения входа кодом в /api/v1/login/2fa.\nНеудачные попытки считаются по имени пользователя и IP: после нескольких попыток вход задерживается,\nпосле слишком многих аккаунт блокируется и пользователю отправляется ссылка для разблокировки.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Аутентификация пользователя",
+                "parameters": [
+                    {
+                        "description": "Логин пользователя",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TokenResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Требуется код двухфакторной аутентификации",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TwoFactorChallengeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверное имя пользователя или пароль",
+                        "schema": {
+                            "$ref": "#/definitions/requests.WrongPasswordError"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток, повторить через Retry-After секунд",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TooManyLoginAttemptsError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить вход"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.CantCreateJWTError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/login/2fa": {
+            "post": {
+                "description": "Завершает вход пользователя с двухфакторной аутентификацией. Принимает код из приложения-аутентификатора\nили неиспользованный код восстановления и возвращает токены.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Подтверждение входа кодом",
+                "parameters": [
+                    {
+                        "description": "Challenge токен и код",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.TwoFactorLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный код или challenge токен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.InvalidTwoFactorCodeError"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток, повторить через Retry-After секунд",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TooManyLoginAttemptsError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить вход"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.CantCreateJWTError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/login/unlock": {
+            "post": {
+                "description": "Снимает блокировку входа по токену из письма, отправленного при блокировке аккаунта.\nТокен действует один раз.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Разблокировка аккаунта",
+                "parameters": [
+                    {
+                        "description": "Токен из письма",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.UnlockAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Аккаунт разблокирован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.UnlockAccountResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или недействительный токен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.InvalidUnlockTokenError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/logout": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Отзывает access токен и все refresh токены его сессии.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Выход",
+                "responses": {
+                    "200": {
+                        "description": "Выход выполнен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.LogoutResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/password/forgot": {
+            "post": {
+                "description": "Отправляет на email пользователя письмо со ссылкой для сброса пароля. Ссылка действует один раз\nи ограниченное время. Ответ одинаковый независимо от того, зарегистрирован ли email,\nписьмо отправляется в фоне. На один email письмо отправляется не чаще PASSWORD_RESET_RESEND_INTERVAL.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Запрос сброса пароля",
+                "parameters": [
+                    {
+                        "description": "Email аккаунта",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Запрос принят",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ForgotPasswordResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/password/reset": {
+            "post": {
+                "description": "Устанавливает новый пароль по токену из письма. Токен действует один раз, после сброса\nвсе сессии пользователя завершаются и блокировка входа снимается.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Сброс пароля",
+                "parameters": [
+                    {
+                        "description": "Токен из письма и новый пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пароль изменен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.AccountUpdatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или недействительный токен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.InvalidResetTokenError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/profile": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает имя пользователя, email и подтвержден ли email.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Профиль пользователя",
+                "responses": {
+                    "200": {
+                        "description": "Профиль",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ProfileResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/reauth": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Подтверждает личность пользователя паролем или кодом двухфакторной аутентификации и возвращает\nновые токены той же сессии со свежим временем аутентификации. Нужна перед крупными снятиями,\nпервым переводом новому получателю и сменой пароля или email.\nНеудачные попытки ограничиваются так же, как при входе.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Повторная аутентификация",
+                "parameters": [
+                    {
+                        "description": "Пароль или код",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.ReauthRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Аутентификация подтверждена",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован, неверный пароль или код",
+                        "schema": {
+                            "$ref": "#/definitions/requests.ReauthFailedError"
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток, повторить через Retry-After секунд",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TooManyLoginAttemptsError"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Через сколько секунд можно повторить попытку"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.CantCreateJWTError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/register": {
+            "post": {
+                "description": "Регистрирует нового пользователя в системе и отправляет на email письмо для его подтверждения.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Регистрация нового пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные для регистрации",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешная регистрация",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Конфликт (имя пользователя или email уже существует)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/token/refresh": {
+            "post": {
+                "description": "Меняет refresh токен на новую пару access и refresh токенов. Каждый refresh токен используется один раз,\nповторное использование refresh токена отзывает все токены его сессии.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Обновление токенов",
+                "parameters": [
+                    {
+                        "description": "Refresh токен",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Токены обновлены",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Refresh токен недействителен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.InvalidRefreshTokenError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.CantCreateJWTError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Возвращает пополнения, снятия, обмены, переводы и корректировки баланса пользователя, начиная с самых новых.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "История операций пользователя",
+                "parameters": [
+                    {
+                        "enum": [
+                            "deposit",
+                            "withdrawal",
+                            "exchange",
+                            "transfer_in",
+                            "transfer_out",
+                            "adjustment_in",
+                            "adjustment_out"
+                        ],
+                        "type": "string",
+                        "description": "Тип операции",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "USD",
+                        "description": "Валюта операции",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-03-01T00:00:00Z",
+                        "description": "Начало периода, RFC3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-04-01T00:00:00Z",
+                        "description": "Конец периода (не включительно), RFC3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "10",
+                        "description": "Минимальная сумма",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "1000",
+                        "description": "Максимальная сумма",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Размер страницы, не больше 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor из предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TransactionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transfers": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Переводит сумму в указанной валюте другому пользователю по имени или email.\nЕсли у получателя нет кошелька в этой валюте, при auto_convert сумма конвертируется в валюту его основного кошелька.\nПервый перевод новому получателю требует повторной аутентификации через /api/v1/reauth.\nНедоступно до подтверждения email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfers"
+                ],
+                "summary": "Перевод другому пользователю",
+                "parameters": [
+                    {
+                        "description": "Данные для перевода",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.TransferRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности, повтор запроса с тем же ключом вернет первый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.TransferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос, нет кошелька в валюте перевода, нет курса для пары валют, получатель не найден или не может принять перевод",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован или требуется повторная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/requests.StepUpRequiredError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно средств или email не подтвержден",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotEnoughFundsError"
+                        }
+                    },
+                    "409": {
+                        "description": "Ключ идемпотентности уже использован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.IdempotencyConflictError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "503": {
+                        "description": "Источник курсов недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/requests.RatesUnavailableError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallet/deposit": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Пополняет баланс пользователя на указанную сумму в указанной валюте.\nДо подтверждения email сумма пополнений в валюте ограничена лимитом, валюты без лимита пополнить нельзя.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "deposit"
+                ],
+                "summary": "Пополнение баланса пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные для пополнения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.DepositRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности, повтор запроса с тем же ключом вернет первый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.DepositResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotAuthorizedError"
+                        }
+                    },
+                    "403": {
+                        "description": "Превышен лимит пополнений до подтверждения email",
+                        "schema": {
+                            "$ref": "#/definitions/requests.UnverifiedDepositLimitError"
+                        }
+                    },
+                    "409": {
+                        "description": "Ключ идемпотентности уже использован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.IdempotencyConflictError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/wallet/withdraw": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Снимает указанную сумму в указанной валюте с баланса пользователя.\nЕсли сумма снятий в валюте за STEP_UP_MAX_AGE превысит порог валюты, нужна повторная\nаутентификация через /api/v1/reauth.\nНедоступно до подтверждения email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "withdraw"
+                ],
+                "summary": "Снятие средств с баланса пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные для снятия",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/requests.WithdrawRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности, повтор запроса с тем же ключом вернет первый ответ",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/requests.DepositResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/requests.BadRequestError"
+                        }
+                    },
+                    "401": {
+                        "description": "Не авторизован или требуется повторная аутентификация",
+                        "schema": {
+                            "$ref": "#/definitions/requests.StepUpRequiredError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно средств или email не подтвержден",
+                        "schema": {
+                            "$ref": "#/definitions/requests.NotEnoughFundsError"
+                        }
+                    },
+                    "409": {
+                        "description": "Ключ идемпотентности уже использован",
+                        "schema": {
+                            "$ref": "#/definitions/requests.IdempotencyConflictError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "jwt.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "description": "Ed25519",
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "description": "RSA",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "jwt.JWKS": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/jwt.JWK"
+                    }
+                }
+            }
+        },
+        "requests.AccountUpdatedResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "password changed"
+                }
+            }
+        },
+        "requests.AdjustmentExpiredError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "adjustment expired"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.AdjustmentNotFoundError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "adjustment not found"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.AdjustmentNotPendingError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "adjustment is not pending"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.AdminUser": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "email_verified": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "frozen": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "frozen_reason": {
+                    "type": "string",
+                    "example": "suspicious activity"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "user"
+                    ]
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "requests.AdminUsersResponse": {
+            "type": "object",
+            "properties": {
+                "next_offset": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/requests.AdminUser"
+                    }
+                }
+            }
+        },
+        "requests.BadRequestError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "invalid json data"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.BalanceAdjustmentRequest": {
+            "type": "object",
+            "required": [
+                "currency",
+                "reason"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "-25.00"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "refund of a duplicated withdrawal"
+                }
+            }
+        },
+        "requests.BalanceAdjustmentResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "-25"
+                },
+                "balance": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-03-01T12:00:00Z"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "alice"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "entry_id": {
+                    "type": "integer",
+                    "example": 108
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-03-02T12:00:00Z"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "refund of a duplicated withdrawal"
+                },
+                "review_note": {
+                    "type": "string",
+                    "example": "wrong currency"
+                },
+                "reviewed_at": {
+                    "type": "string",
+                    "example": "2025-03-01T12:30:00Z"
+                },
+                "reviewed_by": {
+                    "type": "string",
+                    "example": "bob"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "approved"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "requests.BalanceAdjustmentsResponse": {
+            "type": "object",
+            "properties": {
+                "adjustments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/requests.BalanceAdjustmentResponse"
+                    }
+                },
+                "next_offset": {
+                    "type": "integer",
+                    "example": 20
+                }
+            }
+        },
+        "requests.BalanceResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "requests.CantCreateJWTError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "could not create JWT token"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.ChangeEmailRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john.new@example.com"
+                }
+            }
+        },
+        "requests.ChangePasswordRequest": {
+            "type": "object",
+            "required": [
+                "new_password"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "example": "new_secure_password"
+                }
+            }
+        },
+        "requests.CrossRate": {
+            "type": "object",
+            "properties": {
+                "path": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "USD",
+                        "RUB",
+                        "EUR"
+                    ]
+                },
+                "rate": {
+                    "type": "string",
+                    "example": "0.91666667"
+                }
+            }
+        },
+        "requests.DepositRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "currency"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "50.00"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                }
+            }
+        },
+        "requests.DepositResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "Account topped up successfully"
+                }
+            }
+        },
+        "requests.EmailAlreadyVerifiedError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "email is already verified"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.EmailTakenError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "email is taken"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.ExchangeRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "20.00"
+                },
+                "from_currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "quote_id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "to_currency": {
+                    "type": "string",
+                    "example": "EUR"
+                }
+            }
+        },
+        "requests.ExchangeResponse": {
+            "type": "object",
+            "properties": {
+                "exchanged_amount": {
+                    "type": "string",
+                    "example": "17.00"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "exchanged successfully"
+                },
+                "new_balance": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "requests.ForbiddenError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "insufficient permissions"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.ForgotPasswordRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                }
+            }
+        },
+        "requests.ForgotPasswordResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "if the email is registered, a password reset link has been sent"
+                }
+            }
+        },
+        "requests.FreezeAccountRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "suspicious activity"
+                }
+            }
+        },
+        "requests.IdempotencyConflictError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "idempotency key was already used for a different request"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.InvalidRefreshTokenError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "invalid refresh token"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.InvalidResetTokenError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "invalid or expired password reset token"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.InvalidTwoFactorCodeError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "invalid code"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.InvalidUnlockTokenError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "invalid or expired unlock token"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.InvalidVerificationTokenError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "invalid or expired email verification token"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.LoginRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "secure_password"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "requests.LogoutResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "logged out"
+                }
+            }
+        },
+        "requests.NotAuthorizedError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "invalid token"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.NotEnoughFundsError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "not enough money to withdraw"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.ProfileResponse": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "email_verified": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "requests.QuoteExpiredError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "quote expired"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.QuoteRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "from_currency",
+                "to_currency"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "20.00"
+                },
+                "from_currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "to_currency": {
+                    "type": "string",
+                    "example": "EUR"
+                }
+            }
+        },
+        "requests.QuoteResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "20.00"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-03-01T12:00:30Z"
+                },
+                "from_currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "path": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "USD",
+                        "RUB",
+                        "EUR"
+                    ]
+                },
+                "quote_id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "rate": {
+                    "type": "string",
+                    "example": "0.91666667"
+                },
+                "to_amount": {
+                    "type": "string",
+                    "example": "18.33333340"
+                },
+                "to_currency": {
+                    "type": "string",
+                    "example": "EUR"
+                }
+            }
+        },
+        "requests.RateCandle": {
+            "type": "object",
+            "properties": {
+                "close": {
+                    "type": "string",
+                    "example": "0.0121"
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 360
+                },
+                "high": {
+                    "type": "string",
+                    "example": "0.0125"
+                },
+                "low": {
+                    "type": "string",
+                    "example": "0.0119"
+                },
+                "open": {
+                    "type": "string",
+                    "example": "0.012"
+                },
+                "time": {
+                    "type": "string",
+                    "example": "2025-03-01T12:00:00Z"
+                }
+            }
+        },
+        "requests.RateHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "candles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/requests.RateCandle"
+                    }
+                },
+                "from": {
+                    "type": "string",
+                    "example": "2025-03-01T00:00:00Z"
+                },
+                "interval": {
+                    "type": "string",
+                    "example": "1h0m0s"
+                },
+                "pair": {
+                    "type": "string",
+                    "example": "RUB_USD"
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/requests.RatePoint"
+                    }
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2025-03-02T00:00:00Z"
+                }
+            }
+        },
+        "requests.RatePoint": {
+            "type": "object",
+            "properties": {
+                "rate": {
+                    "type": "string",
+                    "example": "0.012"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "grpc"
+                },
+                "time": {
+                    "type": "string",
+                    "example": "2025-03-01T12:00:00Z"
+                }
+            }
+        },
+        "requests.RatesResponse": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "cross_rates": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/requests.CrossRate"
+                    }
+                },
+                "rates": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "stale": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2025-03-01T12:00:00Z"
+                }
+            }
+        },
+        "requests.RatesUnavailableError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "exchange rates are temporarily unavailable"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.ReauthFailedError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "wrong password"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.ReauthRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "secure_password"
+                }
+            }
+        },
+        "requests.RefreshTokenRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string",
+                    "example": "Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6"
+                }
+            }
+        },
+        "requests.RegisterRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "secure_password"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "requests.RejectAdjustmentRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "example": "wrong currency"
+                }
+            }
+        },
+        "requests.ResetPasswordRequest": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "token"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "example": "new_secure_password"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"
+                }
+            }
+        },
+        "requests.RetrieveRatesError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "failed to retrieve exchange rates"
+                },
+                "status": {
                     "type": "string",
                     "example": "error"
                 }
             }
         },
-        "requests.BalanceResponse": {
+        "requests.SelfAdjustmentError": {
             "type": "object",
             "properties": {
-                "balance": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "number"
-                    }
+                "error": {
+                    "type": "string",
+                    "example": "admins cannot adjust their own balance"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
                 }
             }
         },
-        "requests.CantCreateJWTError": {
+        "requests.SelfApprovalError": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string",
-                    "example": "could not create JWT token"
+                    "example": "adjustment cannot be approved by its proposer"
                 },
                 "status": {
                     "type": "string",
@@ -385,102 +3382,315 @@ const docTemplate = `{
                 }
             }
         },
-        "requests.DepositRequest": {
+        "requests.SetRolesRequest": {
+            "type": "object",
+            "required": [
+                "roles"
+            ],
+            "properties": {
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "user",
+                        "support"
+                    ]
+                }
+            }
+        },
+        "requests.StepUpRequiredError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "fresh authentication required"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-03-01T12:15:00Z"
+                },
+                "refresh_token": {
+                    "type": "string",
+                    "example": "Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6"
+                },
+                "refresh_token_expires_at": {
+                    "type": "string",
+                    "example": "2025-03-31T12:00:00Z"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsImtpZCI6ImRlZmF1bHQiLCJ0eXAiOiJKV1QifQ..."
+                }
+            }
+        },
+        "requests.TooManyLoginAttemptsError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "too many failed login attempts"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        },
+        "requests.Transaction": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "20"
+                },
+                "counterparty": {
+                    "type": "string",
+                    "example": "jane_doe"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2025-03-01T12:00:00Z"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "rate": {
+                    "type": "string",
+                    "example": "0.85"
+                },
+                "to_amount": {
+                    "type": "string",
+                    "example": "17"
+                },
+                "to_currency": {
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "exchange"
+                }
+            }
+        },
+        "requests.TransactionsResponse": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string",
+                    "example": "MTc0MDgzMDQwMDAwMDAwMDAwMDo0Mg"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/requests.Transaction"
+                    }
+                }
+            }
+        },
+        "requests.TransferRequest": {
             "type": "object",
             "required": [
                 "amount",
-                "currency"
+                "currency",
+                "recipient"
             ],
             "properties": {
                 "amount": {
-                    "type": "number",
-                    "example": 50
+                    "type": "string",
+                    "example": "25.00"
+                },
+                "auto_convert": {
+                    "type": "boolean",
+                    "example": true
                 },
                 "currency": {
                     "type": "string",
                     "example": "USD"
+                },
+                "recipient": {
+                    "type": "string",
+                    "example": "jane_doe"
                 }
             }
         },
-        "requests.DepositResponse": {
+        "requests.TransferResponse": {
             "type": "object",
             "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "25"
+                },
                 "balance": {
                     "type": "object",
                     "additionalProperties": {
-                        "type": "number"
+                        "type": "string"
                     }
                 },
+                "credited_amount": {
+                    "type": "string",
+                    "example": "2083.33"
+                },
+                "credited_currency": {
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
                 "message": {
                     "type": "string",
-                    "example": "Account topped up successfully"
+                    "example": "transferred successfully"
+                },
+                "rate": {
+                    "type": "string",
+                    "example": "83.3333"
+                },
+                "recipient": {
+                    "type": "string",
+                    "example": "jane_doe"
+                },
+                "transfer_id": {
+                    "type": "integer",
+                    "example": 42
                 }
             }
         },
-        "requests.ExchangeRequest": {
+        "requests.TwoFactorChallengeResponse": {
+            "type": "object",
+            "properties": {
+                "challenge_token": {
+                    "type": "string",
+                    "example": "cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-03-01T12:05:00Z"
+                },
+                "two_factor_required": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "requests.TwoFactorConfirmRequest": {
             "type": "object",
             "required": [
-                "amount",
-                "from_currency",
-                "to_currency"
+                "code"
             ],
             "properties": {
-                "amount": {
-                    "type": "number",
-                    "example": 20
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "requests.TwoFactorConfirmResponse": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "k7xq2-m4pd9",
+                        "3hv6a-wq5tz"
+                    ]
+                }
+            }
+        },
+        "requests.TwoFactorConflictError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "two-factor authentication already enabled"
                 },
-                "from_currency": {
+                "status": {
                     "type": "string",
-                    "example": "USD"
+                    "example": "error"
+                }
+            }
+        },
+        "requests.TwoFactorLoginRequest": {
+            "type": "object",
+            "required": [
+                "challenge_token",
+                "code"
+            ],
+            "properties": {
+                "challenge_token": {
+                    "type": "string",
+                    "example": "cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"
                 },
-                "to_currency": {
+                "code": {
                     "type": "string",
-                    "example": "EUR"
+                    "example": "123456"
                 }
             }
         },
-        "requests.ExchangeResponse": {
+        "requests.TwoFactorSetupResponse": {
             "type": "object",
             "properties": {
-                "exchanged_amount": {
-                    "type": "number",
-                    "example": 17
+                "otpauth_uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/Transferer:alice?algorithm=SHA1\u0026digits=6\u0026issuer=Transferer\u0026period=30\u0026secret=JBSWY3DPEHPK3PXP"
                 },
-                "message": {
+                "qr_code_png": {
                     "type": "string",
-                    "example": "exchanged successfully"
+                    "format": "base64",
+                    "example": "iVBORw0KGgoAAAANSUhEUgAAAQAAAAEA..."
                 },
-                "new_balance": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "number"
-                    }
+                "secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXP"
                 }
             }
         },
-        "requests.LoginRequest": {
+        "requests.UnlockAccountRequest": {
             "type": "object",
             "required": [
-                "password",
-                "username"
+                "token"
             ],
             "properties": {
-                "password": {
+                "token": {
                     "type": "string",
-                    "example": "secure_password"
-                },
-                "username": {
+                    "example": "cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"
+                }
+            }
+        },
+        "requests.UnlockAccountResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
                     "type": "string",
-                    "example": "john_doe"
+                    "example": "account unlocked"
                 }
             }
         },
-        "requests.NotAuthorizedError": {
+        "requests.UnverifiedDepositLimitError": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string",
-                    "example": "invalid token"
+                    "example": "deposit limit exceeded, verify your email"
                 },
                 "status": {
                     "type": "string",
@@ -488,12 +3698,12 @@ const docTemplate = `{
                 }
             }
         },
-        "requests.NotEnoughFundsError": {
+        "requests.UserNotFoundError": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string",
-                    "example": "not enough money to withdraw"
+                    "example": "user not found"
                 },
                 "status": {
                     "type": "string",
@@ -501,45 +3711,37 @@ const docTemplate = `{
                 }
             }
         },
-        "requests.RatesResponse": {
+        "requests.VerificationResendTooSoonError": {
             "type": "object",
             "properties": {
-                "rates": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "number"
-                    }
+                "error": {
+                    "type": "string",
+                    "example": "verification email was sent recently"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
                 }
             }
         },
-        "requests.RegisterRequest": {
+        "requests.VerifyEmailRequest": {
             "type": "object",
             "required": [
-                "email",
-                "password",
-                "username"
+                "token"
             ],
             "properties": {
-                "email": {
-                    "type": "string",
-                    "example": "john.doe@example.com"
-                },
-                "password": {
-                    "type": "string",
-                    "example": "secure_password"
-                },
-                "username": {
+                "token": {
                     "type": "string",
-                    "example": "john_doe"
+                    "example": "cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"
                 }
             }
         },
-        "requests.RetrieveRatesError": {
+        "requests.WalletNotFoundError": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string",
-                    "example": "failed to retrieve exchange rates"
+                    "example": "wallet not found"
                 },
                 "status": {
                     "type": "string",
@@ -555,14 +3757,35 @@ const docTemplate = `{
             ],
             "properties": {
                 "amount": {
-                    "type": "number",
-                    "example": 25
+                    "type": "string",
+                    "example": "25.00"
                 },
                 "currency": {
                     "type": "string",
                     "example": "USD"
                 }
             }
+        },
+        "requests.WrongPasswordError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "wrong password"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "error"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "Access token as \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
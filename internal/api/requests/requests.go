@@ -1,5 +1,11 @@
 package requests
 
+import (
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
 // RegisterRequest структура для запроса регистрации пользователя.
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required" example:"john_doe"`
@@ -19,22 +25,134 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required" example:"secure_password"`
 }
 
+// UnlockAccountRequest структура для разблокировки аккаунта по токену из письма.
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required" example:"cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"`
+}
+
+// UnlockAccountResponse структура для ответа на разблокировку аккаунта.
+type UnlockAccountResponse struct {
+	Message string `json:"message" example:"account unlocked"`
+}
+
+// TokenResponse структура для ответа с токенами при аутентификации и обновлении токенов.
+// token - короткоживущий access токен, refresh_token меняется на новый при каждом обновлении.
+type TokenResponse struct {
+	Token                 string    `json:"token" example:"eyJhbGciOiJIUzI1NiIsImtpZCI6ImRlZmF1bHQiLCJ0eXAiOiJKV1QifQ..."`
+	ExpiresAt             time.Time `json:"expires_at" example:"2025-03-01T12:15:00Z"`
+	RefreshToken          string    `json:"refresh_token" example:"Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at" example:"2025-03-31T12:00:00Z"`
+}
+
+// RefreshTokenRequest структура для запроса обновления токенов.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6"`
+}
+
+// LogoutResponse структура для ответа на запрос выхода.
+type LogoutResponse struct {
+	Message string `json:"message" example:"logged out"`
+}
+
+// ReauthRequest структура для повторной аутентификации, передается пароль или код двухфакторной аутентификации.
+type ReauthRequest struct {
+	Password string `json:"password,omitempty" example:"secure_password"`
+	Code     string `json:"code,omitempty" example:"123456"`
+}
+
+// ChangePasswordRequest структура для запроса смены пароля.
+type ChangePasswordRequest struct {
+	NewPassword string `json:"new_password" binding:"required" example:"new_secure_password"`
+}
+
+// ForgotPasswordRequest структура для запроса письма со ссылкой для сброса пароля.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"john.doe@example.com"`
+}
+
+// ForgotPasswordResponse структура для ответа на запрос сброса пароля.
+// Ответ одинаковый независимо от того, зарегистрирован ли email.
+type ForgotPasswordResponse struct {
+	Message string `json:"message" example:"if the email is registered, a password reset link has been sent"`
+}
+
+// ResetPasswordRequest структура для установки нового пароля по токену из письма.
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required" example:"cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"`
+	NewPassword string `json:"new_password" binding:"required" example:"new_secure_password"`
+}
+
+// ChangeEmailRequest структура для запроса смены email.
+type ChangeEmailRequest struct {
+	Email string `json:"email" binding:"required,email" example:"john.new@example.com"`
+}
+
+// AccountUpdatedResponse структура для ответа на смену пароля или email.
+type AccountUpdatedResponse struct {
+	Message string `json:"message" example:"password changed"`
+}
+
+// VerifyEmailRequest структура для подтверждения email по токену из письма.
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" example:"cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"`
+}
+
+// ProfileResponse структура для ответа с профилем пользователя.
+type ProfileResponse struct {
+	Username      string `json:"username" example:"john_doe"`
+	Email         string `json:"email" example:"john.doe@example.com"`
+	EmailVerified bool   `json:"email_verified" example:"true"`
+}
+
+// TwoFactorChallengeResponse структура для ответа на вход пользователя с включенной двухфакторной аутентификацией.
+// Токены выдаются после подтверждения входа кодом по challenge_token.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required" example:"true"`
+	ChallengeToken    string    `json:"challenge_token" example:"cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"`
+	ExpiresAt         time.Time `json:"expires_at" example:"2025-03-01T12:05:00Z"`
+}
+
+// TwoFactorLoginRequest структура для подтверждения входа кодом из приложения-аутентификатора или кодом восстановления.
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required" example:"cXV4Zm9vYmFyYmF6cXV4Zm9vYmFyYmF6cXV4Zm9vYmFy"`
+	Code           string `json:"code" binding:"required" example:"123456"`
+}
+
+// TwoFactorSetupResponse структура для ответа на подключение двухфакторной аутентификации.
+// qr_code_png - PNG изображение QR кода otpauth_uri в base64.
+type TwoFactorSetupResponse struct {
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/Transferer:alice?algorithm=SHA1&digits=6&issuer=Transferer&period=30&secret=JBSWY3DPEHPK3PXP"`
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	QRCodePNG  []byte `json:"qr_code_png" swaggertype:"string" format:"base64" example:"iVBORw0KGgoAAAANSUhEUgAAAQAAAAEA..."`
+}
+
+// TwoFactorConfirmRequest структура для подтверждения подключения двухфакторной аутентификации.
+type TwoFactorConfirmRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+// TwoFactorConfirmResponse структура для ответа на подтверждение двухфакторной аутентификации.
+// Коды восстановления показываются один раз, каждый из них заменяет код из приложения при входе один раз.
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k7xq2-m4pd9,3hv6a-wq5tz"`
+}
+
 // BalanceResponse структура для ответа на запрос баланса.
 // Example:
 // {
 //   "balance": {
-//     "USD": 150.0,
-//     "EUR": 50.5
+//     "USD": "150",
+//     "EUR": "50.5"
 //   }
 // }
 type BalanceResponse struct {
-	Balance map[string]float64 `json:"balance"`
+	Balance map[string]money.Amount `json:"balance" swaggertype:"object,string"`
 }
 
 // DepositRequest структура для запроса на пополнение баланса.
 type DepositRequest struct {
-	Amount   float32 `json:"amount" binding:"required" example:"50.00"`
-	Currency string  `json:"currency" binding:"required" example:"USD"`
+	Amount   money.Amount `json:"amount" binding:"required" swaggertype:"string" example:"50.00"`
+	Currency string       `json:"currency" binding:"required" example:"USD"`
 }
 
 // DepositResponse структура для ответа на запрос пополнения баланса.
@@ -42,19 +160,19 @@ type DepositRequest struct {
 // {
 //   "message": "Account topped up successfully",
 //   "balance": {
-//     "USD": 200.0,
-//     "EUR": 50.5
+//     "USD": "200",
+//     "EUR": "50.5"
 //   }
 // }
 type DepositResponse struct {
-	Message string             `json:"message" example:"Account topped up successfully"`
-	Balance map[string]float64 `json:"balance"`
+	Message string                  `json:"message" example:"Account topped up successfully"`
+	Balance map[string]money.Amount `json:"balance" swaggertype:"object,string"`
 }
 
 // WithdrawRequest структура для запроса на снятие средств с баланса.
 type WithdrawRequest struct {
-	Amount   float32 `json:"amount" binding:"required" example:"25.00"`
-	Currency string  `json:"currency" binding:"required" example:"USD"`
+	Amount   money.Amount `json:"amount" binding:"required" swaggertype:"string" example:"25.00"`
+	Currency string       `json:"currency" binding:"required" example:"USD"`
 }
 
 // WithdrawResponse структура для ответа на запрос снятия средств.
@@ -62,48 +180,227 @@ type WithdrawRequest struct {
 // {
 //   "message": "Withdrawal successful",
 //   "balance": {
-//     "USD": 50.0,
-//     "EUR": 50.5
+//     "USD": "50",
+//     "EUR": "50.5"
 //   }
 // }
 type WithdrawResponse struct {
-	Message string             `json:"message" example:"Withdrawal successful"`
-	Balance map[string]float64 `json:"balance"`
+	Message string                  `json:"message" example:"Withdrawal successful"`
+	Balance map[string]money.Amount `json:"balance" swaggertype:"object,string"`
 }
 
 // RatesResponse структура для ответа с курсами валют.
+// updated_at - время получения курсов, stale = true если источник курсов недоступен и курсы устарели.
+// Если в запросе указана базовая валюта, cross_rates содержит ее курс ко всем остальным валютам.
 // Example:
 // {
 //   "rates": {
-//     "USD": 1.0,
-//     "EUR": 0.85
+//     "USD": "1",
+//     "EUR": "0.85"
 //   }
 // }
 type RatesResponse struct {
-	Rates map[string]float32 `json:"rates"`
+	Rates      map[string]money.Amount `json:"rates" swaggertype:"object,string"`
+	UpdatedAt  time.Time               `json:"updated_at" example:"2025-03-01T12:00:00Z"`
+	Stale      bool                    `json:"stale,omitempty" example:"false"`
+	Base       string                  `json:"base,omitempty" example:"USD"`
+	CrossRates map[string]CrossRate    `json:"cross_rates,omitempty"`
+}
+
+// CrossRate структура для курса базовой валюты к другой валюте.
+// Path - валюты, через которые считается курс, включая начальную и конечную.
+type CrossRate struct {
+	Rate money.Amount `json:"rate" swaggertype:"string" example:"0.91666667"`
+	Path []string     `json:"path" example:"USD,RUB,EUR"`
+}
+
+// RateHistoryResponse структура для ответа с историей курса валютной пары.
+// Без interval возвращаются все полученные курсы (points), с interval - свечи OHLC (candles).
+type RateHistoryResponse struct {
+	Pair     string       `json:"pair" example:"RUB_USD"`
+	From     time.Time    `json:"from" example:"2025-03-01T00:00:00Z"`
+	To       time.Time    `json:"to" example:"2025-03-02T00:00:00Z"`
+	Interval string       `json:"interval,omitempty" example:"1h0m0s"`
+	Points   []RatePoint  `json:"points,omitempty"`
+	Candles  []RateCandle `json:"candles,omitempty"`
+}
+
+// RatePoint структура для курса, полученного от источника курсов.
+type RatePoint struct {
+	Time   time.Time    `json:"time" example:"2025-03-01T12:00:00Z"`
+	Rate   money.Amount `json:"rate" swaggertype:"string" example:"0.012"`
+	Source string       `json:"source" example:"grpc"`
+}
+
+// RateCandle структура для свечи OHLC за интервал, начинающийся в time.
+type RateCandle struct {
+	Time  time.Time    `json:"time" example:"2025-03-01T12:00:00Z"`
+	Open  money.Amount `json:"open" swaggertype:"string" example:"0.012"`
+	High  money.Amount `json:"high" swaggertype:"string" example:"0.0125"`
+	Low   money.Amount `json:"low" swaggertype:"string" example:"0.0119"`
+	Close money.Amount `json:"close" swaggertype:"string" example:"0.0121"`
+	Count int          `json:"count" example:"360"`
 }
 
 // ExchangeRequest структура для запроса обмена валюты.
+// Если указан quote_id, обмен выполняется по курсу и на сумму котировки,
+// иначе нужны from_currency, to_currency и amount.
 type ExchangeRequest struct {
-	FromCurrency string  `json:"from_currency" binding:"required" example:"USD"`
-	ToCurrency   string  `json:"to_currency" binding:"required" example:"EUR"`
-	Amount       float64 `json:"amount" binding:"required" example:"20.00"`
+	QuoteID      string       `json:"quote_id,omitempty" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	FromCurrency string       `json:"from_currency" example:"USD"`
+	ToCurrency   string       `json:"to_currency" example:"EUR"`
+	Amount       money.Amount `json:"amount" swaggertype:"string" example:"20.00"`
+}
+
+// QuoteRequest структура для запроса котировки обмена валюты.
+type QuoteRequest struct {
+	FromCurrency string       `json:"from_currency" binding:"required" example:"USD"`
+	ToCurrency   string       `json:"to_currency" binding:"required" example:"EUR"`
+	Amount       money.Amount `json:"amount" binding:"required" swaggertype:"string" example:"20.00"`
+}
+
+// QuoteResponse структура для ответа на запрос котировки.
+// Курс зафиксирован до expires_at, обмен по котировке выполняется один раз.
+type QuoteResponse struct {
+	QuoteID      string       `json:"quote_id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	FromCurrency string       `json:"from_currency" example:"USD"`
+	ToCurrency   string       `json:"to_currency" example:"EUR"`
+	Amount       money.Amount `json:"amount" swaggertype:"string" example:"20.00"`
+	Rate         money.Amount `json:"rate" swaggertype:"string" example:"0.91666667"`
+	ToAmount     money.Amount `json:"to_amount" swaggertype:"string" example:"18.33333340"`
+	Path         []string     `json:"path" example:"USD,RUB,EUR"`
+	ExpiresAt    time.Time    `json:"expires_at" example:"2025-03-01T12:00:30Z"`
 }
 
 // ExchangeResponse структура для ответа на запрос обмена валюты.
 // Example:
 // {
 //   "message": "exchanged successfully",
-//   "exchanged_amount": 17.0,
+//   "exchanged_amount": "17",
 //   "new_balance": {
-//     "USD": 80.0,
-//     "EUR": 67.5
+//     "USD": "80",
+//     "EUR": "67.5"
 //   }
 // }
 type ExchangeResponse struct {
-	Message         string             `json:"message" example:"exchanged successfully"`
-	ExchangedAmount float64            `json:"exchanged_amount" example:"17.00"`
-	NewBalance      map[string]float64 `json:"new_balance"`
+	Message         string                  `json:"message" example:"exchanged successfully"`
+	ExchangedAmount money.Amount            `json:"exchanged_amount" swaggertype:"string" example:"17.00"`
+	NewBalance      map[string]money.Amount `json:"new_balance" swaggertype:"object,string"`
+}
+
+// TransferRequest структура для запроса перевода другому пользователю.
+// Если у получателя нет кошелька в валюте перевода и auto_convert = true,
+// сумма конвертируется в валюту его основного кошелька.
+type TransferRequest struct {
+	Recipient   string       `json:"recipient" binding:"required" example:"jane_doe"`
+	Currency    string       `json:"currency" binding:"required" example:"USD"`
+	Amount      money.Amount `json:"amount" binding:"required" swaggertype:"string" example:"25.00"`
+	AutoConvert bool         `json:"auto_convert" example:"true"`
+}
+
+// TransferResponse структура для ответа на запрос перевода.
+type TransferResponse struct {
+	Message          string                  `json:"message" example:"transferred successfully"`
+	TransferID       int64                   `json:"transfer_id" example:"42"`
+	Recipient        string                  `json:"recipient" example:"jane_doe"`
+	Amount           money.Amount            `json:"amount" swaggertype:"string" example:"25"`
+	Currency         string                  `json:"currency" example:"USD"`
+	CreditedAmount   money.Amount            `json:"credited_amount" swaggertype:"string" example:"2083.33"`
+	CreditedCurrency string                  `json:"credited_currency" example:"RUB"`
+	Rate             *money.Amount           `json:"rate,omitempty" swaggertype:"string" example:"83.3333"`
+	Balance          map[string]money.Amount `json:"balance" swaggertype:"object,string"`
+}
+
+// Transaction структура одной операции в истории пользователя.
+// Поля to_currency, to_amount и rate заполняются только при конвертации,
+// counterparty - второй участник перевода.
+type Transaction struct {
+	ID           int64         `json:"id" example:"42"`
+	Type         string        `json:"type" example:"exchange"`
+	Currency     string        `json:"currency" example:"USD"`
+	Amount       money.Amount  `json:"amount" swaggertype:"string" example:"20"`
+	ToCurrency   string        `json:"to_currency,omitempty" example:"EUR"`
+	ToAmount     *money.Amount `json:"to_amount,omitempty" swaggertype:"string" example:"17"`
+	Rate         *money.Amount `json:"rate,omitempty" swaggertype:"string" example:"0.85"`
+	Counterparty string        `json:"counterparty,omitempty" example:"jane_doe"`
+	CreatedAt    time.Time     `json:"created_at" example:"2025-03-01T12:00:00Z"`
+}
+
+// TransactionsResponse структура для ответа на запрос истории операций.
+// next_cursor передается в параметре cursor для получения следующей страницы.
+type TransactionsResponse struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty" example:"MTc0MDgzMDQwMDAwMDAwMDAwMDo0Mg"`
+}
+
+// AdminUser структура пользователя в ответах административного API.
+type AdminUser struct {
+	ID            int      `json:"id" example:"7"`
+	Username      string   `json:"username" example:"john_doe"`
+	Email         string   `json:"email" example:"john.doe@example.com"`
+	EmailVerified bool     `json:"email_verified" example:"true"`
+	Roles         []string `json:"roles" example:"user"`
+	Frozen        bool     `json:"frozen" example:"false"`
+	FrozenReason  string   `json:"frozen_reason,omitempty" example:"suspicious activity"`
+}
+
+// AdminUsersResponse структура для ответа на поиск пользователей.
+// next_offset передается в параметре offset для получения следующей страницы.
+type AdminUsersResponse struct {
+	Users      []AdminUser `json:"users"`
+	NextOffset *int        `json:"next_offset,omitempty" example:"20"`
+}
+
+// FreezeAccountRequest структура для запроса заморозки аккаунта.
+type FreezeAccountRequest struct {
+	Reason string `json:"reason" binding:"required" example:"suspicious activity"`
+}
+
+// BalanceAdjustmentRequest структура для запроса ручной корректировки баланса.
+// Положительная сумма зачисляется на кошелек, отрицательная списывается.
+type BalanceAdjustmentRequest struct {
+	Currency string       `json:"currency" binding:"required" example:"USD"`
+	Amount   money.Amount `json:"amount" swaggertype:"string" example:"-25.00"`
+	Reason   string       `json:"reason" binding:"required" example:"refund of a duplicated withdrawal"`
+}
+
+// BalanceAdjustmentResponse структура заявки на ручную корректировку баланса.
+// status - pending, approved, rejected или expired. Заявка проводится только после одобрения
+// другим администратором: entry_id - проводка в журнале, reviewed_by и reviewed_at - кто и когда
+// одобрил или отклонил заявку, review_note - причина отклонения. balance - баланс пользователя после одобрения.
+type BalanceAdjustmentResponse struct {
+	ID         int64                   `json:"id" example:"42"`
+	Username   string                  `json:"username" example:"john_doe"`
+	Currency   string                  `json:"currency" example:"USD"`
+	Amount     money.Amount            `json:"amount" swaggertype:"string" example:"-25"`
+	Reason     string                  `json:"reason" example:"refund of a duplicated withdrawal"`
+	Status     string                  `json:"status" example:"approved"`
+	CreatedBy  string                  `json:"created_by" example:"alice"`
+	CreatedAt  time.Time               `json:"created_at" example:"2025-03-01T12:00:00Z"`
+	ExpiresAt  *time.Time              `json:"expires_at,omitempty" example:"2025-03-02T12:00:00Z"`
+	EntryID    int64                   `json:"entry_id,omitempty" example:"108"`
+	ReviewedBy string                  `json:"reviewed_by,omitempty" example:"bob"`
+	ReviewedAt *time.Time              `json:"reviewed_at,omitempty" example:"2025-03-01T12:30:00Z"`
+	ReviewNote string                  `json:"review_note,omitempty" example:"wrong currency"`
+	Balance    map[string]money.Amount `json:"balance,omitempty" swaggertype:"object,string"`
+}
+
+// BalanceAdjustmentsResponse структура для ответа со списком заявок на корректировку баланса.
+// next_offset передается в параметре offset для получения следующей страницы.
+type BalanceAdjustmentsResponse struct {
+	Adjustments []BalanceAdjustmentResponse `json:"adjustments"`
+	NextOffset  *int                        `json:"next_offset,omitempty" example:"20"`
+}
+
+// RejectAdjustmentRequest структура для запроса отклонения заявки на корректировку баланса.
+type RejectAdjustmentRequest struct {
+	Reason string `json:"reason" binding:"required" example:"wrong currency"`
+}
+
+// SetRolesRequest структура для запроса изменения ролей пользователя.
+// Роли: user, support, admin, auditor.
+type SetRolesRequest struct {
+	Roles []string `json:"roles" binding:"required" example:"user,support"`
 }
 
 // NotAuthorizedError структура для ответа со статус кодом 401.
@@ -112,6 +409,149 @@ type NotAuthorizedError struct {
 	Error  string `json:"error" example:"invalid token"`
 }
 
+// WrongPasswordError структура для ответа со статус кодом 401 при неверном имени пользователя или пароле.
+type WrongPasswordError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"wrong password"`
+}
+
+// TooManyLoginAttemptsError структура для ответа со статус кодом 429 когда вход временно запрещен.
+// error - "too many failed login attempts" или "account is temporarily locked", если аккаунт заблокирован.
+type TooManyLoginAttemptsError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"too many failed login attempts"`
+}
+
+// InvalidUnlockTokenError структура для ответа со статус кодом 400 при недействительном токене разблокировки.
+type InvalidUnlockTokenError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"invalid or expired unlock token"`
+}
+
+// InvalidResetTokenError структура для ответа со статус кодом 400 при недействительном токене сброса пароля.
+type InvalidResetTokenError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"invalid or expired password reset token"`
+}
+
+// InvalidVerificationTokenError структура для ответа со статус кодом 400 при недействительном токене подтверждения email.
+type InvalidVerificationTokenError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"invalid or expired email verification token"`
+}
+
+// EmailAlreadyVerifiedError структура для ответа со статус кодом 409 когда email уже подтвержден.
+type EmailAlreadyVerifiedError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"email is already verified"`
+}
+
+// VerificationResendTooSoonError структура для ответа со статус кодом 429 когда письмо для подтверждения
+// email было отправлено недавно.
+type VerificationResendTooSoonError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"verification email was sent recently"`
+}
+
+// UnverifiedDepositLimitError структура для ответа со статус кодом 403 когда пополнение до подтверждения email
+// превышает лимит.
+type UnverifiedDepositLimitError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"deposit limit exceeded, verify your email"`
+}
+
+// StepUpRequiredError структура для ответа со статус кодом 401 когда операция требует повторной аутентификации.
+// Заголовок WWW-Authenticate содержит error="insufficient_user_authentication" и max_age в секундах.
+type StepUpRequiredError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"fresh authentication required"`
+}
+
+// ReauthFailedError структура для ответа со статус кодом 401 при неверном пароле или коде повторной аутентификации.
+type ReauthFailedError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"wrong password"`
+}
+
+// EmailTakenError структура для ответа со статус кодом 409 когда email уже используется.
+type EmailTakenError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"email is taken"`
+}
+
+// InvalidRefreshTokenError структура для ответа со статус кодом 401 при обновлении токенов.
+type InvalidRefreshTokenError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"invalid refresh token"`
+}
+
+// InvalidTwoFactorCodeError структура для ответа со статус кодом 401 при неверном коде двухфакторной аутентификации.
+type InvalidTwoFactorCodeError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"invalid code"`
+}
+
+// TwoFactorConflictError структура для ответа со статус кодом 409 когда двухфакторная аутентификация уже включена.
+type TwoFactorConflictError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"two-factor authentication already enabled"`
+}
+
+// ForbiddenError структура для ответа со статус кодом 403 когда у пользователя нет нужной роли.
+type ForbiddenError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"insufficient permissions"`
+}
+
+// AccountFrozenError структура для ответа со статус кодом 403 когда аккаунт заморожен.
+type AccountFrozenError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"account is frozen"`
+}
+
+// UserNotFoundError структура для ответа со статус кодом 404 когда пользователь не найден.
+type UserNotFoundError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"user not found"`
+}
+
+// WalletNotFoundError структура для ответа со статус кодом 404 когда у пользователя нет кошелька в валюте.
+type WalletNotFoundError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"wallet not found"`
+}
+
+// AdjustmentNotFoundError структура для ответа со статус кодом 404 когда заявка на корректировку не найдена.
+type AdjustmentNotFoundError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"adjustment not found"`
+}
+
+// AdjustmentNotPendingError структура для ответа со статус кодом 409 когда заявка на корректировку
+// уже одобрена или отклонена.
+type AdjustmentNotPendingError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"adjustment is not pending"`
+}
+
+// AdjustmentExpiredError структура для ответа со статус кодом 410 когда заявка на корректировку истекла.
+type AdjustmentExpiredError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"adjustment expired"`
+}
+
+// SelfApprovalError структура для ответа со статус кодом 403 когда администратор одобряет свою же заявку.
+type SelfApprovalError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"adjustment cannot be approved by its proposer"`
+}
+
+// SelfAdjustmentError структура для ответа со статус кодом 403 когда администратор корректирует свой же баланс.
+type SelfAdjustmentError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"admins cannot adjust their own balance"`
+}
+
 // BadRequestError структура для ответа со статус кодом 400.
 type BadRequestError struct {
 	Status string `json:"status" example:"error"`
@@ -130,8 +570,26 @@ type RetrieveRatesError struct {
 	Error  string `json:"error" example:"failed to retrieve exchange rates"`
 }
 
+// RatesUnavailableError структура для ответа со статус кодом 503 когда источник курсов временно недоступен.
+type RatesUnavailableError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"exchange rates are temporarily unavailable"`
+}
+
+// IdempotencyConflictError структура для ответа со статус кодом 409 когда ключ идемпотентности уже использован.
+type IdempotencyConflictError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"idempotency key was already used for a different request"`
+}
+
 // NotEnoughMoneyError структура для ответа со статус кодом 403 когда у пользователя недостаточно средств.
 type NotEnoughFundsError struct {
 	Status string `json:"status" example:"error"`
 	Error  string `json:"error" example:"not enough money to withdraw"`
 }
+
+// QuoteExpiredError структура для ответа со статус кодом 410 когда котировка истекла или уже использована.
+type QuoteExpiredError struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"quote expired"`
+}
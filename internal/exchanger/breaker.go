@@ -0,0 +1,95 @@
+package exchanger
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("exchanger circuit breaker is open")
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	// outcomeIgnored is a call that says nothing about the exchanger's health,
+	// e.g. one cancelled by its caller.
+	outcomeIgnored
+)
+
+// Breaker stops calls to the exchanger after maxFailures failures in a row.
+// After openTimeout it lets a single probe through: a successful probe closes it again,
+// a failed one keeps it open for another openTimeout.
+type Breaker struct {
+	maxFailures int
+	openTimeout time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewBreaker(maxFailures int, openTimeout time.Duration) *Breaker {
+	if maxFailures < 1 {
+		maxFailures = 1
+	}
+	return &Breaker{
+		maxFailures: maxFailures,
+		openTimeout: openTimeout,
+		now:         time.Now,
+	}
+}
+
+// Allow reports whether a call may be made. Every allowed call must be followed by record.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = stateHalfOpen
+		b.probing = true
+		return nil
+	case stateHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *Breaker) record(result outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	switch result {
+	case outcomeSuccess:
+		b.state = stateClosed
+		b.failures = 0
+	case outcomeFailure:
+		b.failures++
+		if b.state == stateHalfOpen || b.failures >= b.maxFailures {
+			b.state = stateOpen
+			b.openedAt = b.now()
+		}
+	}
+}
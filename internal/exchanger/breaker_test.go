@@ -0,0 +1,48 @@
+package exchanger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	breaker := NewBreaker(2, 30*time.Second)
+	breaker.now = func() time.Time { return now }
+
+	// failures in a row open it, a success in between resets the count
+	assert.NoError(t, breaker.Allow())
+	breaker.record(outcomeFailure)
+	assert.NoError(t, breaker.Allow())
+	breaker.record(outcomeSuccess)
+	assert.NoError(t, breaker.Allow())
+	breaker.record(outcomeFailure)
+	assert.NoError(t, breaker.Allow())
+	breaker.record(outcomeFailure)
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	// after the timeout a single probe goes through
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, breaker.Allow())
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	// a failed probe opens it for another timeout
+	breaker.record(outcomeFailure)
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+	now = now.Add(29 * time.Second)
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	// an ignored probe lets the next call probe again
+	now = now.Add(time.Second)
+	assert.NoError(t, breaker.Allow())
+	breaker.record(outcomeIgnored)
+	assert.NoError(t, breaker.Allow())
+
+	// a successful probe closes it
+	breaker.record(outcomeSuccess)
+	assert.NoError(t, breaker.Allow())
+	breaker.record(outcomeSuccess)
+	assert.NoError(t, breaker.Allow())
+}
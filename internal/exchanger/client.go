@@ -0,0 +1,141 @@
+// Package exchanger wraps the grpcexchanger client with deadlines, retries and a circuit breaker.
+package exchanger
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Options struct {
+	// CallTimeout bounds every attempt, on top of the caller's own deadline.
+	CallTimeout time.Duration
+	// MaxRetries is how many times a call failing with a transient code is repeated.
+	MaxRetries int
+	// Backoff is the pause before the first retry, doubled before every next one.
+	Backoff time.Duration
+	// BreakerFailures failures in a row open the circuit breaker for BreakerOpenTimeout.
+	BreakerFailures    int
+	BreakerOpenTimeout time.Duration
+}
+
+// Client is a pb.ExchangeServiceClient that gives up on a hung exchanger instead of waiting
+// forever, retries transient failures and stops calling an exchanger that keeps failing.
+type Client struct {
+	client  pb.ExchangeServiceClient
+	opts    Options
+	breaker *Breaker
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func NewClient(client pb.ExchangeServiceClient, opts Options) *Client {
+	return &Client{
+		client:  client,
+		opts:    opts,
+		breaker: NewBreaker(opts.BreakerFailures, opts.BreakerOpenTimeout),
+		sleep:   sleep,
+	}
+}
+
+func (c *Client) GetExchangeRates(ctx context.Context, in *pb.Empty, opts ...grpc.CallOption) (*pb.ExchangeRatesResponse, error) {
+	var response *pb.ExchangeRatesResponse
+	err := c.call(ctx, func(ctx context.Context) (err error) {
+		response, err = c.client.GetExchangeRates(ctx, in, opts...)
+		return err
+	})
+	return response, err
+}
+
+func (c *Client) GetExchangeRateForCurrency(ctx context.Context, in *pb.CurrencyRequest, opts ...grpc.CallOption) (*pb.ExchangeRateResponse, error) {
+	var response *pb.ExchangeRateResponse
+	err := c.call(ctx, func(ctx context.Context) (err error) {
+		response, err = c.client.GetExchangeRateForCurrency(ctx, in, opts...)
+		return err
+	})
+	return response, err
+}
+
+func (c *Client) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.breaker.Allow(); err != nil {
+			return err
+		}
+
+		callCtx, cancel := c.withDeadline(ctx)
+		err := fn(callCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			c.breaker.record(outcomeSuccess)
+			return nil
+		case ctx.Err() != nil:
+			// the caller gave up, this says nothing about the exchanger
+			c.breaker.record(outcomeIgnored)
+			return err
+		case isFailure(status.Code(err)):
+			c.breaker.record(outcomeFailure)
+		default:
+			c.breaker.record(outcomeSuccess)
+			return err
+		}
+
+		if !isTransient(status.Code(err)) || attempt >= c.opts.MaxRetries {
+			return err
+		}
+		if err := c.sleep(ctx, c.backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// withDeadline derives the context of one attempt from the caller's context.
+func (c *Client) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opts.CallTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.opts.CallTimeout)
+}
+
+// backoff doubles the pause with every attempt and adds up to 50% jitter,
+// so that retries of many requests do not hit the exchanger at the same moment.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.opts.Backoff << attempt
+	if d <= 0 {
+		return 0
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// isTransient reports whether a call failing with code may succeed if repeated.
+func isTransient(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// isFailure reports whether code means the exchanger is unhealthy, as opposed to
+// rejecting a bad request.
+func isFailure(code codes.Code) bool {
+	return isTransient(code) || code == codes.Internal || code == codes.Unknown
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,150 @@
+package exchanger
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type mockExchangeServiceClient struct {
+	errs      []error
+	calls     int
+	deadlines []time.Time
+	hang      bool
+}
+
+func (m *mockExchangeServiceClient) GetExchangeRates(ctx context.Context, in *pb.Empty, opts ...grpc.CallOption) (*pb.ExchangeRatesResponse, error) {
+	m.calls++
+	deadline, _ := ctx.Deadline()
+	m.deadlines = append(m.deadlines, deadline)
+
+	if m.hang {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &pb.ExchangeRatesResponse{Rates: map[string]float32{"RUB_USD": 0.012}}, nil
+}
+
+func (m *mockExchangeServiceClient) GetExchangeRateForCurrency(ctx context.Context, in *pb.CurrencyRequest, opts ...grpc.CallOption) (*pb.ExchangeRateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "not implemented")
+}
+
+func newTestClient(mock *mockExchangeServiceClient, opts Options) (*Client, *[]time.Duration) {
+	client := NewClient(mock, opts)
+	var pauses []time.Duration
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		pauses = append(pauses, d)
+		return ctx.Err()
+	}
+	return client, &pauses
+}
+
+func TestClientRetriesTransientErrors(t *testing.T) {
+	mock := &mockExchangeServiceClient{errs: []error{
+		status.Error(codes.Unavailable, "down"),
+		status.Error(codes.ResourceExhausted, "busy"),
+	}}
+	client, pauses := newTestClient(mock, Options{MaxRetries: 2, Backoff: 100 * time.Millisecond, BreakerFailures: 5})
+
+	response, err := client.GetExchangeRates(context.Background(), &pb.Empty{})
+	require.NoError(t, err)
+	assert.Equal(t, float32(0.012), response.Rates["RUB_USD"])
+	assert.Equal(t, 3, mock.calls)
+
+	require.Len(t, *pauses, 2)
+	assert.GreaterOrEqual(t, (*pauses)[0], 100*time.Millisecond)
+	assert.LessOrEqual(t, (*pauses)[0], 150*time.Millisecond)
+	assert.GreaterOrEqual(t, (*pauses)[1], 200*time.Millisecond)
+	assert.LessOrEqual(t, (*pauses)[1], 300*time.Millisecond)
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	mock := &mockExchangeServiceClient{errs: []error{unavailable, unavailable, unavailable, unavailable}}
+	client, _ := newTestClient(mock, Options{MaxRetries: 2, BreakerFailures: 10})
+
+	_, err := client.GetExchangeRates(context.Background(), &pb.Empty{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 3, mock.calls)
+}
+
+func TestClientDoesNotRetryPermanentErrors(t *testing.T) {
+	mock := &mockExchangeServiceClient{errs: []error{status.Error(codes.InvalidArgument, "bad request")}}
+	client, _ := newTestClient(mock, Options{MaxRetries: 2, BreakerFailures: 1})
+
+	_, err := client.GetExchangeRates(context.Background(), &pb.Empty{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, 1, mock.calls)
+
+	// a rejected request does not count against the exchanger's health
+	_, err = client.GetExchangeRates(context.Background(), &pb.Empty{})
+	assert.NoError(t, err)
+}
+
+func TestClientBoundsEveryAttempt(t *testing.T) {
+	mock := &mockExchangeServiceClient{hang: true}
+	client, _ := newTestClient(mock, Options{CallTimeout: 20 * time.Millisecond, MaxRetries: 1, BreakerFailures: 10})
+
+	start := time.Now()
+	_, err := client.GetExchangeRates(context.Background(), &pb.Empty{})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Equal(t, 2, mock.calls)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestClientKeepsCallerDeadline(t *testing.T) {
+	mock := &mockExchangeServiceClient{}
+	client, _ := newTestClient(mock, Options{CallTimeout: time.Minute, BreakerFailures: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	_, err := client.GetExchangeRates(ctx, &pb.Empty{})
+	require.NoError(t, err)
+	assert.Equal(t, deadline, mock.deadlines[0])
+}
+
+func TestClientStopsWhenCallerGivesUp(t *testing.T) {
+	mock := &mockExchangeServiceClient{hang: true}
+	client, _ := newTestClient(mock, Options{MaxRetries: 5, BreakerFailures: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetExchangeRates(ctx, &pb.Empty{})
+	assert.Error(t, err)
+	assert.Equal(t, 1, mock.calls)
+
+	// the caller's deadline is not the exchanger's fault, the breaker stays closed
+	assert.NoError(t, client.breaker.Allow())
+}
+
+func TestClientOpensBreaker(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	mock := &mockExchangeServiceClient{errs: []error{unavailable, unavailable}}
+	client, _ := newTestClient(mock, Options{MaxRetries: 5, BreakerFailures: 2, BreakerOpenTimeout: time.Minute})
+
+	_, err := client.GetExchangeRates(context.Background(), &pb.Empty{})
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, 2, mock.calls)
+
+	_, err = client.GetExchangeRates(context.Background(), &pb.Empty{})
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, 2, mock.calls)
+}
@@ -0,0 +1,192 @@
+// Package fake is an in-memory exchanger service for local development and tests.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
+	"github.com/Foreground-Eclipse/transferer/internal/rates"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Options struct {
+	// Rates are the initial quotes keyed by pair, e.g. {"USD_EUR": 0.92}.
+	Rates map[string]float32
+	// WalkStep is the largest relative change of a quote per Walk, e.g. 0.001 for 0.1%.
+	// Zero keeps the quotes static.
+	WalkStep float64
+	// Latency delays every call, Jitter adds up to that much random delay on top.
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is the share of calls, from 0 to 1, failing with ErrorCode.
+	ErrorRate float64
+	ErrorCode codes.Code
+	// Seed makes the random walk, jitter and errors repeatable. Zero uses the current time.
+	Seed int64
+}
+
+// Server implements pb.ExchangeServiceServer over quotes held in memory.
+type Server struct {
+	pb.UnimplementedExchangeServiceServer
+
+	opts Options
+
+	mu    sync.Mutex
+	rates map[string]float32
+	rand  *rand.Rand
+}
+
+func New(opts Options) (*Server, error) {
+	const op = "exchanger.fake.New"
+
+	if opts.ErrorRate < 0 || opts.ErrorRate > 1 {
+		return nil, fmt.Errorf("%s: error rate must be between 0 and 1", op)
+	}
+	if opts.WalkStep < 0 || opts.WalkStep >= 1 {
+		return nil, fmt.Errorf("%s: walk step must be between 0 and 1", op)
+	}
+
+	quotes := make(map[string]float32, len(opts.Rates))
+	for pair, rate := range opts.Rates {
+		if _, _, err := rates.ParsePair(pair); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("%s: rate for %s must be positive", op, pair)
+		}
+		quotes[pair] = rate
+	}
+
+	if opts.ErrorCode == codes.OK {
+		opts.ErrorCode = codes.Unavailable
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &Server{
+		opts:  opts,
+		rates: quotes,
+		rand:  rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+func (s *Server) GetExchangeRates(ctx context.Context, _ *pb.Empty) (*pb.ExchangeRatesResponse, error) {
+	if err := s.simulate(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes := make(map[string]float32, len(s.rates))
+	for pair, rate := range s.rates {
+		quotes[pair] = rate
+	}
+	return &pb.ExchangeRatesResponse{Rates: quotes}, nil
+}
+
+func (s *Server) GetExchangeRateForCurrency(ctx context.Context, in *pb.CurrencyRequest) (*pb.ExchangeRateResponse, error) {
+	if err := s.simulate(ctx); err != nil {
+		return nil, err
+	}
+
+	from, to := in.GetFromCurrency(), in.GetToCurrency()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rate, ok := s.rates[from+"_"+to]
+	if !ok {
+		inverse, ok := s.rates[to+"_"+from]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "no rate for %s_%s", from, to)
+		}
+		rate = 1 / inverse
+	}
+	return &pb.ExchangeRateResponse{FromCurrency: from, ToCurrency: to, Rate: rate}, nil
+}
+
+// Walk moves every quote by a random step of at most WalkStep. A pair quoted in both
+// directions keeps its two quotes inverse to each other.
+func (s *Server) Walk() {
+	if s.opts.WalkStep == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// sorted, so that a seeded walk is repeatable
+	pairs := make([]string, 0, len(s.rates))
+	for pair := range s.rates {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	walked := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		if walked[pair] {
+			continue
+		}
+		step := (s.rand.Float64()*2 - 1) * s.opts.WalkStep
+		rate := float32(float64(s.rates[pair]) * (1 + step))
+		s.rates[pair] = rate
+		walked[pair] = true
+
+		from, to, _ := rates.ParsePair(pair)
+		if _, ok := s.rates[to+"_"+from]; ok {
+			s.rates[to+"_"+from] = 1 / rate
+			walked[to+"_"+from] = true
+		}
+	}
+}
+
+// Run walks the quotes every interval until ctx is done.
+func (s *Server) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Walk()
+		}
+	}
+}
+
+// simulate delays the call by the configured latency and fails it at the configured error rate.
+func (s *Server) simulate(ctx context.Context) error {
+	s.mu.Lock()
+	delay := s.opts.Latency
+	if s.opts.Jitter > 0 {
+		delay += time.Duration(s.rand.Int63n(int64(s.opts.Jitter) + 1))
+	}
+	fail := s.opts.ErrorRate > 0 && s.rand.Float64() < s.opts.ErrorRate
+	s.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+	}
+
+	if fail {
+		return status.Error(s.opts.ErrorCode, "injected failure")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNew_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "Malformed_Pair", opts: Options{Rates: map[string]float32{"USDEUR": 0.92}}},
+		{name: "Negative_Rate", opts: Options{Rates: map[string]float32{"USD_EUR": -1}}},
+		{name: "Error_Rate", opts: Options{ErrorRate: 1.5}},
+		{name: "Walk_Step", opts: Options{WalkStep: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGetExchangeRateForCurrency(t *testing.T) {
+	server, err := New(Options{Rates: map[string]float32{"USD_EUR": 0.5}})
+	require.NoError(t, err)
+
+	response, err := server.GetExchangeRateForCurrency(context.Background(), &pb.CurrencyRequest{FromCurrency: "USD", ToCurrency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, float32(0.5), response.GetRate())
+
+	response, err = server.GetExchangeRateForCurrency(context.Background(), &pb.CurrencyRequest{FromCurrency: "EUR", ToCurrency: "USD"})
+	require.NoError(t, err)
+	assert.Equal(t, float32(2), response.GetRate())
+
+	_, err = server.GetExchangeRateForCurrency(context.Background(), &pb.CurrencyRequest{FromCurrency: "USD", ToCurrency: "RUB"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestWalk(t *testing.T) {
+	server, err := New(Options{
+		Rates:    map[string]float32{"USD_EUR": 0.5, "EUR_USD": 2, "USD_RUB": 90},
+		WalkStep: 0.01,
+		Seed:     1,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		before, err := server.GetExchangeRates(context.Background(), &pb.Empty{})
+		require.NoError(t, err)
+
+		server.Walk()
+
+		after, err := server.GetExchangeRates(context.Background(), &pb.Empty{})
+		require.NoError(t, err)
+		for pair, rate := range after.GetRates() {
+			assert.InEpsilon(t, before.GetRates()[pair], rate, 0.0101, pair)
+		}
+		assert.InDelta(t, 1, after.GetRates()["USD_EUR"]*after.GetRates()["EUR_USD"], 1e-6)
+	}
+}
+
+func TestWalk_Static(t *testing.T) {
+	server, err := New(Options{Rates: map[string]float32{"USD_EUR": 0.5}})
+	require.NoError(t, err)
+
+	server.Walk()
+
+	response, err := server.GetExchangeRates(context.Background(), &pb.Empty{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float32{"USD_EUR": 0.5}, response.GetRates())
+}
+
+func TestErrorRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		errorRate float64
+		wantCode  codes.Code
+	}{
+		{name: "Never", errorRate: 0, wantCode: codes.OK},
+		{name: "Always", errorRate: 1, wantCode: codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := New(Options{Rates: map[string]float32{"USD_EUR": 0.5}, ErrorRate: tt.errorRate})
+			require.NoError(t, err)
+
+			for i := 0; i < 20; i++ {
+				_, err := server.GetExchangeRates(context.Background(), &pb.Empty{})
+				assert.Equal(t, tt.wantCode, status.Code(err))
+			}
+		})
+	}
+}
+
+func TestErrorRate_Share(t *testing.T) {
+	server, err := New(Options{ErrorRate: 0.3, ErrorCode: codes.Internal, Seed: 1})
+	require.NoError(t, err)
+
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		_, err := server.GetExchangeRates(context.Background(), &pb.Empty{})
+		if err != nil {
+			assert.Equal(t, codes.Internal, status.Code(err))
+			failed++
+		}
+	}
+	assert.InDelta(t, 300, failed, 60)
+}
+
+func TestLatency(t *testing.T) {
+	server, err := New(Options{Latency: 20 * time.Millisecond})
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = server.GetExchangeRates(context.Background(), &pb.Empty{})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = server.GetExchangeRates(ctx, &pb.Empty{})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
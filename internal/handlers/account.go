@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/session"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/twofactor"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CodeVerifier interface {
+	Verify(username, code string) error
+}
+
+type Reauthenticator interface {
+	Reauthenticate(username, sessionID string, methods []string) (session.Tokens, error)
+}
+
+type PasswordChanger interface {
+	UpdatePasswordHash(ctx context.Context, username, passwordHash, keepSessionID string) error
+}
+
+type EmailChanger interface {
+	DoesEmailExists(email string) (bool, error)
+	UpdateEmail(username, email string) error
+}
+
+// HandleReauthenticate godoc
+// @Summary Повторная аутентификация
+// @Description Подтверждает личность пользователя паролем или кодом двухфакторной аутентификации и возвращает
+// @Description новые токены той же сессии со свежим временем аутентификации. Нужна перед крупными снятиями,
+// @Description первым переводом новому получателю и сменой пароля или email.
+// @Description Неудачные попытки ограничиваются так же, как при входе.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body requests.ReauthRequest true "Пароль или код"
+// @Success 200 {object} requests.TokenResponse "Аутентификация подтверждена"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.ReauthFailedError "Не авторизован, неверный пароль или код"
+// @Failure 429 {object} requests.TooManyLoginAttemptsError "Слишком много неудачных попыток, повторить через Retry-After секунд"
+// @Header 429 {integer} Retry-After "Через сколько секунд можно повторить попытку"
+// @Failure 500 {object} requests.CantCreateJWTError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/reauth [post]
+func HandleReauthenticate(logger *zap.Logger, userLogger UserLogger, codeVerifier CodeVerifier, reauthenticator Reauthenticator, loginGuard LoginGuard) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.ReauthRequest
+		const op = "api/v1/HandleReauthenticate"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+		if (req.Password == "") == (req.Code == "") {
+			logError(c, logger, errors.New("either password or code is required"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		// a stolen access token must not give unlimited guesses at the password or code
+		ip := c.ClientIP()
+		attempt, ok := beginLogin(c, logger, loginGuard, op, principal.Username, ip)
+		if !ok {
+			return
+		}
+		fail := func(err error) {
+			if err := loginGuard.Fail(c.Request.Context(), attempt); err != nil {
+				logger.Error("failed to record failed reauthentication", zap.String("op", op), zap.Error(err))
+			}
+			logError(c, logger, err, http.StatusUnauthorized, "")
+		}
+
+		var methods []string
+		if req.Password != "" {
+			passhash, err := userLogger.GetUsersPassHash(principal.Username)
+			if err != nil {
+				logger.Error("failed to get password hash", zap.String("op", op), zap.Error(err))
+				logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
+				return
+			}
+			isRight, err := middleware.VerifyPassword(req.Password, passhash)
+			if err != nil || !isRight {
+				fail(errors.New("wrong password"))
+				return
+			}
+			methods = []string{auth.MethodPassword}
+		} else {
+			err := codeVerifier.Verify(principal.Username, req.Code)
+			if errors.Is(err, twofactor.ErrInvalidCode) {
+				fail(twofactor.ErrInvalidCode)
+				return
+			}
+			if err != nil {
+				logger.Error("failed to verify code", zap.String("op", op), zap.Error(err))
+				logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
+				return
+			}
+			methods = []string{auth.MethodOTP}
+		}
+
+		if err := loginGuard.Succeed(attempt); err != nil {
+			logger.Error("failed to reset failed logins", zap.String("op", op), zap.Error(err))
+		}
+
+		tokens, err := reauthenticator.Reauthenticate(principal.Username, principal.SessionID, methods)
+		if err != nil {
+			logger.Error("failed to reauthenticate", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("user reauthenticated", zap.String("op", op), zap.String("username", principal.Username), zap.Strings("amr", methods))
+		c.JSON(http.StatusOK, tokenResponse(tokens))
+	}
+}
+
+// HandleChangePassword godoc
+// @Summary Смена пароля
+// @Description Меняет пароль пользователя. Требует повторной аутентификации через /api/v1/reauth.
+// @Description Все остальные сессии пользователя отзываются.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body requests.ChangePasswordRequest true "Новый пароль"
+// @Success 200 {object} requests.AccountUpdatedResponse "Пароль изменен"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.StepUpRequiredError "Не авторизован или требуется повторная аутентификация"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/account/password [post]
+func HandleChangePassword(logger *zap.Logger, passwordChanger PasswordChanger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.ChangePasswordRequest
+		const op = "api/v1/HandleChangePassword"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		err := passwordChanger.UpdatePasswordHash(c.Request.Context(), principal.Username, middleware.HashPassword(req.NewPassword), principal.SessionID)
+		if err != nil {
+			logger.Error("failed to change password", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to change password"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("password changed", zap.String("op", op), zap.String("username", principal.Username))
+		c.JSON(http.StatusOK, requests.AccountUpdatedResponse{Message: "password changed"})
+	}
+}
+
+// HandleChangeEmail godoc
+// @Summary Смена email
+// @Description Меняет email пользователя. Требует повторной аутентификации через /api/v1/reauth.
+// @Description Новый email не подтвержден, на него отправляется письмо для подтверждения.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body requests.ChangeEmailRequest true "Новый email"
+// @Success 200 {object} requests.AccountUpdatedResponse "Email изменен"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.StepUpRequiredError "Не авторизован или требуется повторная аутентификация"
+// @Failure 409 {object} requests.EmailTakenError "Email уже используется"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/account/email [post]
+func HandleChangeEmail(logger *zap.Logger, emailChanger EmailChanger, verificationSender VerificationSender) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.ChangeEmailRequest
+		const op = "api/v1/HandleChangeEmail"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		taken, err := emailChanger.DoesEmailExists(req.Email)
+		if err != nil {
+			logger.Error("failed to check email", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to change email"), http.StatusInternalServerError, "")
+			return
+		}
+		if taken {
+			logError(c, logger, storage.ErrEmailTaken, http.StatusConflict, "")
+			return
+		}
+
+		err = emailChanger.UpdateEmail(principal.Username, req.Email)
+		if errors.Is(err, storage.ErrEmailTaken) {
+			logError(c, logger, storage.ErrEmailTaken, http.StatusConflict, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to change email", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to change email"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("email changed", zap.String("op", op), zap.String("username", principal.Username))
+		sendVerification(c.Request.Context(), logger, verificationSender, op, principal.Username)
+		c.JSON(http.StatusOK, requests.AccountUpdatedResponse{Message: "email changed"})
+	}
+}
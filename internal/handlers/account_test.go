@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/lockout"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/session"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/twofactor"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockCodeVerifier struct {
+	err error
+}
+
+func (m *MockCodeVerifier) Verify(username, code string) error {
+	return m.err
+}
+
+type MockReauthenticator struct {
+	err     error
+	methods []string
+}
+
+func (m *MockReauthenticator) Reauthenticate(username, sessionID string, methods []string) (session.Tokens, error) {
+	m.methods = methods
+	if m.err != nil {
+		return session.Tokens{}, m.err
+	}
+	return session.Tokens{
+		AccessToken:  "access-" + username,
+		RefreshToken: "refresh-" + username,
+	}, nil
+}
+
+type MockAccountUpdater struct {
+	emails       map[string]bool
+	passwordHash string
+	email        string
+	err          error
+}
+
+func (m *MockAccountUpdater) UpdatePasswordHash(ctx context.Context, username, passwordHash, keepSessionID string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.passwordHash = passwordHash
+	return nil
+}
+
+func (m *MockAccountUpdater) DoesEmailExists(email string) (bool, error) {
+	return m.emails[email], nil
+}
+
+func (m *MockAccountUpdater) UpdateEmail(username, email string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.email = email
+	return nil
+}
+
+func TestHandleReauthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+	passhash := middleware.HashPassword("secure_password")
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		codeError        error
+		sessionError     error
+		guardError       error
+		expectedStatus   int
+		expectedResponse string
+		expectedMethods  []string
+		expectedFailures int
+	}{
+		{
+			name:             "Password",
+			requestBody:      `{"password":"secure_password"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"token":"access-testuser","expires_at":"0001-01-01T00:00:00Z","refresh_token":"refresh-testuser","refresh_token_expires_at":"0001-01-01T00:00:00Z"}`,
+			expectedMethods:  []string{auth.MethodPassword},
+		},
+		{
+			name:             "Code",
+			requestBody:      `{"code":"123456"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"token":"access-testuser","expires_at":"0001-01-01T00:00:00Z","refresh_token":"refresh-testuser","refresh_token_expires_at":"0001-01-01T00:00:00Z"}`,
+			expectedMethods:  []string{auth.MethodOTP},
+		},
+		{
+			name:             "Neither",
+			requestBody:      `{}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"either password or code is required"}`,
+		},
+		{
+			name:             "Both",
+			requestBody:      `{"password":"secure_password","code":"123456"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"either password or code is required"}`,
+		},
+		{
+			name:             "Wrong_Password",
+			requestBody:      `{"password":"wrong"}`,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"wrong password"}`,
+			expectedFailures: 1,
+		},
+		{
+			name:             "Invalid_Code",
+			requestBody:      `{"code":"000000"}`,
+			codeError:        twofactor.ErrInvalidCode,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"invalid code"}`,
+			expectedFailures: 1,
+		},
+		{
+			name:             "Blocked",
+			requestBody:      `{"code":"123456"}`,
+			guardError:       &lockout.BlockedError{RetryAfter: 30 * time.Second},
+			expectedStatus:   http.StatusTooManyRequests,
+			expectedResponse: `{"status":"error","error":"too many failed login attempts"}`,
+		},
+		{
+			name:             "Session_Error",
+			requestBody:      `{"password":"secure_password"}`,
+			sessionError:     errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"could not create JWT token"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/reauth", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Authorization", validToken)
+
+			reauthenticator := &MockReauthenticator{err: tc.sessionError}
+			loginGuard := &MockLoginGuard{err: tc.guardError}
+			authenticated(HandleReauthenticate(newTestLogger(), &MockUserLogger{passhash: passhash},
+				&MockCodeVerifier{err: tc.codeError}, reauthenticator, loginGuard))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			assert.Equal(t, tc.expectedFailures, loginGuard.failures, "Failures mismatch")
+			if tc.expectedMethods != nil {
+				assert.Equal(t, tc.expectedMethods, reauthenticator.methods)
+				assert.True(t, loginGuard.succeeded)
+			}
+		})
+	}
+}
+
+func TestHandleChangePassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	freshToken, err := generateFreshJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate fresh JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Success",
+			requestBody:      `{"new_password":"new_secure_password"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"password changed"}`,
+		},
+		{
+			name:             "Missing_Password",
+			requestBody:      `{}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"request contains wrong data"}`,
+		},
+		{
+			name:             "Storage_Error",
+			requestBody:      `{"new_password":"new_secure_password"}`,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to change password"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/account/password", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Authorization", freshToken)
+
+			updater := &MockAccountUpdater{err: tc.mockError}
+			authenticated(HandleChangePassword(newTestLogger(), updater))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			if tc.expectedStatus == http.StatusOK {
+				ok, err := middleware.VerifyPassword("new_secure_password", updater.passwordHash)
+				assert.NoError(t, err)
+				assert.True(t, ok, "new password hash must be stored")
+			}
+		})
+	}
+}
+
+func TestHandleChangeEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	freshToken, err := generateFreshJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate fresh JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Success",
+			requestBody:      `{"email":"john.new@example.com"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"email changed"}`,
+		},
+		{
+			name:             "Invalid_Email",
+			requestBody:      `{"email":"not an email"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"request contains wrong data"}`,
+		},
+		{
+			name:             "Email_Taken",
+			requestBody:      `{"email":"jane@example.com"}`,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"status":"error","error":"email is taken"}`,
+		},
+		{
+			name:             "Email_Taken_Concurrently",
+			requestBody:      `{"email":"john.new@example.com"}`,
+			mockError:        storage.ErrEmailTaken,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"status":"error","error":"email is taken"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/account/email", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Authorization", freshToken)
+
+			updater := &MockAccountUpdater{emails: map[string]bool{"jane@example.com": true}, err: tc.mockError}
+			verifications := &MockVerification{}
+			authenticated(HandleChangeEmail(newTestLogger(), updater, verifications))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, []string{"testuser"}, verifications.sent, "the new email is sent a verification link")
+			} else {
+				assert.Empty(t, verifications.sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,457 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAdjustmentsLimit = 20
+	maxAdjustmentsLimit     = 100
+)
+
+type AdjustmentProposer interface {
+	ProposeAdjustment(ctx context.Context, adjustment *models.BalanceAdjustment) error
+}
+
+type AdjustmentGetter interface {
+	GetAdjustment(id int64) (*models.BalanceAdjustment, error)
+}
+
+type AdjustmentLister interface {
+	ListAdjustments(filter models.AdjustmentFilter) ([]models.BalanceAdjustment, error)
+}
+
+type AdjustmentApprover interface {
+	ApproveAdjustment(ctx context.Context, id int64, approver string) (*models.BalanceAdjustment, error)
+	GetUserBalance(username string) (map[string]money.Amount, error)
+}
+
+type AdjustmentRejecter interface {
+	RejectAdjustment(ctx context.Context, id int64, reviewer, note string) (*models.BalanceAdjustment, error)
+}
+
+// HandleAdminProposeAdjustment godoc
+// @Summary Заявка на ручную корректировку баланса
+// @Description Создает заявку на зачисление положительной или списание отрицательной суммы с кошелька пользователя.
+// @Description Баланс изменится только после одобрения заявки другим администратором, неодобренная заявка истекает.
+// @Description Причина обязательна. Корректировать свой баланс нельзя. Доступно роли admin, требует недавней аутентификации.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param   username path string true "Имя пользователя"
+// @Param   request body requests.BalanceAdjustmentRequest true "Валюта, сумма и причина корректировки"
+// @Success 202 {object} requests.BalanceAdjustmentResponse "Заявка создана"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.StepUpRequiredError "Не авторизован или требуется повторная аутентификация"
+// @Failure 403 {object} requests.SelfAdjustmentError "Недостаточно прав или корректировка своего баланса"
+// @Failure 404 {object} requests.WalletNotFoundError "У пользователя нет кошелька в валюте"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{username}/adjustments [post]
+func HandleAdminProposeAdjustment(logger *zap.Logger, proposer AdjustmentProposer, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.BalanceAdjustmentRequest
+		const op = "api/v1/HandleAdminProposeAdjustment"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+		if req.Amount.IsZero() {
+			logError(c, logger, errors.New("amount must not be zero"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+		if !checkAmountScale(c, logger, req.Amount) {
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" {
+			logError(c, logger, errors.New("reason is required"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		adjustment := &models.BalanceAdjustment{
+			Username:  c.Param("username"),
+			Currency:  strings.ToUpper(req.Currency),
+			Amount:    req.Amount,
+			Reason:    req.Reason,
+			CreatedBy: principal.Username,
+			ExpiresAt: time.Now().Add(ttl),
+		}
+		err := proposer.ProposeAdjustment(c.Request.Context(), adjustment)
+		if errors.Is(err, storage.ErrWalletNotFound) {
+			logError(c, logger, storage.ErrWalletNotFound, http.StatusNotFound, "")
+			return
+		}
+		if errors.Is(err, storage.ErrSelfAdjustment) {
+			logError(c, logger, storage.ErrSelfAdjustment, http.StatusForbidden, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to propose adjustment", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to propose adjustment"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("adjustment proposed", zap.String("op", op),
+			zap.Int64("adjustment_id", adjustment.ID),
+			zap.String("username", adjustment.Username),
+			zap.String("currency", adjustment.Currency),
+			zap.String("amount", adjustment.Amount.String()),
+			zap.String("by", principal.Username),
+			zap.String("reason", adjustment.Reason),
+		)
+		c.JSON(http.StatusAccepted, adjustmentResponse(adjustment))
+	}
+}
+
+// HandleAdminListAdjustments godoc
+// @Summary Заявки на корректировку баланса
+// @Description Возвращает заявки на корректировку баланса, начиная с самых новых, вместе с тем, кто их
+// @Description создал, одобрил или отклонил. Доступно ролям support, admin и auditor.
+// @Tags admin
+// @Produce json
+// @Param   status query string false "Статус заявки" Enums(pending, approved, rejected, expired)
+// @Param   username query string false "Пользователь, чей баланс корректируется"
+// @Param   limit query int false "Размер страницы, не больше 100" default(20)
+// @Param   offset query int false "next_offset из предыдущего ответа" default(0)
+// @Success 200 {object} requests.BalanceAdjustmentsResponse "Заявки"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.ForbiddenError "Недостаточно прав"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/adjustments [get]
+func HandleAdminListAdjustments(logger *zap.Logger, lister AdjustmentLister) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleAdminListAdjustments"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		filter, err := parseAdjustmentFilter(c)
+		if err != nil {
+			logError(c, logger, err, http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		// ask for one extra row to know whether there is a next page
+		limit := filter.Limit
+		filter.Limit++
+
+		adjustments, err := lister.ListAdjustments(filter)
+		if err != nil {
+			logger.Error("failed to list adjustments", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to list adjustments"), http.StatusInternalServerError, "")
+			return
+		}
+
+		response := requests.BalanceAdjustmentsResponse{
+			Adjustments: make([]requests.BalanceAdjustmentResponse, 0, len(adjustments)),
+		}
+		if len(adjustments) > limit {
+			adjustments = adjustments[:limit]
+			nextOffset := filter.Offset + limit
+			response.NextOffset = &nextOffset
+		}
+		for i := range adjustments {
+			response.Adjustments = append(response.Adjustments, adjustmentResponse(&adjustments[i]))
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// HandleAdminGetAdjustment godoc
+// @Summary Заявка на корректировку баланса
+// @Description Возвращает заявку на корректировку баланса. Доступно ролям support, admin и auditor.
+// @Tags admin
+// @Produce json
+// @Param   id path int true "Номер заявки"
+// @Success 200 {object} requests.BalanceAdjustmentResponse "Заявка"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.ForbiddenError "Недостаточно прав"
+// @Failure 404 {object} requests.AdjustmentNotFoundError "Заявка не найдена"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/adjustments/{id} [get]
+func HandleAdminGetAdjustment(logger *zap.Logger, getter AdjustmentGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleAdminGetAdjustment"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		id, err := adjustmentID(c)
+		if err != nil {
+			logError(c, logger, err, http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		adjustment, err := getter.GetAdjustment(id)
+		if errors.Is(err, storage.ErrAdjustmentNotFound) {
+			logError(c, logger, storage.ErrAdjustmentNotFound, http.StatusNotFound, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to get adjustment", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to get adjustment"), http.StatusInternalServerError, "")
+			return
+		}
+
+		c.JSON(http.StatusOK, adjustmentResponse(adjustment))
+	}
+}
+
+// HandleAdminApproveAdjustment godoc
+// @Summary Одобрение заявки на корректировку баланса
+// @Description Проводит заявку на корректировку баланса. Одобрить заявку может только другой администратор,
+// @Description не тот, кто ее создал, и не тот, чей баланс корректируется. Если списание больше баланса,
+// @Description заявка остается ожидающей.
+// @Description Доступно роли admin, требует недавней аутентификации.
+// @Tags admin
+// @Produce json
+// @Param   id path int true "Номер заявки"
+// @Success 200 {object} requests.BalanceAdjustmentResponse "Баланс скорректирован"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.StepUpRequiredError "Не авторизован или требуется повторная аутентификация"
+// @Failure 403 {object} requests.SelfApprovalError "Недостаточно прав, заявка создана этим же администратором или корректирует его баланс"
+// @Failure 404 {object} requests.AdjustmentNotFoundError "Заявка не найдена"
+// @Failure 409 {object} requests.AdjustmentNotPendingError "Заявка уже рассмотрена или списание больше баланса"
+// @Failure 410 {object} requests.AdjustmentExpiredError "Заявка истекла"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/adjustments/{id}/approve [post]
+func HandleAdminApproveAdjustment(logger *zap.Logger, approver AdjustmentApprover) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleAdminApproveAdjustment"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		id, err := adjustmentID(c)
+		if err != nil {
+			logError(c, logger, err, http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		adjustment, err := approver.ApproveAdjustment(c.Request.Context(), id, principal.Username)
+		if err != nil {
+			if !writeReviewError(c, logger, err) {
+				logger.Error("failed to approve adjustment", zap.String("op", op), zap.Error(err))
+				logError(c, logger, errors.New("failed to approve adjustment"), http.StatusInternalServerError, "")
+			}
+			return
+		}
+
+		logger.Info("adjustment approved", zap.String("op", op),
+			zap.Int64("adjustment_id", adjustment.ID),
+			zap.Int64("entry_id", adjustment.EntryID),
+			zap.String("username", adjustment.Username),
+			zap.String("currency", adjustment.Currency),
+			zap.String("amount", adjustment.Amount.String()),
+			zap.String("proposed_by", adjustment.CreatedBy),
+			zap.String("by", principal.Username),
+		)
+
+		response := adjustmentResponse(adjustment)
+		balance, err := approver.GetUserBalance(adjustment.Username)
+		if err != nil {
+			// the adjustment is posted already, the balance is only informative
+			logger.Warn("failed to get balance", zap.String("op", op), zap.Error(err))
+		}
+		response.Balance = balance
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// HandleAdminRejectAdjustment godoc
+// @Summary Отклонение заявки на корректировку баланса
+// @Description Отклоняет заявку на корректировку баланса, баланс не меняется. Создатель заявки может
+// @Description отклонить ее сам, чтобы отозвать. Доступно роли admin.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param   id path int true "Номер заявки"
+// @Param   request body requests.RejectAdjustmentRequest true "Причина отклонения"
+// @Success 200 {object} requests.BalanceAdjustmentResponse "Заявка отклонена"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.ForbiddenError "Недостаточно прав"
+// @Failure 404 {object} requests.AdjustmentNotFoundError "Заявка не найдена"
+// @Failure 409 {object} requests.AdjustmentNotPendingError "Заявка уже рассмотрена"
+// @Failure 410 {object} requests.AdjustmentExpiredError "Заявка истекла"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/adjustments/{id}/reject [post]
+func HandleAdminRejectAdjustment(logger *zap.Logger, rejecter AdjustmentRejecter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.RejectAdjustmentRequest
+		const op = "api/v1/HandleAdminRejectAdjustment"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		id, err := adjustmentID(c)
+		if err != nil {
+			logError(c, logger, err, http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" {
+			logError(c, logger, errors.New("reason is required"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		adjustment, err := rejecter.RejectAdjustment(c.Request.Context(), id, principal.Username, req.Reason)
+		if err != nil {
+			if !writeReviewError(c, logger, err) {
+				logger.Error("failed to reject adjustment", zap.String("op", op), zap.Error(err))
+				logError(c, logger, errors.New("failed to reject adjustment"), http.StatusInternalServerError, "")
+			}
+			return
+		}
+
+		logger.Info("adjustment rejected", zap.String("op", op),
+			zap.Int64("adjustment_id", adjustment.ID),
+			zap.String("username", adjustment.Username),
+			zap.String("proposed_by", adjustment.CreatedBy),
+			zap.String("by", principal.Username),
+			zap.String("reason", req.Reason),
+		)
+		c.JSON(http.StatusOK, adjustmentResponse(adjustment))
+	}
+}
+
+// writeReviewError responds to the expected errors of reviewing an adjustment and tells
+// whether err was one of them.
+func writeReviewError(c *gin.Context, logger *zap.Logger, err error) bool {
+	switch {
+	case errors.Is(err, storage.ErrAdjustmentNotFound):
+		logError(c, logger, storage.ErrAdjustmentNotFound, http.StatusNotFound, "")
+	case errors.Is(err, storage.ErrAdjustmentNotPending):
+		logError(c, logger, storage.ErrAdjustmentNotPending, http.StatusConflict, "")
+	case errors.Is(err, storage.ErrAdjustmentExpired):
+		logError(c, logger, storage.ErrAdjustmentExpired, http.StatusGone, "")
+	case errors.Is(err, storage.ErrSelfApproval):
+		logError(c, logger, storage.ErrSelfApproval, http.StatusForbidden, "")
+	case errors.Is(err, storage.ErrSelfAdjustment):
+		logError(c, logger, storage.ErrSelfAdjustment, http.StatusForbidden, "")
+	case errors.Is(err, storage.ErrNotEnoughFunds):
+		logError(c, logger, storage.ErrNotEnoughFunds, http.StatusConflict, "")
+	default:
+		return false
+	}
+	return true
+}
+
+func adjustmentID(c *gin.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid adjustment id")
+	}
+	return id, nil
+}
+
+func parseAdjustmentFilter(c *gin.Context) (models.AdjustmentFilter, error) {
+	filter := models.AdjustmentFilter{
+		Status:   models.AdjustmentStatus(c.Query("status")),
+		Username: c.Query("username"),
+		Limit:    defaultAdjustmentsLimit,
+	}
+
+	switch filter.Status {
+	case "", models.AdjustmentPending, models.AdjustmentApproved, models.AdjustmentRejected, models.AdjustmentExpired:
+	default:
+		return filter, fmt.Errorf("unknown adjustment status %s", filter.Status)
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAdjustmentsLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxAdjustmentsLimit)
+		}
+		filter.Limit = limit
+	}
+
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, errors.New("offset must be a non-negative integer")
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+func adjustmentResponse(adjustment *models.BalanceAdjustment) requests.BalanceAdjustmentResponse {
+	response := requests.BalanceAdjustmentResponse{
+		ID:         adjustment.ID,
+		Username:   adjustment.Username,
+		Currency:   adjustment.Currency,
+		Amount:     adjustment.Amount,
+		Reason:     adjustment.Reason,
+		Status:     string(adjustment.Status),
+		CreatedBy:  adjustment.CreatedBy,
+		CreatedAt:  adjustment.CreatedAt,
+		EntryID:    adjustment.EntryID,
+		ReviewedBy: adjustment.ReviewedBy,
+		ReviewNote: adjustment.ReviewNote,
+	}
+	if !adjustment.ExpiresAt.IsZero() {
+		expiresAt := adjustment.ExpiresAt
+		response.ExpiresAt = &expiresAt
+	}
+	if !adjustment.ReviewedAt.IsZero() {
+		reviewedAt := adjustment.ReviewedAt
+		response.ReviewedAt = &reviewedAt
+	}
+	return response
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var adjustmentCreatedAt = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+type MockAdjustmentStore struct {
+	adjustments []models.BalanceAdjustment
+	balance     map[string]money.Amount
+	err         error
+	filter      models.AdjustmentFilter
+	proposed    *models.BalanceAdjustment
+	reviewer    string
+	note        string
+}
+
+func (m *MockAdjustmentStore) ProposeAdjustment(ctx context.Context, adjustment *models.BalanceAdjustment) error {
+	if m.err != nil {
+		return m.err
+	}
+	adjustment.ID = 42
+	adjustment.Status = models.AdjustmentPending
+	adjustment.CreatedAt = adjustmentCreatedAt
+	m.proposed = adjustment
+	return nil
+}
+
+func (m *MockAdjustmentStore) GetAdjustment(id int64) (*models.BalanceAdjustment, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for i := range m.adjustments {
+		if m.adjustments[i].ID == id {
+			return &m.adjustments[i], nil
+		}
+	}
+	return nil, fmt.Errorf("storage.postgres.GetAdjustment: %w", storage.ErrAdjustmentNotFound)
+}
+
+func (m *MockAdjustmentStore) ListAdjustments(filter models.AdjustmentFilter) ([]models.BalanceAdjustment, error) {
+	m.filter = filter
+	if m.err != nil {
+		return nil, m.err
+	}
+	if len(m.adjustments) > filter.Limit {
+		return m.adjustments[:filter.Limit], nil
+	}
+	return m.adjustments, nil
+}
+
+func (m *MockAdjustmentStore) ApproveAdjustment(ctx context.Context, id int64, approver string) (*models.BalanceAdjustment, error) {
+	m.reviewer = approver
+	adjustment, err := m.GetAdjustment(id)
+	if err != nil {
+		return nil, err
+	}
+	approved := *adjustment
+	approved.Status = models.AdjustmentApproved
+	approved.EntryID = 108
+	approved.ReviewedBy = approver
+	approved.ReviewedAt = adjustmentCreatedAt.Add(30 * time.Minute)
+	return &approved, nil
+}
+
+func (m *MockAdjustmentStore) RejectAdjustment(ctx context.Context, id int64, reviewer, note string) (*models.BalanceAdjustment, error) {
+	m.reviewer, m.note = reviewer, note
+	adjustment, err := m.GetAdjustment(id)
+	if err != nil {
+		return nil, err
+	}
+	rejected := *adjustment
+	rejected.Status = models.AdjustmentRejected
+	rejected.ReviewedBy = reviewer
+	rejected.ReviewedAt = adjustmentCreatedAt.Add(30 * time.Minute)
+	rejected.ReviewNote = note
+	return &rejected, nil
+}
+
+func (m *MockAdjustmentStore) GetUserBalance(username string) (map[string]money.Amount, error) {
+	return m.balance, nil
+}
+
+func pendingAdjustment() models.BalanceAdjustment {
+	return models.BalanceAdjustment{
+		ID:        42,
+		Username:  "alice",
+		Currency:  "USD",
+		Amount:    money.MustParse("-25"),
+		Reason:    "duplicated deposit",
+		Status:    models.AdjustmentPending,
+		CreatedBy: "admin",
+		CreatedAt: adjustmentCreatedAt,
+		ExpiresAt: adjustmentCreatedAt.Add(24 * time.Hour),
+	}
+}
+
+func TestHandleAdminProposeAdjustment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("admin")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Debit",
+			requestBody:      `{"currency":"usd","amount":"-25","reason":" duplicated deposit "}`,
+			expectedStatus:   http.StatusAccepted,
+			expectedResponse: "",
+		},
+		{
+			name:             "Zero_Amount",
+			requestBody:      `{"currency":"USD","amount":"0","reason":"nothing"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"amount must not be zero"}`,
+		},
+		{
+			name:             "Too_Many_Decimal_Places",
+			requestBody:      `{"currency":"USD","amount":"-0.000000001","reason":"rounding"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"amount must have at most 8 decimal places"}`,
+		},
+		{
+			name:             "Missing_Reason",
+			requestBody:      `{"currency":"USD","amount":"10"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"request contains wrong data"}`,
+		},
+		{
+			name:             "Wallet_Not_Found",
+			requestBody:      `{"currency":"JPY","amount":"10","reason":"compensation"}`,
+			mockError:        fmt.Errorf("storage.postgres.ProposeAdjustment: %w", storage.ErrWalletNotFound),
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"status":"error","error":"wallet not found"}`,
+		},
+		{
+			name:             "Own_Balance",
+			requestBody:      `{"currency":"USD","amount":"10","reason":"compensation"}`,
+			mockError:        fmt.Errorf("storage.postgres.ProposeAdjustment: %w", storage.ErrSelfAdjustment),
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"status":"error","error":"admins cannot adjust their own balance"}`,
+		},
+		{
+			name:             "Storage_Error",
+			requestBody:      `{"currency":"USD","amount":"10","reason":"compensation"}`,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to propose adjustment"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, w := newAdminRequest(http.MethodPost, "/api/v1/admin/users/alice/adjustments", validToken, tc.requestBody, "alice")
+			store := &MockAdjustmentStore{err: tc.mockError}
+
+			before := time.Now()
+			authenticated(HandleAdminProposeAdjustment(newTestLogger(), store, 24*time.Hour))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			if tc.expectedResponse != "" {
+				assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+				return
+			}
+
+			proposed := store.proposed
+			if assert.NotNil(t, proposed) {
+				assert.Equal(t, "alice", proposed.Username)
+				assert.Equal(t, "USD", proposed.Currency)
+				assert.Equal(t, "-25", proposed.Amount.String())
+				assert.Equal(t, "duplicated deposit", proposed.Reason)
+				assert.Equal(t, "admin", proposed.CreatedBy)
+				assert.WithinDuration(t, before.Add(24*time.Hour), proposed.ExpiresAt, time.Second)
+			}
+			assert.Contains(t, w.Body.String(), `"status":"pending"`)
+		})
+	}
+}
+
+func TestHandleAdminListAdjustments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("auditor")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	rejected := pendingAdjustment()
+	rejected.ID = 41
+	rejected.Status = models.AdjustmentRejected
+	rejected.ReviewedBy = "bob"
+	rejected.ReviewedAt = adjustmentCreatedAt.Add(time.Hour)
+	rejected.ReviewNote = "wrong currency"
+	adjustments := []models.BalanceAdjustment{pendingAdjustment(), rejected}
+
+	testCases := []struct {
+		name             string
+		query            string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+		expectedFilter   *models.AdjustmentFilter
+	}{
+		{
+			name:           "First_Page",
+			query:          "?limit=1",
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"adjustments":[
+				{"id":42,"username":"alice","currency":"USD","amount":"-25","reason":"duplicated deposit","status":"pending",
+				"created_by":"admin","created_at":"2025-03-01T12:00:00Z","expires_at":"2025-03-02T12:00:00Z"}
+			],"next_offset":1}`,
+		},
+		{
+			name:           "History",
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"adjustments":[
+				{"id":42,"username":"alice","currency":"USD","amount":"-25","reason":"duplicated deposit","status":"pending",
+				"created_by":"admin","created_at":"2025-03-01T12:00:00Z","expires_at":"2025-03-02T12:00:00Z"},
+				{"id":41,"username":"alice","currency":"USD","amount":"-25","reason":"duplicated deposit","status":"rejected",
+				"created_by":"admin","created_at":"2025-03-01T12:00:00Z","expires_at":"2025-03-02T12:00:00Z",
+				"reviewed_by":"bob","reviewed_at":"2025-03-01T13:00:00Z","review_note":"wrong currency"}
+			]}`,
+		},
+		{
+			name:           "Filters_Are_Passed_To_Storage",
+			query:          "?status=pending&username=alice&offset=20",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &models.AdjustmentFilter{Status: models.AdjustmentPending, Username: "alice", Limit: defaultAdjustmentsLimit + 1, Offset: 20},
+		},
+		{
+			name:             "Unknown_Status",
+			query:            "?status=posted",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"unknown adjustment status posted"}`,
+		},
+		{
+			name:             "Storage_Error",
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to list adjustments"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, w := newAdminRequest(http.MethodGet, "/api/v1/admin/adjustments"+tc.query, validToken, "", "")
+			store := &MockAdjustmentStore{adjustments: adjustments, err: tc.mockError}
+
+			authenticated(HandleAdminListAdjustments(newTestLogger(), store))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			if tc.expectedResponse != "" {
+				assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			}
+			if tc.expectedFilter != nil {
+				assert.Equal(t, *tc.expectedFilter, store.filter, "Filter mismatch")
+			}
+		})
+	}
+}
+
+func TestHandleAdminReviewAdjustment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("bob")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		reject           bool
+		id               string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:           "Approve",
+			id:             "42",
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"id":42,"username":"alice","currency":"USD","amount":"-25","reason":"duplicated deposit","status":"approved",
+				"created_by":"admin","created_at":"2025-03-01T12:00:00Z","expires_at":"2025-03-02T12:00:00Z","entry_id":108,
+				"reviewed_by":"bob","reviewed_at":"2025-03-01T12:30:00Z","balance":{"USD":"75"}}`,
+		},
+		{
+			name:             "Approve_Invalid_ID",
+			id:               "abc",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"invalid adjustment id"}`,
+		},
+		{
+			name:             "Approve_Not_Found",
+			id:               "7",
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"status":"error","error":"adjustment not found"}`,
+		},
+		{
+			name:             "Approve_Own_Request",
+			id:               "42",
+			mockError:        fmt.Errorf("storage.postgres.ApproveAdjustment: %w", storage.ErrSelfApproval),
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"status":"error","error":"adjustment cannot be approved by its proposer"}`,
+		},
+		{
+			name:             "Approve_Own_Balance",
+			id:               "42",
+			mockError:        fmt.Errorf("storage.postgres.ApproveAdjustment: %w", storage.ErrSelfAdjustment),
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"status":"error","error":"admins cannot adjust their own balance"}`,
+		},
+		{
+			name:             "Approve_Reviewed",
+			id:               "42",
+			mockError:        fmt.Errorf("storage.postgres.ApproveAdjustment: %w", storage.ErrAdjustmentNotPending),
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"status":"error","error":"adjustment is not pending"}`,
+		},
+		{
+			name:             "Approve_Expired",
+			id:               "42",
+			mockError:        fmt.Errorf("storage.postgres.ApproveAdjustment: %w", storage.ErrAdjustmentExpired),
+			expectedStatus:   http.StatusGone,
+			expectedResponse: `{"status":"error","error":"adjustment expired"}`,
+		},
+		{
+			name:             "Approve_Not_Enough_Funds",
+			id:               "42",
+			mockError:        fmt.Errorf("storage.postgres.ApproveAdjustment: %w", storage.ErrNotEnoughFunds),
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"status":"error","error":"not enough money"}`,
+		},
+		{
+			name:           "Reject",
+			reject:         true,
+			id:             "42",
+			requestBody:    `{"reason":"wrong currency"}`,
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"id":42,"username":"alice","currency":"USD","amount":"-25","reason":"duplicated deposit","status":"rejected",
+				"created_by":"admin","created_at":"2025-03-01T12:00:00Z","expires_at":"2025-03-02T12:00:00Z",
+				"reviewed_by":"bob","reviewed_at":"2025-03-01T12:30:00Z","review_note":"wrong currency"}`,
+		},
+		{
+			name:             "Reject_Without_Reason",
+			reject:           true,
+			id:               "42",
+			requestBody:      `{"reason":" "}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"reason is required"}`,
+		},
+		{
+			name:             "Reject_Storage_Error",
+			reject:           true,
+			id:               "42",
+			requestBody:      `{"reason":"wrong currency"}`,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to reject adjustment"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, w := newAdminRequest(http.MethodPost, "/api/v1/admin/adjustments/"+tc.id, validToken, tc.requestBody, "")
+			c.Params = gin.Params{{Key: "id", Value: tc.id}}
+			store := &MockAdjustmentStore{
+				adjustments: []models.BalanceAdjustment{pendingAdjustment()},
+				balance:     map[string]money.Amount{"USD": money.MustParse("75")},
+				err:         tc.mockError,
+			}
+
+			if tc.reject {
+				authenticated(HandleAdminRejectAdjustment(newTestLogger(), store))(c)
+			} else {
+				authenticated(HandleAdminApproveAdjustment(newTestLogger(), store))(c)
+			}
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+		})
+	}
+}
+
+func TestHandleAdminGetAdjustment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("support")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	store := &MockAdjustmentStore{adjustments: []models.BalanceAdjustment{pendingAdjustment()}}
+
+	for id, expectedStatus := range map[string]int{"42": http.StatusOK, "7": http.StatusNotFound, "0": http.StatusBadRequest} {
+		c, w := newAdminRequest(http.MethodGet, "/api/v1/admin/adjustments/"+id, validToken, "", "")
+		c.Params = gin.Params{{Key: "id", Value: id}}
+
+		authenticated(HandleAdminGetAdjustment(newTestLogger(), store))(c)
+
+		assert.Equal(t, expectedStatus, w.Code, "Status code mismatch for %s", id)
+	}
+}
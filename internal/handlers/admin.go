@@ -0,0 +1,479 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultUsersLimit = 20
+	maxUsersLimit     = 100
+)
+
+type UserLister interface {
+	ListUsers(filter models.UserFilter) ([]models.User, error)
+}
+
+type UserGetter interface {
+	GetUser(username string) (*models.User, error)
+}
+
+type AccountFreezer interface {
+	FreezeAccount(username, by, reason string) error
+	UnfreezeAccount(username string) error
+}
+
+type RoleSetter interface {
+	SetUserRoles(username string, roles []string, by string) error
+}
+
+// HandleAdminListUsers godoc
+// @Summary Поиск пользователей
+// @Description Возвращает пользователей, у которых имя или email содержат q, в порядке регистрации.
+// @Description Доступно ролям support, admin и auditor.
+// @Tags admin
+// @Produce json
+// @Param   q query string false "Часть имени пользователя или email" example(john)
+// @Param   role query string false "Роль пользователя" Enums(user, support, admin, auditor)
+// @Param   limit query int false "Размер страницы, не больше 100" default(20)
+// @Param   offset query int false "next_offset из предыдущего ответа" default(0)
+// @Success 200 {object} requests.AdminUsersResponse "Пользователи"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.ForbiddenError "Недостаточно прав"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users [get]
+func HandleAdminListUsers(logger *zap.Logger, userLister UserLister) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleAdminListUsers"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		filter, err := parseUserFilter(c)
+		if err != nil {
+			logError(c, logger, err, http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		// ask for one extra row to know whether there is a next page
+		limit := filter.Limit
+		filter.Limit++
+
+		users, err := userLister.ListUsers(filter)
+		if err != nil {
+			logger.Error("failed to list users", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to list users"), http.StatusInternalServerError, "")
+			return
+		}
+
+		response := requests.AdminUsersResponse{
+			Users: make([]requests.AdminUser, 0, len(users)),
+		}
+		if len(users) > limit {
+			users = users[:limit]
+			nextOffset := filter.Offset + limit
+			response.NextOffset = &nextOffset
+		}
+		for i := range users {
+			response.Users = append(response.Users, adminUser(&users[i]))
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// HandleAdminGetUser godoc
+// @Summary Пользователь
+// @Description Возвращает пользователя с его ролями и состоянием заморозки. Доступно ролям support, admin и auditor.
+// @Tags admin
+// @Produce json
+// @Param   username path string true "Имя пользователя"
+// @Success 200 {object} requests.AdminUser "Пользователь"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.ForbiddenError "Недостаточно прав"
+// @Failure 404 {object} requests.UserNotFoundError "Пользователь не найден"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{username} [get]
+func HandleAdminGetUser(logger *zap.Logger, userGetter UserGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleAdminGetUser"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		user, ok := getTargetUser(c, logger, userGetter, op)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, adminUser(user))
+	}
+}
+
+// HandleAdminBalance godoc
+// @Summary Баланс пользователя
+// @Description Возвращает баланс кошельков любого пользователя. Доступно ролям support, admin и auditor.
+// @Tags admin
+// @Produce json
+// @Param   username path string true "Имя пользователя"
+// @Success 200 {object} requests.BalanceResponse "OK"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.ForbiddenError "Недостаточно прав"
+// @Failure 404 {object} requests.UserNotFoundError "Пользователь не найден"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{username}/balance [get]
+func HandleAdminBalance(logger *zap.Logger, userGetter UserGetter, balanceGetter BalanceGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleAdminBalance"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		user, ok := getTargetUser(c, logger, userGetter, op)
+		if !ok {
+			return
+		}
+
+		balance, err := balanceGetter.GetUserBalance(user.Username)
+		if err != nil {
+			logger.Error("failed to get balance", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to get balance"), http.StatusInternalServerError, "")
+			return
+		}
+
+		c.JSON(http.StatusOK, requests.BalanceResponse{Balance: balance})
+	}
+}
+
+// HandleAdminTransactions godoc
+// @Summary История операций пользователя
+// @Description Возвращает историю операций любого пользователя с теми же фильтрами, что и /api/v1/transactions.
+// @Description Доступно ролям support, admin и auditor.
+// @Tags admin
+// @Produce json
+// @Param   username path string true "Имя пользователя"
+// @Param   type query string false "Тип операции" Enums(deposit, withdrawal, exchange, transfer_in, transfer_out, adjustment_in, adjustment_out)
+// @Param   currency query string false "Валюта операции" example(USD)
+// @Param   from query string false "Начало периода, RFC3339" example(2025-03-01T00:00:00Z)
+// @Param   to query string false "Конец периода (не включительно), RFC3339" example(2025-04-01T00:00:00Z)
+// @Param   min_amount query string false "Минимальная сумма" example(10)
+// @Param   max_amount query string false "Максимальная сумма" example(1000)
+// @Param   limit query int false "Размер страницы, не больше 100" default(20)
+// @Param   cursor query string false "next_cursor из предыдущего ответа"
+// @Success 200 {object} requests.TransactionsResponse "OK"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.ForbiddenError "Недостаточно прав"
+// @Failure 404 {object} requests.UserNotFoundError "Пользователь не найден"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{username}/transactions [get]
+func HandleAdminTransactions(logger *zap.Logger, userGetter UserGetter, transactionsGetter TransactionsGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleAdminTransactions"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		user, ok := getTargetUser(c, logger, userGetter, op)
+		if !ok {
+			return
+		}
+
+		writeTransactions(c, logger, transactionsGetter, user.Username)
+	}
+}
+
+// HandleAdminFreeze godoc
+// @Summary Заморозка аккаунта
+// @Description Запрещает пользователю пополнения, снятия, обмены и переводы до разморозки. Заморозка уже
+// @Description замороженного аккаунта сохраняет исходную причину. Доступно ролям support и admin,
+// @Description аккаунт администратора может заморозить только admin. Заморозить свой аккаунт нельзя.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param   username path string true "Имя пользователя"
+// @Param   request body requests.FreezeAccountRequest true "Причина заморозки"
+// @Success 200 {object} requests.AccountUpdatedResponse "Аккаунт заморожен"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос или попытка заморозить свой аккаунт"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.ForbiddenError "Недостаточно прав"
+// @Failure 404 {object} requests.UserNotFoundError "Пользователь не найден"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{username}/freeze [post]
+func HandleAdminFreeze(logger *zap.Logger, userGetter UserGetter, freezer AccountFreezer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.FreezeAccountRequest
+		const op = "api/v1/HandleAdminFreeze"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" {
+			logError(c, logger, errors.New("reason is required"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		username := c.Param("username")
+		if username == principal.Username {
+			logError(c, logger, errors.New("cannot freeze your own account"), http.StatusBadRequest, "")
+			return
+		}
+
+		user, ok := getTargetUser(c, logger, userGetter, op)
+		if !ok {
+			return
+		}
+		// support must not be able to lock admins out of the admin API
+		if containsRole(user.Roles, auth.RoleAdmin) && !containsRole(principal.Roles, auth.RoleAdmin) {
+			logError(c, logger, errors.New("only an admin can freeze an admin"), http.StatusForbidden, "")
+			return
+		}
+
+		err := freezer.FreezeAccount(username, principal.Username, req.Reason)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			logError(c, logger, storage.ErrUserNotFound, http.StatusNotFound, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to freeze account", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to freeze account"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("account frozen", zap.String("op", op),
+			zap.String("username", username),
+			zap.String("by", principal.Username),
+			zap.String("reason", req.Reason),
+		)
+		c.JSON(http.StatusOK, requests.AccountUpdatedResponse{Message: "account frozen"})
+	}
+}
+
+// HandleAdminUnfreeze godoc
+// @Summary Разморозка аккаунта
+// @Description Снимает заморозку с аккаунта пользователя. Доступно ролям support и admin.
+// @Tags admin
+// @Produce json
+// @Param   username path string true "Имя пользователя"
+// @Success 200 {object} requests.AccountUpdatedResponse "Аккаунт разморожен"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.ForbiddenError "Недостаточно прав"
+// @Failure 404 {object} requests.UserNotFoundError "Пользователь не найден"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{username}/unfreeze [post]
+func HandleAdminUnfreeze(logger *zap.Logger, freezer AccountFreezer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleAdminUnfreeze"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		username := c.Param("username")
+		err := freezer.UnfreezeAccount(username)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			logError(c, logger, storage.ErrUserNotFound, http.StatusNotFound, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to unfreeze account", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to unfreeze account"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("account unfrozen", zap.String("op", op),
+			zap.String("username", username),
+			zap.String("by", principal.Username),
+		)
+		c.JSON(http.StatusOK, requests.AccountUpdatedResponse{Message: "account unfrozen"})
+	}
+}
+
+// HandleAdminSetRoles godoc
+// @Summary Изменение ролей пользователя
+// @Description Заменяет роли пользователя. Все сессии пользователя отзываются, новые роли попадут в токены при следующем входе.
+// @Description Изменение сохраняется в журнале изменений ролей вместе с тем, кто его сделал.
+// @Description Администратор не может снять роль admin с самого себя. Доступно роли admin, требует недавней аутентификации.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param   username path string true "Имя пользователя"
+// @Param   request body requests.SetRolesRequest true "Роли пользователя"
+// @Success 200 {object} requests.AccountUpdatedResponse "Роли изменены"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.StepUpRequiredError "Не авторизован или требуется повторная аутентификация"
+// @Failure 403 {object} requests.ForbiddenError "Недостаточно прав"
+// @Failure 404 {object} requests.UserNotFoundError "Пользователь не найден"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/admin/users/{username}/roles [put]
+func HandleAdminSetRoles(logger *zap.Logger, roleSetter RoleSetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.SetRolesRequest
+		const op = "api/v1/HandleAdminSetRoles"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		roles, err := normalizeRoles(req.Roles)
+		if err != nil {
+			logError(c, logger, err, http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		username := c.Param("username")
+		if username == principal.Username && !containsRole(roles, auth.RoleAdmin) {
+			logError(c, logger, errors.New("cannot remove your own admin role"), http.StatusBadRequest, "")
+			return
+		}
+
+		err = roleSetter.SetUserRoles(username, roles, principal.Username)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			logError(c, logger, storage.ErrUserNotFound, http.StatusNotFound, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to set roles", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to set roles"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("roles changed", zap.String("op", op),
+			zap.String("username", username),
+			zap.Strings("roles", roles),
+			zap.String("by", principal.Username),
+		)
+		c.JSON(http.StatusOK, requests.AccountUpdatedResponse{Message: "roles changed"})
+	}
+}
+
+// getTargetUser loads the user of the username path parameter. It responds itself and
+// returns false when the user cannot be loaded.
+func getTargetUser(c *gin.Context, logger *zap.Logger, userGetter UserGetter, op string) (*models.User, bool) {
+	user, err := userGetter.GetUser(c.Param("username"))
+	if errors.Is(err, storage.ErrUserNotFound) {
+		logError(c, logger, storage.ErrUserNotFound, http.StatusNotFound, "")
+		return nil, false
+	}
+	if err != nil {
+		logger.Error("failed to get user", zap.String("op", op), zap.Error(err))
+		logError(c, logger, errors.New("failed to get user"), http.StatusInternalServerError, "")
+		return nil, false
+	}
+	return user, true
+}
+
+func parseUserFilter(c *gin.Context) (models.UserFilter, error) {
+	filter := models.UserFilter{
+		Query: strings.TrimSpace(c.Query("q")),
+		Role:  c.Query("role"),
+		Limit: defaultUsersLimit,
+	}
+
+	if filter.Role != "" && !auth.IsRole(filter.Role) {
+		return filter, fmt.Errorf("unknown role %s", filter.Role)
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxUsersLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxUsersLimit)
+		}
+		filter.Limit = limit
+	}
+
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, errors.New("offset must be a non-negative integer")
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// normalizeRoles validates roles and drops duplicates. Every user keeps the user role.
+func normalizeRoles(roles []string) ([]string, error) {
+	normalized := []string{auth.RoleUser}
+	for _, role := range roles {
+		if !auth.IsRole(role) {
+			return nil, fmt.Errorf("unknown role %s", role)
+		}
+		if !containsRole(normalized, role) {
+			normalized = append(normalized, role)
+		}
+	}
+	return normalized, nil
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func adminUser(user *models.User) requests.AdminUser {
+	roles := user.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return requests.AdminUser{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Roles:         roles,
+		Frozen:        user.Frozen,
+		FrozenReason:  user.FrozenReason,
+	}
+}
@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockAdminStore struct {
+	users    []models.User
+	balance  map[string]money.Amount
+	err      error
+	filter   models.UserFilter
+	frozenBy string
+	reason   string
+	roles    []string
+	rolesBy  string
+}
+
+func (m *MockAdminStore) ListUsers(filter models.UserFilter) ([]models.User, error) {
+	m.filter = filter
+	if m.err != nil {
+		return nil, m.err
+	}
+	if len(m.users) > filter.Limit {
+		return m.users[:filter.Limit], nil
+	}
+	return m.users, nil
+}
+
+func (m *MockAdminStore) GetUser(username string) (*models.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for i := range m.users {
+		if m.users[i].Username == username {
+			return &m.users[i], nil
+		}
+	}
+	return nil, fmt.Errorf("storage.postgres.GetUser: %w", storage.ErrUserNotFound)
+}
+
+func (m *MockAdminStore) GetUserBalance(username string) (map[string]money.Amount, error) {
+	return m.balance, nil
+}
+
+func (m *MockAdminStore) FreezeAccount(username, by, reason string) error {
+	m.frozenBy, m.reason = by, reason
+	return m.err
+}
+
+func (m *MockAdminStore) UnfreezeAccount(username string) error {
+	return m.err
+}
+
+func (m *MockAdminStore) SetUserRoles(username string, roles []string, by string) error {
+	m.roles, m.rolesBy = roles, by
+	return m.err
+}
+
+func newAdminRequest(method, path, token, body string, username string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(method, path, bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Authorization", token)
+	if username != "" {
+		c.Params = gin.Params{{Key: "username", Value: username}}
+	}
+	return c, w
+}
+
+func TestHandleAdminListUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("admin")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	users := []models.User{
+		{ID: 1, Username: "admin", Email: "admin@example.com", EmailVerified: true, Roles: []string{"user", "admin"}},
+		{ID: 2, Username: "alice", Email: "alice@example.com", Roles: []string{"user"}, Frozen: true, FrozenReason: "chargeback"},
+		{ID: 3, Username: "bob", Roles: []string{"user"}},
+	}
+
+	testCases := []struct {
+		name             string
+		query            string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+		expectedFilter   *models.UserFilter
+	}{
+		{
+			name:           "First_Page",
+			query:          "?limit=2",
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"users":[
+				{"id":1,"username":"admin","email":"admin@example.com","email_verified":true,"roles":["user","admin"],"frozen":false},
+				{"id":2,"username":"alice","email":"alice@example.com","email_verified":false,"roles":["user"],"frozen":true,"frozen_reason":"chargeback"}
+			],"next_offset":2}`,
+		},
+		{
+			name:           "Filters_Are_Passed_To_Storage",
+			query:          "?q=%20ali%20&role=support&offset=40",
+			expectedStatus: http.StatusOK,
+			expectedFilter: &models.UserFilter{Query: "ali", Role: "support", Limit: defaultUsersLimit + 1, Offset: 40},
+		},
+		{
+			name:             "Unknown_Role",
+			query:            "?role=root",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"unknown role root"}`,
+		},
+		{
+			name:             "Invalid_Limit",
+			query:            "?limit=1000",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"limit must be between 1 and 100"}`,
+		},
+		{
+			name:             "Storage_Error",
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to list users"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, w := newAdminRequest(http.MethodGet, "/api/v1/admin/users"+tc.query, validToken, "", "")
+			store := &MockAdminStore{users: users, err: tc.mockError}
+
+			authenticated(HandleAdminListUsers(newTestLogger(), store))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			if tc.expectedResponse != "" {
+				assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			}
+			if tc.expectedFilter != nil {
+				assert.Equal(t, *tc.expectedFilter, store.filter, "Filter mismatch")
+			}
+		})
+	}
+}
+
+func TestHandleAdminUserViews(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("support")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	store := &MockAdminStore{
+		users:   []models.User{{ID: 2, Username: "alice", Email: "alice@example.com", Roles: []string{"user"}}},
+		balance: map[string]money.Amount{"USD": money.MustParse("10.5")},
+	}
+	transactions := &MockTransactionsGetter{transactions: []models.Transaction{
+		{ID: 7, Type: models.AdjustmentIn, Currency: "USD", Amount: money.MustParse("10.5"), CreatedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}}
+
+	testCases := []struct {
+		name             string
+		handler          gin.HandlerFunc
+		username         string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Get_User",
+			handler:          HandleAdminGetUser(newTestLogger(), store),
+			username:         "alice",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"id":2,"username":"alice","email":"alice@example.com","email_verified":false,"roles":["user"],"frozen":false}`,
+		},
+		{
+			name:             "Get_User_Not_Found",
+			handler:          HandleAdminGetUser(newTestLogger(), store),
+			username:         "nobody",
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"status":"error","error":"user not found"}`,
+		},
+		{
+			name:             "Balance",
+			handler:          HandleAdminBalance(newTestLogger(), store, store),
+			username:         "alice",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"balance":{"USD":"10.5"}}`,
+		},
+		{
+			name:             "Balance_User_Not_Found",
+			handler:          HandleAdminBalance(newTestLogger(), store, store),
+			username:         "nobody",
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"status":"error","error":"user not found"}`,
+		},
+		{
+			name:             "Transactions",
+			handler:          HandleAdminTransactions(newTestLogger(), store, transactions),
+			username:         "alice",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"transactions":[{"id":7,"type":"adjustment_in","currency":"USD","amount":"10.5","created_at":"2025-03-01T12:00:00Z"}]}`,
+		},
+		{
+			name:             "Transactions_User_Not_Found",
+			handler:          HandleAdminTransactions(newTestLogger(), store, transactions),
+			username:         "nobody",
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"status":"error","error":"user not found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, w := newAdminRequest(http.MethodGet, "/api/v1/admin/users/"+tc.username, validToken, "", tc.username)
+
+			authenticated(tc.handler)(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+		})
+	}
+}
+
+func TestHandleAdminFreeze(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("support")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	adminToken, err := generateRoleJWT("admin", auth.RoleUser, auth.RoleAdmin)
+	if err != nil {
+		t.Fatalf("Failed to generate admin JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		unfreeze         bool
+		token            string
+		username         string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+		expectedReason   string
+		expectedBy       string
+	}{
+		{
+			name:             "Freeze",
+			requestBody:      `{"reason":"  chargeback  "}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"account frozen"}`,
+			expectedReason:   "chargeback",
+			expectedBy:       "support",
+		},
+		{
+			name:             "Freeze_Self",
+			username:         "support",
+			requestBody:      `{"reason":"chargeback"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"cannot freeze your own account"}`,
+		},
+		{
+			name:             "Freeze_Admin_By_Support",
+			username:         "root",
+			requestBody:      `{"reason":"chargeback"}`,
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"status":"error","error":"only an admin can freeze an admin"}`,
+		},
+		{
+			name:             "Freeze_Admin_By_Admin",
+			token:            adminToken,
+			username:         "root",
+			requestBody:      `{"reason":"compromised"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"account frozen"}`,
+			expectedReason:   "compromised",
+			expectedBy:       "admin",
+		},
+		{
+			name:             "Freeze_Without_Reason",
+			requestBody:      `{"reason":"   "}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"reason is required"}`,
+		},
+		{
+			name:             "Freeze_User_Not_Found",
+			requestBody:      `{"reason":"chargeback"}`,
+			mockError:        storage.ErrUserNotFound,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"status":"error","error":"user not found"}`,
+		},
+		{
+			name:             "Unfreeze",
+			unfreeze:         true,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"account unfrozen"}`,
+		},
+		{
+			name:             "Unfreeze_Storage_Error",
+			unfreeze:         true,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to unfreeze account"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, username := tc.token, tc.username
+			if token == "" {
+				token = validToken
+			}
+			if username == "" {
+				username = "alice"
+			}
+			c, w := newAdminRequest(http.MethodPost, "/api/v1/admin/users/"+username+"/freeze", token, tc.requestBody, username)
+			store := &MockAdminStore{
+				users: []models.User{
+					{ID: 1, Username: "root", Roles: []string{auth.RoleUser, auth.RoleAdmin}},
+					{ID: 2, Username: "alice", Roles: []string{auth.RoleUser}},
+					{ID: 3, Username: "support", Roles: []string{auth.RoleUser, auth.RoleSupport}},
+				},
+				err: tc.mockError,
+			}
+
+			if tc.unfreeze {
+				authenticated(HandleAdminUnfreeze(newTestLogger(), store))(c)
+			} else {
+				authenticated(HandleAdminFreeze(newTestLogger(), store, store))(c)
+			}
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			assert.Equal(t, tc.expectedReason, store.reason)
+			assert.Equal(t, tc.expectedBy, store.frozenBy)
+		})
+	}
+}
+
+func TestHandleAdminSetRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("admin")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		username         string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+		expectedRoles    []string
+	}{
+		{
+			name:             "Success",
+			username:         "alice",
+			requestBody:      `{"roles":["support","auditor","support"]}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"roles changed"}`,
+			expectedRoles:    []string{"user", "support", "auditor"},
+		},
+		{
+			name:             "Unknown_Role",
+			username:         "alice",
+			requestBody:      `{"roles":["root"]}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"unknown role root"}`,
+		},
+		{
+			name:             "Own_Admin_Role",
+			username:         "admin",
+			requestBody:      `{"roles":["support"]}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"cannot remove your own admin role"}`,
+		},
+		{
+			name:             "User_Not_Found",
+			username:         "nobody",
+			requestBody:      `{"roles":[]}`,
+			mockError:        storage.ErrUserNotFound,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"status":"error","error":"user not found"}`,
+			expectedRoles:    []string{"user"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, w := newAdminRequest(http.MethodPut, "/api/v1/admin/users/"+tc.username+"/roles", validToken, tc.requestBody, tc.username)
+			store := &MockAdminStore{err: tc.mockError}
+
+			authenticated(HandleAdminSetRoles(newTestLogger(), store))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			assert.Equal(t, tc.expectedRoles, store.roles)
+			if tc.expectedRoles != nil {
+				assert.Equal(t, "admin", store.rolesBy)
+			}
+		})
+	}
+}
@@ -5,13 +5,14 @@ import (
 	"net/http"
 
 	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
-	jwt "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 type BalanceGetter interface {
-	GetUserBalance(username string) (map[string]float64, error)
+	GetUserBalance(username string) (map[string]money.Amount, error)
 }
 
 // HandleBalance godoc
@@ -22,6 +23,7 @@ type BalanceGetter interface {
 // @Produce  json
 // @Success 200 {object} requests.BalanceResponse "OK"
 // @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Security ApiKeyAuth
 // @Router /api/v1/balance [get]
 func HandleBalance(logger *zap.Logger, balanceGetter BalanceGetter) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -29,8 +31,8 @@ func HandleBalance(logger *zap.Logger, balanceGetter BalanceGetter) gin.HandlerF
 
 		logger.Info("proceeding new request", zap.String("op", op))
 
-		tokenString := c.GetHeader("Authorization")
-		if tokenString == "" {
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
 			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
 			return
 		}
@@ -38,14 +40,10 @@ func HandleBalance(logger *zap.Logger, balanceGetter BalanceGetter) gin.HandlerF
 		logger.Info("request data: ",
 			zap.String("discordid: ", c.Request.Method),
 			zap.String("URL", c.Request.URL.String()),
-			zap.String("Token", tokenString),
+			zap.String("username", principal.Username),
 		)
 
-		username, err := jwt.ValidateToken(tokenString)
-		if err != nil {
-			logError(c, logger, err, http.StatusUnauthorized, "")
-			return
-		}
+		username := principal.Username
 
 		balance, err := balanceGetter.GetUserBalance(username)
 		if err != nil {
@@ -7,6 +7,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Foreground-Eclipse/transferer/config"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 	"github.com/stretchr/testify/assert"
@@ -14,11 +18,11 @@ import (
 )
 
 type MockBalanceGetter struct {
-	balance map[string]float64
+	balance map[string]money.Amount
 	err     error
 }
 
-func (m *MockBalanceGetter) GetUserBalance(username string) (map[string]float64, error) {
+func (m *MockBalanceGetter) GetUserBalance(username string) (map[string]money.Amount, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -32,6 +36,28 @@ func newTestLogger() *zap.Logger {
 
 var jwtKey = []byte("supersecretkey")
 
+// testKeySet validates the tokens made by GenerateJWT.
+var testKeySet = mustKeySet(config.JWTConfig{JWTSecret: string(jwtKey), AccessTokenTTL: time.Hour})
+
+func mustKeySet(cfg config.JWTConfig) *auth.KeySet {
+	keySet, err := auth.NewKeySet(cfg, nil)
+	if err != nil {
+		panic(err)
+	}
+	return keySet
+}
+
+// authenticated runs handler behind the authentication middleware, as the router does.
+func authenticated(handler gin.HandlerFunc) gin.HandlerFunc {
+	authenticate := middleware.Authenticate(newTestLogger(), testKeySet)
+	return func(c *gin.Context) {
+		authenticate(c)
+		if !c.IsAborted() {
+			handler(c)
+		}
+	}
+}
+
 type JWTClaim struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
@@ -51,6 +77,29 @@ func GenerateJWT(username string) (tokenString string, err error) {
 	return
 }
 
+// generateFreshJWT makes a token of a user who has just authenticated with a password,
+// as issued after /api/v1/reauth.
+func generateFreshJWT(username string) (string, error) {
+	token, err := testKeySet.IssueAccessToken(auth.Subject{
+		Username:    username,
+		AuthTime:    time.Now(),
+		AuthMethods: []string{auth.MethodPassword},
+	}, "")
+	if err != nil {
+		return "", err
+	}
+	return token.Token, nil
+}
+
+// generateRoleJWT makes a token of a user with the given roles.
+func generateRoleJWT(username string, roles ...string) (string, error) {
+	token, err := testKeySet.IssueAccessToken(auth.Subject{Username: username, Roles: roles}, "")
+	if err != nil {
+		return "", err
+	}
+	return token.Token, nil
+}
+
 func ValidateToken(signedToken string) (string, error) {
 	token, err := jwt.ParseWithClaims(
 		signedToken,
@@ -85,7 +134,7 @@ func TestHandleBalance(t *testing.T) {
 	testCases := []struct {
 		name             string
 		token            string
-		mockBalance      map[string]float64
+		mockBalance      map[string]money.Amount
 		mockError        error
 		expectedStatus   int
 		expectedResponse string
@@ -95,7 +144,7 @@ func TestHandleBalance(t *testing.T) {
 			token:            "",
 			mockBalance:      nil,
 			mockError:        nil,
-			expectedStatus:   http.StatusOK,
+			expectedStatus:   http.StatusUnauthorized,
 			expectedResponse: `{"error":"not authorized", "status":"error"}`,
 		},
 		{
@@ -103,16 +152,16 @@ func TestHandleBalance(t *testing.T) {
 			token:            "invalid_token",
 			mockBalance:      nil,
 			mockError:        errors.New("invalid token"),
-			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"error":"token contains an invalid number of segments", "status":"error"}`,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"invalid token", "status":"error"}`,
 		},
 		{
 			name:             "Valid Token - Success",
 			token:            validToken,
-			mockBalance:      map[string]float64{"USD": 100, "EUR": 50},
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("100"), "EUR": money.MustParse("50")},
 			mockError:        nil,
 			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"balance":{"EUR":50,"USD":100}}`,
+			expectedResponse: `{"balance":{"EUR":"50","USD":"100"}}`,
 		},
 		{
 			name:             "Valid Token - GetBalance Error",
@@ -141,7 +190,7 @@ func TestHandleBalance(t *testing.T) {
 				err:     tc.mockError,
 			}
 
-			HandleBalance(logger, mockBalanceGetter)(c)
+			authenticated(HandleBalance(logger, mockBalanceGetter))(c)
 
 			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
 			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch") // Use JSONEq for comparing JSON
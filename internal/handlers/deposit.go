@@ -3,33 +3,40 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
-	jwt "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 type BalanceUpdater interface {
-	UpdateUsersBalance(username, currency string, amount float64) error
-	GetUserBalance(username string) (map[string]float64, error)
+	UpdateUsersBalance(username, currency string, amount money.Amount) error
+	GetUserBalance(username string) (map[string]money.Amount, error)
 }
 
 // HandleDeposit godoc
 // @Summary Пополнение баланса пользователя
 // @Description  Пополняет баланс пользователя на указанную сумму в указанной валюте.
+// @Description  До подтверждения email сумма пополнений в валюте ограничена лимитом, валюты без лимита пополнить нельзя.
 // @Tags deposit
 // @Accept  json
 // @Produce  json
 // @Param   request body requests.DepositRequest true "Данные для пополнения"
+// @Param   Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с тем же ключом вернет первый ответ"
 // @Success 200 {object} requests.DepositResponse "OK"
 // @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
 // @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.UnverifiedDepositLimitError "Превышен лимит пополнений до подтверждения email"
+// @Failure 409 {object} requests.IdempotencyConflictError "Ключ идемпотентности уже использован"
 // @Security ApiKeyAuth
-// @Router /api/v1/deposit [post]
-func HandleDeposit(logger *zap.Logger, balanceUpdater BalanceUpdater) gin.HandlerFunc {
+// @Router /api/v1/wallet/deposit [post]
+func HandleDeposit(logger *zap.Logger, balanceUpdater BalanceUpdater, idempotencyStore IdempotencyStore, depositLimiter DepositLimiter, limits UnverifiedDepositLimits) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		const op = "api/v1/HandleDeposit"
 		var req requests.DepositRequest
@@ -44,14 +51,22 @@ func HandleDeposit(logger *zap.Logger, balanceUpdater BalanceUpdater) gin.Handle
 			return
 		}
 
+		if !req.Amount.IsPositive() {
+			logError(c, logger, errors.New("amount must be positive"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+		if !checkAmountScale(c, logger, req.Amount) {
+			return
+		}
+
 		reqBody, err := json.Marshal(req)
 		if err != nil {
 			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to marshal request body")
 			return
 		}
 
-		tokenString := c.GetHeader("Authorization")
-		if tokenString == "" {
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
 			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
 			return
 		}
@@ -59,17 +74,39 @@ func HandleDeposit(logger *zap.Logger, balanceUpdater BalanceUpdater) gin.Handle
 		logger.Info("request data: ",
 			zap.String("discordid: ", c.Request.Method),
 			zap.String("URL", c.Request.URL.String()),
-			zap.String("Token", tokenString),
+			zap.String("username", principal.Username),
 			zap.String("body", string(reqBody)),
 		)
 
-		username, err := jwt.ValidateToken(tokenString)
-		if err != nil {
-			logError(c, logger, err, http.StatusUnauthorized, "")
+		username := principal.Username
+
+		finish, handled := startIdempotentRequest(c, logger, idempotencyStore, op, username, reqBody)
+		if handled {
 			return
 		}
+		defer finish()
+
+		limited := limits != nil
+		if limited {
+			verified, err := depositLimiter.IsEmailVerified(username)
+			if err != nil {
+				logger.Error("failed to check email verification", zap.String("op", op), zap.Error(err))
+				logError(c, logger, errors.New("failed to check deposit limit"), http.StatusInternalServerError, "")
+				return
+			}
+			limited = !verified
+		}
 
-		err = balanceUpdater.UpdateUsersBalance(username, req.Currency, float64(req.Amount))
+		if limited {
+			// a currency without a limit is capped at zero
+			err = depositLimiter.DepositWithinLimit(username, req.Currency, req.Amount, limits[req.Currency])
+		} else {
+			err = balanceUpdater.UpdateUsersBalance(username, req.Currency, req.Amount)
+		}
+		if errors.Is(err, storage.ErrDepositLimitExceeded) {
+			logError(c, logger, errors.New("deposit limit exceeded, verify your email"), http.StatusForbidden, "")
+			return
+		}
 		if err != nil {
 			logError(c, logger, err, http.StatusOK, "")
 			return
@@ -91,3 +128,13 @@ func HandleDeposit(logger *zap.Logger, balanceUpdater BalanceUpdater) gin.Handle
 	}
 
 }
+
+// checkAmountScale reports whether amount fits the database scale. If not, it answers 400,
+// since the amount would be rounded or, below the smallest unit, break the positive check.
+func checkAmountScale(c *gin.Context, logger *zap.Logger, amount money.Amount) bool {
+	if amount.FitsScale() {
+		return true
+	}
+	logError(c, logger, fmt.Errorf("amount must have at most %d decimal places", money.Scale), http.StatusBadRequest, "failed to process request")
+	return false
+}
@@ -7,23 +7,24 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
 type MockBalanceUpdater struct {
-	balance map[string]float64
+	balance map[string]money.Amount
 	err     error
 }
 
-func (m *MockBalanceUpdater) UpdateUsersBalance(username, currency string, amount float64) error {
+func (m *MockBalanceUpdater) UpdateUsersBalance(username, currency string, amount money.Amount) error {
 	if m.err != nil {
 		return m.err
 	}
 	return nil
 }
 
-func (m *MockBalanceUpdater) GetUserBalance(username string) (map[string]float64, error) {
+func (m *MockBalanceUpdater) GetUserBalance(username string) (map[string]money.Amount, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -42,7 +43,7 @@ func TestHandleDeposit(t *testing.T) {
 		name             string
 		token            string
 		requestBody      string
-		mockBalance      map[string]float64
+		mockBalance      map[string]money.Amount
 		mockError        error
 		expectedStatus   int
 		expectedResponse string
@@ -53,7 +54,7 @@ func TestHandleDeposit(t *testing.T) {
 			requestBody:      `{"currency":"USD","amount":100}`,
 			mockBalance:      nil,
 			mockError:        nil,
-			expectedStatus:   http.StatusOK,
+			expectedStatus:   http.StatusUnauthorized,
 			expectedResponse: `{"error":"not authorized", "status":"error"}`,
 		},
 		{
@@ -62,8 +63,8 @@ func TestHandleDeposit(t *testing.T) {
 			requestBody:      `{"currency":"USD","amount":100}`,
 			mockBalance:      nil,
 			mockError:        errors.New("invalid token"),
-			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"error":"token contains an invalid number of segments", "status":"error"}`,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"invalid token", "status":"error"}`,
 		},
 		{
 			name:             "Invalid JSON - Wrong Type",
@@ -74,14 +75,21 @@ func TestHandleDeposit(t *testing.T) {
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: `{"error":"request contains wrong data", "status":"error"}`,
 		},
+		{
+			name:             "Too Many Decimal Places",
+			token:            validToken,
+			requestBody:      `{"currency":"USD","amount":"0.000000001"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"amount must have at most 8 decimal places", "status":"error"}`,
+		},
 		{
 			name:             "Valid Request - Success",
 			token:            validToken,
 			requestBody:      `{"currency":"USD","amount":100}`,
-			mockBalance:      map[string]float64{"USD": 200, "EUR": 50},
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("200"), "EUR": money.MustParse("50")},
 			mockError:        nil,
 			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"message":"Account topped up successfully","balance":{"EUR":50,"USD":200}}`,
+			expectedResponse: `{"message":"Account topped up successfully","balance":{"EUR":"50","USD":"200"}}`,
 		},
 		{
 			name:             "Valid Request - Update Balance Error",
@@ -122,7 +130,7 @@ func TestHandleDeposit(t *testing.T) {
 				err:     tc.mockError,
 			}
 
-			HandleDeposit(logger, mockBalanceUpdater)(c)
+			authenticated(HandleDeposit(logger, mockBalanceUpdater, nil, nil, nil))(c)
 
 			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
 			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
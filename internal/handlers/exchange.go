@@ -4,35 +4,46 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
 
-	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
 	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
-	jwt "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/rates"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 type Exchanger interface {
-	GetUserBalance(username string) (map[string]float64, error)
-	UpdateUsersBalance(username, currency string, amount float64) error
+	GetUserBalance(username string) (map[string]money.Amount, error)
+	Exchange(ctx context.Context, username, fromCurrency, toCurrency string, amount, rate money.Amount) (money.Amount, error)
+	ExchangeQuote(ctx context.Context, username, quoteID string) (*models.Quote, error)
 }
 
 // HandleExchange godoc
 // @Summary Обмен валюты пользователя
 // @Description  Выполняет обмен валюты пользователя из одной валюты в другую.
+// @Description  С quote_id обмен выполняется ровно по курсу котировки из /api/v1/exchange/quote или не выполняется вовсе,
+// @Description  from_currency, to_currency и amount в этом случае передавать нельзя.
 // @Tags exchange
 // @Accept  json
 // @Produce  json
 // @Param   request body requests.ExchangeRequest true "Данные для обмена"
+// @Param   Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с тем же ключом вернет первый ответ"
 // @Success 200 {object} requests.ExchangeResponse "OK"
-// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос или нет курса для пары валют"
 // @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 403 {object} requests.NotEnoughFundsError "Недостаточно средств"
+// @Failure 404 {object} requests.BadRequestError "Котировка не найдена"
+// @Failure 409 {object} requests.IdempotencyConflictError "Ключ идемпотентности уже использован"
+// @Failure 410 {object} requests.QuoteExpiredError "Котировка истекла или уже использована"
+// @Failure 503 {object} requests.RatesUnavailableError "Источник курсов недоступен"
 // @Security ApiKeyAuth
 // @Router /api/v1/exchange [post]
-func HandleExchange(logger *zap.Logger, client pb.ExchangeServiceClient, exchanger Exchanger) gin.HandlerFunc {
+func HandleExchange(logger *zap.Logger, rateProvider rates.Provider, exchanger Exchanger, idempotencyStore IdempotencyStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		const op = "api/v1/HandleExchange"
 
@@ -48,14 +59,39 @@ func HandleExchange(logger *zap.Logger, client pb.ExchangeServiceClient, exchang
 			return
 		}
 
+		if req.QuoteID != "" && (req.FromCurrency != "" || req.ToCurrency != "" || !req.Amount.IsZero()) {
+			logError(c, logger, errors.New("quote_id cannot be combined with from_currency, to_currency or amount"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		if req.QuoteID == "" {
+			if req.FromCurrency == "" || req.ToCurrency == "" {
+				logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+
+			if !req.Amount.IsPositive() {
+				logError(c, logger, errors.New("amount must be positive"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			if !checkAmountScale(c, logger, req.Amount) {
+				return
+			}
+
+			if req.FromCurrency == req.ToCurrency {
+				logError(c, logger, errors.New("cannot exchange currency to itself"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+		}
+
 		reqBody, err := json.Marshal(req)
 		if err != nil {
 			logError(c, logger, err, http.StatusBadRequest, "failed to marshal request body")
 			return
 		}
 
-		tokenString := c.GetHeader("Authorization")
-		if tokenString == "" {
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
 			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
 			return
 		}
@@ -63,50 +99,64 @@ func HandleExchange(logger *zap.Logger, client pb.ExchangeServiceClient, exchang
 		logger.Info("request data: ",
 			zap.String("discordid: ", c.Request.Method),
 			zap.String("URL", c.Request.URL.String()),
-			zap.String("Token", tokenString),
+			zap.String("username", principal.Username),
 			zap.String("Body", string(reqBody)),
 		)
 
-		username, err := jwt.ValidateToken(tokenString)
-		if err != nil {
-			logError(c, logger, err, http.StatusUnauthorized, "")
-			return
-		}
-
-		ctx := context.Background()
-		request := &pb.Empty{}
-		response, err := client.GetExchangeRates(ctx, request)
-		if err != nil {
-			logError(c, logger, errors.New("failed to retrieve exchange rates"), http.StatusInternalServerError, "")
-			return
-		}
+		username := principal.Username
 
-		balance, err := exchanger.GetUserBalance(username)
-		if err != nil {
-			logError(c, logger, err, http.StatusInternalServerError, "")
-			return
-		}
-		if balance[req.FromCurrency] < req.Amount {
-			logError(c, logger, errors.New("not enough money"), http.StatusOK, "")
-			return
-		}
-
-		err = exchanger.UpdateUsersBalance(username, req.FromCurrency, req.Amount*-1.0)
-		if err != nil {
-			logError(c, logger, err, http.StatusInternalServerError, "")
+		finish, handled := startIdempotentRequest(c, logger, idempotencyStore, op, username, reqBody)
+		if handled {
 			return
 		}
+		defer finish()
+
+		var toAdd money.Amount
+		if req.QuoteID != "" {
+			quote, err := exchanger.ExchangeQuote(c.Request.Context(), username, req.QuoteID)
+			if errors.Is(err, storage.ErrQuoteNotFound) {
+				logError(c, logger, storage.ErrQuoteNotFound, http.StatusNotFound, "")
+				return
+			}
+			if errors.Is(err, storage.ErrQuoteExpired) {
+				logError(c, logger, storage.ErrQuoteExpired, http.StatusGone, "")
+				return
+			}
+			if errors.Is(err, storage.ErrQuoteUsed) {
+				logError(c, logger, storage.ErrQuoteUsed, http.StatusGone, "")
+				return
+			}
+			if errors.Is(err, storage.ErrNotEnoughFunds) {
+				logError(c, logger, storage.ErrNotEnoughFunds, http.StatusForbidden, "")
+				return
+			}
+			if err != nil {
+				logError(c, logger, err, http.StatusInternalServerError, "")
+				return
+			}
+			toAdd = quote.ToAmount
+		} else {
+			quotes, err := rateProvider.GetRates(c.Request.Context())
+			if err != nil {
+				logRatesError(c, logger, err)
+				return
+			}
 
-		toAdd, err := convertCurrencies(req.FromCurrency, req.ToCurrency, req.Amount, response)
-		if err != nil {
-			logError(c, logger, err, http.StatusInternalServerError, "")
-			return
-		}
+			crossRate, err := exchangeRate(req.FromCurrency, req.ToCurrency, quotes)
+			if err != nil {
+				logExchangeRateError(c, logger, err)
+				return
+			}
 
-		err = exchanger.UpdateUsersBalance(username, req.ToCurrency, toAdd)
-		if err != nil {
-			logError(c, logger, err, http.StatusInternalServerError, "")
-			return
+			toAdd, err = exchanger.Exchange(c.Request.Context(), username, req.FromCurrency, req.ToCurrency, req.Amount, crossRate.Rate)
+			if errors.Is(err, storage.ErrNotEnoughFunds) {
+				logError(c, logger, storage.ErrNotEnoughFunds, http.StatusForbidden, "")
+				return
+			}
+			if err != nil {
+				logError(c, logger, err, http.StatusInternalServerError, "")
+				return
+			}
 		}
 
 		var resp requests.ExchangeResponse
@@ -123,22 +173,22 @@ func HandleExchange(logger *zap.Logger, client pb.ExchangeServiceClient, exchang
 
 }
 
-func convertCurrencies(fromCurrency, toCurrency string, amount float64, response *pb.ExchangeRatesResponse) (float64, error) {
-	fromRateKey := fmt.Sprintf("RUB_%s", fromCurrency)
-	fromRate, fromOK := response.Rates[fromRateKey]
-	if !fromOK {
-		return 0, fmt.Errorf("курс для валюты %s не найден", fromCurrency)
+// exchangeRate returns how many units of toCurrency one unit of fromCurrency buys,
+// crossing through other quoted currencies when the pair is not quoted directly.
+func exchangeRate(fromCurrency, toCurrency string, quotes map[string]money.Amount) (rates.CrossRate, error) {
+	graph, err := rates.NewGraphFromQuotes(quotes)
+	if err != nil {
+		return rates.CrossRate{}, err
 	}
+	return graph.Rate(fromCurrency, toCurrency)
+}
 
-	toRateKey := fmt.Sprintf("RUB_%s", toCurrency)
-	toRate, toOK := response.Rates[toRateKey]
-	if !toOK {
-		return 0, fmt.Errorf("курс для валюты %s не найден", toCurrency)
+// logExchangeRateError responds to a failed exchangeRate, with 400 if there is no rate
+// between the requested currencies.
+func logExchangeRateError(c *gin.Context, logger *zap.Logger, err error) {
+	if errors.Is(err, rates.ErrNoRate) {
+		logError(c, logger, err, http.StatusBadRequest, "")
+		return
 	}
-
-	amountInRUB := amount / float64(fromRate)
-
-	amountInToCurrency := amountInRUB * float64(toRate)
-
-	return amountInToCurrency, nil
+	logError(c, logger, err, http.StatusInternalServerError, "")
 }
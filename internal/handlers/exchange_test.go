@@ -4,65 +4,81 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/Foreground-Eclipse/transferer/internal/rates"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
-
-	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
 )
 
 type MockExchanger struct {
-	balance map[string]float64
+	balance map[string]money.Amount
+	quotes  map[string]*models.Quote
 	err     error
 }
 
-func (m *MockExchanger) GetUserBalance(username string) (map[string]float64, error) {
+func (m *MockExchanger) GetUserBalance(username string) (map[string]money.Amount, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
 	return m.balance, nil
 }
 
-func (m *MockExchanger) UpdateUsersBalance(username, currency string, amount float64) error {
+func (m *MockExchanger) Exchange(ctx context.Context, username, fromCurrency, toCurrency string, amount, rate money.Amount) (money.Amount, error) {
 	if m.err != nil {
-		return m.err
+		return money.Zero, m.err
 	}
-	if m.balance != nil {
-		if _, ok := m.balance[currency]; ok {
-			m.balance[currency] += amount
-		} else {
-			m.balance[currency] = amount
-		}
+	if m.balance[fromCurrency].LessThan(amount) {
+		return money.Zero, storage.ErrNotEnoughFunds
 	}
-	return nil
+	converted := amount.Mul(rate).Round()
+	m.balance[fromCurrency] = m.balance[fromCurrency].Sub(amount)
+	m.balance[toCurrency] = m.balance[toCurrency].Add(converted)
+	return converted, nil
 }
 
-func (m *MockExchangeServiceClient) GetExchangeRates(ctx context.Context, in *pb.Empty, opts ...grpc.CallOption) (*pb.ExchangeRatesResponse, error) {
-	if m.err != nil {
-		return nil, m.err
+func (m *MockExchanger) ExchangeQuote(ctx context.Context, username, quoteID string) (*models.Quote, error) {
+	quote, ok := m.quotes[quoteID]
+	if !ok {
+		return nil, fmt.Errorf("exchange quote: %w", storage.ErrQuoteNotFound)
 	}
-
-	return &pb.ExchangeRatesResponse{
-		Rates: m.rates,
-	}, nil
+	if !quote.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("exchange quote: %w", storage.ErrQuoteExpired)
+	}
+	if _, err := m.Exchange(ctx, username, quote.FromCurrency, quote.ToCurrency, quote.Amount, quote.Rate); err != nil {
+		return nil, err
+	}
+	return quote, nil
 }
 
-type MockExchangeServiceClient struct {
-	rates map[string]float32
+var testRatesTime = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+type MockRateProvider struct {
+	rates map[string]money.Amount
+	stale bool
 	err   error
 }
 
-func (m *MockExchangeServiceClient) GetExchangeRateForCurrency(ctx context.Context, in *pb.CurrencyRequest, opts ...grpc.CallOption) (*pb.ExchangeRateResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetExchangeRateForCurrency not implemented")
+func (m *MockRateProvider) GetRates(ctx context.Context) (map[string]money.Amount, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.rates, nil
 }
 
-func (s *MockExchangeServiceClient) mustEmbedUnimplementedExchangeServiceServer() {}
+func (m *MockRateProvider) Snapshot(ctx context.Context) (rates.Snapshot, error) {
+	if m.err != nil {
+		return rates.Snapshot{}, m.err
+	}
+	return rates.Snapshot{Quotes: m.rates, FetchedAt: testRatesTime, Stale: m.stale}, nil
+}
 
 func TestHandleExchange(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -76,10 +92,11 @@ func TestHandleExchange(t *testing.T) {
 		name               string
 		token              string
 		requestBody        string
-		mockBalance        map[string]float64
+		mockBalance        map[string]money.Amount
+		mockQuotes         map[string]*models.Quote
 		mockExchangerError error
-		mockGrpcRates      map[string]float32
-		mockGrpcError      error
+		mockRates          map[string]money.Amount
+		mockRatesError     error
 		expectedStatus     int
 		expectedResponse   string
 	}{
@@ -89,12 +106,12 @@ func TestHandleExchange(t *testing.T) {
 			requestBody:        `{"from_currency":"USD","to_currency":"EUR","amount":100}`,
 			mockBalance:        nil,
 			mockExchangerError: nil,
-			mockGrpcRates: map[string]float32{
-				"RUB_USD": 0.012,
-				"RUB_EUR": 0.011,
+			mockRates: map[string]money.Amount{
+				"RUB_USD": money.MustParse("0.012"),
+				"RUB_EUR": money.MustParse("0.011"),
 			},
-			mockGrpcError:    nil,
-			expectedStatus:   http.StatusOK,
+			mockRatesError:   nil,
+			expectedStatus:   http.StatusUnauthorized,
 			expectedResponse: `{"error":"not authorized", "status":"error"}`,
 		},
 		{
@@ -103,13 +120,13 @@ func TestHandleExchange(t *testing.T) {
 			requestBody:        `{"from_currency":"USD","to_currency":"EUR","amount":100}`,
 			mockBalance:        nil,
 			mockExchangerError: nil,
-			mockGrpcRates: map[string]float32{
-				"RUB_USD": 0.012,
-				"RUB_EUR": 0.011,
+			mockRates: map[string]money.Amount{
+				"RUB_USD": money.MustParse("0.012"),
+				"RUB_EUR": money.MustParse("0.011"),
 			},
-			mockGrpcError:    nil,
-			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"error":"token contains an invalid number of segments", "status":"error"}`,
+			mockRatesError:   nil,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"invalid token", "status":"error"}`,
 		},
 		{
 			name:               "Invalid JSON - Wrong Type",
@@ -117,36 +134,50 @@ func TestHandleExchange(t *testing.T) {
 			requestBody:        `{"from_currency":"USD","to_currency":"EUR","amount":"string"}`,
 			mockBalance:        nil,
 			mockExchangerError: nil,
-			mockGrpcRates: map[string]float32{
-				"RUB_USD": 0.012,
-				"RUB_EUR": 0.011,
+			mockRates: map[string]money.Amount{
+				"RUB_USD": money.MustParse("0.012"),
+				"RUB_EUR": money.MustParse("0.011"),
 			},
-			mockGrpcError:    nil,
+			mockRatesError:   nil,
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: `{"error":"request contains wrong data", "status":"error"}`,
 		},
+		{
+			name:               "Same Currency",
+			token:              validToken,
+			requestBody:        `{"from_currency":"USD","to_currency":"USD","amount":100}`,
+			mockBalance:        nil,
+			mockExchangerError: nil,
+			mockRates: map[string]money.Amount{
+				"RUB_USD": money.MustParse("0.012"),
+				"RUB_EUR": money.MustParse("0.011"),
+			},
+			mockRatesError:   nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"cannot exchange currency to itself", "status":"error"}`,
+		},
 		{
 			name:               "Not Enough Money",
 			token:              validToken,
 			requestBody:        `{"from_currency":"USD","to_currency":"EUR","amount":100}`,
-			mockBalance:        map[string]float64{"USD": 50, "EUR": 50},
+			mockBalance:        map[string]money.Amount{"USD": money.MustParse("50"), "EUR": money.MustParse("50")},
 			mockExchangerError: nil,
-			mockGrpcRates: map[string]float32{
-				"RUB_USD": 0.012,
-				"RUB_EUR": 0.011,
+			mockRates: map[string]money.Amount{
+				"RUB_USD": money.MustParse("0.012"),
+				"RUB_EUR": money.MustParse("0.011"),
 			},
-			mockGrpcError:    nil,
-			expectedStatus:   http.StatusOK,
+			mockRatesError:   nil,
+			expectedStatus:   http.StatusForbidden,
 			expectedResponse: `{"error":"not enough money", "status":"error"}`,
 		},
 		{
-			name:               "gRPC Error",
+			name:               "Rates Provider Error",
 			token:              validToken,
 			requestBody:        `{"from_currency":"USD","to_currency":"EUR","amount":100}`,
-			mockBalance:        map[string]float64{"USD": 100, "EUR": 50},
+			mockBalance:        map[string]money.Amount{"USD": money.MustParse("100"), "EUR": money.MustParse("50")},
 			mockExchangerError: nil,
-			mockGrpcRates:      nil,
-			mockGrpcError:      status.Error(codes.Unavailable, "gRPC service unavailable"),
+			mockRates:          nil,
+			mockRatesError:     errors.New("exchanger unavailable"),
 			expectedStatus:     http.StatusInternalServerError,
 			expectedResponse:   `{"error":"failed to retrieve exchange rates", "status":"error"}`,
 		},
@@ -156,11 +187,11 @@ func TestHandleExchange(t *testing.T) {
 			requestBody:        `{"from_currency":"USD","to_currency":"EUR","amount":100}`,
 			mockBalance:        nil,
 			mockExchangerError: errors.New("database error"),
-			mockGrpcRates: map[string]float32{
-				"RUB_USD": 0.012,
-				"RUB_EUR": 0.011,
+			mockRates: map[string]money.Amount{
+				"RUB_USD": money.MustParse("0.012"),
+				"RUB_EUR": money.MustParse("0.011"),
 			},
-			mockGrpcError:    nil,
+			mockRatesError:   nil,
 			expectedStatus:   http.StatusInternalServerError,
 			expectedResponse: `{"error":"database error", "status":"error"}`,
 		},
@@ -168,27 +199,98 @@ func TestHandleExchange(t *testing.T) {
 			name:               "Valid Request - Success",
 			token:              validToken,
 			requestBody:        `{"from_currency":"USD","to_currency":"EUR","amount":100}`,
-			mockBalance:        map[string]float64{"USD": 100, "EUR": 50},
+			mockBalance:        map[string]money.Amount{"USD": money.MustParse("100"), "EUR": money.MustParse("50")},
 			mockExchangerError: nil,
-			mockGrpcRates: map[string]float32{
-				"RUB_USD": 0.012,
-				"RUB_EUR": 0.011,
+			mockRates: map[string]money.Amount{
+				"RUB_USD": money.MustParse("0.012"),
+				"RUB_EUR": money.MustParse("0.011"),
 			},
-			mockGrpcError:    nil,
+			mockRatesError:   nil,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"exchanged successfully","exchanged_amount":"91.666667","new_balance":{"EUR":"141.666667","USD":"0"}}`,
+		},
+		{
+			name:        "Quote - Success",
+			token:       validToken,
+			requestBody: `{"quote_id":"q1"}`,
+			mockBalance: map[string]money.Amount{"USD": money.MustParse("100"), "EUR": money.MustParse("50")},
+			mockQuotes: map[string]*models.Quote{"q1": {
+				ID: "q1", FromCurrency: "USD", ToCurrency: "EUR", Amount: money.MustParse("100"),
+				Rate: money.MustParse("0.9"), ToAmount: money.MustParse("90"), ExpiresAt: time.Now().Add(time.Minute),
+			}},
+			mockRatesError:   errors.New("exchanger unavailable"),
 			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"message":"exchanged successfully","exchanged_amount":91.66666666666667,"new_balance":{"EUR":141.66666666666669,"USD":0}}`,
+			expectedResponse: `{"message":"exchanged successfully","exchanged_amount":"90","new_balance":{"EUR":"140","USD":"0"}}`,
+		},
+		{
+			name:        "Quote - Expired",
+			token:       validToken,
+			requestBody: `{"quote_id":"q1"}`,
+			mockBalance: map[string]money.Amount{"USD": money.MustParse("100"), "EUR": money.MustParse("50")},
+			mockQuotes: map[string]*models.Quote{"q1": {
+				ID: "q1", FromCurrency: "USD", ToCurrency: "EUR", Amount: money.MustParse("100"),
+				Rate: money.MustParse("0.9"), ToAmount: money.MustParse("90"), ExpiresAt: time.Now().Add(-time.Second),
+			}},
+			expectedStatus:   http.StatusGone,
+			expectedResponse: `{"error":"quote expired", "status":"error"}`,
+		},
+		{
+			name:             "Quote - Not Found",
+			token:            validToken,
+			requestBody:      `{"quote_id":"unknown"}`,
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("100"), "EUR": money.MustParse("50")},
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"error":"quote not found", "status":"error"}`,
+		},
+		{
+			name:        "Quote - Not Enough Money",
+			token:       validToken,
+			requestBody: `{"quote_id":"q1"}`,
+			mockBalance: map[string]money.Amount{"USD": money.MustParse("10"), "EUR": money.MustParse("50")},
+			mockQuotes: map[string]*models.Quote{"q1": {
+				ID: "q1", FromCurrency: "USD", ToCurrency: "EUR", Amount: money.MustParse("100"),
+				Rate: money.MustParse("0.9"), ToAmount: money.MustParse("90"), ExpiresAt: time.Now().Add(time.Minute),
+			}},
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"error":"not enough money", "status":"error"}`,
+		},
+		{
+			name:             "Quote - With Currencies",
+			token:            validToken,
+			requestBody:      `{"quote_id":"q1","from_currency":"USD","to_currency":"RUB","amount":"100"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"quote_id cannot be combined with from_currency, to_currency or amount", "status":"error"}`,
+		},
+		{
+			name:        "No Rate For Pair",
+			token:       validToken,
+			requestBody: `{"from_currency":"USD","to_currency":"GBP","amount":100}`,
+			mockBalance: map[string]money.Amount{"USD": money.MustParse("100"), "GBP": money.MustParse("50")},
+			mockRates: map[string]money.Amount{
+				"RUB_USD": money.MustParse("0.012"),
+				"RUB_EUR": money.MustParse("0.011"),
+			},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"no exchange rate from USD to GBP", "status":"error"}`,
+		},
+		{
+			name:             "Missing Currencies Without Quote",
+			token:            validToken,
+			requestBody:      `{"amount":"10"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"request contains wrong data", "status":"error"}`,
 		},
 		{
 			name:               "Update Users Balance error",
 			token:              validToken,
 			requestBody:        `{"from_currency":"USD","to_currency":"EUR","amount":100}`,
-			mockBalance:        map[string]float64{"USD": 100, "EUR": 50},
+			mockBalance:        map[string]money.Amount{"USD": money.MustParse("100"), "EUR": money.MustParse("50")},
 			mockExchangerError: errors.New("update balance error"),
-			mockGrpcRates: map[string]float32{
-				"RUB_USD": 0.012,
-				"RUB_EUR": 0.011,
+			mockRates: map[string]money.Amount{
+				"RUB_USD": money.MustParse("0.012"),
+				"RUB_EUR": money.MustParse("0.011"),
 			},
-			mockGrpcError:    nil,
+			mockRatesError:   nil,
 			expectedStatus:   http.StatusInternalServerError,
 			expectedResponse: `{"error":"update balance error", "status":"error"}`,
 		},
@@ -209,15 +311,16 @@ func TestHandleExchange(t *testing.T) {
 
 			mockExchanger := &MockExchanger{
 				balance: tc.mockBalance,
+				quotes:  tc.mockQuotes,
 				err:     tc.mockExchangerError,
 			}
 
-			mockGrpcClient := &MockExchangeServiceClient{
-				rates: tc.mockGrpcRates,
-				err:   tc.mockGrpcError,
+			mockRateProvider := &MockRateProvider{
+				rates: tc.mockRates,
+				err:   tc.mockRatesError,
 			}
 
-			HandleExchange(logger, mockGrpcClient, mockExchanger)(c)
+			authenticated(HandleExchange(logger, mockRateProvider, mockExchanger, nil))(c)
 
 			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
 			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
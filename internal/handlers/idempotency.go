@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+type IdempotencyStore interface {
+	StartIdempotentRequest(username, key, requestHash string) (*models.IdempotencyRecord, error)
+	CompleteIdempotentRequest(username, key string, statusCode int, body []byte) error
+	ReleaseIdempotentRequest(username, key string) error
+}
+
+// bodyRecorder keeps a copy of everything the handler writes so it can be replayed later.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// startIdempotentRequest honours the Idempotency-Key header. If the key was already used it
+// writes the stored response (or a conflict) and returns handled == true. Otherwise the caller
+// must run finish once it has written its response, finish is a no-op when there is no key.
+func startIdempotentRequest(c *gin.Context, logger *zap.Logger, store IdempotencyStore, op, username string, reqBody []byte) (finish func(), handled bool) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" || store == nil {
+		return func() {}, false
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		logError(c, logger, errors.New("idempotency key is too long"), http.StatusBadRequest, "failed to process request")
+		return nil, true
+	}
+
+	hash := sha256.Sum256(append([]byte(op+"\n"), reqBody...))
+	requestHash := hex.EncodeToString(hash[:])
+
+	record, err := store.StartIdempotentRequest(username, key, requestHash)
+	if err != nil {
+		logError(c, logger, err, http.StatusInternalServerError, "failed to check idempotency key")
+		return nil, true
+	}
+
+	if record != nil {
+		switch {
+		case !record.Completed:
+			logError(c, logger, errors.New("request with this idempotency key is still in progress"), http.StatusConflict, "")
+		case record.RequestHash != requestHash:
+			logError(c, logger, errors.New("idempotency key was already used for a different request"), http.StatusConflict, "")
+		default:
+			logger.Info("replaying idempotent request", zap.String("op", op), zap.String("key", key))
+			c.Header(idempotentReplayedHeader, "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", record.Body)
+		}
+		return nil, true
+	}
+
+	recorder := &bodyRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+
+	return func() {
+		// server errors are usually transient and a request rejected for a stale authentication
+		// is repeated after re-authenticating, let the client retry them with the same key
+		if recorder.Status() >= http.StatusInternalServerError || recorder.Status() == http.StatusUnauthorized {
+			if err := store.ReleaseIdempotentRequest(username, key); err != nil {
+				logger.Warn("failed to release idempotency key", zap.Error(err))
+			}
+			return
+		}
+		if err := store.CompleteIdempotentRequest(username, key, recorder.Status(), recorder.body.Bytes()); err != nil {
+			logger.Warn("failed to store idempotent response", zap.Error(err))
+		}
+	}, false
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockIdempotencyStore struct {
+	records map[string]*models.IdempotencyRecord
+}
+
+func (m *MockIdempotencyStore) StartIdempotentRequest(username, key, requestHash string) (*models.IdempotencyRecord, error) {
+	if record, ok := m.records[username+"/"+key]; ok {
+		return record, nil
+	}
+	m.records[username+"/"+key] = &models.IdempotencyRecord{RequestHash: requestHash}
+	return nil, nil
+}
+
+func (m *MockIdempotencyStore) CompleteIdempotentRequest(username, key string, statusCode int, body []byte) error {
+	record := m.records[username+"/"+key]
+	record.Completed = true
+	record.StatusCode = statusCode
+	record.Body = append([]byte(nil), body...)
+	return nil
+}
+
+func (m *MockIdempotencyStore) ReleaseIdempotentRequest(username, key string) error {
+	delete(m.records, username+"/"+key)
+	return nil
+}
+
+func TestHandleDepositIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	store := &MockIdempotencyStore{records: map[string]*models.IdempotencyRecord{
+		"testuser/in-progress": {RequestHash: "whatever"},
+	}}
+	balanceUpdater := &MockCountingBalanceUpdater{balance: map[string]money.Amount{"USD": money.MustParse("100")}}
+
+	testCases := []struct {
+		name             string
+		key              string
+		requestBody      string
+		updaterError     error
+		expectedStatus   int
+		expectedResponse string
+		expectedReplayed bool
+		expectedUpdates  int
+	}{
+		{
+			name:             "First Request",
+			key:              "key-1",
+			requestBody:      `{"currency":"USD","amount":50}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Account topped up successfully","balance":{"USD":"150"}}`,
+			expectedUpdates:  1,
+		},
+		{
+			name:             "Retry Is Replayed",
+			key:              "key-1",
+			requestBody:      `{"currency":"USD","amount":50}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Account topped up successfully","balance":{"USD":"150"}}`,
+			expectedReplayed: true,
+			expectedUpdates:  1,
+		},
+		{
+			name:             "Same Key Different Body",
+			key:              "key-1",
+			requestBody:      `{"currency":"USD","amount":60}`,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"error":"idempotency key was already used for a different request", "status":"error"}`,
+			expectedUpdates:  1,
+		},
+		{
+			name:             "Key In Progress",
+			key:              "in-progress",
+			requestBody:      `{"currency":"USD","amount":50}`,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"error":"request with this idempotency key is still in progress", "status":"error"}`,
+			expectedUpdates:  1,
+		},
+		{
+			name:             "No Key",
+			key:              "",
+			requestBody:      `{"currency":"USD","amount":50}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Account topped up successfully","balance":{"USD":"200"}}`,
+			expectedUpdates:  2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body := bytes.NewBufferString(tc.requestBody)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/wallet/deposit", body)
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Authorization", validToken)
+			if tc.key != "" {
+				c.Request.Header.Set("Idempotency-Key", tc.key)
+			}
+
+			authenticated(HandleDeposit(newTestLogger(), balanceUpdater, store, nil, nil))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			assert.Equal(t, tc.expectedReplayed, w.Header().Get("Idempotent-Replayed") == "true")
+			assert.Equal(t, tc.expectedUpdates, balanceUpdater.updates, "Balance updates mismatch")
+		})
+	}
+}
+
+func TestHandleDepositIdempotencyAtLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	store := &MockIdempotencyStore{records: map[string]*models.IdempotencyRecord{}}
+	balanceUpdater := &MockBalanceUpdater{balance: map[string]money.Amount{"USD": money.MustParse("1000")}}
+	limiter := &MockDepositLimiter{deposited: money.MustParse("600")}
+	limits := UnverifiedDepositLimits{"USD": money.MustParse("1000")}
+
+	deposit := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/wallet/deposit", bytes.NewBufferString(`{"currency":"USD","amount":400}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.Header.Set("Authorization", validToken)
+		c.Request.Header.Set("Idempotency-Key", "key-1")
+
+		authenticated(HandleDeposit(newTestLogger(), balanceUpdater, store, limiter, limits))(c)
+		return w
+	}
+
+	first := deposit()
+	assert.Equal(t, http.StatusOK, first.Code)
+
+	// The first deposit used up the limit, the retry must still get its stored response.
+	limiter.deposited = money.MustParse("1000")
+	retry := deposit()
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+}
+
+func TestIdempotencyKeyReleasedOnServerError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	store := &MockIdempotencyStore{records: map[string]*models.IdempotencyRecord{}}
+	exchanger := &MockExchanger{err: errors.New("database error")}
+	client := &MockRateProvider{rates: map[string]money.Amount{"RUB_USD": money.MustParse("0.012"), "RUB_EUR": money.MustParse("0.011")}}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := bytes.NewBufferString(`{"from_currency":"USD","to_currency":"EUR","amount":100}`)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/exchange", body)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Authorization", validToken)
+	c.Request.Header.Set("Idempotency-Key", "key-1")
+
+	authenticated(HandleExchange(newTestLogger(), client, exchanger, store))(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, store.records, "Key must be released after a server error")
+}
+
+func TestIdempotencyKeyReleasedOnStepUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	store := &MockIdempotencyStore{records: map[string]*models.IdempotencyRecord{}}
+	transferer := &MockTransferer{
+		users:   map[string][]string{"anna": {"USD"}},
+		balance: map[string]money.Amount{"USD": money.MustParse("100")},
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := bytes.NewBufferString(`{"recipient":"anna","currency":"USD","amount":"5"}`)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/transfers", body)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Authorization", validToken)
+	c.Request.Header.Set("Idempotency-Key", "key-1")
+
+	authenticated(HandleTransfer(newTestLogger(), &MockRateProvider{}, transferer, store, StepUpPolicy{MaxAge: time.Minute}))(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, store.records, "Key must be released so the transfer can be retried after re-authenticating")
+}
+
+type MockCountingBalanceUpdater struct {
+	balance map[string]money.Amount
+	updates int
+}
+
+func (m *MockCountingBalanceUpdater) UpdateUsersBalance(username, currency string, amount money.Amount) error {
+	m.updates++
+	m.balance[currency] = m.balance[currency].Add(amount)
+	return nil
+}
+
+func (m *MockCountingBalanceUpdater) GetUserBalance(username string) (map[string]money.Amount, error) {
+	balance := make(map[string]money.Amount, len(m.balance))
+	for currency, amount := range m.balance {
+		balance[currency] = amount
+	}
+	return balance, nil
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// jwksMaxAge is how long verifiers may cache the key set. A new signing key has to be
+// published at least this long before tokens are signed with it.
+const jwksMaxAge = "public, max-age=300"
+
+type JWKSProvider interface {
+	JWKS() auth.JWKS
+}
+
+// HandleJWKS godoc
+// @Summary Публичные ключи JWT
+// @Description Возвращает публичные ключи RS256 и EdDSA в формате JWKS для проверки токенов другими сервисами. Секреты HS256 не публикуются.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} jwt.JWKS "OK"
+// @Router /.well-known/jwks.json [get]
+func HandleJWKS(logger *zap.Logger, jwksProvider JWKSProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "HandleJWKS"
+
+		logger.Debug("proceeding new request", zap.String("op", op))
+
+		c.Header("Cache-Control", jwksMaxAge)
+		c.JSON(http.StatusOK, jwksProvider.JWKS())
+	}
+}
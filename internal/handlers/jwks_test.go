@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockJWKSProvider struct {
+	jwks auth.JWKS
+}
+
+func (m *MockJWKSProvider) JWKS() auth.JWKS {
+	return m.jwks
+}
+
+func TestHandleJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+
+	provider := &MockJWKSProvider{jwks: auth.JWKS{Keys: []auth.JWK{{
+		KeyType: "OKP", KeyID: "2025-06", Use: "sig", Alg: "EdDSA", Curve: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+	}}}}
+	HandleJWKS(newTestLogger(), provider)(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"keys":[{"kty":"OKP","kid":"2025-06","use":"sig","alg":"EdDSA","crv":"Ed25519",
+		"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`, w.Body.String())
+}
@@ -1,15 +1,19 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
 
-	"github.com/Foreground-Eclipse/transferer/config"
 	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/lockout"
 	"github.com/Foreground-Eclipse/transferer/internal/middleware"
-	jwt "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/Foreground-Eclipse/transferer/internal/session"
+	"github.com/Foreground-Eclipse/transferer/internal/twofactor"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -18,18 +22,46 @@ type UserLogger interface {
 	GetUsersPassHash(username string) (string, error)
 }
 
+type SessionStarter interface {
+	StartSession(username string, methods []string) (session.Tokens, error)
+}
+
+type LoginGuard interface {
+	Begin(username, ip string) (*lockout.Attempt, error)
+	Fail(ctx context.Context, attempt *lockout.Attempt) error
+	Continue(attempt *lockout.Attempt) error
+	Succeed(attempt *lockout.Attempt) error
+}
+
+type AccountUnlocker interface {
+	Unlock(token string) (string, error)
+}
+
+type LoginChallenger interface {
+	Enabled(username string) (bool, error)
+	StartChallenge(username string) (twofactor.Challenge, error)
+}
+
 // HandleLoginUser godoc
 // @Summary Аутентификация пользователя
-// @Description Аутентифицирует пользователя и возвращает JWT токен.
+// @Description Аутентифицирует пользователя и возвращает короткоживущий access токен и refresh токен.
+// @Description Если у пользователя включена двухфакторная аутентификация, возвращает challenge_token,
+// @Description токены выдаются после подтверждения входа кодом в /api/v1/login/2fa.
+// @Description Неудачные попытки считаются по имени пользователя и IP: после нескольких попыток вход задерживается,
+// @Description после слишком многих аккаунт блокируется и пользователю отправляется ссылка для разблокировки.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body requests.LoginRequest true "Логин пользователя"
-// @Success 200 {object} map[string]interface{} "Успешная аутентификация"
+// @Success 200 {object} requests.TokenResponse "Успешная аутентификация"
+// @Success 202 {object} requests.TwoFactorChallengeResponse "Требуется код двухфакторной аутентификации"
 // @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.WrongPasswordError "Неверное имя пользователя или пароль"
+// @Failure 429 {object} requests.TooManyLoginAttemptsError "Слишком много неудачных попыток, повторить через Retry-After секунд"
+// @Header 429 {integer} Retry-After "Через сколько секунд можно повторить вход"
 // @Failure 500 {object} requests.CantCreateJWTError "Внутренняя ошибка сервера"
 // @Router /api/v1/login [post]
-func HandleLoginUser(logger *zap.Logger, userLogger UserLogger, cfg *config.Config) gin.HandlerFunc {
+func HandleLoginUser(logger *zap.Logger, userLogger UserLogger, sessionStarter SessionStarter, loginChallenger LoginChallenger, loginGuard LoginGuard) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req requests.LoginRequest
 		const op = "api/v1/HandleLoginUser"
@@ -44,44 +76,133 @@ func HandleLoginUser(logger *zap.Logger, userLogger UserLogger, cfg *config.Conf
 			return
 		}
 
-		reqBody, err := json.Marshal(req)
-		if err != nil {
-			logError(c, logger, err, http.StatusBadRequest, "failed to marshal request body")
-			return
-		}
-
 		logger.Info("request data: ",
 			zap.String("discordid: ", c.Request.Method),
 			zap.String("URL", c.Request.URL.String()),
-			zap.String("body", string(reqBody)),
 		)
 
+		ip := c.ClientIP()
+		attempt, ok := beginLogin(c, logger, loginGuard, op, req.Username, ip)
+		if !ok {
+			return
+		}
+
+		// an unknown username looks the same as a wrong password
+		isRight := false
 		passhash, err := userLogger.GetUsersPassHash(req.Username)
-		if err != nil {
-			logError(c, logger, errors.New("wrong password"), http.StatusOK, "wrong password")
+		if err == nil {
+			isRight, err = middleware.VerifyPassword(req.Password, passhash)
+		}
+		if err != nil || !isRight {
+			if err := loginGuard.Fail(c.Request.Context(), attempt); err != nil {
+				logger.Error("failed to record failed login", zap.String("op", op), zap.Error(err))
+			}
+			logError(c, logger, errors.New("wrong password"), http.StatusUnauthorized, "wrong password")
 			return
 		}
 
-		isRight, err := middleware.VerifyPassword(req.Password, passhash)
+		twoFactor, err := loginChallenger.Enabled(req.Username)
 		if err != nil {
-			logError(c, logger, errors.New("wrong password"), http.StatusOK, "wrong password")
+			logger.Error("failed to check two-factor authentication", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
 			return
 		}
-
-		if !isRight {
-			logError(c, logger, errors.New("wrong password"), http.StatusOK, "wrong password")
+		if twoFactor {
+			// the failed logins are reset once the second factor is right too
+			if err := loginGuard.Continue(attempt); err != nil {
+				logger.Error("failed to release login attempt", zap.String("op", op), zap.Error(err))
+			}
+			challenge, err := loginChallenger.StartChallenge(req.Username)
+			if err != nil {
+				logger.Error("failed to start login challenge", zap.String("op", op), zap.Error(err))
+				logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
+				return
+			}
+			c.JSON(http.StatusAccepted, requests.TwoFactorChallengeResponse{
+				TwoFactorRequired: true,
+				ChallengeToken:    challenge.Token,
+				ExpiresAt:         challenge.ExpiresAt,
+			})
 			return
 		}
 
-		token, err := jwt.GenerateJWT(req.Username, cfg)
+		if err := loginGuard.Succeed(attempt); err != nil {
+			logger.Error("failed to reset failed logins", zap.String("op", op), zap.Error(err))
+		}
+
+		tokens, err := sessionStarter.StartSession(req.Username, []string{auth.MethodPassword})
 		if err != nil {
+			logger.Error("failed to start session", zap.String("op", op), zap.Error(err))
 			logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
+			return
 		}
 
-		c.JSON(http.StatusOK, map[string]interface{}{
-			"token": token,
-		})
+		c.JSON(http.StatusOK, tokenResponse(tokens))
 
 	}
 
 }
+
+// beginLogin counts an attempt of username to authenticate from ip and reports whether it
+// may be made. If not, it answers 429 with Retry-After.
+func beginLogin(c *gin.Context, logger *zap.Logger, loginGuard LoginGuard, op, username, ip string) (*lockout.Attempt, bool) {
+	attempt, err := loginGuard.Begin(username, ip)
+	var blocked *lockout.BlockedError
+	if errors.As(err, &blocked) {
+		logger.Warn("login blocked", zap.String("op", op), zap.String("username", username),
+			zap.String("ip", ip), zap.Bool("locked", blocked.Locked))
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		logError(c, logger, blocked, http.StatusTooManyRequests, "")
+		return nil, false
+	}
+	if err != nil {
+		logger.Error("failed to check failed logins", zap.String("op", op), zap.Error(err))
+		logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
+		return nil, false
+	}
+	return attempt, true
+}
+
+// HandleUnlockAccount godoc
+// @Summary Разблокировка аккаунта
+// @Description Снимает блокировку входа по токену из письма, отправленного при блокировке аккаунта.
+// @Description Токен действует один раз.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body requests.UnlockAccountRequest true "Токен из письма"
+// @Success 200 {object} requests.UnlockAccountResponse "Аккаунт разблокирован"
+// @Failure 400 {object} requests.InvalidUnlockTokenError "Некорректный запрос или недействительный токен"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Router /api/v1/login/unlock [post]
+func HandleUnlockAccount(logger *zap.Logger, unlocker AccountUnlocker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.UnlockAccountRequest
+		const op = "api/v1/HandleUnlockAccount"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		username, err := unlocker.Unlock(req.Token)
+		if errors.Is(err, lockout.ErrInvalidUnlockToken) {
+			logError(c, logger, lockout.ErrInvalidUnlockToken, http.StatusBadRequest, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to unlock account", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to unlock account"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("account unlocked", zap.String("op", op), zap.String("username", username))
+		c.JSON(http.StatusOK, requests.UnlockAccountResponse{Message: "account unlocked"})
+	}
+}
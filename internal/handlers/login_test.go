@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
-	"github.com/Foreground-Eclipse/transferer/config"
+	"github.com/Foreground-Eclipse/transferer/internal/lockout"
 	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/session"
+	"github.com/Foreground-Eclipse/transferer/internal/twofactor"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -27,6 +32,83 @@ func (m *MockUserLogger) GetUsersPassHash(username string) (string, error) {
 	return m.passhash, nil
 }
 
+type MockSessionStarter struct {
+	err     error
+	methods []string
+}
+
+func (m *MockSessionStarter) StartSession(username string, methods []string) (session.Tokens, error) {
+	m.methods = methods
+	if m.err != nil {
+		return session.Tokens{}, m.err
+	}
+	return session.Tokens{
+		AccessToken:  "access-" + username,
+		RefreshToken: "refresh-" + username,
+	}, nil
+}
+
+type MockLoginChallenger struct {
+	enabled bool
+	err     error
+}
+
+func (m *MockLoginChallenger) Enabled(username string) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	return m.enabled, nil
+}
+
+func (m *MockLoginChallenger) StartChallenge(username string) (twofactor.Challenge, error) {
+	return twofactor.Challenge{Token: "challenge-" + username, ExpiresAt: testTokensTime.Add(5 * time.Minute)}, nil
+}
+
+type MockLoginGuard struct {
+	err error
+	// limit blocks logins after that many failures, if set
+	limit     int
+	failures  int
+	continued bool
+	succeeded bool
+}
+
+func (m *MockLoginGuard) Begin(username, ip string) (*lockout.Attempt, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.limit > 0 && m.failures >= m.limit {
+		return nil, &lockout.BlockedError{RetryAfter: time.Minute}
+	}
+	return &lockout.Attempt{Username: username, IP: ip}, nil
+}
+
+func (m *MockLoginGuard) Fail(ctx context.Context, attempt *lockout.Attempt) error {
+	m.failures++
+	return nil
+}
+
+func (m *MockLoginGuard) Continue(attempt *lockout.Attempt) error {
+	m.continued = true
+	return nil
+}
+
+func (m *MockLoginGuard) Succeed(attempt *lockout.Attempt) error {
+	m.succeeded = true
+	return nil
+}
+
+type MockAccountUnlocker struct {
+	err error
+}
+
+func (m *MockAccountUnlocker) Unlock(token string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	return "testuser", nil
+}
+
 func TestHandleLoginUser(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -38,8 +120,14 @@ func TestHandleLoginUser(t *testing.T) {
 		requestBody      string
 		mockPasshash     string
 		mockError        error
+		sessionError     error
+		twoFactor        bool
+		twoFactorError   error
+		guardError       error
 		expectedStatus   int
 		expectedResponse string
+		expectedFailures int
+		expectedRetry    string
 	}{
 		{
 			name:             "Invalid JSON - Wrong Type",
@@ -54,16 +142,44 @@ func TestHandleLoginUser(t *testing.T) {
 			requestBody:      `{"username":"nonexistentuser","password":"password123"}`,
 			mockPasshash:     "",
 			mockError:        errors.New("user not found"),
-			expectedStatus:   http.StatusOK,
+			expectedStatus:   http.StatusUnauthorized,
 			expectedResponse: `{"error":"wrong password", "status":"error"}`,
+			expectedFailures: 1,
 		},
 		{
 			name:             "Invalid Credentials - Wrong Password",
 			requestBody:      `{"username":"testuser","password":"wrongpassword"}`,
 			mockPasshash:     hashedPassword,
 			mockError:        nil,
-			expectedStatus:   http.StatusOK,
+			expectedStatus:   http.StatusUnauthorized,
 			expectedResponse: `{"error":"wrong password", "status":"error"}`,
+			expectedFailures: 1,
+		},
+		{
+			name:             "Too_Many_Attempts",
+			requestBody:      `{"username":"testuser","password":"password123"}`,
+			mockPasshash:     hashedPassword,
+			guardError:       fmt.Errorf("check: %w", &lockout.BlockedError{RetryAfter: 1500 * time.Millisecond}),
+			expectedStatus:   http.StatusTooManyRequests,
+			expectedResponse: `{"error":"too many failed login attempts", "status":"error"}`,
+			expectedRetry:    "2",
+		},
+		{
+			name:             "Account_Locked",
+			requestBody:      `{"username":"testuser","password":"password123"}`,
+			mockPasshash:     hashedPassword,
+			guardError:       &lockout.BlockedError{RetryAfter: 30 * time.Minute, Locked: true},
+			expectedStatus:   http.StatusTooManyRequests,
+			expectedResponse: `{"error":"account is temporarily locked", "status":"error"}`,
+			expectedRetry:    "1800",
+		},
+		{
+			name:             "Guard_Error",
+			requestBody:      `{"username":"testuser","password":"password123"}`,
+			mockPasshash:     hashedPassword,
+			guardError:       errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"could not create JWT token", "status":"error"}`,
 		},
 		{
 			name:             "Valid Credentials - Success",
@@ -73,6 +189,30 @@ func TestHandleLoginUser(t *testing.T) {
 			expectedStatus:   http.StatusOK,
 			expectedResponse: `{"token":""}`,
 		},
+		{
+			name:             "Session_Error",
+			requestBody:      `{"username":"testuser","password":"password123"}`,
+			mockPasshash:     hashedPassword,
+			sessionError:     errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"could not create JWT token", "status":"error"}`,
+		},
+		{
+			name:             "Two_Factor_Required",
+			requestBody:      `{"username":"testuser","password":"password123"}`,
+			mockPasshash:     hashedPassword,
+			twoFactor:        true,
+			expectedStatus:   http.StatusAccepted,
+			expectedResponse: `{"two_factor_required":true,"challenge_token":"challenge-testuser","expires_at":"2025-03-01T12:05:00Z"}`,
+		},
+		{
+			name:             "Two_Factor_Error",
+			requestBody:      `{"username":"testuser","password":"password123"}`,
+			mockPasshash:     hashedPassword,
+			twoFactorError:   errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"could not create JWT token", "status":"error"}`,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -90,23 +230,80 @@ func TestHandleLoginUser(t *testing.T) {
 				err:      tc.mockError,
 			}
 
-			cfg := &config.Config{
-				JWT: config.JWTConfig{
-					JWTExpirationTimeHours: 1,
-				},
-			}
-
-			HandleLoginUser(logger, mockUserLogger, cfg)(c)
+			mockLoginChallenger := &MockLoginChallenger{enabled: tc.twoFactor, err: tc.twoFactorError}
+			mockLoginGuard := &MockLoginGuard{err: tc.guardError}
+			HandleLoginUser(logger, mockUserLogger, &MockSessionStarter{err: tc.sessionError}, mockLoginChallenger, mockLoginGuard)(c)
 			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.Equal(t, tc.expectedFailures, mockLoginGuard.failures, "Failed logins mismatch")
+			assert.Equal(t, tc.expectedRetry, w.Header().Get("Retry-After"))
 
 			if tc.name == "Valid Credentials - Success" {
 				var response map[string]string
 				err := json.Unmarshal(w.Body.Bytes(), &response)
 				assert.NoError(t, err, "Failed to unmarshal response")
-				assert.NotEmpty(t, response["token"], "Token should not be empty")
+				assert.Equal(t, "access-testuser", response["token"])
+				assert.Equal(t, "refresh-testuser", response["refresh_token"])
+				assert.True(t, mockLoginGuard.succeeded, "Failed logins must be reset")
 			} else {
 				assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
 			}
+			if tc.twoFactor {
+				assert.True(t, mockLoginGuard.continued)
+				assert.False(t, mockLoginGuard.succeeded, "failed logins are reset after the second factor")
+			}
+		})
+	}
+}
+
+func TestHandleUnlockAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Success",
+			requestBody:      `{"token":"unlock"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"account unlocked"}`,
+		},
+		{
+			name:             "Missing_Token",
+			requestBody:      `{}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"request contains wrong data"}`,
+		},
+		{
+			name:             "Invalid_Token",
+			requestBody:      `{"token":"expired"}`,
+			mockError:        lockout.ErrInvalidUnlockToken,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"invalid or expired unlock token"}`,
+		},
+		{
+			name:             "Storage_Error",
+			requestBody:      `{"token":"unlock"}`,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to unlock account"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/login/unlock", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			HandleUnlockAccount(newTestLogger(), &MockAccountUnlocker{err: tc.mockError})(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
 		})
 	}
 }
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/passwordreset"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// passwordResetRequestTimeout bounds a password reset requested in the background.
+const passwordResetRequestTimeout = time.Minute
+
+type PasswordResetRequester interface {
+	Request(ctx context.Context, email string) error
+}
+
+type PasswordResetter interface {
+	Reset(ctx context.Context, token, passwordHash string) (string, error)
+}
+
+// HandleForgotPassword godoc
+// @Summary Запрос сброса пароля
+// @Description Отправляет на email пользователя письмо со ссылкой для сброса пароля. Ссылка действует один раз
+// @Description и ограниченное время. Ответ одинаковый независимо от того, зарегистрирован ли email,
+// @Description письмо отправляется в фоне. На один email письмо отправляется не чаще PASSWORD_RESET_RESEND_INTERVAL.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body requests.ForgotPasswordRequest true "Email аккаунта"
+// @Success 202 {object} requests.ForgotPasswordResponse "Запрос принят"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Router /api/v1/password/forgot [post]
+func HandleForgotPassword(logger *zap.Logger, requester PasswordResetRequester) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.ForgotPasswordRequest
+		const op = "api/v1/HandleForgotPassword"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		// requested in the background, so neither the status nor the time of the response
+		// tell whether the email is registered
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), passwordResetRequestTimeout)
+		go func() {
+			defer cancel()
+			err := requester.Request(ctx, req.Email)
+			if errors.Is(err, passwordreset.ErrRequestedRecently) {
+				logger.Warn("password reset requested too often", zap.String("op", op), zap.Error(err))
+				return
+			}
+			if err != nil {
+				logger.Error("failed to request password reset", zap.String("op", op), zap.Error(err))
+			}
+		}()
+
+		c.JSON(http.StatusAccepted, requests.ForgotPasswordResponse{
+			Message: "if the email is registered, a password reset link has been sent",
+		})
+	}
+}
+
+// HandleResetPassword godoc
+// @Summary Сброс пароля
+// @Description Устанавливает новый пароль по токену из письма. Токен действует один раз, после сброса
+// @Description все сессии пользователя завершаются и блокировка входа снимается.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body requests.ResetPasswordRequest true "Токен из письма и новый пароль"
+// @Success 200 {object} requests.AccountUpdatedResponse "Пароль изменен"
+// @Failure 400 {object} requests.InvalidResetTokenError "Некорректный запрос или недействительный токен"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Router /api/v1/password/reset [post]
+func HandleResetPassword(logger *zap.Logger, resetter PasswordResetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.ResetPasswordRequest
+		const op = "api/v1/HandleResetPassword"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		username, err := resetter.Reset(c.Request.Context(), req.Token, middleware.HashPassword(req.NewPassword))
+		if errors.Is(err, passwordreset.ErrInvalidToken) {
+			logError(c, logger, passwordreset.ErrInvalidToken, http.StatusBadRequest, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to reset password", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to change password"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("password reset", zap.String("op", op), zap.String("username", username))
+		c.JSON(http.StatusOK, requests.AccountUpdatedResponse{Message: "password changed"})
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/passwordreset"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockPasswordReset struct {
+	err          error
+	requested    chan string
+	passwordHash string
+}
+
+func (m *MockPasswordReset) Request(ctx context.Context, email string) error {
+	m.requested <- email
+	return m.err
+}
+
+func (m *MockPasswordReset) Reset(ctx context.Context, token, passwordHash string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	m.passwordHash = passwordHash
+	return "testuser", nil
+}
+
+func TestHandleForgotPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+		expectedEmail    string
+	}{
+		{
+			name:             "Success",
+			requestBody:      `{"email":"test@example.com"}`,
+			expectedStatus:   http.StatusAccepted,
+			expectedResponse: `{"message":"if the email is registered, a password reset link has been sent"}`,
+			expectedEmail:    "test@example.com",
+		},
+		{
+			name:             "Invalid_Email",
+			requestBody:      `{"email":"not an email"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"request contains wrong data"}`,
+		},
+		{
+			name:             "Empty_JSON",
+			requestBody:      ``,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"empty json"}`,
+		},
+		{
+			name:             "Mailer_Error",
+			requestBody:      `{"email":"test@example.com"}`,
+			mockError:        errors.New("smtp is down"),
+			expectedStatus:   http.StatusAccepted,
+			expectedResponse: `{"message":"if the email is registered, a password reset link has been sent"}`,
+			expectedEmail:    "test@example.com",
+		},
+		{
+			name:             "Requested_Recently",
+			requestBody:      `{"email":"test@example.com"}`,
+			mockError:        passwordreset.ErrRequestedRecently,
+			expectedStatus:   http.StatusAccepted,
+			expectedResponse: `{"message":"if the email is registered, a password reset link has been sent"}`,
+			expectedEmail:    "test@example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/password/forgot", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			mock := &MockPasswordReset{err: tc.mockError, requested: make(chan string, 1)}
+			HandleForgotPassword(newTestLogger(), mock)(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			if tc.expectedEmail == "" {
+				assert.Empty(t, mock.requested, "no reset requested")
+				return
+			}
+			select {
+			case email := <-mock.requested:
+				assert.Equal(t, tc.expectedEmail, email)
+			case <-time.After(time.Second):
+				t.Fatal("password reset was not requested")
+			}
+		})
+	}
+}
+
+func TestHandleResetPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Success",
+			requestBody:      `{"token":"reset","new_password":"new_password"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"password changed"}`,
+		},
+		{
+			name:             "Missing_Password",
+			requestBody:      `{"token":"reset"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"request contains wrong data"}`,
+		},
+		{
+			name:             "Invalid_Token",
+			requestBody:      `{"token":"expired","new_password":"new_password"}`,
+			mockError:        passwordreset.ErrInvalidToken,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"invalid or expired password reset token"}`,
+		},
+		{
+			name:             "Storage_Error",
+			requestBody:      `{"token":"reset","new_password":"new_password"}`,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to change password"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/password/reset", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			mock := &MockPasswordReset{err: tc.mockError}
+			HandleResetPassword(newTestLogger(), mock)(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			if tc.expectedStatus == http.StatusOK {
+				isRight, err := middleware.VerifyPassword("new_password", mock.passwordHash)
+				assert.NoError(t, err)
+				assert.True(t, isRight, "the new password is stored hashed")
+			}
+		})
+	}
+}
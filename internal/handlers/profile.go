@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ProfileGetter interface {
+	GetUser(username string) (*models.User, error)
+}
+
+// HandleProfile godoc
+// @Summary Профиль пользователя
+// @Description Возвращает имя пользователя, email и подтвержден ли email.
+// @Tags account
+// @Produce json
+// @Success 200 {object} requests.ProfileResponse "Профиль"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/profile [get]
+func HandleProfile(logger *zap.Logger, profileGetter ProfileGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleProfile"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		user, err := profileGetter.GetUser(principal.Username)
+		if err != nil {
+			logger.Error("failed to get user", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to get profile"), http.StatusInternalServerError, "")
+			return
+		}
+
+		c.JSON(http.StatusOK, requests.ProfileResponse{
+			Username:      user.Username,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+		})
+	}
+}
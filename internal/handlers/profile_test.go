@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockProfileGetter struct {
+	user *models.User
+	err  error
+}
+
+func (m *MockProfileGetter) GetUser(username string) (*models.User, error) {
+	return m.user, m.err
+}
+
+func TestHandleProfile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		token            string
+		mock             *MockProfileGetter
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Unverified",
+			token:            validToken,
+			mock:             &MockProfileGetter{user: &models.User{Username: "testuser", Email: "test@example.com"}},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"username":"testuser","email":"test@example.com","email_verified":false}`,
+		},
+		{
+			name:             "Verified",
+			token:            validToken,
+			mock:             &MockProfileGetter{user: &models.User{Username: "testuser", Email: "test@example.com", EmailVerified: true}},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"username":"testuser","email":"test@example.com","email_verified":true}`,
+		},
+		{
+			name:             "No_Token",
+			mock:             &MockProfileGetter{},
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"not authorized"}`,
+		},
+		{
+			name:             "Storage_Error",
+			token:            validToken,
+			mock:             &MockProfileGetter{err: errors.New("db is down")},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to get profile"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/profile", nil)
+			c.Request.Header.Set("Authorization", tc.token)
+
+			authenticated(HandleProfile(newTestLogger(), tc.mock))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/rates"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// quoteTTL is how long a quoted rate stays valid.
+const quoteTTL = 30 * time.Second
+
+type QuoteCreator interface {
+	CreateQuote(username, fromCurrency, toCurrency string, amount, rate money.Amount, ttl time.Duration) (*models.Quote, error)
+}
+
+// HandleExchangeQuote godoc
+// @Summary Котировка обмена валюты
+// @Description  Фиксирует текущий курс обмена на 30 секунд и возвращает сумму, которую получит пользователь.
+// @Description  Обмен по котировке выполняется через /api/v1/exchange с quote_id.
+// @Tags exchange
+// @Accept  json
+// @Produce  json
+// @Param   request body requests.QuoteRequest true "Данные для котировки"
+// @Success 200 {object} requests.QuoteResponse "OK"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос или нет курса для пары валют"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 500 {object} requests.RetrieveRatesError "Ошибка получения курсов"
+// @Failure 503 {object} requests.RatesUnavailableError "Источник курсов недоступен"
+// @Security ApiKeyAuth
+// @Router /api/v1/exchange/quote [post]
+func HandleExchangeQuote(logger *zap.Logger, rateProvider rates.Provider, quoteCreator QuoteCreator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleExchangeQuote"
+
+		var req requests.QuoteRequest
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		if !req.Amount.IsPositive() {
+			logError(c, logger, errors.New("amount must be positive"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+		if !checkAmountScale(c, logger, req.Amount) {
+			return
+		}
+
+		if req.FromCurrency == req.ToCurrency {
+			logError(c, logger, errors.New("cannot exchange currency to itself"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		reqBody, err := json.Marshal(req)
+		if err != nil {
+			logError(c, logger, err, http.StatusBadRequest, "failed to marshal request body")
+			return
+		}
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		logger.Info("request data: ",
+			zap.String("discordid: ", c.Request.Method),
+			zap.String("URL", c.Request.URL.String()),
+			zap.String("username", principal.Username),
+			zap.String("Body", string(reqBody)),
+		)
+
+		username := principal.Username
+
+		quotes, err := rateProvider.GetRates(c.Request.Context())
+		if err != nil {
+			logRatesError(c, logger, err)
+			return
+		}
+
+		crossRate, err := exchangeRate(req.FromCurrency, req.ToCurrency, quotes)
+		if err != nil {
+			logExchangeRateError(c, logger, err)
+			return
+		}
+
+		quote, err := quoteCreator.CreateQuote(username, req.FromCurrency, req.ToCurrency, req.Amount, crossRate.Rate, quoteTTL)
+		if err != nil {
+			logError(c, logger, err, http.StatusInternalServerError, "")
+			return
+		}
+
+		c.JSON(http.StatusOK, requests.QuoteResponse{
+			QuoteID:      quote.ID,
+			FromCurrency: quote.FromCurrency,
+			ToCurrency:   quote.ToCurrency,
+			Amount:       quote.Amount,
+			Rate:         quote.Rate,
+			ToAmount:     quote.ToAmount,
+			Path:         crossRate.Path,
+			ExpiresAt:    quote.ExpiresAt,
+		})
+	}
+}
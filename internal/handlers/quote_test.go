@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testQuoteExpiry = time.Date(2025, 3, 1, 12, 0, 30, 0, time.UTC)
+
+type MockQuoteCreator struct {
+	ttl time.Duration
+	err error
+}
+
+func (m *MockQuoteCreator) CreateQuote(username, fromCurrency, toCurrency string, amount, rate money.Amount, ttl time.Duration) (*models.Quote, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.ttl = ttl
+	return &models.Quote{
+		ID:           "q1",
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		Amount:       amount,
+		Rate:         rate,
+		ToAmount:     amount.Mul(rate).Round(),
+		ExpiresAt:    testQuoteExpiry,
+	}, nil
+}
+
+func TestHandleExchangeQuote(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		token            string
+		requestBody      string
+		mockQuoteError   error
+		mockRatesError   error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "No Token",
+			token:            "",
+			requestBody:      `{"from_currency":"USD","to_currency":"EUR","amount":"100"}`,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"not authorized", "status":"error"}`,
+		},
+		{
+			name:             "Same Currency",
+			token:            validToken,
+			requestBody:      `{"from_currency":"USD","to_currency":"USD","amount":"100"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"cannot exchange currency to itself", "status":"error"}`,
+		},
+		{
+			name:             "Zero Amount",
+			token:            validToken,
+			requestBody:      `{"from_currency":"USD","to_currency":"EUR","amount":"0"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"amount must be positive", "status":"error"}`,
+		},
+		{
+			name:             "Rates Provider Error",
+			token:            validToken,
+			requestBody:      `{"from_currency":"USD","to_currency":"EUR","amount":"100"}`,
+			mockRatesError:   errors.New("exchanger unavailable"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"failed to retrieve exchange rates", "status":"error"}`,
+		},
+		{
+			name:             "No Rate For Pair",
+			token:            validToken,
+			requestBody:      `{"from_currency":"USD","to_currency":"GBP","amount":"100"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"no exchange rate from USD to GBP", "status":"error"}`,
+		},
+		{
+			name:             "CreateQuote Error",
+			token:            validToken,
+			requestBody:      `{"from_currency":"USD","to_currency":"EUR","amount":"100"}`,
+			mockQuoteError:   errors.New("database error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"database error", "status":"error"}`,
+		},
+		{
+			name:           "Valid Request - Success",
+			token:          validToken,
+			requestBody:    `{"from_currency":"USD","to_currency":"EUR","amount":"100"}`,
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"quote_id":"q1","from_currency":"USD","to_currency":"EUR","amount":"100",
+				"rate":"0.91666667","to_amount":"91.666667","path":["USD","RUB","EUR"],"expires_at":"2025-03-01T12:00:30Z"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body := bytes.NewBufferString(tc.requestBody)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/exchange/quote", body)
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Authorization", tc.token)
+
+			mockQuoteCreator := &MockQuoteCreator{err: tc.mockQuoteError}
+			mockRateProvider := &MockRateProvider{
+				rates: map[string]money.Amount{"RUB_USD": money.MustParse("0.012"), "RUB_EUR": money.MustParse("0.011")},
+				err:   tc.mockRatesError,
+			}
+
+			authenticated(HandleExchangeQuote(newTestLogger(), mockRateProvider, mockQuoteCreator))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, quoteTTL, mockQuoteCreator.ttl)
+			}
+		})
+	}
+}
@@ -3,63 +3,101 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
-	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
 	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
-	jwt "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/Foreground-Eclipse/transferer/internal/rates"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+type RatesGetter interface {
+	Snapshot(ctx context.Context) (rates.Snapshot, error)
+}
+
 // HandleRates godoc
 // @Summary Получение курсов валют
-// @Description  Получает текущие курсы валют.
+// @Description  Получает текущие курсы валют и время, когда они были получены.
+// @Description  Если источник курсов недоступен, возвращаются последние полученные курсы с признаком stale.
+// @Description  С параметром base дополнительно возвращает лучший курс базовой валюты ко всем остальным, в том числе через промежуточные валюты.
 // @Tags rates
 // @Accept  json
 // @Produce  json
+// @Param   base query string false "Базовая валюта" example(USD)
 // @Success 200 {object} requests.RatesResponse "OK"
+// @Failure 400 {object} requests.BadRequestError "Неизвестная базовая валюта"
 // @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
 // @Failure 500 {object} requests.RetrieveRatesError "Внутренняя ошибка сервера"
+// @Failure 503 {object} requests.RatesUnavailableError "Источник курсов недоступен"
 // @Security ApiKeyAuth
-// @Router /api/v1/rates [get]
-func HandleRates(logger *zap.Logger, client pb.ExchangeServiceClient) gin.HandlerFunc {
+// @Router /api/v1/exchange/rates [get]
+func HandleRates(logger *zap.Logger, ratesGetter RatesGetter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		const op = "api/v1/HandleRates"
 
 		logger.Info("proceeding new request", zap.String("op", op))
 
-		tokenString := c.GetHeader("Authorization")
-		if tokenString == "" {
-			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
-			return
-		}
-
 		logger.Info("request data: ",
 			zap.String("discordid: ", c.Request.Method),
 			zap.String("URL", c.Request.URL.String()),
-			zap.String("Token", tokenString),
 		)
 
-		_, err := jwt.ValidateToken(tokenString)
+		snapshot, err := ratesGetter.Snapshot(c.Request.Context())
 		if err != nil {
-			logError(c, logger, err, http.StatusUnauthorized, "")
+			logRatesError(c, logger, err)
 			return
 		}
 
-		ctx := context.Background()
-		request := &pb.Empty{}
-		response, err := client.GetExchangeRates(ctx, request)
-		if err != nil {
-			logError(c, logger, errors.New("failed to retrieve exchange rates"), http.StatusInternalServerError, "")
-			return
-		}
+		var resp requests.RatesResponse
+		resp.Rates = snapshot.Quotes
+		resp.UpdatedAt = snapshot.FetchedAt
+		resp.Stale = snapshot.Stale
+
+		if base := strings.ToUpper(c.Query("base")); base != "" {
+			graph, err := rates.NewGraphFromQuotes(snapshot.Quotes)
+			if err != nil {
+				logError(c, logger, err, http.StatusInternalServerError, "")
+				return
+			}
+			if _, err := graph.Rate(base, base); err != nil {
+				logError(c, logger, fmt.Errorf("unknown currency %s", base), http.StatusBadRequest, "")
+				return
+			}
 
-		var rates requests.RatesResponse
-		rates.Rates = response.Rates
+			resp.Base = base
+			resp.CrossRates = make(map[string]requests.CrossRate)
+			for _, currency := range graph.Currencies() {
+				if currency == base {
+					continue
+				}
+				crossRate, err := graph.Rate(base, currency)
+				if errors.Is(err, rates.ErrNoRate) {
+					continue
+				}
+				if err != nil {
+					logError(c, logger, err, http.StatusInternalServerError, "")
+					return
+				}
+				resp.CrossRates[currency] = requests.CrossRate{Rate: crossRate.Rate, Path: crossRate.Path}
+			}
+		}
 
-		c.JSON(http.StatusOK, rates)
+		c.JSON(http.StatusOK, resp)
 
 	}
 
 }
+
+// logRatesError responds to a failed rate fetch, with 503 if the rate source is unavailable
+// and the request may succeed later.
+func logRatesError(c *gin.Context, logger *zap.Logger, err error) {
+	logger.Warn("failed to retrieve exchange rates", zap.Error(err))
+	if errors.Is(err, rates.ErrUnavailable) {
+		c.Header("Retry-After", "5")
+		logError(c, logger, rates.ErrUnavailable, http.StatusServiceUnavailable, "")
+		return
+	}
+	logError(c, logger, errors.New("failed to retrieve exchange rates"), http.StatusInternalServerError, "")
+}
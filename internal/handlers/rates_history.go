@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/rates"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRateHistoryRange = 24 * time.Hour
+	minRateHistoryInterval  = time.Minute
+	// maxRateHistoryPoints bounds both raw points and candles in one response.
+	maxRateHistoryPoints = 1000
+)
+
+type RateHistoryGetter interface {
+	GetRateHistory(fromCurrency, toCurrency string, start, end time.Time, limit int) ([]models.RatePoint, error)
+	GetRateCandles(fromCurrency, toCurrency string, start, end time.Time, interval time.Duration) ([]models.RateCandle, error)
+}
+
+// HandleRatesHistory godoc
+// @Summary История курса валютной пары
+// @Description  Возвращает курсы пары, полученные от источника курсов за период, или свечи OHLC, если указан interval.
+// @Tags rates
+// @Accept  json
+// @Produce  json
+// @Param   pair query string true "Валютная пара" example(RUB_USD)
+// @Param   from query string false "Начало периода, RFC3339, по умолчанию сутки до конца периода" example(2025-03-01T00:00:00Z)
+// @Param   to query string false "Конец периода (не включительно), RFC3339, по умолчанию текущее время" example(2025-03-02T00:00:00Z)
+// @Param   interval query string false "Размер свечи, не меньше 1m" example(1h)
+// @Success 200 {object} requests.RateHistoryResponse "OK"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Security ApiKeyAuth
+// @Router /api/v1/exchange/rates/history [get]
+func HandleRatesHistory(logger *zap.Logger, historyGetter RateHistoryGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleRatesHistory"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		logger.Info("request data: ",
+			zap.String("discordid: ", c.Request.Method),
+			zap.String("URL", c.Request.URL.String()),
+		)
+
+		pair := strings.ToUpper(c.Query("pair"))
+		fromCurrency, toCurrency, err := rates.ParsePair(pair)
+		if err != nil {
+			logError(c, logger, errors.New("pair must look like RUB_USD"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		end := time.Now().UTC()
+		if value := c.Query("to"); value != "" {
+			if end, err = time.Parse(time.RFC3339, value); err != nil {
+				logError(c, logger, errors.New("to must be an RFC3339 timestamp"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+		}
+		start := end.Add(-defaultRateHistoryRange)
+		if value := c.Query("from"); value != "" {
+			if start, err = time.Parse(time.RFC3339, value); err != nil {
+				logError(c, logger, errors.New("from must be an RFC3339 timestamp"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+		}
+		if !start.Before(end) {
+			logError(c, logger, errors.New("from must be before to"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		response := requests.RateHistoryResponse{Pair: pair, From: start, To: end}
+
+		if value := c.Query("interval"); value != "" {
+			interval, err := time.ParseDuration(value)
+			if err != nil || interval < minRateHistoryInterval {
+				logError(c, logger, fmt.Errorf("interval must be a duration of at least %s", minRateHistoryInterval), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			if end.Sub(start)/interval > maxRateHistoryPoints {
+				logError(c, logger, fmt.Errorf("more than %d intervals requested, use a longer interval", maxRateHistoryPoints), http.StatusBadRequest, "failed to process request")
+				return
+			}
+
+			candles, err := historyGetter.GetRateCandles(fromCurrency, toCurrency, start, end, interval)
+			if err != nil {
+				logger.Error("failed to get rate candles", zap.String("op", op), zap.Error(err))
+				logError(c, logger, errors.New("failed to get rate history"), http.StatusInternalServerError, "")
+				return
+			}
+
+			response.Interval = interval.String()
+			response.Candles = make([]requests.RateCandle, 0, len(candles))
+			for _, candle := range candles {
+				response.Candles = append(response.Candles, requests.RateCandle{
+					Time:  candle.Start,
+					Open:  candle.Open,
+					High:  candle.High,
+					Low:   candle.Low,
+					Close: candle.Close,
+					Count: candle.Count,
+				})
+			}
+
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		// ask for one extra point to know whether the range is too wide
+		points, err := historyGetter.GetRateHistory(fromCurrency, toCurrency, start, end, maxRateHistoryPoints+1)
+		if err != nil {
+			logger.Error("failed to get rate history", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to get rate history"), http.StatusInternalServerError, "")
+			return
+		}
+		if len(points) > maxRateHistoryPoints {
+			logError(c, logger, fmt.Errorf("more than %d rates in range, narrow it or set interval", maxRateHistoryPoints), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		response.Points = make([]requests.RatePoint, 0, len(points))
+		for _, point := range points {
+			response.Points = append(response.Points, requests.RatePoint{
+				Time:   point.FetchedAt,
+				Rate:   point.Rate,
+				Source: point.Source,
+			})
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
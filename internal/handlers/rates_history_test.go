@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockRateHistoryGetter struct {
+	points   []models.RatePoint
+	candles  []models.RateCandle
+	err      error
+	from     string
+	to       string
+	start    time.Time
+	end      time.Time
+	interval time.Duration
+}
+
+func (m *MockRateHistoryGetter) GetRateHistory(fromCurrency, toCurrency string, start, end time.Time, limit int) ([]models.RatePoint, error) {
+	m.from, m.to, m.start, m.end = fromCurrency, toCurrency, start, end
+	if m.err != nil {
+		return nil, m.err
+	}
+	if len(m.points) > limit {
+		return m.points[:limit], nil
+	}
+	return m.points, nil
+}
+
+func (m *MockRateHistoryGetter) GetRateCandles(fromCurrency, toCurrency string, start, end time.Time, interval time.Duration) ([]models.RateCandle, error) {
+	m.from, m.to, m.start, m.end, m.interval = fromCurrency, toCurrency, start, end, interval
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.candles, nil
+}
+
+func TestHandleRatesHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	t0 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	points := []models.RatePoint{
+		{Rate: money.MustParse("0.012"), Source: "grpc", FetchedAt: t0},
+		{Rate: money.MustParse("0.0125"), Source: "grpc", FetchedAt: t0.Add(10 * time.Second)},
+	}
+	candles := []models.RateCandle{{
+		Start: t0, Open: money.MustParse("0.012"), High: money.MustParse("0.0125"),
+		Low: money.MustParse("0.0119"), Close: money.MustParse("0.0121"), Count: 360,
+	}}
+	tooManyPoints := make([]models.RatePoint, maxRateHistoryPoints+1)
+
+	testCases := []struct {
+		name             string
+		query            string
+		mockPoints       []models.RatePoint
+		mockCandles      []models.RateCandle
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+		expectedInterval time.Duration
+	}{
+		{
+			name:             "Missing Pair",
+			query:            "",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"pair must look like RUB_USD", "status":"error"}`,
+		},
+		{
+			name:             "Invalid From",
+			query:            "?pair=RUB_USD&from=yesterday",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"from must be an RFC3339 timestamp", "status":"error"}`,
+		},
+		{
+			name:             "From After To",
+			query:            "?pair=RUB_USD&from=2025-03-02T00:00:00Z&to=2025-03-01T00:00:00Z",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"from must be before to", "status":"error"}`,
+		},
+		{
+			name:             "Interval Too Short",
+			query:            "?pair=RUB_USD&from=2025-03-01T00:00:00Z&to=2025-03-02T00:00:00Z&interval=10s",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"interval must be a duration of at least 1m0s", "status":"error"}`,
+		},
+		{
+			name:             "Too Many Intervals",
+			query:            "?pair=RUB_USD&from=2025-01-01T00:00:00Z&to=2025-03-01T00:00:00Z&interval=1m",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"more than 1000 intervals requested, use a longer interval", "status":"error"}`,
+		},
+		{
+			name:             "Too Many Points",
+			query:            "?pair=RUB_USD&from=2025-03-01T00:00:00Z&to=2025-03-02T00:00:00Z",
+			mockPoints:       tooManyPoints,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"more than 1000 rates in range, narrow it or set interval", "status":"error"}`,
+		},
+		{
+			name:             "Storage Error",
+			query:            "?pair=RUB_USD&from=2025-03-01T00:00:00Z&to=2025-03-02T00:00:00Z",
+			mockError:        errors.New("pq: relation \"rate_history\" does not exist"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"failed to get rate history", "status":"error"}`,
+		},
+		{
+			name:             "Storage Error With Interval",
+			query:            "?pair=RUB_USD&from=2025-03-01T00:00:00Z&to=2025-03-02T00:00:00Z&interval=1h",
+			mockError:        errors.New("pq: relation \"rate_history\" does not exist"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"failed to get rate history", "status":"error"}`,
+		},
+		{
+			name:           "Points",
+			query:          "?pair=rub_usd&from=2025-03-01T00:00:00Z&to=2025-03-02T00:00:00Z",
+			mockPoints:     points,
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"pair":"RUB_USD","from":"2025-03-01T00:00:00Z","to":"2025-03-02T00:00:00Z","points":[
+				{"time":"2025-03-01T12:00:00Z","rate":"0.012","source":"grpc"},
+				{"time":"2025-03-01T12:00:10Z","rate":"0.0125","source":"grpc"}]}`,
+		},
+		{
+			name:           "Candles",
+			query:          "?pair=RUB_USD&from=2025-03-01T00:00:00Z&to=2025-03-02T00:00:00Z&interval=1h",
+			mockCandles:    candles,
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"pair":"RUB_USD","from":"2025-03-01T00:00:00Z","to":"2025-03-02T00:00:00Z","interval":"1h0m0s","candles":[
+				{"time":"2025-03-01T12:00:00Z","open":"0.012","high":"0.0125","low":"0.0119","close":"0.0121","count":360}]}`,
+			expectedInterval: time.Hour,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/exchange/rates/history"+tc.query, nil)
+			c.Request.Header.Set("Authorization", validToken)
+
+			mockHistoryGetter := &MockRateHistoryGetter{
+				points:  tc.mockPoints,
+				candles: tc.mockCandles,
+				err:     tc.mockError,
+			}
+
+			authenticated(HandleRatesHistory(newTestLogger(), mockHistoryGetter))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, "RUB", mockHistoryGetter.from)
+				assert.Equal(t, "USD", mockHistoryGetter.to)
+				assert.Equal(t, tc.expectedInterval, mockHistoryGetter.interval)
+			}
+		})
+	}
+}
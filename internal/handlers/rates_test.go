@@ -1,18 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/Foreground-Eclipse/transferer/internal/rates"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
-// MockExchangeServiceClient - Mock gRPC client
-
 func TestHandleRates(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -24,42 +24,78 @@ func TestHandleRates(t *testing.T) {
 	testCases := []struct {
 		name             string
 		token            string
-		mockGrpcRates    map[string]float32
-		mockGrpcError    error
+		query            string
+		mockRates        map[string]money.Amount
+		mockStale        bool
+		mockRatesError   error
 		expectedStatus   int
 		expectedResponse string
 	}{
 		{
 			name:             "No Token",
 			token:            "",
-			mockGrpcRates:    nil,
-			mockGrpcError:    nil,
-			expectedStatus:   http.StatusOK,
+			mockRates:        nil,
+			mockRatesError:   nil,
+			expectedStatus:   http.StatusUnauthorized,
 			expectedResponse: `{"error":"not authorized", "status":"error"}`,
 		},
 		{
 			name:             "Invalid Token",
 			token:            "invalid_token",
-			mockGrpcRates:    nil,
-			mockGrpcError:    nil,
-			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"error":"token contains an invalid number of segments", "status":"error"}`,
+			mockRates:        nil,
+			mockRatesError:   nil,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"invalid token", "status":"error"}`,
 		},
 		{
-			name:             "gRPC Error",
+			name:             "Rates Provider Error",
 			token:            validToken,
-			mockGrpcRates:    nil,
-			mockGrpcError:    status.Error(codes.Unavailable, "gRPC service unavailable"),
+			mockRates:        nil,
+			mockRatesError:   errors.New("exchanger unavailable"),
 			expectedStatus:   http.StatusInternalServerError,
-			expectedResponse: `{"error":"Failed to retrieve exchange rates", "status":"error"}`,
+			expectedResponse: `{"error":"failed to retrieve exchange rates", "status":"error"}`,
 		},
 		{
 			name:             "Valid Request - Success",
 			token:            validToken,
-			mockGrpcRates:    map[string]float32{"RUB_USD": 0.012, "RUB_EUR": 0.011},
-			mockGrpcError:    nil,
+			mockRates:        map[string]money.Amount{"RUB_USD": money.MustParse("0.012"), "RUB_EUR": money.MustParse("0.011")},
+			mockRatesError:   nil,
 			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"rates":{"RUB_EUR":0.011,"RUB_USD":0.012}}`,
+			expectedResponse: `{"rates":{"RUB_EUR":"0.011","RUB_USD":"0.012"},"updated_at":"2025-03-01T12:00:00Z"}`,
+		},
+		{
+			name:             "Rates Unavailable",
+			token:            validToken,
+			mockRatesError:   fmt.Errorf("rates: %w", rates.ErrUnavailable),
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedResponse: `{"error":"exchange rates are temporarily unavailable", "status":"error"}`,
+		},
+		{
+			name:             "Stale Rates",
+			token:            validToken,
+			mockRates:        map[string]money.Amount{"RUB_USD": money.MustParse("0.012")},
+			mockStale:        true,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"rates":{"RUB_USD":"0.012"},"updated_at":"2025-03-01T12:00:00Z","stale":true}`,
+		},
+		{
+			name:           "Cross Rates For Base",
+			token:          validToken,
+			query:          "?base=usd",
+			mockRates:      map[string]money.Amount{"RUB_USD": money.MustParse("0.012"), "RUB_EUR": money.MustParse("0.011"), "USD_GBP": money.MustParse("0.8")},
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"rates":{"RUB_EUR":"0.011","RUB_USD":"0.012","USD_GBP":"0.8"},"updated_at":"2025-03-01T12:00:00Z","base":"USD","cross_rates":{
+				"EUR":{"rate":"0.91666667","path":["USD","RUB","EUR"]},
+				"GBP":{"rate":"0.8","path":["USD","GBP"]},
+				"RUB":{"rate":"83.33333333","path":["USD","RUB"]}}}`,
+		},
+		{
+			name:             "Unknown Base",
+			token:            validToken,
+			query:            "?base=JPY",
+			mockRates:        map[string]money.Amount{"RUB_USD": money.MustParse("0.012"), "RUB_EUR": money.MustParse("0.011")},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"unknown currency JPY", "status":"error"}`,
 		},
 	}
 
@@ -68,17 +104,18 @@ func TestHandleRates(t *testing.T) {
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
 
-			c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/rates", nil)
+			c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/rates"+tc.query, nil)
 			c.Request.Header.Set("Authorization", tc.token)
 
 			logger := newTestLogger()
 
-			mockGrpcClient := &MockExchangeServiceClient{
-				rates: tc.mockGrpcRates,
-				err:   tc.mockGrpcError,
+			mockRateProvider := &MockRateProvider{
+				rates: tc.mockRates,
+				stale: tc.mockStale,
+				err:   tc.mockRatesError,
 			}
 
-			HandleRates(logger, mockGrpcClient)(c)
+			authenticated(HandleRates(logger, mockRateProvider))(c)
 
 			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
 			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
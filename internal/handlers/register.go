@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
@@ -36,7 +35,7 @@ func (e *UsernameAlreadyExists) Error() string {
 
 // HandleRegisterUser godoc
 // @Summary Регистрация нового пользователя
-// @Description Регистрирует нового пользователя в системе.
+// @Description Регистрирует нового пользователя в системе и отправляет на email письмо для его подтверждения.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -46,7 +45,7 @@ func (e *UsernameAlreadyExists) Error() string {
 // @Failure 409 {object} map[string]interface{} "Конфликт (имя пользователя или email уже существует)"
 // @Failure 500 {object} map[string]interface{} "Внутренняя ошибка сервера"
 // @Router /api/v1/register [post]
-func HandleRegisterUser(logger *zap.Logger, userRegisterer UserRegisterer) gin.HandlerFunc {
+func HandleRegisterUser(logger *zap.Logger, userRegisterer UserRegisterer, verificationSender VerificationSender) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req requests.RegisterRequest
 		const op = "api/v1/HandleRegisterUser"
@@ -60,19 +59,12 @@ func HandleRegisterUser(logger *zap.Logger, userRegisterer UserRegisterer) gin.H
 			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
 			return
 		}
-		reqBody, err := json.Marshal(req)
-		if err != nil {
-			logError(c, logger, err, http.StatusBadRequest, "failed to marshal request body")
-			return
-		}
-
 		logger.Info("request data: ",
 			zap.String("discordid: ", c.Request.Method),
 			zap.String("URL", c.Request.URL.String()),
-			zap.String("body", string(reqBody)),
 		)
 
-		err = verifyCredentials(req.Email, req.Username, userRegisterer)
+		err := verifyCredentials(req.Email, req.Username, userRegisterer)
 
 		var emailErr *EmailAlreadyExistsError
 		var usernameErr *UsernameAlreadyExists
@@ -93,6 +85,12 @@ func HandleRegisterUser(logger *zap.Logger, userRegisterer UserRegisterer) gin.H
 			return
 		}
 
+		if err != nil {
+			logger.Error("failed to check credentials", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to check credentials"), http.StatusInternalServerError, "")
+			return
+		}
+
 		passHash := middleware.HashPassword(req.Password)
 
 		err = userRegisterer.RegisterUser(req.Username, passHash, req.Email)
@@ -100,6 +98,8 @@ func HandleRegisterUser(logger *zap.Logger, userRegisterer UserRegisterer) gin.H
 			logError(c, logger, err, http.StatusInternalServerError, "failed to add the user")
 			return
 		}
+		sendVerification(c.Request.Context(), logger, verificationSender, op, req.Username)
+
 		c.JSON(http.StatusOK, map[string]interface{}{
 			"message": "user registered",
 		})
@@ -44,6 +44,7 @@ func TestHandleRegisterUser(t *testing.T) {
 		mockDoesEmailExistsErr error
 		expectedStatus         int
 		expectedResponse       string
+		expectedVerifications  []string
 	}{
 		{
 			name:                   "Invalid JSON - Wrong Type",
@@ -88,6 +89,7 @@ func TestHandleRegisterUser(t *testing.T) {
 			mockDoesEmailExistsErr: nil,
 			expectedStatus:         http.StatusOK,
 			expectedResponse:       `{"message":"user registered"}`,
+			expectedVerifications:  []string{"testuser"},
 		},
 		{
 			name:                   "Registration Error",
@@ -108,8 +110,8 @@ func TestHandleRegisterUser(t *testing.T) {
 			mockRegisterErr:        nil,
 			mockDoesUserExistsErr:  errors.New("user check error"),
 			mockDoesEmailExistsErr: nil,
-			expectedStatus:         http.StatusOK,
-			expectedResponse:       `{"error":"user check error", "status":"error"}`,
+			expectedStatus:         http.StatusInternalServerError,
+			expectedResponse:       `{"error":"failed to check credentials", "status":"error"}`,
 		},
 		{
 			name:                   "DoesEmailExists Error",
@@ -119,8 +121,8 @@ func TestHandleRegisterUser(t *testing.T) {
 			mockRegisterErr:        nil,
 			mockDoesUserExistsErr:  nil,
 			mockDoesEmailExistsErr: errors.New("email check error"),
-			expectedStatus:         http.StatusOK,
-			expectedResponse:       `{"error":"email check error", "status":"error"}`,
+			expectedStatus:         http.StatusInternalServerError,
+			expectedResponse:       `{"error":"failed to check credentials", "status":"error"}`,
 		},
 	}
 
@@ -143,10 +145,12 @@ func TestHandleRegisterUser(t *testing.T) {
 				doesEmailExistsErr: tc.mockDoesEmailExistsErr,
 			}
 
-			HandleRegisterUser(logger, mockUserRegisterer)(c)
+			verifications := &MockVerification{}
+			HandleRegisterUser(logger, mockUserRegisterer, verifications)(c)
 
 			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
 			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			assert.Equal(t, tc.expectedVerifications, verifications.sent, "Verification emails mismatch")
 		})
 	}
 }
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/session"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SessionRefresher interface {
+	RefreshSession(ctx context.Context, refreshToken string) (session.Tokens, error)
+}
+
+type SessionEnder interface {
+	EndSession(ctx context.Context, sessionID, tokenID string, expiresAt time.Time) error
+}
+
+// HandleRefreshToken godoc
+// @Summary Обновление токенов
+// @Description Меняет refresh токен на новую пару access и refresh токенов. Каждый refresh токен используется один раз,
+// @Description повторное использование refresh токена отзывает все токены его сессии.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body requests.RefreshTokenRequest true "Refresh токен"
+// @Success 200 {object} requests.TokenResponse "Токены обновлены"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.InvalidRefreshTokenError "Refresh токен недействителен"
+// @Failure 500 {object} requests.CantCreateJWTError "Внутренняя ошибка сервера"
+// @Router /api/v1/token/refresh [post]
+func HandleRefreshToken(logger *zap.Logger, sessionRefresher SessionRefresher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.RefreshTokenRequest
+		const op = "api/v1/HandleRefreshToken"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		tokens, err := sessionRefresher.RefreshSession(c.Request.Context(), req.RefreshToken)
+		if errors.Is(err, storage.ErrRefreshTokenReused) {
+			logger.Warn("refresh token reused, session revoked", zap.String("op", op), zap.String("ip", c.ClientIP()))
+		}
+		if errors.Is(err, session.ErrInvalidRefreshToken) {
+			logError(c, logger, session.ErrInvalidRefreshToken, http.StatusUnauthorized, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to refresh session", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
+			return
+		}
+
+		c.JSON(http.StatusOK, tokenResponse(tokens))
+	}
+}
+
+// HandleLogout godoc
+// @Summary Выход
+// @Description Отзывает access токен и все refresh токены его сессии.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} requests.LogoutResponse "Выход выполнен"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/logout [post]
+func HandleLogout(logger *zap.Logger, sessionEnder SessionEnder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleLogout"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		err := sessionEnder.EndSession(c.Request.Context(), principal.SessionID, principal.TokenID, principal.ExpiresAt)
+		if err != nil {
+			logError(c, logger, err, http.StatusInternalServerError, "failed to log out")
+			return
+		}
+
+		c.JSON(http.StatusOK, requests.LogoutResponse{Message: "logged out"})
+	}
+}
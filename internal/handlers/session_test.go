@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/session"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTokensTime = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+type MockSessionRefresher struct {
+	err error
+}
+
+func (m *MockSessionRefresher) RefreshSession(ctx context.Context, refreshToken string) (session.Tokens, error) {
+	if m.err != nil {
+		return session.Tokens{}, m.err
+	}
+	return session.Tokens{
+		AccessToken:           "access",
+		AccessTokenExpiresAt:  testTokensTime.Add(15 * time.Minute),
+		RefreshToken:          "next-" + refreshToken,
+		RefreshTokenExpiresAt: testTokensTime.Add(720 * time.Hour),
+	}, nil
+}
+
+type MockSessionEnder struct {
+	err                error
+	sessionID, tokenID string
+}
+
+func (m *MockSessionEnder) EndSession(ctx context.Context, sessionID, tokenID string, expiresAt time.Time) error {
+	m.sessionID, m.tokenID = sessionID, tokenID
+	return m.err
+}
+
+func TestHandleRefreshToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:           "Success",
+			requestBody:    `{"refresh_token":"first"}`,
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"token":"access","expires_at":"2025-03-01T12:15:00Z",
+				"refresh_token":"next-first","refresh_token_expires_at":"2025-03-31T12:00:00Z"}`,
+		},
+		{
+			name:             "Missing_Token",
+			requestBody:      `{}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"request contains wrong data"}`,
+		},
+		{
+			name:             "Invalid_Token",
+			requestBody:      `{"refresh_token":"unknown"}`,
+			mockError:        fmt.Errorf("session.Manager.RefreshSession: %w", session.ErrInvalidRefreshToken),
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"invalid refresh token"}`,
+		},
+		{
+			name:             "Reused_Token",
+			requestBody:      `{"refresh_token":"first"}`,
+			mockError:        fmt.Errorf("session.Manager.RefreshSession: %w: %w", session.ErrInvalidRefreshToken, storage.ErrRefreshTokenReused),
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"invalid refresh token"}`,
+		},
+		{
+			name:             "Storage_Error",
+			requestBody:      `{"refresh_token":"first"}`,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"could not create JWT token"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/token/refresh", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			HandleRefreshToken(newTestLogger(), &MockSessionRefresher{err: tc.mockError})(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+		})
+	}
+}
+
+func TestHandleLogout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	accessToken, err := testKeySet.IssueAccessToken(auth.Subject{UserID: 1, Username: "testuser"}, "session-1")
+	if err != nil {
+		t.Fatalf("Failed to issue access token: %v", err)
+	}
+	validToken := "Bearer " + accessToken.Token
+
+	testCases := []struct {
+		name             string
+		token            string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Success",
+			token:            validToken,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"logged out"}`,
+		},
+		{
+			name:             "No_Token",
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"not authorized"}`,
+		},
+		{
+			name:             "Invalid_Token",
+			token:            "invalid",
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"invalid token"}`,
+		},
+		{
+			name:             "Storage_Error",
+			token:            validToken,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"db is down"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/logout", nil)
+			if tc.token != "" {
+				c.Request.Header.Set("Authorization", tc.token)
+			}
+
+			mockSessionEnder := &MockSessionEnder{err: tc.mockError}
+			authenticated(HandleLogout(newTestLogger(), mockSessionEnder))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, "session-1", mockSessionEnder.sessionID)
+				assert.Equal(t, accessToken.ID, mockSessionEnder.tokenID)
+			}
+		})
+	}
+}
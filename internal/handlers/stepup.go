@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// StepUpPolicy decides when a sensitive operation needs a fresh authentication.
+// A withdrawal needs one when it and the other withdrawals of the last MaxAge add up to more
+// than the threshold of its currency, currencies without a threshold never do.
+// A zero MaxAge turns step-up off.
+type StepUpPolicy struct {
+	MaxAge             time.Duration
+	WithdrawThresholds map[string]money.Amount
+}
+
+// satisfiedBy reports whether principal authenticated recently enough for any operation.
+func (p StepUpPolicy) satisfiedBy(principal middleware.Principal) bool {
+	return p.MaxAge <= 0 || principal.AuthenticatedWithin(p.MaxAge)
+}
+
+// withdrawalLimit returns how much of currency principal may withdraw within MaxAge
+// without a fresh authentication, ok is false if there is no limit.
+func (p StepUpPolicy) withdrawalLimit(principal middleware.Principal, currency string) (limit money.Amount, ok bool) {
+	if p.satisfiedBy(principal) {
+		return money.Zero, false
+	}
+	limit, ok = p.WithdrawThresholds[currency]
+	return limit, ok
+}
+
+// reject asks the client to authenticate again before repeating the request.
+func (p StepUpPolicy) reject(c *gin.Context, logger *zap.Logger, principal middleware.Principal, reason string) {
+	logger.Info("fresh authentication required",
+		zap.String("username", principal.Username),
+		zap.String("reason", reason),
+	)
+	middleware.AbortWithStepUp(c, p.MaxAge)
+}
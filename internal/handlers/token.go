@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/session"
+)
+
+func tokenResponse(tokens session.Tokens) requests.TokenResponse {
+	return requests.TokenResponse{
+		Token:                 tokens.AccessToken,
+		ExpiresAt:             tokens.AccessTokenExpiresAt,
+		RefreshToken:          tokens.RefreshToken,
+		RefreshTokenExpiresAt: tokens.RefreshTokenExpiresAt,
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTransactionsLimit = 20
+	maxTransactionsLimit     = 100
+)
+
+type TransactionsGetter interface {
+	GetTransactions(username string, filter models.TransactionFilter) ([]models.Transaction, error)
+}
+
+// HandleTransactions godoc
+// @Summary История операций пользователя
+// @Description  Возвращает пополнения, снятия, обмены, переводы и корректировки баланса пользователя, начиная с самых новых.
+// @Tags transactions
+// @Accept  json
+// @Produce  json
+// @Param   type query string false "Тип операции" Enums(deposit, withdrawal, exchange, transfer_in, transfer_out, adjustment_in, adjustment_out)
+// @Param   currency query string false "Валюта операции" example(USD)
+// @Param   from query string false "Начало периода, RFC3339" example(2025-03-01T00:00:00Z)
+// @Param   to query string false "Конец периода (не включительно), RFC3339" example(2025-04-01T00:00:00Z)
+// @Param   min_amount query string false "Минимальная сумма" example(10)
+// @Param   max_amount query string false "Максимальная сумма" example(1000)
+// @Param   limit query int false "Размер страницы, не больше 100" default(20)
+// @Param   cursor query string false "next_cursor из предыдущего ответа"
+// @Success 200 {object} requests.TransactionsResponse "OK"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Security ApiKeyAuth
+// @Router /api/v1/transactions [get]
+func HandleTransactions(logger *zap.Logger, transactionsGetter TransactionsGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleTransactions"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		logger.Info("request data: ",
+			zap.String("discordid: ", c.Request.Method),
+			zap.String("URL", c.Request.URL.String()),
+			zap.String("username", principal.Username),
+		)
+
+		writeTransactions(c, logger, transactionsGetter, principal.Username)
+	}
+}
+
+// writeTransactions responds with the page of the history of username the query asks for.
+func writeTransactions(c *gin.Context, logger *zap.Logger, transactionsGetter TransactionsGetter, username string) {
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		logError(c, logger, err, http.StatusBadRequest, "failed to process request")
+		return
+	}
+
+	// ask for one extra row to know whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+
+	transactions, err := transactionsGetter.GetTransactions(username, filter)
+	if err != nil {
+		logError(c, logger, err, http.StatusInternalServerError, "")
+		return
+	}
+
+	response := requests.TransactionsResponse{
+		Transactions: make([]requests.Transaction, 0, len(transactions)),
+	}
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		last := transactions[limit-1]
+		response.NextCursor = encodeTransactionCursor(models.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	for _, t := range transactions {
+		item := requests.Transaction{
+			ID:           t.ID,
+			Type:         string(t.Type),
+			Currency:     t.Currency,
+			Amount:       t.Amount,
+			ToCurrency:   t.ToCurrency,
+			Counterparty: t.Counterparty,
+			CreatedAt:    t.CreatedAt,
+		}
+		if t.ToCurrency != "" {
+			toAmount, rate := t.ToAmount, t.Rate
+			item.ToAmount, item.Rate = &toAmount, &rate
+		}
+		response.Transactions = append(response.Transactions, item)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func parseTransactionFilter(c *gin.Context) (models.TransactionFilter, error) {
+	filter := models.TransactionFilter{Limit: defaultTransactionsLimit}
+
+	switch kind := models.EntryKind(c.Query("type")); kind {
+	case "", models.EntryDeposit, models.EntryWithdrawal, models.EntryExchange, models.TransferIn, models.TransferOut,
+		models.AdjustmentIn, models.AdjustmentOut:
+		filter.Type = kind
+	default:
+		return filter, fmt.Errorf("unknown transaction type %s", kind)
+	}
+
+	filter.Currency = strings.ToUpper(c.Query("currency"))
+
+	for param, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC3339 timestamp", param)
+			}
+			*dst = t
+		}
+	}
+
+	for param, dst := range map[string]**money.Amount{"min_amount": &filter.MinAmount, "max_amount": &filter.MaxAmount} {
+		if value := c.Query(param); value != "" {
+			amount, err := money.Parse(value)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be a decimal number", param)
+			}
+			*dst = &amount
+		}
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxTransactionsLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxTransactionsLimit)
+		}
+		filter.Limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := decodeTransactionCursor(value)
+		if err != nil {
+			return filter, errors.New("invalid cursor")
+		}
+		filter.After = &cursor
+	}
+
+	return filter, nil
+}
+
+func encodeTransactionCursor(cursor models.TransactionCursor) string {
+	raw := fmt.Sprintf("%d:%d", cursor.CreatedAt.UnixNano(), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTransactionCursor(value string) (models.TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return models.TransactionCursor{}, err
+	}
+
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return models.TransactionCursor{}, errors.New("malformed cursor")
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return models.TransactionCursor{}, err
+	}
+	transactionID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return models.TransactionCursor{}, err
+	}
+
+	return models.TransactionCursor{CreatedAt: time.Unix(0, unixNano).UTC(), ID: transactionID}, nil
+}
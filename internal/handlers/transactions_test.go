@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockTransactionsGetter struct {
+	transactions []models.Transaction
+	err          error
+	filter       models.TransactionFilter
+}
+
+func (m *MockTransactionsGetter) GetTransactions(username string, filter models.TransactionFilter) ([]models.Transaction, error) {
+	m.filter = filter
+	if m.err != nil {
+		return nil, m.err
+	}
+	if len(m.transactions) > filter.Limit {
+		return m.transactions[:filter.Limit], nil
+	}
+	return m.transactions, nil
+}
+
+func TestHandleTransactions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	createdAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	transactions := []models.Transaction{
+		{ID: 3, Type: models.EntryExchange, Currency: "USD", Amount: money.MustParse("20"), ToCurrency: "EUR", ToAmount: money.MustParse("17"), Rate: money.MustParse("0.85"), CreatedAt: createdAt},
+		{ID: 2, Type: models.EntryWithdrawal, Currency: "USD", Amount: money.MustParse("5"), CreatedAt: createdAt},
+		{ID: 1, Type: models.EntryDeposit, Currency: "USD", Amount: money.MustParse("100"), CreatedAt: createdAt.Add(-time.Hour)},
+	}
+	nextCursor := encodeTransactionCursor(models.TransactionCursor{CreatedAt: createdAt, ID: 2})
+
+	testCases := []struct {
+		name             string
+		token            string
+		query            string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+		expectedFilter   *models.TransactionFilter
+	}{
+		{
+			name:             "Invalid Token",
+			token:            "invalid_token",
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"invalid token", "status":"error"}`,
+		},
+		{
+			name:           "First Page",
+			token:          validToken,
+			query:          "?limit=2",
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"transactions":[
+				{"id":3,"type":"exchange","currency":"USD","amount":"20","to_currency":"EUR","to_amount":"17","rate":"0.85","created_at":"2025-03-01T12:00:00Z"},
+				{"id":2,"type":"withdrawal","currency":"USD","amount":"5","created_at":"2025-03-01T12:00:00Z"}
+			],"next_cursor":"` + nextCursor + `"}`,
+		},
+		{
+			name:             "Filters Are Passed To Storage",
+			token:            validToken,
+			query:            "?type=deposit&currency=usd&from=2025-03-01T00:00:00Z&min_amount=10&cursor=" + nextCursor,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "",
+			expectedFilter: &models.TransactionFilter{
+				Type:      models.EntryDeposit,
+				Currency:  "USD",
+				From:      time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+				MinAmount: func() *money.Amount { a := money.MustParse("10"); return &a }(),
+				After:     &models.TransactionCursor{CreatedAt: createdAt, ID: 2},
+				Limit:     defaultTransactionsLimit + 1,
+			},
+		},
+		{
+			name:             "Unknown Type",
+			token:            validToken,
+			query:            "?type=refund",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"unknown transaction type refund", "status":"error"}`,
+		},
+		{
+			name:             "Invalid Date",
+			token:            validToken,
+			query:            "?from=yesterday",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"from must be an RFC3339 timestamp", "status":"error"}`,
+		},
+		{
+			name:             "Invalid Cursor",
+			token:            validToken,
+			query:            "?cursor=%21%21",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"invalid cursor", "status":"error"}`,
+		},
+		{
+			name:             "Storage Error",
+			token:            validToken,
+			mockError:        errors.New("database error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"database error", "status":"error"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/transactions"+tc.query, nil)
+			c.Request.Header.Set("Authorization", tc.token)
+
+			mockGetter := &MockTransactionsGetter{transactions: transactions, err: tc.mockError}
+
+			authenticated(HandleTransactions(newTestLogger(), mockGetter))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			if tc.expectedResponse != "" {
+				assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			}
+			if tc.expectedFilter != nil {
+				assert.Equal(t, *tc.expectedFilter, mockGetter.filter, "Filter mismatch")
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/rates"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Transferer interface {
+	FindRecipient(usernameOrEmail string) (string, []string, error)
+	Transfer(ctx context.Context, sender, recipient, currency, toCurrency string, amount, rate money.Amount) (*models.Transfer, error)
+	GetUserBalance(username string) (map[string]money.Amount, error)
+	HasTransferredTo(sender, recipient string) (bool, error)
+}
+
+// HandleTransfer godoc
+// @Summary Перевод другому пользователю
+// @Description  Переводит сумму в указанной валюте другому пользователю по имени или email.
+// @Description  Если у получателя нет кошелька в этой валюте, при auto_convert сумма конвертируется в валюту его основного кошелька.
+// @Description  Первый перевод новому получателю требует повторной аутентификации через /api/v1/reauth.
+// @Description  Недоступно до подтверждения email.
+// @Tags transfers
+// @Accept  json
+// @Produce  json
+// @Param   request body requests.TransferRequest true "Данные для перевода"
+// @Param   Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с тем же ключом вернет первый ответ"
+// @Success 200 {object} requests.TransferResponse "OK"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос, нет кошелька в валюте перевода, нет курса для пары валют, получатель не найден или не может принять перевод"
+// @Failure 401 {object} requests.StepUpRequiredError "Не авторизован или требуется повторная аутентификация"
+// @Failure 403 {object} requests.NotEnoughFundsError "Недостаточно средств или email не подтвержден"
+// @Failure 409 {object} requests.IdempotencyConflictError "Ключ идемпотентности уже использован"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Failure 503 {object} requests.RatesUnavailableError "Источник курсов недоступен"
+// @Security ApiKeyAuth
+// @Router /api/v1/transfers [post]
+func HandleTransfer(logger *zap.Logger, rateProvider rates.Provider, transferer Transferer, idempotencyStore IdempotencyStore, stepUp StepUpPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleTransfer"
+		var req requests.TransferRequest
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		if !req.Amount.IsPositive() {
+			logError(c, logger, errors.New("amount must be positive"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+		if !checkAmountScale(c, logger, req.Amount) {
+			return
+		}
+
+		reqBody, err := json.Marshal(req)
+		if err != nil {
+			logError(c, logger, err, http.StatusBadRequest, "failed to marshal request body")
+			return
+		}
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		logger.Info("request data: ",
+			zap.String("discordid: ", c.Request.Method),
+			zap.String("URL", c.Request.URL.String()),
+			zap.String("username", principal.Username),
+			zap.String("body", string(reqBody)),
+		)
+
+		username := principal.Username
+
+		finish, handled := startIdempotentRequest(c, logger, idempotencyStore, op, username, reqBody)
+		if handled {
+			return
+		}
+		defer finish()
+
+		// An unknown recipient is answered like one that cannot take the transfer, and only
+		// after the step-up check, so the endpoint cannot tell which emails are registered.
+		recipient, currencies, err := transferer.FindRecipient(req.Recipient)
+		found := err == nil
+		if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+			logger.Error("failed to find recipient", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to transfer"), http.StatusInternalServerError, "")
+			return
+		}
+
+		if found && recipient == username {
+			logError(c, logger, errors.New("cannot transfer to yourself"), http.StatusBadRequest, "")
+			return
+		}
+
+		if !stepUp.satisfiedBy(principal) {
+			known := false
+			if found {
+				known, err = transferer.HasTransferredTo(username, recipient)
+				if err != nil {
+					logger.Error("failed to check earlier transfers", zap.String("op", op), zap.Error(err))
+					logError(c, logger, errors.New("failed to transfer"), http.StatusInternalServerError, "")
+					return
+				}
+			}
+			if !known {
+				stepUp.reject(c, logger, principal, "transfer to a new recipient")
+				return
+			}
+		}
+
+		toCurrency, rate := req.Currency, money.Zero
+		if !slices.Contains(currencies, req.Currency) {
+			if !found || !req.AutoConvert || len(currencies) == 0 {
+				logError(c, logger, fmt.Errorf("cannot transfer %s to this recipient", req.Currency), http.StatusBadRequest, "")
+				return
+			}
+
+			// the first wallet is the one the recipient got on registration
+			toCurrency = currencies[0]
+
+			quotes, err := rateProvider.GetRates(c.Request.Context())
+			if err != nil {
+				logRatesError(c, logger, err)
+				return
+			}
+
+			crossRate, err := exchangeRate(req.Currency, toCurrency, quotes)
+			if err != nil {
+				logExchangeRateError(c, logger, err)
+				return
+			}
+			rate = crossRate.Rate
+		}
+
+		transfer, err := transferer.Transfer(c.Request.Context(), username, recipient, req.Currency, toCurrency, req.Amount, rate)
+		if errors.Is(err, storage.ErrNotEnoughFunds) {
+			logError(c, logger, storage.ErrNotEnoughFunds, http.StatusForbidden, "")
+			return
+		}
+		if errors.Is(err, storage.ErrWalletNotFound) {
+			logError(c, logger, fmt.Errorf("no %s wallet to transfer from", req.Currency), http.StatusBadRequest, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to transfer", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to transfer"), http.StatusInternalServerError, "")
+			return
+		}
+
+		balance, err := transferer.GetUserBalance(username)
+		if err != nil {
+			logger.Error("failed to get balance", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to get balance"), http.StatusInternalServerError, "")
+			return
+		}
+
+		response := requests.TransferResponse{
+			Message:          "transferred successfully",
+			TransferID:       transfer.EntryID,
+			Recipient:        transfer.Recipient,
+			Amount:           transfer.Amount,
+			Currency:         transfer.Currency,
+			CreditedAmount:   transfer.CreditedAmount,
+			CreditedCurrency: transfer.CreditedCurrency,
+			Balance:          balance,
+		}
+		if transfer.CreditedCurrency != transfer.Currency {
+			response.Rate = &transfer.Rate
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
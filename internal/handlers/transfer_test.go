@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockTransferer struct {
+	users   map[string][]string
+	known   map[string]bool
+	balance map[string]money.Amount
+	err     error
+}
+
+func (m *MockTransferer) FindRecipient(usernameOrEmail string) (string, []string, error) {
+	currencies, ok := m.users[usernameOrEmail]
+	if !ok {
+		return "", nil, fmt.Errorf("find: %w", storage.ErrUserNotFound)
+	}
+	return usernameOrEmail, currencies, nil
+}
+
+func (m *MockTransferer) Transfer(ctx context.Context, sender, recipient, currency, toCurrency string, amount, rate money.Amount) (*models.Transfer, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.balance[currency].LessThan(amount) {
+		return nil, fmt.Errorf("transfer: %w", storage.ErrNotEnoughFunds)
+	}
+	m.balance[currency] = m.balance[currency].Sub(amount)
+
+	transfer := &models.Transfer{
+		EntryID:          7,
+		Recipient:        recipient,
+		Currency:         currency,
+		Amount:           amount,
+		CreditedCurrency: toCurrency,
+		CreditedAmount:   amount,
+	}
+	if toCurrency != currency {
+		transfer.Rate = rate
+		transfer.CreditedAmount = amount.Mul(rate).Round()
+	}
+	return transfer, nil
+}
+
+func (m *MockTransferer) GetUserBalance(username string) (map[string]money.Amount, error) {
+	return m.balance, nil
+}
+
+func (m *MockTransferer) HasTransferredTo(sender, recipient string) (bool, error) {
+	return m.known[recipient], nil
+}
+
+func TestHandleTransfer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	freshToken, err := generateFreshJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate fresh JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		token            string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Invalid JSON - Wrong Type",
+			requestBody:      `{"recipient":"jane","currency":"USD","amount":"string"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"request contains wrong data", "status":"error"}`,
+		},
+		{
+			name:             "Negative Amount",
+			requestBody:      `{"recipient":"jane","currency":"USD","amount":"-5"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"amount must be positive", "status":"error"}`,
+		},
+		{
+			name:             "Too Many Decimal Places",
+			requestBody:      `{"recipient":"jane","currency":"USD","amount":"5.123456789"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"amount must have at most 8 decimal places", "status":"error"}`,
+		},
+		{
+			name:             "Unknown Recipient",
+			requestBody:      `{"recipient":"nobody","currency":"USD","amount":"5"}`,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"fresh authentication required", "status":"error"}`,
+		},
+		{
+			name:             "Unknown Recipient With Fresh Authentication",
+			token:            freshToken,
+			requestBody:      `{"recipient":"nobody","currency":"USD","amount":"5","auto_convert":true}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"cannot transfer USD to this recipient", "status":"error"}`,
+		},
+		{
+			name:             "Transfer To Yourself",
+			requestBody:      `{"recipient":"testuser","currency":"USD","amount":"5"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"cannot transfer to yourself", "status":"error"}`,
+		},
+		{
+			name:           "Same Currency",
+			requestBody:    `{"recipient":"jane","currency":"USD","amount":"25"}`,
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"message":"transferred successfully","transfer_id":7,"recipient":"jane","amount":"25","currency":"USD",
+				"credited_amount":"25","credited_currency":"USD","balance":{"USD":"75"}}`,
+		},
+		{
+			name:             "No Wallet Without Auto Convert",
+			requestBody:      `{"recipient":"ivan","currency":"USD","amount":"25"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"cannot transfer USD to this recipient", "status":"error"}`,
+		},
+		{
+			name:           "No Wallet With Auto Convert",
+			requestBody:    `{"recipient":"ivan","currency":"USD","amount":"24","auto_convert":true}`,
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"message":"transferred successfully","transfer_id":7,"recipient":"ivan","amount":"24","currency":"USD",
+				"credited_amount":"1999.99999992","credited_currency":"RUB","rate":"83.33333333","balance":{"USD":"76"}}`,
+		},
+		{
+			name:             "Not Enough Money",
+			requestBody:      `{"recipient":"jane","currency":"USD","amount":"500"}`,
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"error":"not enough money", "status":"error"}`,
+		},
+		{
+			name:             "Transfer Error",
+			requestBody:      `{"recipient":"jane","currency":"USD","amount":"5"}`,
+			mockError:        errors.New("database error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"error":"failed to transfer", "status":"error"}`,
+		},
+		{
+			name:             "No Sender Wallet",
+			requestBody:      `{"recipient":"jane","currency":"EUR","amount":"5"}`,
+			mockError:        fmt.Errorf("transfer: No wallet found for user testuser and currency EUR: %w", storage.ErrWalletNotFound),
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"error":"no EUR wallet to transfer from", "status":"error"}`,
+		},
+		{
+			name:             "New Recipient Without Fresh Authentication",
+			requestBody:      `{"recipient":"anna","currency":"USD","amount":"5"}`,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"fresh authentication required", "status":"error"}`,
+		},
+		{
+			name:           "New Recipient With Fresh Authentication",
+			token:          freshToken,
+			requestBody:    `{"recipient":"anna","currency":"USD","amount":"5"}`,
+			expectedStatus: http.StatusOK,
+			expectedResponse: `{"message":"transferred successfully","transfer_id":7,"recipient":"anna","amount":"5","currency":"USD",
+				"credited_amount":"5","credited_currency":"USD","balance":{"USD":"95"}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body := bytes.NewBufferString(tc.requestBody)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/transfers", body)
+			c.Request.Header.Set("Content-Type", "application/json")
+			token := tc.token
+			if token == "" {
+				token = validToken
+			}
+			c.Request.Header.Set("Authorization", token)
+
+			mockTransferer := &MockTransferer{
+				users: map[string][]string{
+					"testuser": {"USD", "EUR", "RUB"},
+					"jane":     {"USD", "EUR", "RUB"},
+					"ivan":     {"RUB"},
+					"anna":     {"USD"},
+				},
+				known:   map[string]bool{"jane": true, "ivan": true},
+				balance: map[string]money.Amount{"USD": money.MustParse("100")},
+				err:     tc.mockError,
+			}
+			mockRateProvider := &MockRateProvider{
+				rates: map[string]money.Amount{"RUB_USD": money.MustParse("0.012"), "RUB_EUR": money.MustParse("0.011"), "RUB_RUB": money.MustParse("1")},
+			}
+
+			authenticated(HandleTransfer(newTestLogger(), mockRateProvider, mockTransferer, nil, StepUpPolicy{MaxAge: 5 * time.Minute}))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+		})
+	}
+}
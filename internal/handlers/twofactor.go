@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/twofactor"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type TwoFactorEnroller interface {
+	Setup(username string) (twofactor.Enrollment, error)
+	Confirm(ctx context.Context, username, code string) ([]string, error)
+}
+
+type ChallengeCompleter interface {
+	ChallengeUsername(token string) (string, error)
+	CompleteChallenge(token, code string) (string, error)
+}
+
+// HandleTwoFactorSetup godoc
+// @Summary Подключение двухфакторной аутентификации
+// @Description Создает секрет TOTP (RFC 6238) и возвращает otpauth URI и QR код для приложения-аутентификатора.
+// @Description С заголовком Accept: image/png возвращает только PNG изображение QR кода.
+// @Description Двухфакторная аутентификация включается после подтверждения кодом.
+// @Tags 2fa
+// @Produce json
+// @Produce png
+// @Success 200 {object} requests.TwoFactorSetupResponse "Секрет создан"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 409 {object} requests.TwoFactorConflictError "Двухфакторная аутентификация уже включена"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/2fa/setup [post]
+func HandleTwoFactorSetup(logger *zap.Logger, enroller TwoFactorEnroller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleTwoFactorSetup"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		enrollment, err := enroller.Setup(principal.Username)
+		if errors.Is(err, twofactor.ErrAlreadyEnabled) {
+			logError(c, logger, twofactor.ErrAlreadyEnabled, http.StatusConflict, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to set up two-factor authentication", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to set up two-factor authentication"), http.StatusInternalServerError, "")
+			return
+		}
+
+		if c.NegotiateFormat(gin.MIMEJSON, "image/png") == "image/png" {
+			c.Data(http.StatusOK, "image/png", enrollment.QRCode)
+			return
+		}
+		c.JSON(http.StatusOK, requests.TwoFactorSetupResponse{
+			OTPAuthURI: enrollment.URI,
+			Secret:     enrollment.Secret,
+			QRCodePNG:  enrollment.QRCode,
+		})
+	}
+}
+
+// HandleTwoFactorConfirm godoc
+// @Summary Подтверждение двухфакторной аутентификации
+// @Description Включает двухфакторную аутентификацию по коду из приложения-аутентификатора и возвращает коды восстановления.
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Param request body requests.TwoFactorConfirmRequest true "Код из приложения"
+// @Success 200 {object} requests.TwoFactorConfirmResponse "Двухфакторная аутентификация включена"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос или неверный код"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 409 {object} requests.TwoFactorConflictError "Двухфакторная аутентификация уже включена или не подключена"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/2fa/confirm [post]
+func HandleTwoFactorConfirm(logger *zap.Logger, enroller TwoFactorEnroller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.TwoFactorConfirmRequest
+		const op = "api/v1/HandleTwoFactorConfirm"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		recoveryCodes, err := enroller.Confirm(c.Request.Context(), principal.Username, req.Code)
+		switch {
+		case errors.Is(err, twofactor.ErrInvalidCode):
+			logError(c, logger, twofactor.ErrInvalidCode, http.StatusBadRequest, "")
+			return
+		case errors.Is(err, twofactor.ErrNotEnrolled):
+			logError(c, logger, twofactor.ErrNotEnrolled, http.StatusConflict, "")
+			return
+		case errors.Is(err, twofactor.ErrAlreadyEnabled):
+			logError(c, logger, twofactor.ErrAlreadyEnabled, http.StatusConflict, "")
+			return
+		case err != nil:
+			logger.Error("failed to confirm two-factor authentication", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to confirm two-factor authentication"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("two-factor authentication enabled", zap.String("op", op), zap.String("username", principal.Username))
+		c.JSON(http.StatusOK, requests.TwoFactorConfirmResponse{RecoveryCodes: recoveryCodes})
+	}
+}
+
+// HandleLoginTwoFactor godoc
+// @Summary Подтверждение входа кодом
+// @Description Завершает вход пользователя с двухфакторной аутентификацией. Принимает код из приложения-аутентификатора
+// @Description или неиспользованный код восстановления и возвращает токены.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body requests.TwoFactorLoginRequest true "Challenge токен и код"
+// @Success 200 {object} requests.TokenResponse "Успешная аутентификация"
+// @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
+// @Failure 401 {object} requests.InvalidTwoFactorCodeError "Неверный код или challenge токен"
+// @Failure 429 {object} requests.TooManyLoginAttemptsError "Слишком много неудачных попыток, повторить через Retry-After секунд"
+// @Header 429 {integer} Retry-After "Через сколько секунд можно повторить вход"
+// @Failure 500 {object} requests.CantCreateJWTError "Внутренняя ошибка сервера"
+// @Router /api/v1/login/2fa [post]
+func HandleLoginTwoFactor(logger *zap.Logger, challengeCompleter ChallengeCompleter, sessionStarter SessionStarter, loginGuard LoginGuard) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.TwoFactorLoginRequest
+		const op = "api/v1/HandleLoginTwoFactor"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		username, err := challengeCompleter.ChallengeUsername(req.ChallengeToken)
+		if errors.Is(err, twofactor.ErrInvalidChallenge) {
+			logError(c, logger, twofactor.ErrInvalidChallenge, http.StatusUnauthorized, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to get login challenge", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
+			return
+		}
+
+		// the few attempts of a challenge must not add up to unlimited guesses across
+		// fresh challenges
+		attempt, ok := beginLogin(c, logger, loginGuard, op, username, c.ClientIP())
+		if !ok {
+			return
+		}
+
+		_, err = challengeCompleter.CompleteChallenge(req.ChallengeToken, req.Code)
+		switch {
+		case errors.Is(err, twofactor.ErrInvalidCode):
+			if err := loginGuard.Fail(c.Request.Context(), attempt); err != nil {
+				logger.Error("failed to record failed login", zap.String("op", op), zap.Error(err))
+			}
+			logError(c, logger, twofactor.ErrInvalidCode, http.StatusUnauthorized, "")
+			return
+		case errors.Is(err, twofactor.ErrInvalidChallenge):
+			logError(c, logger, twofactor.ErrInvalidChallenge, http.StatusUnauthorized, "")
+			return
+		case err != nil:
+			logger.Error("failed to complete login challenge", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
+			return
+		}
+
+		if err := loginGuard.Succeed(attempt); err != nil {
+			logger.Error("failed to reset failed logins", zap.String("op", op), zap.Error(err))
+		}
+
+		tokens, err := sessionStarter.StartSession(username, []string{auth.MethodPassword, auth.MethodOTP})
+		if err != nil {
+			logger.Error("failed to start session", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("could not create JWT token"), http.StatusInternalServerError, "")
+			return
+		}
+
+		c.JSON(http.StatusOK, tokenResponse(tokens))
+	}
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/lockout"
+	"github.com/Foreground-Eclipse/transferer/internal/twofactor"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTwoFactorEnroller struct {
+	err error
+}
+
+func (m *MockTwoFactorEnroller) Setup(username string) (twofactor.Enrollment, error) {
+	if m.err != nil {
+		return twofactor.Enrollment{}, m.err
+	}
+	return twofactor.Enrollment{
+		Secret: "JBSWY3DPEHPK3PXP",
+		URI:    "otpauth://totp/Transferer:" + username + "?secret=JBSWY3DPEHPK3PXP",
+		QRCode: []byte("png"),
+	}, nil
+}
+
+func (m *MockTwoFactorEnroller) Confirm(ctx context.Context, username, code string) ([]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []string{"k7xq2-m4pd9", "3hv6a-wq5tz"}, nil
+}
+
+type MockChallengeCompleter struct {
+	usernameErr error
+	err         error
+}
+
+func (m *MockChallengeCompleter) ChallengeUsername(token string) (string, error) {
+	if m.usernameErr != nil {
+		return "", m.usernameErr
+	}
+	return "testuser", nil
+}
+
+func (m *MockChallengeCompleter) CompleteChallenge(token, code string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	return "testuser", nil
+}
+
+func TestHandleTwoFactorSetup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		token            string
+		accept           string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Success",
+			token:            validToken,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"otpauth_uri":"otpauth://totp/Transferer:testuser?secret=JBSWY3DPEHPK3PXP","secret":"JBSWY3DPEHPK3PXP","qr_code_png":"cG5n"}`,
+		},
+		{
+			name:             "No_Token",
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"not authorized"}`,
+		},
+		{
+			name:             "Already_Enabled",
+			token:            validToken,
+			mockError:        twofactor.ErrAlreadyEnabled,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"status":"error","error":"two-factor authentication already enabled"}`,
+		},
+		{
+			name:             "Storage_Error",
+			token:            validToken,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to set up two-factor authentication"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/2fa/setup", nil)
+			if tc.token != "" {
+				c.Request.Header.Set("Authorization", tc.token)
+			}
+
+			authenticated(HandleTwoFactorSetup(newTestLogger(), &MockTwoFactorEnroller{err: tc.mockError}))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+		})
+	}
+}
+
+func TestHandleTwoFactorSetup_PNG(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/2fa/setup", nil)
+	c.Request.Header.Set("Authorization", validToken)
+	c.Request.Header.Set("Accept", "image/png")
+
+	authenticated(HandleTwoFactorSetup(newTestLogger(), &MockTwoFactorEnroller{}))(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "png", w.Body.String())
+}
+
+func TestHandleTwoFactorConfirm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Success",
+			requestBody:      `{"code":"123456"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"recovery_codes":["k7xq2-m4pd9","3hv6a-wq5tz"]}`,
+		},
+		{
+			name:             "Missing_Code",
+			requestBody:      `{}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"request contains wrong data"}`,
+		},
+		{
+			name:             "Invalid_Code",
+			requestBody:      `{"code":"000000"}`,
+			mockError:        twofactor.ErrInvalidCode,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"invalid code"}`,
+		},
+		{
+			name:             "Not_Enrolled",
+			requestBody:      `{"code":"123456"}`,
+			mockError:        twofactor.ErrNotEnrolled,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"status":"error","error":"two-factor authentication not set up"}`,
+		},
+		{
+			name:             "Storage_Error",
+			requestBody:      `{"code":"123456"}`,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to confirm two-factor authentication"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/2fa/confirm", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Authorization", validToken)
+
+			authenticated(HandleTwoFactorConfirm(newTestLogger(), &MockTwoFactorEnroller{err: tc.mockError}))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+		})
+	}
+}
+
+func TestHandleLoginTwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		usernameError    error
+		mockError        error
+		sessionError     error
+		guardError       error
+		expectedStatus   int
+		expectedResponse string
+		expectedFailures int
+	}{
+		{
+			name:             "Success",
+			requestBody:      `{"challenge_token":"challenge","code":"123456"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"token":"access-testuser","expires_at":"0001-01-01T00:00:00Z","refresh_token":"refresh-testuser","refresh_token_expires_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:             "Unknown_Challenge",
+			requestBody:      `{"challenge_token":"unknown","code":"123456"}`,
+			usernameError:    twofactor.ErrInvalidChallenge,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"invalid or expired login challenge"}`,
+		},
+		{
+			name:             "Too_Many_Attempts",
+			requestBody:      `{"challenge_token":"challenge","code":"123456"}`,
+			guardError:       &lockout.BlockedError{RetryAfter: time.Minute},
+			expectedStatus:   http.StatusTooManyRequests,
+			expectedResponse: `{"status":"error","error":"too many failed login attempts"}`,
+		},
+		{
+			name:             "Missing_Code",
+			requestBody:      `{"challenge_token":"challenge"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"request contains wrong data"}`,
+		},
+		{
+			name:             "Invalid_Code",
+			requestBody:      `{"challenge_token":"challenge","code":"000000"}`,
+			mockError:        twofactor.ErrInvalidCode,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"invalid code"}`,
+			expectedFailures: 1,
+		},
+		{
+			name:             "Invalid_Challenge",
+			requestBody:      `{"challenge_token":"expired","code":"123456"}`,
+			mockError:        twofactor.ErrInvalidChallenge,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"invalid or expired login challenge"}`,
+		},
+		{
+			name:             "Session_Error",
+			requestBody:      `{"challenge_token":"challenge","code":"123456"}`,
+			sessionError:     errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"could not create JWT token"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/login/2fa", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			sessionStarter := &MockSessionStarter{err: tc.sessionError}
+			challengeCompleter := &MockChallengeCompleter{usernameErr: tc.usernameError, err: tc.mockError}
+			loginGuard := &MockLoginGuard{err: tc.guardError}
+			HandleLoginTwoFactor(newTestLogger(), challengeCompleter, sessionStarter, loginGuard)(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			assert.Equal(t, tc.expectedFailures, loginGuard.failures, "Failed logins mismatch")
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, []string{auth.MethodPassword, auth.MethodOTP}, sessionStarter.methods)
+				assert.True(t, loginGuard.succeeded, "Failed logins must be reset")
+			}
+		})
+	}
+}
+
+func TestHandleLoginTwoFactor_FreshChallenges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// every challenge allows a few attempts, the guard limits them all together
+	loginGuard := &MockLoginGuard{limit: 6}
+	challengeCompleter := &MockChallengeCompleter{err: twofactor.ErrInvalidCode}
+	for i := 0; i < loginGuard.limit; i++ {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := fmt.Sprintf(`{"challenge_token":"challenge-%d","code":"000000"}`, i)
+		c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/login/2fa", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		HandleLoginTwoFactor(newTestLogger(), challengeCompleter, &MockSessionStarter{}, loginGuard)(c)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/login/2fa", bytes.NewBufferString(`{"challenge_token":"challenge-new","code":"000000"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	HandleLoginTwoFactor(newTestLogger(), challengeCompleter, &MockSessionStarter{}, loginGuard)(c)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
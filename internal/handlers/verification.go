@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/verification"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type VerificationSender interface {
+	Send(ctx context.Context, username string) error
+}
+
+type EmailVerifier interface {
+	Verify(ctx context.Context, token string) (string, error)
+}
+
+type VerificationResender interface {
+	Resend(ctx context.Context, username string) error
+}
+
+type DepositLimiter interface {
+	IsEmailVerified(username string) (bool, error)
+	DepositWithinLimit(username, currency string, amount, limit money.Amount) error
+}
+
+// UnverifiedDepositLimits caps how much of each currency a user can deposit in total until
+// they verify their email. Currencies without a limit cannot be deposited until then,
+// nil limits do not restrict deposits at all.
+type UnverifiedDepositLimits map[string]money.Amount
+
+// sendVerification mails a verification link to the user. A failure is only logged,
+// the user can ask for another link.
+func sendVerification(ctx context.Context, logger *zap.Logger, sender VerificationSender, op, username string) {
+	if err := sender.Send(ctx, username); err != nil {
+		logger.Error("failed to send verification email", zap.String("op", op), zap.String("username", username), zap.Error(err))
+	}
+}
+
+// HandleVerifyEmail godoc
+// @Summary Подтверждение email
+// @Description Подтверждает email пользователя по токену из письма. Токен действует один раз и только
+// @Description для email, на который он был отправлен.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Param request body requests.VerifyEmailRequest true "Токен из письма"
+// @Success 200 {object} requests.AccountUpdatedResponse "Email подтвержден"
+// @Failure 400 {object} requests.InvalidVerificationTokenError "Некорректный запрос или недействительный токен"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Router /api/v1/email/verify [post]
+func HandleVerifyEmail(logger *zap.Logger, verifier EmailVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req requests.VerifyEmailRequest
+		const op = "api/v1/HandleVerifyEmail"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		if err := c.BindJSON(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				logError(c, logger, errors.New("empty json"), http.StatusBadRequest, "failed to process request")
+				return
+			}
+			logError(c, logger, errors.New("request contains wrong data"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+
+		username, err := verifier.Verify(c.Request.Context(), req.Token)
+		if errors.Is(err, verification.ErrInvalidToken) {
+			logError(c, logger, verification.ErrInvalidToken, http.StatusBadRequest, "")
+			return
+		}
+		if err != nil {
+			logger.Error("failed to verify email", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to verify email"), http.StatusInternalServerError, "")
+			return
+		}
+
+		logger.Info("email verified", zap.String("op", op), zap.String("username", username))
+		c.JSON(http.StatusOK, requests.AccountUpdatedResponse{Message: "email verified"})
+	}
+}
+
+// HandleResendVerification godoc
+// @Summary Повторная отправка письма для подтверждения email
+// @Description Отправляет новое письмо со ссылкой для подтверждения email. Ранее отправленные ссылки
+// @Description продолжают действовать. Новое письмо можно запросить не чаще одного раза в интервал,
+// @Description заголовок Retry-After содержит время ожидания в секундах.
+// @Tags account
+// @Produce json
+// @Success 202 {object} requests.AccountUpdatedResponse "Письмо отправлено"
+// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
+// @Failure 409 {object} requests.EmailAlreadyVerifiedError "Email уже подтвержден"
+// @Failure 429 {object} requests.VerificationResendTooSoonError "Письмо отправлено недавно, повторить через Retry-After секунд"
+// @Header 429 {integer} Retry-After "Через сколько секунд можно запросить новое письмо"
+// @Failure 500 {object} requests.BadRequestError "Внутренняя ошибка сервера"
+// @Security ApiKeyAuth
+// @Router /api/v1/email/verify/resend [post]
+func HandleResendVerification(logger *zap.Logger, resender VerificationResender) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		const op = "api/v1/HandleResendVerification"
+
+		logger.Info("proceeding new request", zap.String("op", op))
+
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
+			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
+			return
+		}
+
+		err := resender.Resend(c.Request.Context(), principal.Username)
+		var tooSoon *verification.ResendTooSoonError
+		switch {
+		case errors.As(err, &tooSoon):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(tooSoon.RetryAfter.Seconds()))))
+			logError(c, logger, tooSoon, http.StatusTooManyRequests, "")
+			return
+		case errors.Is(err, verification.ErrAlreadyVerified):
+			logError(c, logger, verification.ErrAlreadyVerified, http.StatusConflict, "")
+			return
+		case err != nil:
+			logger.Error("failed to resend verification email", zap.String("op", op), zap.Error(err))
+			logError(c, logger, errors.New("failed to send verification email"), http.StatusInternalServerError, "")
+			return
+		}
+
+		c.JSON(http.StatusAccepted, requests.AccountUpdatedResponse{Message: "verification email sent"})
+	}
+}
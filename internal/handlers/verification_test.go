@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/verification"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockVerification struct {
+	err  error
+	sent []string
+}
+
+func (m *MockVerification) Send(ctx context.Context, username string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, username)
+	return nil
+}
+
+func (m *MockVerification) Resend(ctx context.Context, username string) error {
+	return m.Send(ctx, username)
+}
+
+func (m *MockVerification) Verify(ctx context.Context, token string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	return "testuser", nil
+}
+
+type MockDepositLimiter struct {
+	verified  bool
+	deposited money.Amount
+	err       error
+}
+
+func (m *MockDepositLimiter) IsEmailVerified(username string) (bool, error) {
+	return m.verified, m.err
+}
+
+func (m *MockDepositLimiter) DepositWithinLimit(username, currency string, amount, limit money.Amount) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.deposited.Add(amount).GreaterThan(limit) {
+		return storage.ErrDepositLimitExceeded
+	}
+	return nil
+}
+
+func TestHandleVerifyEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		mockError        error
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Success",
+			requestBody:      `{"token":"verify"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"email verified"}`,
+		},
+		{
+			name:             "Missing_Token",
+			requestBody:      `{}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"request contains wrong data"}`,
+		},
+		{
+			name:             "Invalid_Token",
+			requestBody:      `{"token":"expired"}`,
+			mockError:        verification.ErrInvalidToken,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"status":"error","error":"invalid or expired email verification token"}`,
+		},
+		{
+			name:             "Storage_Error",
+			requestBody:      `{"token":"verify"}`,
+			mockError:        errors.New("db is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to verify email"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/email/verify", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			HandleVerifyEmail(newTestLogger(), &MockVerification{err: tc.mockError})(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+		})
+	}
+}
+
+func TestHandleResendVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+
+	testCases := []struct {
+		name               string
+		token              string
+		mockError          error
+		expectedStatus     int
+		expectedResponse   string
+		expectedRetryAfter string
+	}{
+		{
+			name:             "Success",
+			token:            validToken,
+			expectedStatus:   http.StatusAccepted,
+			expectedResponse: `{"message":"verification email sent"}`,
+		},
+		{
+			name:             "No_Token",
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"not authorized"}`,
+		},
+		{
+			name:             "Already_Verified",
+			token:            validToken,
+			mockError:        verification.ErrAlreadyVerified,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"status":"error","error":"email is already verified"}`,
+		},
+		{
+			name:               "Too_Soon",
+			token:              validToken,
+			mockError:          &verification.ResendTooSoonError{RetryAfter: 1500 * time.Millisecond},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedResponse:   `{"status":"error","error":"verification email was sent recently"}`,
+			expectedRetryAfter: "2",
+		},
+		{
+			name:             "Mailer_Error",
+			token:            validToken,
+			mockError:        errors.New("smtp is down"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to send verification email"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/email/verify/resend", nil)
+			c.Request.Header.Set("Authorization", tc.token)
+
+			authenticated(HandleResendVerification(newTestLogger(), &MockVerification{err: tc.mockError}))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			assert.Equal(t, tc.expectedRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestHandleDeposit_UnverifiedLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	validToken, err := GenerateJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate valid JWT: %v", err)
+	}
+	limits := UnverifiedDepositLimits{"USD": money.MustParse("1000")}
+
+	testCases := []struct {
+		name             string
+		requestBody      string
+		limiter          *MockDepositLimiter
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "Unverified_Within_Limit",
+			requestBody:      `{"currency":"USD","amount":400}`,
+			limiter:          &MockDepositLimiter{deposited: money.MustParse("600")},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Account topped up successfully","balance":{"USD":"1000"}}`,
+		},
+		{
+			name:             "Unverified_Over_Limit",
+			requestBody:      `{"currency":"USD","amount":400.01}`,
+			limiter:          &MockDepositLimiter{deposited: money.MustParse("600")},
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"status":"error","error":"deposit limit exceeded, verify your email"}`,
+		},
+		{
+			name:             "Verified_Not_Limited",
+			requestBody:      `{"currency":"USD","amount":5000}`,
+			limiter:          &MockDepositLimiter{verified: true, deposited: money.MustParse("600")},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Account topped up successfully","balance":{"USD":"1000"}}`,
+		},
+		{
+			name:             "Unverified_Currency_Without_Limit",
+			requestBody:      `{"currency":"EUR","amount":1}`,
+			limiter:          &MockDepositLimiter{},
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"status":"error","error":"deposit limit exceeded, verify your email"}`,
+		},
+		{
+			name:             "Verified_Currency_Without_Limit",
+			requestBody:      `{"currency":"EUR","amount":5000}`,
+			limiter:          &MockDepositLimiter{verified: true},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Account topped up successfully","balance":{"USD":"1000"}}`,
+		},
+		{
+			name:             "Storage_Error",
+			requestBody:      `{"currency":"USD","amount":100}`,
+			limiter:          &MockDepositLimiter{err: errors.New("db is down")},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to check deposit limit"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/wallet/deposit", bytes.NewBufferString(tc.requestBody))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Authorization", validToken)
+
+			balanceUpdater := &MockBalanceUpdater{balance: map[string]money.Amount{"USD": money.MustParse("1000")}}
+			authenticated(HandleDeposit(newTestLogger(), balanceUpdater, nil, tc.limiter, limits))(c)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+		})
+	}
+}
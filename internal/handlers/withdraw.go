@@ -5,33 +5,41 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/Foreground-Eclipse/transferer/internal/api/requests"
-	jwt "github.com/Foreground-Eclipse/transferer/pkg/auth"
-	"github.com/Foreground-Eclipse/transferer/utils"
+	"github.com/Foreground-Eclipse/transferer/internal/middleware"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 type BalanceWithdrawer interface {
-	UpdateUsersBalance(username, currency string, amount float64) error
-	GetUserBalance(username string) (map[string]float64, error)
+	UpdateUsersBalance(username, currency string, amount money.Amount) error
+	WithdrawWithinLimit(username, currency string, amount, limit money.Amount, since time.Time) error
+	GetUserBalance(username string) (map[string]money.Amount, error)
 }
 
 // HandleWithdraw godoc
 // @Summary Снятие средств с баланса пользователя
 // @Description  Снимает указанную сумму в указанной валюте с баланса пользователя.
+// @Description  Если сумма снятий в валюте за STEP_UP_MAX_AGE превысит порог валюты, нужна повторная
+// @Description  аутентификация через /api/v1/reauth.
+// @Description  Недоступно до подтверждения email.
 // @Tags withdraw
 // @Accept  json
 // @Produce  json
 // @Param   request body requests.WithdrawRequest true "Данные для снятия"
+// @Param   Idempotency-Key header string false "Ключ идемпотентности, повтор запроса с тем же ключом вернет первый ответ"
 // @Success 200 {object} requests.DepositResponse "OK"
 // @Failure 400 {object} requests.BadRequestError "Некорректный запрос"
-// @Failure 401 {object} requests.NotAuthorizedError "Не авторизован"
-// @Failure 403 {object} requests.NotEnoughFundsError "Недостаточно средств"
+// @Failure 401 {object} requests.StepUpRequiredError "Не авторизован или требуется повторная аутентификация"
+// @Failure 403 {object} requests.NotEnoughFundsError "Недостаточно средств или email не подтвержден"
+// @Failure 409 {object} requests.IdempotencyConflictError "Ключ идемпотентности уже использован"
 // @Security ApiKeyAuth
-// @Router /api/v1/withdraw [post]
-func HandleWithdraw(logger *zap.Logger, balanceWithdrawer BalanceWithdrawer) gin.HandlerFunc {
+// @Router /api/v1/wallet/withdraw [post]
+func HandleWithdraw(logger *zap.Logger, balanceWithdrawer BalanceWithdrawer, idempotencyStore IdempotencyStore, stepUp StepUpPolicy) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		const op = "api/v1/HandleWithdraw"
 		var req requests.WithdrawRequest
@@ -46,14 +54,22 @@ func HandleWithdraw(logger *zap.Logger, balanceWithdrawer BalanceWithdrawer) gin
 			return
 		}
 
+		if !req.Amount.IsPositive() {
+			logError(c, logger, errors.New("amount must be positive"), http.StatusBadRequest, "failed to process request")
+			return
+		}
+		if !checkAmountScale(c, logger, req.Amount) {
+			return
+		}
+
 		reqBody, err := json.Marshal(req)
 		if err != nil {
 			logError(c, logger, err, http.StatusBadRequest, "failed to marshal request body")
 			return
 		}
 
-		tokenString := c.GetHeader("Authorization")
-		if tokenString == "" {
+		principal, ok := middleware.PrincipalFrom(c)
+		if !ok {
 			logError(c, logger, errors.New("not authorized"), http.StatusUnauthorized, "")
 			return
 		}
@@ -61,35 +77,38 @@ func HandleWithdraw(logger *zap.Logger, balanceWithdrawer BalanceWithdrawer) gin
 		logger.Info("request data: ",
 			zap.String("discordid: ", c.Request.Method),
 			zap.String("URL", c.Request.URL.String()),
-			zap.String("Token", tokenString),
+			zap.String("username", principal.Username),
 			zap.String("body", string(reqBody)),
 		)
 
-		username, err := jwt.ValidateToken(tokenString)
-		if err != nil {
-			logError(c, logger, err, http.StatusUnauthorized, "")
+		username := principal.Username
+
+		finish, handled := startIdempotentRequest(c, logger, idempotencyStore, op, username, reqBody)
+		if handled {
 			return
 		}
+		defer finish()
 
-		balance, err := balanceWithdrawer.GetUserBalance(username)
-		if err != nil {
-			logError(c, logger, err, http.StatusOK, "")
+		if limit, limited := stepUp.withdrawalLimit(principal, req.Currency); limited {
+			since := time.Now().Add(-stepUp.MaxAge)
+			err = balanceWithdrawer.WithdrawWithinLimit(username, req.Currency, req.Amount, limit, since)
+		} else {
+			err = balanceWithdrawer.UpdateUsersBalance(username, req.Currency, req.Amount.Neg())
+		}
+		if errors.Is(err, storage.ErrWithdrawalLimitExceeded) {
+			stepUp.reject(c, logger, principal, "withdrawals above threshold")
 			return
 		}
-
-		err = utils.VerifyWithdrawalAmount(req.Amount, balance[req.Currency])
-		if err != nil {
-			logError(c, logger, err, http.StatusOK, "")
+		if errors.Is(err, storage.ErrNotEnoughFunds) {
+			logError(c, logger, errors.New("not enough money to withdraw"), http.StatusForbidden, "")
 			return
 		}
-
-		err = balanceWithdrawer.UpdateUsersBalance(username, req.Currency, float64(req.Amount*-1.0))
 		if err != nil {
 			logError(c, logger, err, http.StatusOK, "")
 			return
 		}
 
-		balance, err = balanceWithdrawer.GetUserBalance(username)
+		balance, err := balanceWithdrawer.GetUserBalance(username)
 		if err != nil {
 			logError(c, logger, err, http.StatusOK, "")
 			return
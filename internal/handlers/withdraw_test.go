@@ -6,29 +6,49 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
 type MockBalanceWithdrawer struct {
-	balance map[string]float64
-	err     error
+	balance   map[string]money.Amount
+	withdrawn map[string]money.Amount
+	err       error
 }
 
-func (m *MockBalanceWithdrawer) UpdateUsersBalance(username, currency string, amount float64) error {
+func (m *MockBalanceWithdrawer) UpdateUsersBalance(username, currency string, amount money.Amount) error {
 	if m.err != nil {
 		return m.err
 	}
 	if m.balance != nil {
 		if _, ok := m.balance[currency]; ok {
-			m.balance[currency] += amount
+			if m.balance[currency].Add(amount).IsNegative() {
+				return storage.ErrNotEnoughFunds
+			}
+			m.balance[currency] = m.balance[currency].Add(amount)
 		}
 	}
 	return nil
 }
 
-func (m *MockBalanceWithdrawer) GetUserBalance(username string) (map[string]float64, error) {
+func (m *MockBalanceWithdrawer) WithdrawWithinLimit(username, currency string, amount, limit money.Amount, since time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.balance[currency].LessThan(amount) {
+		return storage.ErrNotEnoughFunds
+	}
+	if m.withdrawn[currency].Add(amount).GreaterThan(limit) {
+		return storage.ErrWithdrawalLimitExceeded
+	}
+	return m.UpdateUsersBalance(username, currency, amount.Neg())
+}
+
+func (m *MockBalanceWithdrawer) GetUserBalance(username string) (map[string]money.Amount, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -44,11 +64,22 @@ func TestHandleWithdraw(t *testing.T) {
 		t.Fatalf("Failed to generate valid JWT: %v", err)
 	}
 
+	freshToken, err := generateFreshJWT("testuser")
+	if err != nil {
+		t.Fatalf("Failed to generate fresh JWT: %v", err)
+	}
+
+	stepUp := StepUpPolicy{
+		MaxAge:             5 * time.Minute,
+		WithdrawThresholds: map[string]money.Amount{"USD": money.MustParse("500")},
+	}
+
 	testCases := []struct {
 		name             string
 		token            string
 		requestBody      string
-		mockBalance      map[string]float64
+		mockBalance      map[string]money.Amount
+		mockWithdrawn    map[string]money.Amount
 		mockError        error
 		expectedStatus   int
 		expectedResponse string
@@ -57,25 +88,25 @@ func TestHandleWithdraw(t *testing.T) {
 			name:             "No Token",
 			token:            "",
 			requestBody:      `{"currency":"USD","amount":50}`,
-			mockBalance:      map[string]float64{"USD": 100},
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("100")},
 			mockError:        nil,
-			expectedStatus:   http.StatusOK,
+			expectedStatus:   http.StatusUnauthorized,
 			expectedResponse: `{"error":"not authorized", "status":"error"}`,
 		},
 		{
 			name:             "Invalid Token",
 			token:            "invalid_token",
 			requestBody:      `{"currency":"USD","amount":50}`,
-			mockBalance:      map[string]float64{"USD": 100},
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("100")},
 			mockError:        nil,
-			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"error":"token contains an invalid number of segments", "status":"error"}`,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"invalid token", "status":"error"}`,
 		},
 		{
 			name:             "Invalid JSON - Wrong Type",
 			token:            validToken,
 			requestBody:      `{"currency":"USD","amount":"string"}`,
-			mockBalance:      map[string]float64{"USD": 100},
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("100")},
 			mockError:        nil,
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: `{"error":"request contains wrong data", "status":"error"}`,
@@ -84,16 +115,16 @@ func TestHandleWithdraw(t *testing.T) {
 			name:             "Insufficient Funds",
 			token:            validToken,
 			requestBody:      `{"currency":"USD","amount":150}`,
-			mockBalance:      map[string]float64{"USD": 100},
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("100")},
 			mockError:        nil,
-			expectedStatus:   http.StatusOK,
+			expectedStatus:   http.StatusForbidden,
 			expectedResponse: `{"error":"not enough money to withdraw", "status":"error"}`,
 		},
 		{
 			name:             "Withdrawal Error",
 			token:            validToken,
 			requestBody:      `{"currency":"USD","amount":50}`,
-			mockBalance:      map[string]float64{"USD": 100},
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("100")},
 			mockError:        errors.New("database error"),
 			expectedStatus:   http.StatusOK,
 			expectedResponse: `{"error":"database error", "status":"error"}`,
@@ -102,10 +133,61 @@ func TestHandleWithdraw(t *testing.T) {
 			name:             "Successful Withdrawal",
 			token:            validToken,
 			requestBody:      `{"currency":"USD","amount":50}`,
-			mockBalance:      map[string]float64{"USD": 100.0, "EUR": 50.0},
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("100"), "EUR": money.MustParse("50")},
 			mockError:        nil,
 			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"message":"Withdrawal successfull","balance":{"EUR":50,"USD":50}}`,
+			expectedResponse: `{"message":"Withdrawal successfull","balance":{"EUR":"50","USD":"50"}}`,
+		},
+		{
+			name:             "Above Threshold Without Fresh Authentication",
+			token:            validToken,
+			requestBody:      `{"currency":"USD","amount":600}`,
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("1000")},
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"fresh authentication required", "status":"error"}`,
+		},
+		{
+			name:             "Above Threshold With Fresh Authentication",
+			token:            freshToken,
+			requestBody:      `{"currency":"USD","amount":600}`,
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("1000")},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Withdrawal successfull","balance":{"USD":"400"}}`,
+		},
+		{
+			name:             "Recent Withdrawals Under Threshold",
+			token:            validToken,
+			requestBody:      `{"currency":"USD","amount":200}`,
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("1000")},
+			mockWithdrawn:    map[string]money.Amount{"USD": money.MustParse("300")},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Withdrawal successfull","balance":{"USD":"800"}}`,
+		},
+		{
+			name:             "Recent Withdrawals Above Threshold Without Fresh Authentication",
+			token:            validToken,
+			requestBody:      `{"currency":"USD","amount":201}`,
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("1000")},
+			mockWithdrawn:    map[string]money.Amount{"USD": money.MustParse("300")},
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"error":"fresh authentication required", "status":"error"}`,
+		},
+		{
+			name:             "Recent Withdrawals Above Threshold With Fresh Authentication",
+			token:            freshToken,
+			requestBody:      `{"currency":"USD","amount":201}`,
+			mockBalance:      map[string]money.Amount{"USD": money.MustParse("1000")},
+			mockWithdrawn:    map[string]money.Amount{"USD": money.MustParse("300")},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Withdrawal successfull","balance":{"USD":"799"}}`,
+		},
+		{
+			name:             "Above Threshold Of Another Currency",
+			token:            validToken,
+			requestBody:      `{"currency":"EUR","amount":600}`,
+			mockBalance:      map[string]money.Amount{"EUR": money.MustParse("1000")},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Withdrawal successfull","balance":{"EUR":"400"}}`,
 		},
 	}
 
@@ -123,11 +205,12 @@ func TestHandleWithdraw(t *testing.T) {
 			logger := newTestLogger()
 
 			mockBalanceWithdrawer := &MockBalanceWithdrawer{
-				balance: tc.mockBalance,
-				err:     tc.mockError,
+				balance:   tc.mockBalance,
+				withdrawn: tc.mockWithdrawn,
+				err:       tc.mockError,
 			}
 
-			HandleWithdraw(logger, mockBalanceWithdrawer)(c)
+			authenticated(HandleWithdraw(logger, mockBalanceWithdrawer, nil, stepUp))(c)
 
 			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
 			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
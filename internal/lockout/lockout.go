@@ -0,0 +1,303 @@
+// Package lockout throttles password guessing. Failed logins are counted per username and
+// per client IP: after a few free attempts every failure delays the next login exponentially,
+// and too many failures lock the account until it is unlocked from the link mailed to the
+// user or the lockout expires.
+package lockout
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/mail"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+)
+
+var ErrInvalidUnlockToken = errors.New("invalid or expired unlock token")
+
+// BlockedError is returned by Begin while logins are not allowed.
+type BlockedError struct {
+	RetryAfter time.Duration
+	// Locked means the account reached the failure limit and the user was mailed an unlock link.
+	Locked bool
+}
+
+func (e *BlockedError) Error() string {
+	if e.Locked {
+		return "account is temporarily locked"
+	}
+	return "too many failed login attempts"
+}
+
+type Store interface {
+	GetUser(username string) (*models.User, error)
+	GetLoginFailures(scope, subject string) (*models.LoginFailures, error)
+	ClaimLoginAttempt(scope, subject string, at, since time.Time, limit int) (int, error)
+	ReleaseLoginAttempt(scope, subject string) error
+	BlockLogin(scope, subject string, until time.Time) error
+	ResetLoginFailures(scope, subject string) error
+	CreateUnlockToken(token *models.UnlockToken) error
+	UseUnlockToken(hash string) (string, error)
+}
+
+// Options are the limits of a Guard, see config.LockoutConfig.
+type Options struct {
+	FreeAttempts    int
+	MaxFailures     int
+	IPMaxFailures   int
+	Backoff         time.Duration
+	MaxBackoff      time.Duration
+	LockoutDuration time.Duration
+	Window          time.Duration
+	UnlockTokenTTL  time.Duration
+	// UnlockURL is the page the unlock link points to, the token is added as the token
+	// query parameter. Without it the token itself is mailed.
+	UnlockURL string
+}
+
+type Guard struct {
+	store  Store
+	mailer mail.Mailer
+	opts   Options
+	now    func() time.Time
+}
+
+func NewGuard(store Store, mailer mail.Mailer, opts Options) *Guard {
+	return &Guard{
+		store:  store,
+		mailer: mailer,
+		opts:   opts,
+		now:    time.Now,
+	}
+}
+
+// Attempt is a login attempt counted by Begin, settled by Fail or Succeed.
+type Attempt struct {
+	Username string
+	IP       string
+
+	userFailures int
+	ipFailures   int
+}
+
+// Begin counts a login attempt of username from ip before the password or code is checked,
+// or returns a *BlockedError if username may not log in from ip yet. The attempt counts as a
+// failure until Succeed, so a burst of parallel guesses cannot get past the limits while the
+// first of them are still being checked.
+func (g *Guard) Begin(username, ip string) (*Attempt, error) {
+	const op = "lockout.Guard.Begin"
+
+	now := g.now()
+	since := now.Add(-g.opts.Window)
+
+	userFailures, err := g.store.ClaimLoginAttempt(models.LoginScopeUser, username, now, since, g.opts.MaxFailures)
+	if errors.Is(err, storage.ErrLoginBlocked) {
+		return nil, fmt.Errorf("%s: %w", op, g.blockedError(models.LoginScopeUser, username, now))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ipFailures, err := g.store.ClaimLoginAttempt(models.LoginScopeIP, ip, now, since, g.opts.IPMaxFailures)
+	if err != nil {
+		// the attempt is not made, so it does not count against username
+		if err := g.store.ReleaseLoginAttempt(models.LoginScopeUser, username); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if errors.Is(err, storage.ErrLoginBlocked) {
+		return nil, fmt.Errorf("%s: %w", op, g.blockedError(models.LoginScopeIP, ip, now))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Attempt{
+		Username:     username,
+		IP:           ip,
+		userFailures: userFailures,
+		ipFailures:   ipFailures,
+	}, nil
+}
+
+// Fail records that attempt failed and blocks further logins as needed.
+// When the account gets locked its owner is mailed an unlock link.
+func (g *Guard) Fail(ctx context.Context, attempt *Attempt) error {
+	const op = "lockout.Guard.Fail"
+
+	now := g.now()
+	if err := g.block(models.LoginScopeUser, attempt.Username, attempt.userFailures, g.opts.MaxFailures, now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// only the failure that locks the account sends the link
+	if attempt.userFailures == g.opts.MaxFailures {
+		if err := g.sendUnlockLink(ctx, attempt.Username); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := g.block(models.LoginScopeIP, attempt.IP, attempt.ipFailures, g.opts.IPMaxFailures, now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Succeed forgets the failed logins of the username of attempt. Failures of the IP are kept,
+// only attempt itself stops counting, so logging in to an own account does not let an
+// attacker keep guessing other passwords.
+func (g *Guard) Succeed(attempt *Attempt) error {
+	const op = "lockout.Guard.Succeed"
+
+	if err := g.store.ResetLoginFailures(models.LoginScopeUser, attempt.Username); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := g.store.ReleaseLoginAttempt(models.LoginScopeIP, attempt.IP); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Continue stops counting attempt against its IP once the password was right and the login
+// waits for the second factor. It still counts against the username until Succeed, so
+// fresh challenges give no extra guesses at the code.
+func (g *Guard) Continue(attempt *Attempt) error {
+	const op = "lockout.Guard.Continue"
+
+	if err := g.store.ReleaseLoginAttempt(models.LoginScopeIP, attempt.IP); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Unlock unlocks the account of the unlock link token. A token can be used once.
+func (g *Guard) Unlock(token string) (string, error) {
+	const op = "lockout.Guard.Unlock"
+
+	username, err := g.store.UseUnlockToken(hashToken(token))
+	if errors.Is(err, storage.ErrUnlockTokenNotFound) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidUnlockToken)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err := g.store.ResetLoginFailures(models.LoginScopeUser, username); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return username, nil
+}
+
+// block delays the next login of subject after its failures-th failure.
+func (g *Guard) block(scope, subject string, failures, maxFailures int, now time.Time) error {
+	var until time.Time
+	if failures >= maxFailures {
+		until = now.Add(g.opts.LockoutDuration)
+	} else if delay := g.backoff(failures); delay > 0 {
+		until = now.Add(delay)
+	} else {
+		return nil
+	}
+
+	err := g.store.BlockLogin(scope, subject, until)
+	if errors.Is(err, storage.ErrLoginFailuresNotFound) {
+		// reset by a successful login in the meantime
+		return nil
+	}
+	return err
+}
+
+// blockedError tells how long subject, whose attempt was refused, has to wait.
+func (g *Guard) blockedError(scope, subject string, now time.Time) error {
+	failures, err := g.store.GetLoginFailures(scope, subject)
+	if errors.Is(err, storage.ErrLoginFailuresNotFound) {
+		// reset in the meantime
+		return &BlockedError{RetryAfter: time.Second}
+	}
+	if err != nil {
+		return err
+	}
+
+	if !failures.BlockedUntil.After(now) {
+		// the limit is taken by attempts that are still being checked
+		return &BlockedError{RetryAfter: time.Second}
+	}
+	return &BlockedError{
+		RetryAfter: failures.BlockedUntil.Sub(now),
+		Locked:     scope == models.LoginScopeUser && failures.Failures >= g.opts.MaxFailures,
+	}
+}
+
+// backoff is the delay after the failures-th failure: none for the free attempts,
+// then Backoff doubled with every failure up to MaxBackoff.
+func (g *Guard) backoff(failures int) time.Duration {
+	n := failures - g.opts.FreeAttempts
+	if n <= 0 {
+		return 0
+	}
+	delay := g.opts.Backoff
+	for i := 1; i < n && delay < g.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > g.opts.MaxBackoff {
+		delay = g.opts.MaxBackoff
+	}
+	return delay
+}
+
+// sendUnlockLink mails the owner of username a link unlocking the account.
+// Nothing is sent for unknown usernames and users without an email.
+func (g *Guard) sendUnlockLink(ctx context.Context, username string) error {
+	user, err := g.store.GetUser(username)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return nil
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate unlock token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	err = g.store.CreateUnlockToken(&models.UnlockToken{
+		Hash:      hashToken(token),
+		Username:  username,
+		ExpiresAt: g.now().Add(g.opts.UnlockTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return g.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Your Transferer account was locked",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"your account was locked after too many failed login attempts. It unlocks by itself in %s.\n\n"+
+			"If it was you, unlock it now with %s\n"+
+			"It can be used once within %s.\n\n"+
+			"If it was not you, someone may be guessing your password, consider changing it.\n",
+			username, g.opts.LockoutDuration, g.unlockLink(token), g.opts.UnlockTokenTTL),
+	})
+}
+
+func (g *Guard) unlockLink(token string) string {
+	if g.opts.UnlockURL == "" {
+		return "the unlock token " + token
+	}
+	return "the link " + mail.WithToken(g.opts.UnlockURL, token)
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
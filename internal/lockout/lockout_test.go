@@ -0,0 +1,364 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/mail"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps counters and unlock tokens the way the postgres storage does.
+type memoryStore struct {
+	mu       sync.Mutex
+	users    map[string]*models.User
+	failures map[string]*models.LoginFailures
+	tokens   map[string]*models.UnlockToken
+	now      func() time.Time
+}
+
+func newMemoryStore(now func() time.Time) *memoryStore {
+	return &memoryStore{
+		users: map[string]*models.User{
+			"alice": {Username: "alice", Email: "alice@example.com"},
+		},
+		failures: map[string]*models.LoginFailures{},
+		tokens:   map[string]*models.UnlockToken{},
+		now:      now,
+	}
+}
+
+func (s *memoryStore) GetUser(username string) (*models.User, error) {
+	user, ok := s.users[username]
+	if !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *memoryStore) GetLoginFailures(scope, subject string) (*models.LoginFailures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures, ok := s.failures[scope+"/"+subject]
+	if !ok {
+		return nil, storage.ErrLoginFailuresNotFound
+	}
+	copied := *failures
+	return &copied, nil
+}
+
+func (s *memoryStore) ClaimLoginAttempt(scope, subject string, at, since time.Time, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures, ok := s.failures[scope+"/"+subject]
+	expired := ok && !failures.BlockedUntil.IsZero() && !failures.BlockedUntil.After(at)
+	switch {
+	case !ok || failures.LastFailureAt.Before(since) || (expired && failures.Failures >= limit):
+		failures = &models.LoginFailures{Scope: scope, Subject: subject}
+		s.failures[scope+"/"+subject] = failures
+	case failures.BlockedUntil.After(at) || (!expired && failures.Failures >= limit):
+		return 0, storage.ErrLoginBlocked
+	}
+	failures.Failures++
+	failures.LastFailureAt = at
+	return failures.Failures, nil
+}
+
+func (s *memoryStore) ReleaseLoginAttempt(scope, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if failures, ok := s.failures[scope+"/"+subject]; ok && failures.Failures > 0 {
+		failures.Failures--
+	}
+	return nil
+}
+
+func (s *memoryStore) BlockLogin(scope, subject string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures, ok := s.failures[scope+"/"+subject]
+	if !ok {
+		return storage.ErrLoginFailuresNotFound
+	}
+	if until.After(failures.BlockedUntil) {
+		failures.BlockedUntil = until
+	}
+	return nil
+}
+
+func (s *memoryStore) ResetLoginFailures(scope, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, scope+"/"+subject)
+	return nil
+}
+
+func (s *memoryStore) CreateUnlockToken(token *models.UnlockToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *token
+	s.tokens[token.Hash] = &copied
+	return nil
+}
+
+func (s *memoryStore) UseUnlockToken(hash string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[hash]
+	if !ok || !token.ExpiresAt.After(s.now()) {
+		return "", storage.ErrUnlockTokenNotFound
+	}
+	delete(s.tokens, hash)
+	return token.Username, nil
+}
+
+type memoryMailer struct {
+	sent []mail.Message
+}
+
+func (m *memoryMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var testOptions = Options{
+	FreeAttempts:    3,
+	MaxFailures:     6,
+	IPMaxFailures:   10,
+	Backoff:         time.Second,
+	MaxBackoff:      4 * time.Second,
+	LockoutDuration: 30 * time.Minute,
+	Window:          15 * time.Minute,
+	UnlockTokenTTL:  time.Hour,
+	UnlockURL:       "https://transferer.example/unlock",
+}
+
+func newTestGuard() (*Guard, *memoryMailer, *time.Time) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	mailer := &memoryMailer{}
+	guard := NewGuard(newMemoryStore(clock), mailer, testOptions)
+	guard.now = clock
+	return guard, mailer, &now
+}
+
+func blockedError(t *testing.T, err error) *BlockedError {
+	t.Helper()
+
+	var blocked *BlockedError
+	require.ErrorAs(t, err, &blocked)
+	return blocked
+}
+
+// fail makes a login attempt of username from ip that fails.
+func fail(t *testing.T, guard *Guard, username, ip string) {
+	t.Helper()
+
+	attempt, err := guard.Begin(username, ip)
+	require.NoError(t, err)
+	require.NoError(t, guard.Fail(context.Background(), attempt))
+}
+
+func TestGuard_Backoff(t *testing.T) {
+	guard, mailer, now := newTestGuard()
+
+	for i := 0; i < testOptions.FreeAttempts; i++ {
+		fail(t, guard, "alice", "10.0.0.1")
+	}
+
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second} {
+		fail(t, guard, "alice", "10.0.0.1")
+		_, err := guard.Begin("alice", "10.0.0.1")
+		blocked := blockedError(t, err)
+		assert.Equal(t, expected, blocked.RetryAfter)
+		assert.False(t, blocked.Locked)
+
+		// the delay applies to the username from any IP
+		_, err = guard.Begin("alice", "10.0.0.2")
+		assert.Error(t, err)
+
+		*now = now.Add(expected)
+	}
+	assert.Empty(t, mailer.sent)
+
+	attempt, err := guard.Begin("alice", "10.0.0.1")
+	require.NoError(t, err)
+	require.NoError(t, guard.Succeed(attempt))
+	fail(t, guard, "alice", "10.0.0.1")
+	_, err = guard.Begin("alice", "10.0.0.2")
+	assert.NoError(t, err, "a successful login resets the count")
+}
+
+func TestGuard_BackoffIsCapped(t *testing.T) {
+	guard, _, _ := newTestGuard()
+
+	assert.Equal(t, time.Duration(0), guard.backoff(3))
+	assert.Equal(t, time.Second, guard.backoff(4))
+	assert.Equal(t, 2*time.Second, guard.backoff(5))
+	assert.Equal(t, 4*time.Second, guard.backoff(6))
+	assert.Equal(t, 4*time.Second, guard.backoff(60))
+}
+
+func TestGuard_LockoutAndUnlock(t *testing.T) {
+	guard, mailer, now := newTestGuard()
+
+	for i := 0; i < testOptions.MaxFailures; i++ {
+		fail(t, guard, "alice", "10.0.0.1")
+		*now = now.Add(10 * time.Second)
+	}
+
+	_, err := guard.Begin("alice", "10.0.0.3")
+	blocked := blockedError(t, err)
+	assert.True(t, blocked.Locked)
+	assert.Equal(t, testOptions.LockoutDuration-10*time.Second, blocked.RetryAfter)
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "alice@example.com", mailer.sent[0].To)
+	match := regexp.MustCompile(`https://transferer\.example/unlock\?token=([A-Za-z0-9_-]+)`).FindStringSubmatch(mailer.sent[0].Body)
+	require.Len(t, match, 2, "the mail contains the unlock link")
+
+	_, err = guard.Unlock("wrong")
+	assert.ErrorIs(t, err, ErrInvalidUnlockToken)
+
+	username, err := guard.Unlock(match[1])
+	require.NoError(t, err)
+	assert.Equal(t, "alice", username)
+	_, err = guard.Begin("alice", "10.0.0.3")
+	assert.NoError(t, err)
+
+	_, err = guard.Unlock(match[1])
+	assert.ErrorIs(t, err, ErrInvalidUnlockToken, "a token is used once")
+}
+
+func TestGuard_LockoutExpires(t *testing.T) {
+	guard, _, now := newTestGuard()
+
+	for i := 0; i < testOptions.MaxFailures; i++ {
+		fail(t, guard, "alice", "10.0.0.1")
+		*now = now.Add(testOptions.MaxBackoff)
+	}
+	_, err := guard.Begin("alice", "10.0.0.2")
+	assert.Error(t, err)
+
+	*now = now.Add(testOptions.LockoutDuration)
+	// the failures are forgotten by now, the next one starts a new count
+	fail(t, guard, "alice", "10.0.0.2")
+	_, err = guard.Begin("alice", "10.0.0.2")
+	assert.NoError(t, err)
+}
+
+func TestGuard_UnknownUser(t *testing.T) {
+	guard, mailer, now := newTestGuard()
+
+	for i := 0; i < testOptions.MaxFailures; i++ {
+		fail(t, guard, "mallory", "10.0.0.1")
+		*now = now.Add(testOptions.MaxBackoff)
+	}
+
+	_, err := guard.Begin("mallory", "10.0.0.2")
+	blocked := blockedError(t, err)
+	assert.True(t, blocked.Locked, "unknown usernames are locked like known ones")
+	assert.Empty(t, mailer.sent)
+}
+
+func TestGuard_IPLimit(t *testing.T) {
+	guard, _, now := newTestGuard()
+
+	// a different username every time, so only the IP counter grows
+	for i := 0; i < testOptions.IPMaxFailures; i++ {
+		fail(t, guard, "user"+string(rune('a'+i)), "10.0.0.1")
+		*now = now.Add(testOptions.MaxBackoff)
+	}
+
+	_, err := guard.Begin("alice", "10.0.0.1")
+	blocked := blockedError(t, err)
+	assert.False(t, blocked.Locked)
+	assert.Equal(t, testOptions.LockoutDuration-testOptions.MaxBackoff, blocked.RetryAfter)
+
+	_, err = guard.Begin("alice", "10.0.0.2")
+	assert.NoError(t, err, "the refused attempt does not count against the username")
+	failures, err := guard.store.GetLoginFailures(models.LoginScopeUser, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures.Failures)
+}
+
+func TestGuard_SucceedKeepsIPUnblocked(t *testing.T) {
+	guard, _, _ := newTestGuard()
+
+	for i := 0; i < 2*testOptions.IPMaxFailures; i++ {
+		attempt, err := guard.Begin("user"+string(rune('a'+i)), "10.0.0.1")
+		require.NoError(t, err)
+		require.NoError(t, guard.Succeed(attempt))
+	}
+}
+
+func TestGuard_Continue(t *testing.T) {
+	guard, _, _ := newTestGuard()
+
+	attempt, err := guard.Begin("alice", "10.0.0.1")
+	require.NoError(t, err)
+	require.NoError(t, guard.Continue(attempt))
+
+	failures, err := guard.store.GetLoginFailures(models.LoginScopeUser, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures.Failures, "the attempt counts until the second factor succeeds")
+	failures, err = guard.store.GetLoginFailures(models.LoginScopeIP, "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, failures.Failures)
+}
+
+func TestGuard_ConcurrentAttempts(t *testing.T) {
+	guard, mailer, _ := newTestGuard()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		attempts []*Attempt
+	)
+	for i := 0; i < 5*testOptions.MaxFailures; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			attempt, err := guard.Begin("alice", fmt.Sprintf("10.0.%d.1", i))
+			if err != nil {
+				var blocked *BlockedError
+				assert.ErrorAs(t, err, &blocked)
+				return
+			}
+			mu.Lock()
+			attempts = append(attempts, attempt)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	require.Len(t, attempts, testOptions.MaxFailures, "attempts still being checked count against the limit")
+
+	for _, attempt := range attempts {
+		wg.Add(1)
+		go func(attempt *Attempt) {
+			defer wg.Done()
+			assert.NoError(t, guard.Fail(context.Background(), attempt))
+		}(attempt)
+	}
+	wg.Wait()
+
+	_, err := guard.Begin("alice", "10.0.200.1")
+	blocked := blockedError(t, err)
+	assert.True(t, blocked.Locked)
+	assert.Equal(t, testOptions.LockoutDuration, blocked.RetryAfter)
+	assert.Len(t, mailer.sent, 1)
+}
@@ -0,0 +1,100 @@
+// Package mail sends the emails of the service, such as account unlock and password reset links.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Outbox writes every message as an .eml file to a directory instead of sending it,
+// for development and for delivery by another process.
+type Outbox struct {
+	dir  string
+	from string
+	now  func() time.Time
+}
+
+// NewOutbox creates dir if it does not exist.
+func NewOutbox(dir, from string) (*Outbox, error) {
+	const op = "mail.NewOutbox"
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &Outbox{dir: dir, from: from, now: time.Now}, nil
+}
+
+func (o *Outbox) Send(ctx context.Context, msg Message) error {
+	const op = "mail.Outbox.Send"
+
+	id, err := newMessageID()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	now := o.now()
+	name := filepath.Join(o.dir, fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), id))
+
+	// written under a temporary name first so a reader never sees a partial message
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, format(o.from, msg, id, now), 0o640); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// WithToken returns the link to pageURL carrying token as the token query parameter.
+func WithToken(pageURL, token string) string {
+	separator := "?"
+	if strings.Contains(pageURL, "?") {
+		separator = "&"
+	}
+	return pageURL + separator + "token=" + url.QueryEscape(token)
+}
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message, id string, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@transferer>\r\n", id)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.Write(bytes.ReplaceAll([]byte(msg.Body), []byte("\n"), []byte("\r\n")))
+	return b.Bytes()
+}
+
+func newMessageID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate message id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
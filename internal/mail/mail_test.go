@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	outbox, err := NewOutbox(dir, "Transferer <no-reply@transferer.local>")
+	require.NoError(t, err)
+
+	err = outbox.Send(context.Background(), Message{
+		To:      "alice@example.com",
+		Subject: "Разблокировка аккаунта",
+		Body:    "line one\nline two",
+	})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, ".eml", filepath.Ext(files[0]))
+
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	msg, err := mail.ReadMessage(f)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Transferer <no-reply@transferer.local>", msg.Header.Get("From"))
+	assert.Equal(t, "alice@example.com", msg.Header.Get("To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Разблокировка аккаунта", subject)
+	_, err = msg.Header.Date()
+	assert.NoError(t, err)
+	body, err := io.ReadAll(msg.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "line one\r\nline two", string(body))
+}
@@ -0,0 +1,116 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// sendTimeout bounds a delivery when the context has no deadline.
+const sendTimeout = 30 * time.Second
+
+// SMTP sends messages through an SMTP server. The connection is upgraded with STARTTLS
+// when the server offers it, credentials are only sent over TLS or to localhost.
+type SMTP struct {
+	host     string
+	addr     string
+	auth     smtp.Auth
+	from     string
+	envelope string
+	now      func() time.Time
+}
+
+// NewSMTP returns a sender through host:port. Without username no authentication is done.
+func NewSMTP(host string, port int, username, password, from string) (*SMTP, error) {
+	const op = "mail.NewSMTP"
+
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid from address: %w", op, err)
+	}
+
+	sender := &SMTP{
+		host:     host,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		from:     from,
+		envelope: address.Address,
+		now:      time.Now,
+	}
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender, nil
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	const op = "mail.SMTP.Send"
+
+	id, err := newMessageID()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.send(ctx, msg.To, format(s.from, msg, id, s.now())); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *SMTP) send(ctx context.Context, to string, body []byte) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sendTimeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to greet: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if s.auth != nil {
+		if err := client.Auth(s.auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.envelope); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,98 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// delivery is what the fake SMTP server received.
+type delivery struct {
+	from, to string
+	data     []byte
+}
+
+// serveSMTP accepts one connection on l and speaks just enough SMTP to take a message.
+func serveSMTP(t *testing.T, l net.Listener, delivered chan<- delivery) {
+	t.Helper()
+
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	var d delivery
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case command == "EHLO" || command == "HELO":
+			text.PrintfLine("250 localhost")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			d.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			text.PrintfLine("250 ok")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			d.to = strings.Trim(line[len("RCPT TO:"):], "<> ")
+			text.PrintfLine("250 ok")
+		case command == "DATA":
+			text.PrintfLine("354 go ahead")
+			d.data, _ = io.ReadAll(text.DotReader())
+			text.PrintfLine("250 queued")
+			delivered <- d
+		case command == "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestSMTP_Send(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	delivered := make(chan delivery, 1)
+	go serveSMTP(t, l, delivered)
+
+	addr := l.Addr().(*net.TCPAddr)
+	sender, err := NewSMTP("127.0.0.1", addr.Port, "", "", "Transferer <no-reply@transferer.local>")
+	require.NoError(t, err)
+
+	err = sender.Send(context.Background(), Message{
+		To:      "alice@example.com",
+		Subject: "Password reset",
+		Body:    "reset it",
+	})
+	require.NoError(t, err)
+
+	d := <-delivered
+	assert.Equal(t, "no-reply@transferer.local", d.from)
+	assert.Equal(t, "alice@example.com", d.to)
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(d.data)))
+	require.NoError(t, err)
+	assert.Equal(t, "Password reset", msg.Header.Get("Subject"))
+	body, err := io.ReadAll(msg.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "reset it\n", string(body))
+}
+
+func TestNewSMTP_InvalidFrom(t *testing.T) {
+	_, err := NewSMTP("localhost", 25, "", "", "not an address")
+	assert.Error(t, err)
+}
@@ -0,0 +1,273 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	principalKey = "principal"
+	realm        = "transferer"
+)
+
+type TokenParser interface {
+	ParseToken(signedToken string) (*auth.JWTClaim, error)
+}
+
+type EmailVerificationChecker interface {
+	IsEmailVerified(username string) (bool, error)
+}
+
+type AccountFreezeChecker interface {
+	IsAccountFrozen(username string) (bool, error)
+}
+
+// Principal is the authenticated user of a request. AuthTime is when they last entered
+// a password or a code, AuthMethods how.
+type Principal struct {
+	UserID      int64
+	Username    string
+	Roles       []string
+	TokenID     string
+	SessionID   string
+	ExpiresAt   time.Time
+	AuthTime    time.Time
+	AuthMethods []string
+}
+
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthenticatedWithin tells whether the principal authenticated no longer than maxAge ago.
+func (p Principal) AuthenticatedWithin(maxAge time.Duration) bool {
+	return !p.AuthTime.IsZero() && time.Since(p.AuthTime) <= maxAge
+}
+
+// Authenticate validates the access token of the Authorization header and puts the Principal
+// into the context. Requests without a valid token are rejected with the WWW-Authenticate
+// challenge of RFC 6750.
+//
+// The header is "Bearer <token>". A bare token is accepted as well for older clients.
+func Authenticate(logger *zap.Logger, tokenParser TokenParser) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, err := bearerToken(c.GetHeader("Authorization"))
+		if err != nil {
+			abortWithChallenge(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		if tokenString == "" {
+			abortWithChallenge(c, http.StatusUnauthorized, "", "not authorized")
+			return
+		}
+
+		claims, err := tokenParser.ParseToken(tokenString)
+		switch {
+		case errors.Is(err, auth.ErrRevocationCheck):
+			logger.Error("failed to authenticate request", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]interface{}{
+				"status": "error",
+				"error":  "failed to authenticate request",
+			})
+			return
+		case errors.Is(err, auth.ErrTokenExpired), errors.Is(err, auth.ErrTokenRevoked):
+			abortWithChallenge(c, http.StatusUnauthorized, "invalid_token", err.Error())
+			return
+		case err != nil:
+			logger.Info("invalid access token", zap.Error(err))
+			abortWithChallenge(c, http.StatusUnauthorized, "invalid_token", "invalid token")
+			return
+		}
+
+		c.Set(principalKey, principalFromClaims(claims))
+		c.Next()
+	}
+}
+
+// PrincipalFrom returns the principal put into the context by Authenticate.
+func PrincipalFrom(c *gin.Context) (Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	principal, ok := value.(Principal)
+	return principal, ok
+}
+
+// RequireRecentAuth rejects requests of principals who did not authenticate within maxAge,
+// they have to re-authenticate first. It runs after Authenticate.
+func RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			abortWithChallenge(c, http.StatusUnauthorized, "", "not authorized")
+			return
+		}
+		if !principal.AuthenticatedWithin(maxAge) {
+			AbortWithStepUp(c, maxAge)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail rejects requests of principals who have not verified their email
+// with 403. It runs after Authenticate.
+func RequireVerifiedEmail(logger *zap.Logger, checker EmailVerificationChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			abortWithChallenge(c, http.StatusUnauthorized, "", "not authorized")
+			return
+		}
+
+		verified, err := checker.IsEmailVerified(principal.Username)
+		if err != nil {
+			logger.Error("failed to check email verification", zap.String("username", principal.Username), zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]interface{}{
+				"status": "error",
+				"error":  "failed to check email verification",
+			})
+			return
+		}
+		if !verified {
+			c.AbortWithStatusJSON(http.StatusForbidden, map[string]interface{}{
+				"status": "error",
+				"error":  "email is not verified",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests of principals who have none of roles with 403.
+// Roles are taken from the access token, so a changed role applies from the next token refresh.
+// It runs after Authenticate.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			abortWithChallenge(c, http.StatusUnauthorized, "", "not authorized")
+			return
+		}
+		for _, role := range roles {
+			if principal.HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, map[string]interface{}{
+			"status": "error",
+			"error":  "insufficient permissions",
+		})
+	}
+}
+
+// RequireActiveAccount rejects requests of principals whose account is frozen with 403.
+// It runs after Authenticate.
+func RequireActiveAccount(logger *zap.Logger, checker AccountFreezeChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			abortWithChallenge(c, http.StatusUnauthorized, "", "not authorized")
+			return
+		}
+
+		frozen, err := checker.IsAccountFrozen(principal.Username)
+		if err != nil {
+			logger.Error("failed to check account freeze", zap.String("username", principal.Username), zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]interface{}{
+				"status": "error",
+				"error":  "failed to check account status",
+			})
+			return
+		}
+		if frozen {
+			c.AbortWithStatusJSON(http.StatusForbidden, map[string]interface{}{
+				"status": "error",
+				"error":  "account is frozen",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// AbortWithStepUp rejects a request that needs an authentication no older than maxAge
+// with the challenge of RFC 9470.
+func AbortWithStepUp(c *gin.Context, maxAge time.Duration) {
+	c.Header("WWW-Authenticate", fmt.Sprintf(
+		"Bearer realm=%q, error=%q, error_description=%q, max_age=%d",
+		realm, "insufficient_user_authentication", "fresh authentication required", int64(maxAge.Seconds())))
+	c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]interface{}{
+		"status": "error",
+		"error":  "fresh authentication required",
+	})
+}
+
+// bearerToken extracts the token from an Authorization header. An empty token without
+// an error means there are no credentials, e.g. the header is missing or of another scheme.
+func bearerToken(header string) (string, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", nil
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found {
+		// a bare token of older clients
+		return header, nil
+	}
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", nil
+	}
+	token = strings.TrimSpace(token)
+	if token == "" || strings.Contains(token, " ") {
+		return "", errors.New("malformed bearer token")
+	}
+	return token, nil
+}
+
+func abortWithChallenge(c *gin.Context, status int, errorCode, description string) {
+	challenge := fmt.Sprintf("Bearer realm=%q", realm)
+	if errorCode != "" {
+		challenge += fmt.Sprintf(", error=%q, error_description=%q", errorCode, description)
+	}
+	c.Header("WWW-Authenticate", challenge)
+	c.AbortWithStatusJSON(status, map[string]interface{}{
+		"status": "error",
+		"error":  description,
+	})
+}
+
+func principalFromClaims(claims *auth.JWTClaim) Principal {
+	// tokens issued before user IDs were added to them have no subject
+	userID, _ := strconv.ParseInt(claims.Subject, 10, 64)
+	principal := Principal{
+		UserID:      userID,
+		Username:    claims.Username,
+		Roles:       claims.Roles,
+		TokenID:     claims.Id,
+		SessionID:   claims.SessionID,
+		ExpiresAt:   time.Unix(claims.ExpiresAt, 0),
+		AuthMethods: claims.AuthMethods,
+	}
+	if claims.AuthTime != 0 {
+		principal.AuthTime = time.Unix(claims.AuthTime, 0)
+	}
+	return principal
+}
@@ -0,0 +1,410 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/config"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type MockDenylist struct {
+	revoked map[string]bool
+	err     error
+}
+
+func (m *MockDenylist) IsAccessTokenRevoked(jti string) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	return m.revoked[jti], nil
+}
+
+func newKeySet(t *testing.T, ttl time.Duration, denylist auth.Denylist) *auth.KeySet {
+	t.Helper()
+
+	keySet, err := auth.NewKeySet(config.JWTConfig{JWTSecret: "secret", AccessTokenTTL: ttl}, denylist)
+	require.NoError(t, err)
+	return keySet
+}
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	denylist := &MockDenylist{revoked: map[string]bool{}}
+	keySet := newKeySet(t, time.Hour, denylist)
+	subject := auth.Subject{UserID: 42, Username: "alice", Roles: []string{auth.RoleUser}}
+	valid, err := keySet.IssueAccessToken(subject, "session-1")
+	require.NoError(t, err)
+	revoked, err := keySet.IssueAccessToken(subject, "session-2")
+	require.NoError(t, err)
+	denylist.revoked[revoked.ID] = true
+	expired, err := newKeySet(t, -time.Minute, nil).IssueAccessToken(subject, "")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name              string
+		header            string
+		expectedStatus    int
+		expectedChallenge string
+		expectedResponse  string
+	}{
+		{
+			name:           "Bearer",
+			header:         "Bearer " + valid.Token,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Bearer_Lowercase",
+			header:         "bearer " + valid.Token,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Bare_Token",
+			header:         valid.Token,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:              "No_Header",
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="transferer"`,
+			expectedResponse:  `{"status":"error","error":"not authorized"}`,
+		},
+		{
+			name:              "Other_Scheme",
+			header:            "Basic YWxpY2U6c2VjcmV0",
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="transferer"`,
+			expectedResponse:  `{"status":"error","error":"not authorized"}`,
+		},
+		{
+			name:              "Malformed_Bearer",
+			header:            "Bearer a b",
+			expectedStatus:    http.StatusBadRequest,
+			expectedChallenge: `Bearer realm="transferer", error="invalid_request", error_description="malformed bearer token"`,
+			expectedResponse:  `{"status":"error","error":"malformed bearer token"}`,
+		},
+		{
+			name:              "Invalid_Token",
+			header:            "Bearer invalid",
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="transferer", error="invalid_token", error_description="invalid token"`,
+			expectedResponse:  `{"status":"error","error":"invalid token"}`,
+		},
+		{
+			name:              "Expired_Token",
+			header:            "Bearer " + expired.Token,
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="transferer", error="invalid_token", error_description="token expired"`,
+			expectedResponse:  `{"status":"error","error":"token expired"}`,
+		},
+		{
+			name:              "Revoked_Token",
+			header:            "Bearer " + revoked.Token,
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="transferer", error="invalid_token", error_description="token revoked"`,
+			expectedResponse:  `{"status":"error","error":"token revoked"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+
+			var principal Principal
+			router.GET("/", Authenticate(zap.NewNop(), keySet), func(c *gin.Context) {
+				principal, _ = PrincipalFrom(c)
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			assert.Equal(t, tc.expectedChallenge, w.Header().Get("WWW-Authenticate"))
+			if tc.expectedStatus != http.StatusOK {
+				assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+				return
+			}
+
+			assert.Equal(t, Principal{
+				UserID:    42,
+				Username:  "alice",
+				Roles:     []string{auth.RoleUser},
+				TokenID:   valid.ID,
+				SessionID: "session-1",
+				ExpiresAt: time.Unix(valid.ExpiresAt.Unix(), 0),
+			}, principal)
+			assert.True(t, principal.HasRole(auth.RoleUser))
+		})
+	}
+}
+
+func TestAuthenticate_RevocationCheckError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keySet := newKeySet(t, time.Hour, &MockDenylist{err: errors.New("db is down")})
+	token, err := keySet.IssueAccessToken(auth.Subject{UserID: 1, Username: "alice"}, "")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("Authorization", "Bearer "+token.Token)
+
+	Authenticate(zap.NewNop(), keySet)(c)
+
+	assert.True(t, c.IsAborted())
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"status":"error","error":"failed to authenticate request"}`, w.Body.String())
+	_, ok := PrincipalFrom(c)
+	assert.False(t, ok)
+}
+
+func TestRequireRecentAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keySet := newKeySet(t, time.Hour, nil)
+
+	testCases := []struct {
+		name             string
+		authTime         time.Time
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:           "Fresh",
+			authTime:       time.Now().Add(-time.Minute),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:             "Stale",
+			authTime:         time.Now().Add(-time.Hour),
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"fresh authentication required"}`,
+		},
+		{
+			name:             "No_Auth_Time",
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"status":"error","error":"fresh authentication required"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := keySet.IssueAccessToken(auth.Subject{
+				UserID:      1,
+				Username:    "alice",
+				AuthTime:    tc.authTime,
+				AuthMethods: []string{auth.MethodPassword},
+			}, "")
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+			router.POST("/", Authenticate(zap.NewNop(), keySet), RequireRecentAuth(5*time.Minute), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token.Token)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			if tc.expectedStatus == http.StatusOK {
+				return
+			}
+			assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			assert.Equal(t,
+				`Bearer realm="transferer", error="insufficient_user_authentication", error_description="fresh authentication required", max_age=300`,
+				w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+type MockEmailVerificationChecker struct {
+	verified bool
+	err      error
+}
+
+func (m *MockEmailVerificationChecker) IsEmailVerified(username string) (bool, error) {
+	return m.verified, m.err
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keySet := newKeySet(t, time.Hour, nil)
+	token, err := keySet.IssueAccessToken(auth.Subject{UserID: 1, Username: "alice"}, "")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name             string
+		checker          *MockEmailVerificationChecker
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:           "Verified",
+			checker:        &MockEmailVerificationChecker{verified: true},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:             "Not_Verified",
+			checker:          &MockEmailVerificationChecker{},
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"status":"error","error":"email is not verified"}`,
+		},
+		{
+			name:             "Storage_Error",
+			checker:          &MockEmailVerificationChecker{err: errors.New("db is down")},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to check email verification"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+			router.POST("/", Authenticate(zap.NewNop(), keySet), RequireVerifiedEmail(zap.NewNop(), tc.checker), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token.Token)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			if tc.expectedStatus != http.StatusOK {
+				assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keySet := newKeySet(t, time.Hour, nil)
+
+	testCases := []struct {
+		name           string
+		roles          []string
+		expectedStatus int
+	}{
+		{
+			name:           "Admin",
+			roles:          []string{auth.RoleUser, auth.RoleAdmin},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Auditor",
+			roles:          []string{auth.RoleAuditor},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "User",
+			roles:          []string{auth.RoleUser},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "No_Roles",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := keySet.IssueAccessToken(auth.Subject{UserID: 1, Username: "alice", Roles: tc.roles}, "")
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+			router.GET("/", Authenticate(zap.NewNop(), keySet), RequireRole(auth.RoleAdmin, auth.RoleAuditor), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token.Token)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			if tc.expectedStatus != http.StatusOK {
+				assert.JSONEq(t, `{"status":"error","error":"insufficient permissions"}`, w.Body.String())
+			}
+		})
+	}
+}
+
+type MockAccountFreezeChecker struct {
+	frozen bool
+	err    error
+}
+
+func (m *MockAccountFreezeChecker) IsAccountFrozen(username string) (bool, error) {
+	return m.frozen, m.err
+}
+
+func TestRequireActiveAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keySet := newKeySet(t, time.Hour, nil)
+	token, err := keySet.IssueAccessToken(auth.Subject{UserID: 1, Username: "alice"}, "")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name             string
+		checker          *MockAccountFreezeChecker
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:           "Active",
+			checker:        &MockAccountFreezeChecker{},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:             "Frozen",
+			checker:          &MockAccountFreezeChecker{frozen: true},
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"status":"error","error":"account is frozen"}`,
+		},
+		{
+			name:             "Storage_Error",
+			checker:          &MockAccountFreezeChecker{err: errors.New("db is down")},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: `{"status":"error","error":"failed to check account status"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			_, router := gin.CreateTestContext(w)
+			router.POST("/", Authenticate(zap.NewNop(), keySet), RequireActiveAccount(zap.NewNop(), tc.checker), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token.Token)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code, "Status code mismatch")
+			if tc.expectedStatus != http.StatusOK {
+				assert.JSONEq(t, tc.expectedResponse, w.Body.String(), "Response body mismatch")
+			}
+		})
+	}
+}
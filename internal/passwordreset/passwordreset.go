@@ -0,0 +1,131 @@
+// Package passwordreset lets users who forgot their password set a new one from a link
+// with a one-time token mailed to the email of their account.
+package passwordreset
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/mail"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired password reset token")
+	// ErrRequestedRecently means a link was mailed to the email less than the resend interval ago.
+	ErrRequestedRecently = errors.New("password reset was requested recently")
+)
+
+type Store interface {
+	GetUserByEmail(email string) (*models.User, error)
+	CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken, notBefore time.Time) error
+	ResetPassword(ctx context.Context, hash, passwordHash string) (string, error)
+}
+
+// Options are the settings of a Manager, see config.PasswordResetConfig.
+type Options struct {
+	TokenTTL time.Duration
+	// ResendInterval is how long after a link another one can be mailed to the same email.
+	ResendInterval time.Duration
+	// URL is the page the reset link points to, the token is added as the token query
+	// parameter. Without it the token itself is mailed.
+	URL string
+}
+
+type Manager struct {
+	store  Store
+	mailer mail.Mailer
+	opts   Options
+	now    func() time.Time
+}
+
+func NewManager(store Store, mailer mail.Mailer, opts Options) *Manager {
+	return &Manager{
+		store:  store,
+		mailer: mailer,
+		opts:   opts,
+		now:    time.Now,
+	}
+}
+
+// Request mails a password reset link to email. Nothing is sent, and no error returned,
+// when no user has this email, so callers cannot tell registered emails apart.
+// Returns ErrRequestedRecently when a link was mailed to email less than the resend interval ago.
+func (m *Manager) Request(ctx context.Context, email string) error {
+	const op = "passwordreset.Manager.Request"
+
+	user, err := m.store.GetUserByEmail(email)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("%s: failed to generate password reset token: %w", op, err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	now := m.now()
+	err = m.store.CreatePasswordResetToken(ctx, &models.PasswordResetToken{
+		Hash:      hashToken(token),
+		Username:  user.Username,
+		ExpiresAt: now.Add(m.opts.TokenTTL),
+	}, now.Add(-m.opts.ResendInterval))
+	if errors.Is(err, storage.ErrPasswordResetRequestedRecently) {
+		return fmt.Errorf("%s: %w", op, ErrRequestedRecently)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = m.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Transferer password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"a password reset was requested for your account. Set a new password with %s\n"+
+			"It can be used once within %s and signs you out everywhere.\n\n"+
+			"If you did not request it, ignore this email, your password stays the same.\n",
+			user.Username, m.link(token), m.opts.TokenTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Reset sets passwordHash as the password of the user token was mailed to and returns
+// the username. A token can be used once.
+func (m *Manager) Reset(ctx context.Context, token, passwordHash string) (string, error) {
+	const op = "passwordreset.Manager.Reset"
+
+	username, err := m.store.ResetPassword(ctx, hashToken(token), passwordHash)
+	if errors.Is(err, storage.ErrPasswordResetTokenNotFound) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return username, nil
+}
+
+func (m *Manager) link(token string) string {
+	if m.opts.URL == "" {
+		return "the reset token " + token
+	}
+	return "the link " + mail.WithToken(m.opts.URL, token)
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
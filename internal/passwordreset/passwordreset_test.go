@@ -0,0 +1,145 @@
+package passwordreset
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/mail"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps users and reset tokens the way the postgres storage does.
+type memoryStore struct {
+	users  map[string]*models.User
+	tokens map[string]*models.PasswordResetToken
+	sentAt map[string]time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		users: map[string]*models.User{
+			"alice": {Username: "alice", Email: "alice@example.com", PasswordHash: "old"},
+		},
+		tokens: map[string]*models.PasswordResetToken{},
+		sentAt: map[string]time.Time{},
+	}
+}
+
+func (s *memoryStore) GetUserByEmail(email string) (*models.User, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, storage.ErrUserNotFound
+}
+
+func (s *memoryStore) CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken, notBefore time.Time) error {
+	if s.sentAt[token.Username].After(notBefore) {
+		return storage.ErrPasswordResetRequestedRecently
+	}
+	copied := *token
+	s.tokens[token.Hash] = &copied
+	s.sentAt[token.Username] = time.Now()
+	return nil
+}
+
+func (s *memoryStore) ResetPassword(ctx context.Context, hash, passwordHash string) (string, error) {
+	token, ok := s.tokens[hash]
+	if !ok || !token.ExpiresAt.After(time.Now()) {
+		return "", storage.ErrPasswordResetTokenNotFound
+	}
+	for h, other := range s.tokens {
+		if other.Username == token.Username {
+			delete(s.tokens, h)
+		}
+	}
+	s.users[token.Username].PasswordHash = passwordHash
+	return token.Username, nil
+}
+
+type memoryMailer struct {
+	sent []mail.Message
+}
+
+func (m *memoryMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var linkPattern = regexp.MustCompile(`https://transferer\.example/reset\?token=([A-Za-z0-9_-]+)`)
+
+func TestManager_Reset(t *testing.T) {
+	ctx := context.Background()
+	store, mailer := newMemoryStore(), &memoryMailer{}
+	manager := NewManager(store, mailer, Options{TokenTTL: time.Hour, URL: "https://transferer.example/reset"})
+
+	require.NoError(t, manager.Request(ctx, "alice@example.com"))
+	require.NoError(t, manager.Request(ctx, "alice@example.com"))
+	require.Len(t, mailer.sent, 2)
+	assert.Equal(t, "alice@example.com", mailer.sent[0].To)
+
+	first := linkPattern.FindStringSubmatch(mailer.sent[0].Body)
+	second := linkPattern.FindStringSubmatch(mailer.sent[1].Body)
+	require.Len(t, first, 2, "the mail contains the reset link")
+	require.Len(t, second, 2)
+	assert.NotEqual(t, first[1], second[1])
+	for hash := range store.tokens {
+		assert.NotContains(t, []string{first[1], second[1]}, hash, "only hashes are stored")
+	}
+
+	_, err := manager.Reset(ctx, "wrong", "new")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	username, err := manager.Reset(ctx, second[1], "new")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", username)
+	assert.Equal(t, "new", store.users["alice"].PasswordHash)
+
+	_, err = manager.Reset(ctx, second[1], "newer")
+	assert.ErrorIs(t, err, ErrInvalidToken, "a token is used once")
+	_, err = manager.Reset(ctx, first[1], "newer")
+	assert.ErrorIs(t, err, ErrInvalidToken, "a reset revokes the other tokens")
+}
+
+func TestManager_ResetExpired(t *testing.T) {
+	ctx := context.Background()
+	store, mailer := newMemoryStore(), &memoryMailer{}
+	manager := NewManager(store, mailer, Options{TokenTTL: -time.Minute, URL: "https://transferer.example/reset"})
+
+	require.NoError(t, manager.Request(ctx, "alice@example.com"))
+	match := linkPattern.FindStringSubmatch(mailer.sent[0].Body)
+	require.Len(t, match, 2)
+
+	_, err := manager.Reset(ctx, match[1], "new")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, "old", store.users["alice"].PasswordHash)
+}
+
+func TestManager_RequestUnknownEmail(t *testing.T) {
+	mailer := &memoryMailer{}
+	manager := NewManager(newMemoryStore(), mailer, Options{TokenTTL: time.Hour})
+
+	require.NoError(t, manager.Request(context.Background(), "nobody@example.com"))
+	assert.Empty(t, mailer.sent)
+}
+
+func TestManager_RequestTooSoon(t *testing.T) {
+	ctx := context.Background()
+	mailer := &memoryMailer{}
+	manager := NewManager(newMemoryStore(), mailer, Options{TokenTTL: time.Hour, ResendInterval: time.Minute})
+
+	require.NoError(t, manager.Request(ctx, "alice@example.com"))
+	err := manager.Request(ctx, "alice@example.com")
+	assert.ErrorIs(t, err, ErrRequestedRecently)
+	assert.Len(t, mailer.sent, 1, "no second mail within the resend interval")
+
+	manager.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	require.NoError(t, manager.Request(ctx, "alice@example.com"))
+	assert.Len(t, mailer.sent, 2)
+}
@@ -0,0 +1,119 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"golang.org/x/sync/singleflight"
+)
+
+// Snapshot is a set of quotes and the time they were fetched from the provider.
+// Stale is set when the provider failed and an older snapshot is served instead.
+type Snapshot struct {
+	Quotes    map[string]money.Amount
+	FetchedAt time.Time
+	Stale     bool
+}
+
+// refreshTimeout bounds a refresh, which does not end with the context of any one caller.
+const refreshTimeout = 10 * time.Second
+
+// Cache keeps the last quotes of a provider in memory for ttl.
+// Concurrent refreshes are collapsed into one call to the provider. If a refresh fails,
+// the last snapshot keeps being served until it is maxStale old.
+type Cache struct {
+	provider       Provider
+	ttl            time.Duration
+	maxStale       time.Duration
+	refreshTimeout time.Duration
+	now            func() time.Time
+
+	group singleflight.Group
+
+	mu       sync.RWMutex
+	snapshot *Snapshot
+}
+
+func NewCache(provider Provider, ttl, maxStale time.Duration) *Cache {
+	return &Cache{
+		provider:       provider,
+		ttl:            ttl,
+		maxStale:       maxStale,
+		refreshTimeout: refreshTimeout,
+		now:            time.Now,
+	}
+}
+
+// GetRates implements Provider.
+func (c *Cache) GetRates(ctx context.Context) (map[string]money.Amount, error) {
+	snapshot, err := c.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Quotes, nil
+}
+
+// Snapshot returns the cached quotes, refreshing them first if they are older than the TTL.
+// The refresh is shared by all callers waiting for it, so it runs detached from ctx and
+// each caller only stops waiting when its own ctx is done.
+func (c *Cache) Snapshot(ctx context.Context) (Snapshot, error) {
+	c.mu.RLock()
+	cached := c.snapshot
+	c.mu.RUnlock()
+
+	if cached != nil && c.now().Sub(cached.FetchedAt) < c.ttl {
+		return cached.copy(), nil
+	}
+
+	ch := c.group.DoChan("rates", func() (interface{}, error) {
+		refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.refreshTimeout)
+		defer cancel()
+		return c.refresh(refreshCtx)
+	})
+
+	select {
+	case result := <-ch:
+		if result.Err != nil {
+			return Snapshot{}, result.Err
+		}
+		return result.Val.(*Snapshot).copy(), nil
+	case <-ctx.Done():
+		return Snapshot{}, ctx.Err()
+	}
+}
+
+func (c *Cache) refresh(ctx context.Context) (*Snapshot, error) {
+	const op = "rates.Cache.refresh"
+
+	quotes, err := c.provider.GetRates(ctx)
+	if err == nil {
+		snapshot := &Snapshot{Quotes: quotes, FetchedAt: c.now()}
+		c.mu.Lock()
+		c.snapshot = snapshot
+		c.mu.Unlock()
+		return snapshot, nil
+	}
+
+	c.mu.RLock()
+	cached := c.snapshot
+	c.mu.RUnlock()
+
+	if cached != nil && c.now().Sub(cached.FetchedAt) <= c.maxStale {
+		stale := *cached
+		stale.Stale = true
+		return &stale, nil
+	}
+	return nil, fmt.Errorf("%s: %w", op, err)
+}
+
+// copy returns the snapshot with its own quotes map, so callers cannot change the cache.
+func (s *Snapshot) copy() Snapshot {
+	quotes := make(map[string]money.Amount, len(s.Quotes))
+	for pair, rate := range s.Quotes {
+		quotes[pair] = rate
+	}
+	return Snapshot{Quotes: quotes, FetchedAt: s.FetchedAt, Stale: s.Stale}
+}
@@ -0,0 +1,160 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingProvider struct {
+	calls   atomic.Int32
+	err     error
+	release chan struct{}
+}
+
+func (p *countingProvider) GetRates(ctx context.Context) (map[string]money.Amount, error) {
+	p.calls.Add(1)
+	if p.release != nil {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return map[string]money.Amount{"USD_EUR": money.MustParse("0.9")}, nil
+}
+
+func newTestCache(provider Provider, now *time.Time) *Cache {
+	cache := NewCache(provider, 10*time.Second, time.Minute)
+	cache.now = func() time.Time { return *now }
+	return cache
+}
+
+func TestCacheReusesRatesWithinTTL(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	provider := &countingProvider{}
+	cache := newTestCache(provider, &now)
+
+	snapshot, err := cache.Snapshot(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, now, snapshot.FetchedAt)
+	assert.False(t, snapshot.Stale)
+
+	now = now.Add(5 * time.Second)
+	_, err = cache.Snapshot(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), provider.calls.Load())
+
+	now = now.Add(5 * time.Second)
+	snapshot, err = cache.Snapshot(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), provider.calls.Load())
+	assert.Equal(t, now, snapshot.FetchedAt)
+}
+
+func TestCacheCollapsesConcurrentRefreshes(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	provider := &countingProvider{release: make(chan struct{})}
+	cache := newTestCache(provider, &now)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			quotes, err := cache.GetRates(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "0.9", quotes["USD_EUR"].String())
+		}()
+	}
+
+	// let the goroutines pile up on the first refresh
+	time.Sleep(50 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), provider.calls.Load())
+}
+
+func TestCacheRefreshOutlivesCanceledCaller(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	provider := &countingProvider{release: make(chan struct{})}
+	cache := newTestCache(provider, &now)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := cache.Snapshot(ctx)
+		firstErr <- err
+	}()
+	require.Eventually(t, func() bool { return provider.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	// the caller that started the refresh gives up without waiting for it
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		snapshot, err := cache.Snapshot(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "0.9", snapshot.Quotes["USD_EUR"].String())
+	}()
+	close(provider.release)
+	<-done
+
+	assert.Equal(t, int32(1), provider.calls.Load())
+}
+
+func TestCacheServesStaleRatesOnError(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	fetchedAt := now
+	provider := &countingProvider{}
+	cache := newTestCache(provider, &now)
+
+	_, err := cache.Snapshot(context.Background())
+	require.NoError(t, err)
+
+	provider.err = errors.New("exchanger is down")
+	now = now.Add(30 * time.Second)
+	snapshot, err := cache.Snapshot(context.Background())
+	require.NoError(t, err)
+	assert.True(t, snapshot.Stale)
+	assert.Equal(t, fetchedAt, snapshot.FetchedAt)
+	assert.Equal(t, "0.9", snapshot.Quotes["USD_EUR"].String())
+
+	now = fetchedAt.Add(time.Minute + time.Second)
+	_, err = cache.Snapshot(context.Background())
+	assert.Error(t, err)
+}
+
+func TestCacheFailsWithoutRates(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestCache(&countingProvider{err: errors.New("exchanger is down")}, &now)
+
+	_, err := cache.GetRates(context.Background())
+	assert.Error(t, err)
+}
+
+func TestCacheSnapshotsAreCopies(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestCache(&countingProvider{}, &now)
+
+	quotes, err := cache.GetRates(context.Background())
+	require.NoError(t, err)
+	quotes["USD_EUR"] = money.Zero
+
+	quotes, err = cache.GetRates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "0.9", quotes["USD_EUR"].String())
+}
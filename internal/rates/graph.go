@@ -0,0 +1,168 @@
+// Package rates turns currency pair quotes into exchange rates between any two currencies.
+package rates
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
+// MaxLegs bounds how many conversions a cross rate may chain.
+const MaxLegs = 3
+
+var ErrNoRate = errors.New("no exchange rate")
+
+// Pair returns the key a quote for from and to is stored under, e.g. USD_EUR.
+func Pair(from, to string) string {
+	return from + "_" + to
+}
+
+// ParsePair splits a pair key such as USD_EUR into its currencies.
+func ParsePair(pair string) (from, to string, err error) {
+	from, to, ok := strings.Cut(pair, "_")
+	if !ok || from == "" || to == "" || strings.Contains(to, "_") {
+		return "", "", fmt.Errorf("malformed currency pair %q", pair)
+	}
+	return from, to, nil
+}
+
+// edge converts one currency into another: an amount is multiplied by num and divided by den.
+// Keeping the fraction lets a path be divided once instead of rounding at every inverse leg.
+type edge struct {
+	num, den money.Amount
+	quoted   bool
+}
+
+// Graph holds pair quotes as a graph of currencies.
+// Every quote is usable in both directions, a direct quote wins over a derived inverse.
+type Graph struct {
+	edges map[string]map[string]edge
+}
+
+func NewGraph() *Graph {
+	return &Graph{edges: make(map[string]map[string]edge)}
+}
+
+// NewGraphFromQuotes builds a graph from quotes keyed by pair, as the exchanger returns them.
+func NewGraphFromQuotes(quotes map[string]money.Amount) (*Graph, error) {
+	g := NewGraph()
+	for pair, rate := range quotes {
+		from, to, err := ParsePair(pair)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.Add(from, to, rate); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// Add records that one unit of from buys rate units of to.
+// Quotes of a currency against itself carry no information and are skipped.
+func (g *Graph) Add(from, to string, rate money.Amount) error {
+	if !rate.IsPositive() {
+		return fmt.Errorf("rate for %s must be positive", Pair(from, to))
+	}
+	if from == to {
+		return nil
+	}
+
+	one := money.NewFromInt(1)
+	g.set(from, to, edge{num: rate, den: one, quoted: true})
+	if inverse, ok := g.edges[to][from]; !ok || !inverse.quoted {
+		g.set(to, from, edge{num: one, den: rate})
+	}
+	return nil
+}
+
+func (g *Graph) set(from, to string, e edge) {
+	if g.edges[from] == nil {
+		g.edges[from] = make(map[string]edge)
+	}
+	g.edges[from][to] = e
+}
+
+// Currencies returns every currency that has at least one quote, sorted.
+func (g *Graph) Currencies() []string {
+	currencies := make([]string, 0, len(g.edges))
+	for currency := range g.edges {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// CrossRate is the rate between two currencies and the currencies it was computed through,
+// From and To included.
+type CrossRate struct {
+	From string
+	To   string
+	Rate money.Amount
+	Path []string
+}
+
+// Rate returns the best rate for converting from into to: the one that buys the most units
+// of to, through at most MaxLegs conversions. Among equal rates the shorter path wins.
+func (g *Graph) Rate(from, to string) (CrossRate, error) {
+	if from == to {
+		if _, ok := g.edges[from]; !ok {
+			return CrossRate{}, fmt.Errorf("%w for %s", ErrNoRate, from)
+		}
+		return CrossRate{From: from, To: to, Rate: money.NewFromInt(1), Path: []string{from}}, nil
+	}
+
+	best := CrossRate{From: from, To: to}
+	one := money.NewFromInt(1)
+	path := []string{from}
+	visited := map[string]bool{from: true}
+
+	var walk func(currency string, num, den money.Amount)
+	walk = func(currency string, num, den money.Amount) {
+		if currency == to {
+			rate := num.Div(den)
+			better := best.Path == nil || rate.GreaterThan(best.Rate) ||
+				(rate.Equal(best.Rate) && len(path) < len(best.Path))
+			if better {
+				best.Rate = rate
+				best.Path = append([]string(nil), path...)
+			}
+			return
+		}
+		if len(path) > MaxLegs {
+			return
+		}
+
+		for _, next := range g.neighbours(currency) {
+			if visited[next] {
+				continue
+			}
+			e := g.edges[currency][next]
+			visited[next] = true
+			path = append(path, next)
+			walk(next, num.Mul(e.num), den.Mul(e.den))
+			path = path[:len(path)-1]
+			visited[next] = false
+		}
+	}
+	walk(from, one, one)
+
+	if best.Path == nil || !best.Rate.IsPositive() {
+		return CrossRate{}, fmt.Errorf("%w from %s to %s", ErrNoRate, from, to)
+	}
+	return best, nil
+}
+
+// neighbours returns the currencies reachable from currency in one conversion, sorted so
+// that ties are always resolved the same way.
+func (g *Graph) neighbours(currency string) []string {
+	next := make([]string, 0, len(g.edges[currency]))
+	for to := range g.edges[currency] {
+		next = append(next, to)
+	}
+	sort.Strings(next)
+	return next
+}
@@ -0,0 +1,90 @@
+package rates
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePair(t *testing.T) {
+	from, to, err := ParsePair("USD_EUR")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", from)
+	assert.Equal(t, "EUR", to)
+
+	for _, pair := range []string{"USDEUR", "_EUR", "USD_", "USD_EUR_GBP"} {
+		_, _, err := ParsePair(pair)
+		assert.Error(t, err, pair)
+	}
+}
+
+func TestGraphRate(t *testing.T) {
+	graph, err := NewGraphFromQuotes(map[string]money.Amount{
+		"RUB_USD": money.MustParse("0.0125"),
+		"RUB_EUR": money.MustParse("0.01"),
+		"RUB_RUB": money.MustParse("1"),
+		"EUR_GBP": money.MustParse("0.8"),
+		"CHF_JPY": money.MustParse("170"),
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		from, to     string
+		expectedRate string
+		expectedPath []string
+	}{
+		{name: "Direct", from: "RUB", to: "USD", expectedRate: "0.0125", expectedPath: []string{"RUB", "USD"}},
+		{name: "Inverse", from: "USD", to: "RUB", expectedRate: "80", expectedPath: []string{"USD", "RUB"}},
+		{name: "Cross", from: "USD", to: "EUR", expectedRate: "0.8", expectedPath: []string{"USD", "RUB", "EUR"}},
+		{name: "Two Hops", from: "USD", to: "GBP", expectedRate: "0.64", expectedPath: []string{"USD", "RUB", "EUR", "GBP"}},
+		{name: "Same Currency", from: "EUR", to: "EUR", expectedRate: "1", expectedPath: []string{"EUR"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rate, err := graph.Rate(tc.from, tc.to)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRate, rate.Rate.String())
+			assert.Equal(t, tc.expectedPath, rate.Path)
+		})
+	}
+
+	_, err = graph.Rate("USD", "JPY")
+	assert.True(t, errors.Is(err, ErrNoRate))
+	_, err = graph.Rate("XXX", "XXX")
+	assert.True(t, errors.Is(err, ErrNoRate))
+}
+
+func TestGraphPrefersBestRate(t *testing.T) {
+	graph := NewGraph()
+	require.NoError(t, graph.Add("USD", "EUR", money.MustParse("0.9")))
+	require.NoError(t, graph.Add("USD", "GBP", money.MustParse("0.8")))
+	require.NoError(t, graph.Add("GBP", "EUR", money.MustParse("1.2")))
+
+	rate, err := graph.Rate("USD", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, "0.96", rate.Rate.String())
+	assert.Equal(t, []string{"USD", "GBP", "EUR"}, rate.Path)
+}
+
+func TestGraphDirectQuoteWinsOverInverse(t *testing.T) {
+	graph := NewGraph()
+	require.NoError(t, graph.Add("USD", "EUR", money.MustParse("0.9")))
+	require.NoError(t, graph.Add("EUR", "USD", money.MustParse("1.1")))
+
+	rate, err := graph.Rate("EUR", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "1.1", rate.Rate.String())
+
+	rate, err = graph.Rate("USD", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, "0.9", rate.Rate.String())
+}
+
+func TestGraphRejectsNonPositiveRate(t *testing.T) {
+	assert.Error(t, NewGraph().Add("USD", "EUR", money.Zero))
+}
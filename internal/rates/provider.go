@@ -0,0 +1,120 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
+	"github.com/Foreground-Eclipse/transferer/internal/exchanger"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnavailable means the rate source cannot be reached right now and retrying later may help.
+var ErrUnavailable = errors.New("exchange rates are temporarily unavailable")
+
+// Provider is a source of pair quotes keyed by pair, e.g. {"RUB_USD": "0.012"}.
+type Provider interface {
+	GetRates(ctx context.Context) (map[string]money.Amount, error)
+}
+
+// GRPCProvider gets quotes from the grpcexchanger service.
+type GRPCProvider struct {
+	client pb.ExchangeServiceClient
+}
+
+func NewGRPCProvider(client pb.ExchangeServiceClient) *GRPCProvider {
+	return &GRPCProvider{client: client}
+}
+
+func (p *GRPCProvider) GetRates(ctx context.Context) (map[string]money.Amount, error) {
+	const op = "rates.GRPCProvider.GetRates"
+
+	response, err := p.client.GetExchangeRates(ctx, &pb.Empty{})
+	if errors.Is(err, exchanger.ErrCircuitOpen) {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrUnavailable, err)
+	}
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrUnavailable, err)
+	default:
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	quotes := make(map[string]money.Amount, len(response.Rates))
+	for pair, rate := range response.Rates {
+		quotes[pair] = money.NewFromFloat32(rate)
+	}
+	return quotes, nil
+}
+
+// StaticProvider serves a fixed set of quotes, e.g. from config.
+type StaticProvider struct {
+	quotes map[string]money.Amount
+}
+
+func NewStaticProvider(quotes map[string]money.Amount) (*StaticProvider, error) {
+	if _, err := NewGraphFromQuotes(quotes); err != nil {
+		return nil, fmt.Errorf("rates.NewStaticProvider: %w", err)
+	}
+	return &StaticProvider{quotes: quotes}, nil
+}
+
+// ParseStaticQuotes parses quotes given as strings, e.g. {"USD_EUR": "0.92"}.
+func ParseStaticQuotes(raw map[string]string) (map[string]money.Amount, error) {
+	quotes := make(map[string]money.Amount, len(raw))
+	for pair, value := range raw {
+		rate, err := money.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate for %s: %w", pair, err)
+		}
+		quotes[pair] = rate
+	}
+	return quotes, nil
+}
+
+func (p *StaticProvider) GetRates(ctx context.Context) (map[string]money.Amount, error) {
+	quotes := make(map[string]money.Amount, len(p.quotes))
+	for pair, rate := range p.quotes {
+		quotes[pair] = rate
+	}
+	return quotes, nil
+}
+
+// FileProvider reads quotes from a JSON file such as {"USD_EUR": "0.92", "RUB_USD": "0.011"}.
+// The file is read on every call, so rates can be changed without a restart.
+type FileProvider struct {
+	path string
+}
+
+// NewFileProvider checks that path holds valid quotes and returns a provider reading it.
+func NewFileProvider(path string) (*FileProvider, error) {
+	p := &FileProvider{path: path}
+	if _, err := p.GetRates(context.Background()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *FileProvider) GetRates(ctx context.Context) (map[string]money.Amount, error) {
+	const op = "rates.FileProvider.GetRates"
+
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var quotes map[string]money.Amount
+	if err := json.Unmarshal(data, &quotes); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse %s: %w", op, p.path, err)
+	}
+	if _, err := NewGraphFromQuotes(quotes); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return quotes, nil
+}
@@ -0,0 +1,100 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/Foreground-Eclipse/grpcexchanger/proto"
+	"github.com/Foreground-Eclipse/transferer/internal/exchanger"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type mockExchangeServiceClient struct {
+	rates map[string]float32
+	err   error
+}
+
+func (m *mockExchangeServiceClient) GetExchangeRates(ctx context.Context, in *pb.Empty, opts ...grpc.CallOption) (*pb.ExchangeRatesResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &pb.ExchangeRatesResponse{Rates: m.rates}, nil
+}
+
+func (m *mockExchangeServiceClient) GetExchangeRateForCurrency(ctx context.Context, in *pb.CurrencyRequest, opts ...grpc.CallOption) (*pb.ExchangeRateResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestGRPCProvider(t *testing.T) {
+	provider := NewGRPCProvider(&mockExchangeServiceClient{rates: map[string]float32{"RUB_USD": 0.012}})
+	quotes, err := provider.GetRates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "0.012", quotes["RUB_USD"].String())
+
+	provider = NewGRPCProvider(&mockExchangeServiceClient{err: errors.New("unavailable")})
+	_, err = provider.GetRates(context.Background())
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrUnavailable))
+
+	for _, err := range []error{status.Error(codes.Unavailable, "down"), exchanger.ErrCircuitOpen} {
+		provider = NewGRPCProvider(&mockExchangeServiceClient{err: err})
+		_, err = provider.GetRates(context.Background())
+		assert.True(t, errors.Is(err, ErrUnavailable), err)
+	}
+}
+
+func TestStaticProvider(t *testing.T) {
+	quotes, err := ParseStaticQuotes(map[string]string{"USD_EUR": "0.92"})
+	require.NoError(t, err)
+
+	provider, err := NewStaticProvider(quotes)
+	require.NoError(t, err)
+
+	got, err := provider.GetRates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]money.Amount{"USD_EUR": money.MustParse("0.92")}, got)
+
+	// callers may not change the provider's quotes
+	got["USD_EUR"] = money.Zero
+	got, _ = provider.GetRates(context.Background())
+	assert.Equal(t, "0.92", got["USD_EUR"].String())
+
+	_, err = ParseStaticQuotes(map[string]string{"USD_EUR": "abc"})
+	assert.Error(t, err)
+	_, err = NewStaticProvider(map[string]money.Amount{"USDEUR": money.MustParse("1")})
+	assert.Error(t, err)
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"USD_EUR": "0.92", "RUB_USD": 0.011}`), 0o600))
+
+	provider, err := NewFileProvider(path)
+	require.NoError(t, err)
+
+	quotes, err := provider.GetRates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "0.92", quotes["USD_EUR"].String())
+	assert.Equal(t, "0.011", quotes["RUB_USD"].String())
+
+	// changes to the file are picked up without a restart
+	require.NoError(t, os.WriteFile(path, []byte(`{"USD_EUR": "0.95"}`), 0o600))
+	quotes, err = provider.GetRates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "0.95", quotes["USD_EUR"].String())
+
+	_, err = NewFileProvider(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"USD_EUR": "-1"}`), 0o600))
+	_, err = NewFileProvider(path)
+	assert.Error(t, err)
+}
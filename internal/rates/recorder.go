@@ -0,0 +1,45 @@
+package rates
+
+import (
+	"context"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"go.uber.org/zap"
+)
+
+type HistoryStore interface {
+	SaveRates(ctx context.Context, source string, fetchedAt time.Time, quotes map[string]money.Amount) error
+}
+
+// Recorder is a Provider that stores every set of quotes it gets from the wrapped provider.
+// A failure to store the quotes is logged and does not fail the fetch.
+type Recorder struct {
+	provider Provider
+	source   string
+	store    HistoryStore
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+func NewRecorder(logger *zap.Logger, provider Provider, source string, store HistoryStore) *Recorder {
+	return &Recorder{
+		provider: provider,
+		source:   source,
+		store:    store,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+func (r *Recorder) GetRates(ctx context.Context) (map[string]money.Amount, error) {
+	quotes, err := r.provider.GetRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.store.SaveRates(ctx, r.source, r.now(), quotes); err != nil {
+		r.logger.Error("failed to save rates", zap.String("source", r.source), zap.Error(err))
+	}
+	return quotes, nil
+}
@@ -0,0 +1,56 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type mockHistoryStore struct {
+	source    string
+	fetchedAt time.Time
+	quotes    map[string]money.Amount
+	err       error
+}
+
+func (m *mockHistoryStore) SaveRates(ctx context.Context, source string, fetchedAt time.Time, quotes map[string]money.Amount) error {
+	m.source, m.fetchedAt, m.quotes = source, fetchedAt, quotes
+	return m.err
+}
+
+func TestRecorderSavesFetchedRates(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &mockHistoryStore{}
+	recorder := NewRecorder(zap.NewNop(), &countingProvider{}, "grpc", store)
+	recorder.now = func() time.Time { return now }
+
+	quotes, err := recorder.GetRates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "grpc", store.source)
+	assert.Equal(t, now, store.fetchedAt)
+	assert.Equal(t, quotes, store.quotes)
+}
+
+func TestRecorderIgnoresStoreErrors(t *testing.T) {
+	store := &mockHistoryStore{err: errors.New("database error")}
+	recorder := NewRecorder(zap.NewNop(), &countingProvider{}, "grpc", store)
+
+	quotes, err := recorder.GetRates(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "0.9", quotes["USD_EUR"].String())
+}
+
+func TestRecorderDoesNotSaveFailedFetch(t *testing.T) {
+	store := &mockHistoryStore{}
+	recorder := NewRecorder(zap.NewNop(), &countingProvider{err: errors.New("down")}, "grpc", store)
+
+	_, err := recorder.GetRates(context.Background())
+	assert.Error(t, err)
+	assert.Empty(t, store.source)
+}
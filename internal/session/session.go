@@ -0,0 +1,202 @@
+// Package session issues access and refresh tokens and rotates and revokes them.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+)
+
+// ErrInvalidRefreshToken means the refresh token is unknown, expired or revoked.
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+type Store interface {
+	GetUser(username string) (*models.User, error)
+	CreateRefreshToken(token *models.RefreshToken) error
+	ConsumeRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error)
+	RevokeSession(ctx context.Context, familyID string) error
+	RevokeAccessToken(id string, expiresAt time.Time) error
+}
+
+type AccessTokenIssuer interface {
+	IssueAccessToken(subject auth.Subject, sessionID string) (auth.AccessToken, error)
+}
+
+// Tokens are the credentials handed to a client on login and refresh.
+type Tokens struct {
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+// Manager keeps sessions of refresh tokens. Each refresh replaces the refresh token with a new
+// one of the same family, and a replaced token presented again revokes the family.
+type Manager struct {
+	issuer     AccessTokenIssuer
+	store      Store
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+func NewManager(issuer AccessTokenIssuer, store Store, refreshTTL time.Duration) *Manager {
+	return &Manager{
+		issuer:     issuer,
+		store:      store,
+		refreshTTL: refreshTTL,
+		now:        time.Now,
+	}
+}
+
+// authentication is when and how the user of a session last proved who they are.
+type authentication struct {
+	time    time.Time
+	methods []string
+}
+
+// StartSession issues the first tokens of a new session of username, who just authenticated
+// with methods, e.g. auth.MethodPassword.
+func (m *Manager) StartSession(username string, methods []string) (Tokens, error) {
+	const op = "session.Manager.StartSession"
+
+	familyID, err := randomHex(16)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+	tokens, err := m.issue(username, familyID, authentication{time: m.now(), methods: methods})
+	if err != nil {
+		return Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return tokens, nil
+}
+
+// RefreshSession replaces refreshToken with new tokens of the same session.
+func (m *Manager) RefreshSession(ctx context.Context, refreshToken string) (Tokens, error) {
+	const op = "session.Manager.RefreshSession"
+
+	previous, err := m.store.ConsumeRefreshToken(ctx, hashToken(refreshToken))
+	switch {
+	case errors.Is(err, storage.ErrRefreshTokenReused):
+		return Tokens{}, fmt.Errorf("%s: %w: %w", op, ErrInvalidRefreshToken, err)
+	case errors.Is(err, storage.ErrRefreshTokenNotFound),
+		errors.Is(err, storage.ErrRefreshTokenExpired),
+		errors.Is(err, storage.ErrRefreshTokenRevoked):
+		return Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+	case err != nil:
+		return Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	authenticated := authentication{time: previous.AuthTime, methods: previous.AuthMethods}
+	tokens, err := m.issue(previous.Username, previous.FamilyID, authenticated)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return tokens, nil
+}
+
+// Reauthenticate issues tokens of the session sessionID after username authenticated again
+// with methods, so they carry a fresh auth_time. Tokens issued before keep the old one.
+func (m *Manager) Reauthenticate(username, sessionID string, methods []string) (Tokens, error) {
+	const op = "session.Manager.Reauthenticate"
+
+	if sessionID == "" {
+		// tokens issued before sessions were introduced belong to none
+		return m.StartSession(username, methods)
+	}
+	tokens, err := m.issue(username, sessionID, authentication{time: m.now(), methods: methods})
+	if err != nil {
+		return Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return tokens, nil
+}
+
+// EndSession revokes the access token tokenID and, if it belongs to a session, the whole session.
+func (m *Manager) EndSession(ctx context.Context, sessionID, tokenID string, expiresAt time.Time) error {
+	const op = "session.Manager.EndSession"
+
+	if sessionID != "" {
+		if err := m.store.RevokeSession(ctx, sessionID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if tokenID != "" {
+		if err := m.store.RevokeAccessToken(tokenID, expiresAt); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return nil
+}
+
+// issue issues an access token and a refresh token of the family. The user is read again on
+// every refresh, so the access token carries the current roles.
+func (m *Manager) issue(username, familyID string, authenticated authentication) (Tokens, error) {
+	user, err := m.store.GetUser(username)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	roles := user.Roles
+	if len(roles) == 0 {
+		roles = []string{auth.RoleUser}
+	}
+	subject := auth.Subject{
+		UserID:      int64(user.ID),
+		Username:    user.Username,
+		Roles:       roles,
+		AuthTime:    authenticated.time,
+		AuthMethods: authenticated.methods,
+	}
+	accessToken, err := m.issuer.IssueAccessToken(subject, familyID)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Tokens{}, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(secret)
+
+	stored := &models.RefreshToken{
+		Hash:            hashToken(refreshToken),
+		FamilyID:        familyID,
+		Username:        username,
+		AccessTokenID:   accessToken.ID,
+		AccessExpiresAt: accessToken.ExpiresAt,
+		ExpiresAt:       m.now().Add(m.refreshTTL),
+		AuthTime:        authenticated.time,
+		AuthMethods:     authenticated.methods,
+	}
+	if err := m.store.CreateRefreshToken(stored); err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:           accessToken.Token,
+		AccessTokenExpiresAt:  accessToken.ExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomHex(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
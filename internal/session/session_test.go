@@ -0,0 +1,208 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/config"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	auth "github.com/Foreground-Eclipse/transferer/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps refresh tokens the way the postgres storage does.
+type memoryStore struct {
+	tokens        map[string]*models.RefreshToken
+	used, revoked map[string]bool
+	deniedAccess  map[string]bool
+	roles         []string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		tokens:       map[string]*models.RefreshToken{},
+		used:         map[string]bool{},
+		revoked:      map[string]bool{},
+		deniedAccess: map[string]bool{},
+	}
+}
+
+func (s *memoryStore) GetUser(username string) (*models.User, error) {
+	if username != "alice" {
+		return nil, storage.ErrUserNotFound
+	}
+	return &models.User{ID: 1, Username: username, Roles: s.roles}, nil
+}
+
+func (s *memoryStore) CreateRefreshToken(token *models.RefreshToken) error {
+	copied := *token
+	s.tokens[token.Hash] = &copied
+	return nil
+}
+
+func (s *memoryStore) ConsumeRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	token, ok := s.tokens[hash]
+	switch {
+	case !ok:
+		return nil, storage.ErrRefreshTokenNotFound
+	case s.revoked[hash]:
+		return nil, storage.ErrRefreshTokenRevoked
+	case s.used[hash]:
+		s.RevokeSession(ctx, token.FamilyID)
+		return nil, storage.ErrRefreshTokenReused
+	case !token.ExpiresAt.After(time.Now()):
+		return nil, storage.ErrRefreshTokenExpired
+	}
+	s.used[hash] = true
+	return token, nil
+}
+
+func (s *memoryStore) RevokeSession(ctx context.Context, familyID string) error {
+	for hash, token := range s.tokens {
+		if token.FamilyID == familyID {
+			s.revoked[hash] = true
+			s.deniedAccess[token.AccessTokenID] = true
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) RevokeAccessToken(id string, expiresAt time.Time) error {
+	s.deniedAccess[id] = true
+	return nil
+}
+
+func (s *memoryStore) IsAccessTokenRevoked(id string) (bool, error) {
+	return s.deniedAccess[id], nil
+}
+
+func newTestManager(t *testing.T) (*Manager, *auth.KeySet, *memoryStore) {
+	t.Helper()
+
+	store := newMemoryStore()
+	keySet, err := auth.NewKeySet(config.JWTConfig{JWTSecret: "secret", AccessTokenTTL: time.Minute}, store)
+	require.NoError(t, err)
+	return NewManager(keySet, store, time.Hour), keySet, store
+}
+
+func TestManager_RefreshSession(t *testing.T) {
+	manager, keySet, store := newTestManager(t)
+	ctx := context.Background()
+
+	first, err := manager.StartSession("alice", []string{auth.MethodPassword})
+	require.NoError(t, err)
+	claims, err := keySet.ParseToken(first.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+	assert.Equal(t, "1", claims.Subject)
+	assert.Equal(t, []string{auth.RoleUser}, claims.Roles)
+	assert.NotEmpty(t, claims.SessionID)
+
+	second, err := manager.RefreshSession(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	secondClaims, err := keySet.ParseToken(second.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, claims.SessionID, secondClaims.SessionID)
+
+	assert.Equal(t, claims.AuthTime, secondClaims.AuthTime, "refresh keeps the authentication time")
+	assert.Equal(t, []string{auth.MethodPassword}, secondClaims.AuthMethods)
+
+	store.roles = []string{auth.RoleUser, auth.RoleAdmin}
+	third, err := manager.RefreshSession(ctx, second.RefreshToken)
+	require.NoError(t, err)
+	thirdClaims, err := keySet.ParseToken(third.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, []string{auth.RoleUser, auth.RoleAdmin}, thirdClaims.Roles, "refresh carries the current roles")
+}
+
+func TestManager_Reauthenticate(t *testing.T) {
+	manager, keySet, _ := newTestManager(t)
+	loginTime := time.Now().Add(-10 * time.Minute)
+	manager.now = func() time.Time { return loginTime }
+
+	first, err := manager.StartSession("alice", []string{auth.MethodPassword})
+	require.NoError(t, err)
+	claims, err := keySet.ParseToken(first.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, loginTime.Unix(), claims.AuthTime)
+
+	manager.now = time.Now
+	second, err := manager.Reauthenticate("alice", claims.SessionID, []string{auth.MethodOTP})
+	require.NoError(t, err)
+	secondClaims, err := keySet.ParseToken(second.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, claims.SessionID, secondClaims.SessionID)
+	assert.Greater(t, secondClaims.AuthTime, claims.AuthTime)
+	assert.Equal(t, []string{auth.MethodOTP}, secondClaims.AuthMethods)
+
+	// the refresh token issued before keeps the old authentication time
+	refreshed, err := manager.RefreshSession(context.Background(), first.RefreshToken)
+	require.NoError(t, err)
+	refreshedClaims, err := keySet.ParseToken(refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, claims.AuthTime, refreshedClaims.AuthTime)
+}
+
+func TestManager_RefreshSession_Reuse(t *testing.T) {
+	manager, keySet, _ := newTestManager(t)
+	ctx := context.Background()
+
+	first, err := manager.StartSession("alice", []string{auth.MethodPassword})
+	require.NoError(t, err)
+	second, err := manager.RefreshSession(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	other, err := manager.StartSession("alice", []string{auth.MethodPassword})
+	require.NoError(t, err)
+
+	_, err = manager.RefreshSession(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.ErrorIs(t, err, storage.ErrRefreshTokenReused)
+
+	// the whole family is revoked, other sessions are not
+	_, err = manager.RefreshSession(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = keySet.ParseToken(second.AccessToken)
+	assert.ErrorIs(t, err, auth.ErrTokenRevoked)
+
+	_, err = keySet.ParseToken(other.AccessToken)
+	assert.NoError(t, err)
+	_, err = manager.RefreshSession(ctx, other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestManager_StartSession_UnknownUser(t *testing.T) {
+	manager, _, _ := newTestManager(t)
+
+	_, err := manager.StartSession("bob", []string{auth.MethodPassword})
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+}
+
+func TestManager_RefreshSession_Unknown(t *testing.T) {
+	manager, _, _ := newTestManager(t)
+
+	_, err := manager.RefreshSession(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestManager_EndSession(t *testing.T) {
+	manager, keySet, _ := newTestManager(t)
+	ctx := context.Background()
+
+	tokens, err := manager.StartSession("alice", []string{auth.MethodPassword})
+	require.NoError(t, err)
+	claims, err := keySet.ParseToken(tokens.AccessToken)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.EndSession(ctx, claims.SessionID, claims.Id, time.Unix(claims.ExpiresAt, 0)))
+
+	_, err = keySet.ParseToken(tokens.AccessToken)
+	assert.ErrorIs(t, err, auth.ErrTokenRevoked)
+	_, err = manager.RefreshSession(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.False(t, errors.Is(err, storage.ErrRefreshTokenReused))
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
+// CREATE TABLE IF NOT EXISTS balance_adjustments (
+//     ID BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+//     user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+//     entry_id BIGINT REFERENCES journal_entries(ID),
+//     currency VARCHAR(3) NOT NULL,
+//     amount NUMERIC(19, 8) NOT NULL,
+//     reason TEXT NOT NULL,
+//     status VARCHAR(10) NOT NULL,
+//     created_by INTEGER REFERENCES users(ID) ON DELETE SET NULL,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+//     expires_at TIMESTAMPTZ,
+//     reviewed_by INTEGER REFERENCES users(ID) ON DELETE SET NULL,
+//     reviewed_at TIMESTAMPTZ,
+//     review_note TEXT NOT NULL DEFAULT ''
+
+// AdjustmentStatus is the state of a balance adjustment request. Only pending requests
+// can be approved or rejected.
+type AdjustmentStatus string
+
+const (
+	AdjustmentPending  AdjustmentStatus = "pending"
+	AdjustmentApproved AdjustmentStatus = "approved"
+	AdjustmentRejected AdjustmentStatus = "rejected"
+	// AdjustmentExpired requests were not reviewed before ExpiresAt.
+	AdjustmentExpired AdjustmentStatus = "expired"
+)
+
+// BalanceAdjustment is a manual correction of a wallet balance. A positive Amount credits
+// the wallet, a negative one debits it. An admin proposes it and another admin approves it,
+// only then it is posted as EntryID. ReviewedBy is who approved or rejected it, ReviewNote why
+// it was rejected.
+type BalanceAdjustment struct {
+	ID         int64
+	EntryID    int64
+	Username   string
+	Currency   string
+	Amount     money.Amount
+	Reason     string
+	Status     AdjustmentStatus
+	CreatedBy  string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	ReviewedBy string
+	ReviewedAt time.Time
+	ReviewNote string
+}
+
+// AdjustmentFilter narrows down ListAdjustments, zero values mean no filter.
+type AdjustmentFilter struct {
+	Status   AdjustmentStatus
+	Username string
+	Limit    int
+	Offset   int
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// CREATE TABLE IF NOT EXISTS email_verification_tokens (
+//     token_hash CHAR(64) PRIMARY KEY,
+//     user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+//     email VARCHAR(255) NOT NULL,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+//     expires_at TIMESTAMPTZ NOT NULL
+
+// EmailVerificationToken proves that the user can read mail sent to Email. It only verifies
+// the email it was sent to, not one the user changed to afterwards. Only the SHA-256 hash
+// of the token is kept.
+type EmailVerificationToken struct {
+	Hash      string
+	Username  string
+	Email     string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,17 @@
+package models
+
+// CREATE TABLE IF NOT EXISTS idempotency_keys (
+//     user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+//     key VARCHAR(255) NOT NULL,
+//     request_hash CHAR(64) NOT NULL,
+//     status_code INTEGER,
+//     response_body BYTEA,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+//     PRIMARY KEY (user_id, key)
+
+type IdempotencyRecord struct {
+	RequestHash string
+	Completed   bool
+	StatusCode  int
+	Body        []byte
+}
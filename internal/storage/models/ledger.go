@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
+// EntryKind describes why a journal entry was posted.
+type EntryKind string
+
+const (
+	EntryDeposit        EntryKind = "deposit"
+	EntryWithdrawal     EntryKind = "withdrawal"
+	EntryExchange       EntryKind = "exchange"
+	EntryTransfer       EntryKind = "transfer"
+	EntryOpeningBalance EntryKind = "opening_balance"
+	EntryAdjustment     EntryKind = "adjustment"
+)
+
+// Direction is the side of a posting.
+type Direction string
+
+const (
+	Debit  Direction = "debit"
+	Credit Direction = "credit"
+)
+
+// System accounts are kept per currency, wallet accounts are one per wallet.
+const (
+	AccountDepositsClearing    = "deposits_clearing"
+	AccountWithdrawalsClearing = "withdrawals_clearing"
+	AccountFXHouse             = "fx_house"
+	AccountOpeningBalances     = "opening_balances"
+	AccountAdjustments         = "manual_adjustments"
+)
+
+// CREATE TABLE IF NOT EXISTS ledger_accounts (
+//     ID INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+//     code VARCHAR(64) UNIQUE NOT NULL,
+//     wallet_id INTEGER UNIQUE REFERENCES wallets(ID) ON DELETE RESTRICT,
+//     currency VARCHAR(3) NOT NULL,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+
+type LedgerAccount struct {
+	ID       int
+	Code     string
+	WalletID *int
+	Currency string
+}
+
+type JournalEntry struct {
+	ID          int64
+	Kind        EntryKind
+	Description string
+	CreatedAt   time.Time
+	Postings    []Posting
+}
+
+type Posting struct {
+	AccountID int
+	Currency  string
+	Direction Direction
+	Amount    money.Amount
+}
+
+// WalletDiscrepancy is a wallet whose cached balance does not match the ledger.
+type WalletDiscrepancy struct {
+	WalletID      int
+	Currency      string
+	CachedBalance money.Amount
+	LedgerBalance money.Amount
+}
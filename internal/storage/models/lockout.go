@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// CREATE TABLE IF NOT EXISTS login_failures (
+//     scope VARCHAR(16) NOT NULL,
+//     subject VARCHAR(255) NOT NULL,
+//     failures INTEGER NOT NULL DEFAULT 0,
+//     last_failure_at TIMESTAMPTZ NOT NULL,
+//     blocked_until TIMESTAMPTZ,
+//     PRIMARY KEY (scope, subject)
+
+// Failed logins are counted per username and per client IP.
+const (
+	LoginScopeUser = "user"
+	LoginScopeIP   = "ip"
+)
+
+// LoginFailures counts the failed logins of a username or an IP, Subject, since the
+// counter was last reset. No login is tried before BlockedUntil.
+type LoginFailures struct {
+	Scope         string
+	Subject       string
+	Failures      int
+	LastFailureAt time.Time
+	BlockedUntil  time.Time
+}
+
+// CREATE TABLE IF NOT EXISTS unlock_tokens (
+//     token_hash CHAR(64) PRIMARY KEY,
+//     user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+//     expires_at TIMESTAMPTZ NOT NULL
+
+// UnlockToken lets the owner of a locked account unlock it from the link mailed to them.
+// Only the SHA-256 hash of the token is kept.
+type UnlockToken struct {
+	Hash      string
+	Username  string
+	ExpiresAt time.Time
+}
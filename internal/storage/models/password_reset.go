@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// CREATE TABLE IF NOT EXISTS password_reset_tokens (
+//     token_hash CHAR(64) PRIMARY KEY,
+//     user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+//     expires_at TIMESTAMPTZ NOT NULL
+
+// PasswordResetToken lets a user who forgot the password set a new one from the link
+// mailed to them. Only the SHA-256 hash of the token is kept.
+type PasswordResetToken struct {
+	Hash      string
+	Username  string
+	ExpiresAt time.Time
+}
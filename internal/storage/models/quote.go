@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
+// CREATE TABLE IF NOT EXISTS exchange_quotes (
+//     ID CHAR(32) PRIMARY KEY,
+//     user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+//     from_currency VARCHAR(3) NOT NULL,
+//     to_currency VARCHAR(3) NOT NULL,
+//     amount NUMERIC(19, 8) NOT NULL,
+//     rate NUMERIC(19, 8) NOT NULL,
+//     to_amount NUMERIC(19, 8) NOT NULL,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+//     expires_at TIMESTAMPTZ NOT NULL,
+//     used_at TIMESTAMPTZ
+
+// Quote is an exchange offer with a locked rate. It can be executed once before ExpiresAt.
+type Quote struct {
+	ID           string
+	FromCurrency string
+	ToCurrency   string
+	Amount       money.Amount
+	Rate         money.Amount
+	ToAmount     money.Amount
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
+// CREATE TABLE IF NOT EXISTS rate_history (
+//     ID BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+//     from_currency VARCHAR(3) NOT NULL,
+//     to_currency VARCHAR(3) NOT NULL,
+//     rate NUMERIC(19, 8) NOT NULL,
+//     source VARCHAR(32) NOT NULL,
+//     fetched_at TIMESTAMPTZ NOT NULL
+
+// RatePoint is a quote of a pair as it was fetched from a rate provider.
+type RatePoint struct {
+	Rate      money.Amount
+	Source    string
+	FetchedAt time.Time
+}
+
+// RateCandle summarizes the quotes of a pair fetched during one interval starting at Start.
+type RateCandle struct {
+	Start time.Time
+	Open  money.Amount
+	High  money.Amount
+	Low   money.Amount
+	Close money.Amount
+	Count int
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// CREATE TABLE IF NOT EXISTS refresh_tokens (
+//     token_hash CHAR(64) PRIMARY KEY,
+//     family_id CHAR(32) NOT NULL,
+//     user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+//     access_token_id CHAR(32) NOT NULL,
+//     access_expires_at TIMESTAMPTZ NOT NULL,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+//     expires_at TIMESTAMPTZ NOT NULL,
+//     used_at TIMESTAMPTZ,
+//     revoked_at TIMESTAMPTZ,
+//     auth_time TIMESTAMPTZ,
+//     amr TEXT[] NOT NULL DEFAULT '{}'
+
+// RefreshToken is a stored refresh token. Only the SHA-256 hash of the token is kept.
+// Every refresh replaces the token with a new one of the same family, FamilyID is the session,
+// AccessTokenID is the jti of the access token issued together with the refresh token.
+// AuthTime and AuthMethods are when and how the user authenticated, they are carried over to
+// the tokens the refresh token is replaced with.
+type RefreshToken struct {
+	Hash            string
+	FamilyID        string
+	Username        string
+	AccessTokenID   string
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	AuthTime        time.Time
+	AuthMethods     []string
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// CREATE TABLE IF NOT EXISTS user_totp (
+//     user_id INTEGER PRIMARY KEY REFERENCES users(ID) ON DELETE CASCADE,
+//     secret VARCHAR(64) NOT NULL,
+//     enabled BOOLEAN NOT NULL DEFAULT FALSE,
+//     last_step BIGINT NOT NULL DEFAULT 0,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+//     confirmed_at TIMESTAMPTZ
+
+// TOTP is the two-factor secret of a user. It is not Enabled until the user confirms it
+// with a code. LastStep is the time step of the last accepted code, codes of it and earlier
+// steps are rejected so a code cannot be replayed.
+type TOTP struct {
+	Username string
+	Secret   string
+	Enabled  bool
+	LastStep int64
+}
+
+// CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+//     user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+//     code_hash CHAR(64) NOT NULL,
+//     used_at TIMESTAMPTZ,
+//     PRIMARY KEY (user_id, code_hash)
+
+// CREATE TABLE IF NOT EXISTS login_challenges (
+//     token_hash CHAR(64) PRIMARY KEY,
+//     user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+//     attempts INTEGER NOT NULL DEFAULT 0,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+//     expires_at TIMESTAMPTZ NOT NULL
+
+// LoginChallenge is a login that passed the password check and waits for the second factor.
+// Only the SHA-256 hash of the challenge token is kept.
+type LoginChallenge struct {
+	Hash      string
+	Username  string
+	Attempts  int
+	ExpiresAt time.Time
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
+// CREATE TABLE IF NOT EXISTS transactions (
+//     ID BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+//     user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+//     entry_id BIGINT NOT NULL REFERENCES journal_entries(ID),
+//     type VARCHAR(16) NOT NULL,
+//     currency VARCHAR(3) NOT NULL,
+//     amount NUMERIC(19, 8) NOT NULL,
+//     to_currency VARCHAR(3),
+//     to_amount NUMERIC(19, 8),
+//     rate NUMERIC(19, 8),
+//     counterparty_id INTEGER REFERENCES users(ID) ON DELETE SET NULL,
+//     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+
+// A transfer is recorded once for each side, the ledger entry itself is an EntryTransfer.
+const (
+	TransferOut EntryKind = "transfer_out"
+	TransferIn  EntryKind = "transfer_in"
+)
+
+// A manual adjustment is recorded by its direction, the ledger entry is an EntryAdjustment.
+const (
+	AdjustmentIn  EntryKind = "adjustment_in"
+	AdjustmentOut EntryKind = "adjustment_out"
+)
+
+// Transaction is a user facing record of a balance change.
+// ToCurrency, ToAmount and Rate are only set when the amount was converted,
+// Counterparty is the other user of a transfer.
+type Transaction struct {
+	ID           int64
+	Type         EntryKind
+	Currency     string
+	Amount       money.Amount
+	ToCurrency   string
+	ToAmount     money.Amount
+	Rate         money.Amount
+	Counterparty string
+	CreatedAt    time.Time
+}
+
+// Transfer is the result of a peer-to-peer transfer.
+type Transfer struct {
+	EntryID          int64
+	Recipient        string
+	Currency         string
+	Amount           money.Amount
+	CreditedCurrency string
+	CreditedAmount   money.Amount
+	Rate             money.Amount
+}
+
+// TransactionCursor points at the last transaction of the previous page.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// TransactionFilter narrows down GetTransactions, zero values mean no filter.
+type TransactionFilter struct {
+	Type      EntryKind
+	Currency  string
+	From      time.Time
+	To        time.Time
+	MinAmount *money.Amount
+	MaxAmount *money.Amount
+	After     *TransactionCursor
+	Limit     int
+}
@@ -5,6 +5,11 @@ package models
 //     username VARCHAR(255) UNIQUE NOT NULL,
 //     password_hash VARCHAR(255) NOT NULL,
 //     email VARCHAR(255) UNIQUE,
+//     email_verified_at TIMESTAMPTZ,
+//     roles TEXT[] NOT NULL DEFAULT '{user}',
+//     frozen_at TIMESTAMPTZ,
+//     frozen_reason TEXT NOT NULL DEFAULT '',
+//     frozen_by INTEGER REFERENCES users(ID) ON DELETE SET NULL,
 //     created_at TIMESTAMPTZ DEFAULT NOW(),
 //     updated_at TIMESTAMPTZ DEFAULT NOW()
 
@@ -13,4 +18,19 @@ type User struct {
 	Username     string
 	PasswordHash string
 	Email        string
+	// EmailVerified is set once the user followed the link mailed to Email.
+	EmailVerified bool
+	Roles         []string
+	// Frozen accounts cannot move money until an admin unfreezes them.
+	Frozen       bool
+	FrozenReason string
+}
+
+// UserFilter narrows down ListUsers. Query matches a part of the username or email,
+// zero values mean no filter.
+type UserFilter struct {
+	Query  string
+	Role   string
+	Limit  int
+	Offset int
 }
@@ -1,7 +1,10 @@
 package models
 
+import "github.com/Foreground-Eclipse/transferer/pkg/money"
+
 type Wallet struct {
+	ID       int
 	UserID   int
 	Currency string
-	Balance  float32
+	Balance  money.Amount
 }
@@ -0,0 +1,326 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+)
+
+// InitAdjustmentApprovalSchema makes manual balance adjustments requests that are posted
+// once another admin approves them. Adjustments posted before count as approved by their author.
+func (s *Storage) InitAdjustmentApprovalSchema() error {
+	const op = "storage.postgres.InitAdjustmentApprovalSchema"
+	query := `
+	ALTER TABLE balance_adjustments ALTER COLUMN entry_id DROP NOT NULL;
+	ALTER TABLE balance_adjustments ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'approved';
+	ALTER TABLE balance_adjustments ALTER COLUMN status DROP DEFAULT;
+	ALTER TABLE balance_adjustments ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+	ALTER TABLE balance_adjustments ADD COLUMN IF NOT EXISTS reviewed_by INTEGER REFERENCES users(ID) ON DELETE SET NULL;
+	ALTER TABLE balance_adjustments ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMPTZ;
+	ALTER TABLE balance_adjustments ADD COLUMN IF NOT EXISTS review_note TEXT NOT NULL DEFAULT '';
+
+	UPDATE balance_adjustments SET reviewed_by = created_by, reviewed_at = created_at
+	WHERE status = 'approved' AND reviewed_at IS NULL;
+
+	CREATE INDEX IF NOT EXISTS balance_adjustments_pending_idx ON balance_adjustments (expires_at) WHERE status = 'pending';`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// adjustmentColumns are the columns read by scanAdjustment, a is balance_adjustments,
+// u its user, c who created it and r who reviewed it.
+const adjustmentColumns = `a.ID, COALESCE(a.entry_id, 0), u.username, a.currency, a.amount, a.reason, a.status,
+	COALESCE(c.username, ''), a.created_at, a.expires_at, COALESCE(r.username, ''), a.reviewed_at, a.review_note`
+
+const adjustmentJoins = `
+	FROM balance_adjustments a
+	JOIN users u ON a.user_id = u.ID
+	LEFT JOIN users c ON a.created_by = c.ID
+	LEFT JOIN users r ON a.reviewed_by = r.ID`
+
+// scanAdjustment reads a row of adjustmentColumns from a *sql.Row or *sql.Rows.
+func scanAdjustment(row interface{ Scan(dest ...any) error }) (*models.BalanceAdjustment, error) {
+	var a models.BalanceAdjustment
+	var expiresAt, reviewedAt sql.NullTime
+	err := row.Scan(&a.ID, &a.EntryID, &a.Username, &a.Currency, &a.Amount, &a.Reason, &a.Status,
+		&a.CreatedBy, &a.CreatedAt, &expiresAt, &a.ReviewedBy, &reviewedAt, &a.ReviewNote)
+	if err != nil {
+		return nil, err
+	}
+	a.ExpiresAt, a.ReviewedAt = expiresAt.Time, reviewedAt.Time
+	return &a, nil
+}
+
+// ProposeAdjustment records adjustment as a pending request of its CreatedBy that expires
+// at its ExpiresAt. Nothing is posted until ApproveAdjustment. Admins may not propose
+// adjustments of their own balance.
+func (s *Storage) ProposeAdjustment(ctx context.Context, adjustment *models.BalanceAdjustment) error {
+	const op = "storage.postgres.ProposeAdjustment"
+
+	if adjustment.Amount.IsZero() {
+		return fmt.Errorf("%s: amount must not be zero", op)
+	}
+	if adjustment.CreatedBy == adjustment.Username {
+		return fmt.Errorf("%s: %w", op, storage.ErrSelfAdjustment)
+	}
+
+	query := `
+	INSERT INTO balance_adjustments (user_id, currency, amount, reason, status, created_by, expires_at)
+	SELECT w.user_id, w.currency, $3, $4, $5, (SELECT ID FROM users WHERE username = $6), $7
+	FROM wallets w
+	JOIN users u ON w.user_id = u.ID
+	WHERE u.username = $1 AND w.currency = $2
+	RETURNING ID, created_at`
+	err := s.db.QueryRowContext(ctx, query, adjustment.Username, adjustment.Currency, adjustment.Amount,
+		adjustment.Reason, models.AdjustmentPending, adjustment.CreatedBy, adjustment.ExpiresAt).
+		Scan(&adjustment.ID, &adjustment.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s: %w", op, storage.ErrWalletNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	adjustment.Status = models.AdjustmentPending
+	return nil
+}
+
+// GetAdjustment returns the adjustment request with id.
+func (s *Storage) GetAdjustment(id int64) (*models.BalanceAdjustment, error) {
+	const op = "storage.postgres.GetAdjustment"
+
+	if _, err := s.ExpireAdjustments(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	adjustment, err := scanAdjustment(s.db.QueryRow(`SELECT `+adjustmentColumns+adjustmentJoins+` WHERE a.ID = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrAdjustmentNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return adjustment, nil
+}
+
+// ListAdjustments returns the adjustment requests matching filter, newest first.
+func (s *Storage) ListAdjustments(filter models.AdjustmentFilter) ([]models.BalanceAdjustment, error) {
+	const op = "storage.postgres.ListAdjustments"
+
+	if _, err := s.ExpireAdjustments(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `SELECT ` + adjustmentColumns + adjustmentJoins + ` WHERE TRUE`
+	var args []any
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND a.status = $%d", len(args))
+	}
+	if filter.Username != "" {
+		args = append(args, filter.Username)
+		query += fmt.Sprintf(" AND u.username = $%d", len(args))
+	}
+	query += " ORDER BY a.ID DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var adjustments []models.BalanceAdjustment
+	for rows.Next() {
+		adjustment, err := scanAdjustment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to scan adjustment: %w", op, err)
+		}
+		adjustments = append(adjustments, *adjustment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return adjustments, nil
+}
+
+// ExpireAdjustments marks the pending requests past their expiry as expired and returns
+// how many there were.
+func (s *Storage) ExpireAdjustments() (int64, error) {
+	const op = "storage.postgres.ExpireAdjustments"
+
+	query := `
+	UPDATE balance_adjustments SET status = $1, reviewed_at = expires_at
+	WHERE status = $2 AND expires_at <= NOW()`
+	result, err := s.db.Exec(query, models.AdjustmentExpired, models.AdjustmentPending)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	expired, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return expired, nil
+}
+
+// ApproveAdjustment posts the pending adjustment request with id to the wallet of its user
+// against the manual adjustments account on behalf of approver, who may be neither its
+// proposer nor its user.
+// A debit may not take the wallet below zero, the request then stays pending.
+func (s *Storage) ApproveAdjustment(ctx context.Context, id int64, approver string) (*models.BalanceAdjustment, error) {
+	const op = "storage.postgres.ApproveAdjustment"
+
+	if _, err := s.ExpireAdjustments(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var adjustment *models.BalanceAdjustment
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		adjustment, err = lockPendingAdjustment(tx, id)
+		if err != nil {
+			return err
+		}
+		if adjustment.CreatedBy == approver {
+			return storage.ErrSelfApproval
+		}
+		if adjustment.Username == approver {
+			return storage.ErrSelfAdjustment
+		}
+
+		wallets, err := lockWallets(tx, adjustment.Username, adjustment.Currency)
+		if err != nil {
+			return err
+		}
+		wallet := wallets[adjustment.Currency]
+		if adjustment.Amount.IsNegative() && wallet.Balance.LessThan(adjustment.Amount.Abs()) {
+			return storage.ErrNotEnoughFunds
+		}
+
+		walletAccount, err := walletAccountID(tx, wallet.ID, adjustment.Currency)
+		if err != nil {
+			return err
+		}
+		adjustmentsAccount, err := systemAccountID(tx, models.AccountAdjustments, adjustment.Currency)
+		if err != nil {
+			return err
+		}
+
+		postings := transferPostings(adjustmentsAccount, walletAccount, adjustment.Currency, adjustment.Amount)
+		description := fmt.Sprintf("adjustment #%d of %s proposed by %s, approved by %s: %s",
+			adjustment.ID, adjustment.Username, adjustment.CreatedBy, approver, adjustment.Reason)
+		entryID, err := postEntry(tx, models.EntryAdjustment, description, postings)
+		if err != nil {
+			return err
+		}
+
+		kind := models.AdjustmentIn
+		if adjustment.Amount.IsNegative() {
+			kind = models.AdjustmentOut
+		}
+		err = insertTransaction(tx, wallet.ID, entryID, models.Transaction{
+			Type:     kind,
+			Currency: adjustment.Currency,
+			Amount:   adjustment.Amount.Abs(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return reviewAdjustment(tx, adjustment, models.AdjustmentApproved, approver, "", entryID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return adjustment, nil
+}
+
+// RejectAdjustment rejects the pending adjustment request with id on behalf of reviewer.
+// Proposers may reject, i.e. withdraw, their own requests.
+func (s *Storage) RejectAdjustment(ctx context.Context, id int64, reviewer, note string) (*models.BalanceAdjustment, error) {
+	const op = "storage.postgres.RejectAdjustment"
+
+	if _, err := s.ExpireAdjustments(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var adjustment *models.BalanceAdjustment
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		adjustment, err = lockPendingAdjustment(tx, id)
+		if err != nil {
+			return err
+		}
+		return reviewAdjustment(tx, adjustment, models.AdjustmentRejected, reviewer, note, 0)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return adjustment, nil
+}
+
+// lockPendingAdjustment returns the adjustment request with id and locks it until the end
+// of tx. Requests that are not pending anymore cannot be reviewed.
+func lockPendingAdjustment(tx *sql.Tx, id int64) (*models.BalanceAdjustment, error) {
+	query := `SELECT ` + adjustmentColumns + adjustmentJoins + ` WHERE a.ID = $1 FOR UPDATE OF a`
+	adjustment, err := scanAdjustment(tx.QueryRow(query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrAdjustmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get adjustment: %w", err)
+	}
+
+	switch {
+	case adjustment.Status == models.AdjustmentExpired:
+		return nil, storage.ErrAdjustmentExpired
+	case adjustment.Status != models.AdjustmentPending:
+		return nil, storage.ErrAdjustmentNotPending
+	case !adjustment.ExpiresAt.IsZero() && !time.Now().Before(adjustment.ExpiresAt):
+		// expired after ExpireAdjustments ran, it is marked by the next run
+		return nil, storage.ErrAdjustmentExpired
+	}
+	return adjustment, nil
+}
+
+// reviewAdjustment records the decision of reviewer on adjustment and updates it.
+func reviewAdjustment(tx *sql.Tx, adjustment *models.BalanceAdjustment, status models.AdjustmentStatus, reviewer, note string, entryID int64) error {
+	var entry any
+	if entryID != 0 {
+		entry = entryID
+	}
+
+	query := `
+	UPDATE balance_adjustments SET
+		status = $2,
+		entry_id = $3,
+		reviewed_by = (SELECT ID FROM users WHERE username = $4),
+		reviewed_at = NOW(),
+		review_note = $5
+	WHERE ID = $1
+	RETURNING reviewed_at`
+	err := tx.QueryRow(query, adjustment.ID, status, entry, reviewer, note).Scan(&adjustment.ReviewedAt)
+	if err != nil {
+		return fmt.Errorf("failed to review adjustment: %w", err)
+	}
+
+	adjustment.Status = status
+	adjustment.EntryID = entryID
+	adjustment.ReviewedBy = reviewer
+	adjustment.ReviewNote = note
+	return nil
+}
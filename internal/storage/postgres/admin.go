@@ -0,0 +1,248 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/lib/pq"
+)
+
+// InitAdminSchema adds roles and freezing to users and creates the record of manual
+// balance adjustments.
+func (s *Storage) InitAdminSchema() error {
+	const op = "storage.postgres.InitAdminSchema"
+	query := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS roles TEXT[] NOT NULL DEFAULT '{user}';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS frozen_at TIMESTAMPTZ;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS frozen_reason TEXT NOT NULL DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS frozen_by INTEGER REFERENCES users(ID) ON DELETE SET NULL;
+
+	CREATE TABLE IF NOT EXISTS balance_adjustments (
+    ID BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    entry_id BIGINT NOT NULL REFERENCES journal_entries(ID),
+    currency VARCHAR(3) NOT NULL,
+    amount NUMERIC(19, 8) NOT NULL,
+    reason TEXT NOT NULL,
+    created_by INTEGER REFERENCES users(ID) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+	CREATE INDEX IF NOT EXISTS balance_adjustments_user_idx ON balance_adjustments (user_id, created_at DESC);
+
+	CREATE TABLE IF NOT EXISTS role_changes (
+    ID BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    old_roles TEXT[] NOT NULL,
+    new_roles TEXT[] NOT NULL,
+    changed_by INTEGER REFERENCES users(ID) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+	CREATE INDEX IF NOT EXISTS role_changes_user_idx ON role_changes (user_id, created_at DESC);
+
+	CREATE TABLE IF NOT EXISTS bootstrap_roles (
+    username VARCHAR(255) NOT NULL,
+    role TEXT NOT NULL,
+    granted BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (username, role)
+);`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// ListUsers returns the users matching filter ordered by ID.
+func (s *Storage) ListUsers(filter models.UserFilter) ([]models.User, error) {
+	const op = "storage.postgres.ListUsers"
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE TRUE`
+	var args []any
+	if filter.Query != "" {
+		args = append(args, "%"+escapeLike(strings.ToLower(filter.Query))+"%")
+		query += fmt.Sprintf(" AND (LOWER(username) LIKE $%d OR LOWER(email) LIKE $%d)", len(args), len(args))
+	}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		query += fmt.Sprintf(" AND $%d = ANY(roles)", len(args))
+	}
+	query += " ORDER BY ID"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to scan user: %w", op, err)
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return users, nil
+}
+
+// SetUserRoles replaces the roles of username on behalf of by and revokes all their sessions,
+// so tokens carrying the old roles stop working at once. The change is recorded in role_changes.
+func (s *Storage) SetUserRoles(username string, roles []string, by string) error {
+	const op = "storage.postgres.SetUserRoles"
+
+	err := s.withTx(context.Background(), func(tx *sql.Tx) error {
+		var userID int
+		var oldRoles []string
+		err := tx.QueryRow(`SELECT ID, roles FROM users WHERE username = $1 FOR UPDATE`, username).
+			Scan(&userID, pq.Array(&oldRoles))
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		if _, err := tx.Exec(`UPDATE users SET roles = $2 WHERE ID = $1`, userID, pq.Array(roles)); err != nil {
+			return fmt.Errorf("failed to update roles: %w", err)
+		}
+
+		_, err = tx.Exec(`
+		INSERT INTO role_changes (user_id, old_roles, new_roles, changed_by)
+		VALUES ($1, $2, $3, (SELECT ID FROM users WHERE username = $4))`,
+			userID, pq.Array(oldRoles), pq.Array(roles), by)
+		if err != nil {
+			return fmt.Errorf("failed to record role change: %w", err)
+		}
+
+		return revokeUserSessions(tx, userID, "")
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// BootstrapRole adds role to the roles of username the first time it is called for them and
+// reports whether it did. The attempt is recorded even when username is not registered,
+// which fails with storage.ErrUserNotFound, so a username listed before anyone registered
+// it never gets role on a later call.
+func (s *Storage) BootstrapRole(username, role string) (bool, error) {
+	const op = "storage.postgres.BootstrapRole"
+
+	var granted, missing bool
+	err := s.withTx(context.Background(), func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+		INSERT INTO bootstrap_roles (username, role, granted) VALUES ($1, $2, FALSE)
+		ON CONFLICT (username, role) DO NOTHING`, username, role)
+		if err != nil {
+			return fmt.Errorf("failed to record bootstrap role: %w", err)
+		}
+		// recorded by an earlier call, whatever it decided stands
+		if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+			return err
+		}
+
+		result, err = tx.Exec(`
+		UPDATE users SET roles = CASE WHEN $2 = ANY(roles) THEN roles ELSE array_append(roles, $2) END
+		WHERE username = $1`, username, role)
+		if err != nil {
+			return fmt.Errorf("failed to grant role: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+			missing = true
+			return nil
+		}
+
+		_, err = tx.Exec(`
+		UPDATE bootstrap_roles SET granted = TRUE WHERE username = $1 AND role = $2`, username, role)
+		if err != nil {
+			return fmt.Errorf("failed to record bootstrap role: %w", err)
+		}
+		granted = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if missing {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return granted, nil
+}
+
+// FreezeAccount freezes the account of username on behalf of by. Freezing a frozen account
+// keeps the original reason.
+func (s *Storage) FreezeAccount(username, by, reason string) error {
+	const op = "storage.postgres.FreezeAccount"
+
+	query := `
+	UPDATE users SET
+		frozen_at = COALESCE(frozen_at, NOW()),
+		frozen_reason = CASE WHEN frozen_at IS NULL THEN $2 ELSE frozen_reason END,
+		frozen_by = CASE WHEN frozen_at IS NULL THEN (SELECT ID FROM users WHERE username = $3) ELSE frozen_by END
+	WHERE username = $1`
+	result, err := s.db.Exec(query, username, reason, by)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// UnfreezeAccount lifts the freeze of the account of username.
+func (s *Storage) UnfreezeAccount(username string) error {
+	const op = "storage.postgres.UnfreezeAccount"
+
+	query := `
+	UPDATE users SET frozen_at = NULL, frozen_reason = '', frozen_by = NULL
+	WHERE username = $1`
+	result, err := s.db.Exec(query, username)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// IsAccountFrozen tells whether the account of username is frozen.
+func (s *Storage) IsAccountFrozen(username string) (bool, error) {
+	const op = "storage.postgres.IsAccountFrozen"
+
+	var frozen bool
+	err := s.db.QueryRow(`SELECT frozen_at IS NOT NULL FROM users WHERE username = $1`, username).Scan(&frozen)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return frozen, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
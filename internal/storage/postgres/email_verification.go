@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
+// InitEmailVerificationSchema adds the verification state to users. Users registered
+// before email verification existed are taken as verified, new ones start unverified.
+func (s *Storage) InitEmailVerificationSchema() error {
+	const op = "storage.postgres.InitEmailVerificationSchema"
+	query := `
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ DEFAULT NOW();
+	ALTER TABLE users ALTER COLUMN email_verified_at DROP DEFAULT;
+
+	CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+	CREATE INDEX IF NOT EXISTS email_verification_tokens_user_idx ON email_verification_tokens (user_id, created_at DESC);`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// IsEmailVerified tells whether username verified their current email.
+func (s *Storage) IsEmailVerified(username string) (bool, error) {
+	const op = "storage.postgres.IsEmailVerified"
+
+	var verified bool
+	err := s.db.QueryRow(`SELECT email_verified_at IS NOT NULL FROM users WHERE username = $1`, username).Scan(&verified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return verified, nil
+}
+
+// CreateEmailVerificationToken stores the token of a verification link, unless the user got
+// one after notBefore, in which case it fails with storage.ErrEmailVerificationSentRecently.
+// The user is locked while checking, so concurrent requests cannot both pass. The token is
+// only stored while the user still has its email. Expired tokens are removed on the way.
+func (s *Storage) CreateEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken, notBefore time.Time) error {
+	const op = "storage.postgres.CreateEmailVerificationToken"
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		err := tx.QueryRow(`SELECT ID FROM users WHERE username = $1 AND email = $2 FOR UPDATE`,
+			token.Username, token.Email).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		var recent bool
+		err = tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM email_verification_tokens WHERE user_id = $1 AND created_at > $2)`,
+			userID, notBefore).Scan(&recent)
+		if err != nil {
+			return fmt.Errorf("failed to check recent email verification tokens: %w", err)
+		}
+		if recent {
+			return storage.ErrEmailVerificationSentRecently
+		}
+
+		_, err = tx.Exec(`
+		INSERT INTO email_verification_tokens (token_hash, user_id, email, expires_at)
+		VALUES ($1, $2, $3, $4)`, token.Hash, userID, token.Email, token.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert email verification token: %w", err)
+		}
+
+		_, err = tx.Exec(`DELETE FROM email_verification_tokens WHERE expires_at <= NOW()`)
+		if err != nil {
+			return fmt.Errorf("failed to remove expired email verification tokens: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// LastEmailVerificationSentAt returns when the latest verification token of username was
+// created, the zero time if there is none.
+func (s *Storage) LastEmailVerificationSentAt(username string) (time.Time, error) {
+	const op = "storage.postgres.LastEmailVerificationSentAt"
+
+	query := `
+	SELECT MAX(t.created_at)
+	FROM email_verification_tokens t
+	JOIN users u ON t.user_id = u.ID
+	WHERE u.username = $1`
+
+	var sentAt sql.NullTime
+	if err := s.db.QueryRow(query, username).Scan(&sentAt); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return sentAt.Time, nil
+}
+
+// VerifyEmail uses the unexpired token with hash to mark the email it was sent to as
+// verified and returns the username. Tokens sent to an email the user no longer has are
+// rejected with storage.ErrEmailVerificationTokenNotFound, as are used ones.
+func (s *Storage) VerifyEmail(ctx context.Context, hash string) (string, error) {
+	const op = "storage.postgres.VerifyEmail"
+
+	var username string
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		var email string
+		err := tx.QueryRow(`
+		DELETE FROM email_verification_tokens
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING user_id, email`, hash).Scan(&userID, &email)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrEmailVerificationTokenNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to use email verification token: %w", err)
+		}
+
+		err = tx.QueryRow(`
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE ID = $1 AND email = $2
+		RETURNING username`, userID, email).Scan(&username)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrEmailVerificationTokenNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to verify email: %w", err)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM email_verification_tokens WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to remove email verification tokens: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return username, nil
+}
+
+// DepositWithinLimit deposits amount of currency to the user's wallet unless the user's
+// deposits in currency would add up to more than limit, in which case it fails with
+// storage.ErrDepositLimitExceeded. The total is summed under the wallet lock, so concurrent
+// deposits cannot both slip under the limit.
+func (s *Storage) DepositWithinLimit(username, currency string, amount, limit money.Amount) error {
+	const op = "storage.postgres.DepositWithinLimit"
+
+	if !amount.IsPositive() {
+		return fmt.Errorf("%s: amount must be positive", op)
+	}
+
+	query := `
+	SELECT COALESCE(SUM(t.amount), 0)
+	FROM transactions t
+	JOIN users u ON t.user_id = u.ID
+	WHERE u.username = $1 AND t.type = $2 AND t.currency = $3`
+
+	err := s.withTx(context.Background(), func(tx *sql.Tx) error {
+		wallet, err := lockWallet(tx, username, currency)
+		if err != nil {
+			return err
+		}
+
+		var deposited money.Amount
+		if err := tx.QueryRow(query, username, string(models.EntryDeposit), currency).Scan(&deposited); err != nil {
+			return fmt.Errorf("failed to get deposited total: %w", err)
+		}
+		if deposited.Add(amount).GreaterThan(limit) {
+			return storage.ErrDepositLimitExceeded
+		}
+
+		return postWalletMovement(tx, wallet, username, amount)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+)
+
+const (
+	// idempotencyKeyTTL is how long a used key keeps replaying its response.
+	idempotencyKeyTTL = "24 hours"
+	// idempotencyPendingTTL frees keys whose request died before storing a response.
+	idempotencyPendingTTL = "5 minutes"
+)
+
+func (s *Storage) InitIdempotencySchema() error {
+	const op = "storage.postgres.InitIdempotencySchema"
+	query := `
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INTEGER,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, key)
+);`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// StartIdempotentRequest reserves key for the user. It returns nil if the key is new,
+// otherwise the record left by the first request with this key.
+func (s *Storage) StartIdempotentRequest(username, key, requestHash string) (*models.IdempotencyRecord, error) {
+	const op = "storage.postgres.StartIdempotentRequest"
+
+	queryExpire := `
+	DELETE FROM idempotency_keys k
+	USING users u
+	WHERE k.user_id = u.ID AND u.username = $1 AND k.key = $2
+	  AND (k.created_at < NOW() - $3::interval
+	       OR (k.status_code IS NULL AND k.created_at < NOW() - $4::interval))`
+	if _, err := s.db.Exec(queryExpire, username, key, idempotencyKeyTTL, idempotencyPendingTTL); err != nil {
+		return nil, fmt.Errorf("%s: failed to expire key: %w", op, err)
+	}
+
+	queryInsert := `
+	INSERT INTO idempotency_keys (user_id, key, request_hash)
+	SELECT ID, $2, $3 FROM users WHERE username = $1
+	ON CONFLICT (user_id, key) DO NOTHING
+	RETURNING user_id`
+	var userID int
+	err := s.db.QueryRow(queryInsert, username, key, requestHash).Scan(&userID)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: failed to reserve key: %w", op, err)
+	}
+
+	querySelect := `
+	SELECT k.request_hash, k.status_code, k.response_body
+	FROM idempotency_keys k
+	JOIN users u ON k.user_id = u.ID
+	WHERE u.username = $1 AND k.key = $2`
+	var record models.IdempotencyRecord
+	var statusCode sql.NullInt64
+	err = s.db.QueryRow(querySelect, username, key).Scan(&record.RequestHash, &statusCode, &record.Body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: user %s does not exist", op, username)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get key: %w", op, err)
+	}
+
+	record.Completed = statusCode.Valid
+	record.StatusCode = int(statusCode.Int64)
+	return &record, nil
+}
+
+// CompleteIdempotentRequest stores the response of the request that reserved key.
+func (s *Storage) CompleteIdempotentRequest(username, key string, statusCode int, body []byte) error {
+	const op = "storage.postgres.CompleteIdempotentRequest"
+
+	query := `
+	UPDATE idempotency_keys k
+	SET status_code = $3, response_body = $4
+	FROM users u
+	WHERE k.user_id = u.ID AND u.username = $1 AND k.key = $2`
+	_, err := s.db.Exec(query, username, key, statusCode, body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ReleaseIdempotentRequest forgets an unfinished key so the request can be retried.
+func (s *Storage) ReleaseIdempotentRequest(username, key string) error {
+	const op = "storage.postgres.ReleaseIdempotentRequest"
+
+	query := `
+	DELETE FROM idempotency_keys k
+	USING users u
+	WHERE k.user_id = u.ID AND u.username = $1 AND k.key = $2 AND k.status_code IS NULL`
+	_, err := s.db.Exec(query, username, key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
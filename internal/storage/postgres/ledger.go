@@ -0,0 +1,417 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/lib/pq"
+)
+
+// InitLedgerSchema creates the append-only double-entry ledger.
+// wallets.balance is only a cache of the postings made against the wallet's account.
+func (s *Storage) InitLedgerSchema() error {
+	const op = "storage.postgres.InitLedgerSchema"
+	query := `
+	CREATE TABLE IF NOT EXISTS ledger_accounts (
+    ID INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    code VARCHAR(64) UNIQUE NOT NULL,
+    wallet_id INTEGER UNIQUE REFERENCES wallets(ID) ON DELETE RESTRICT,
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+	CREATE TABLE IF NOT EXISTS journal_entries (
+    ID BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+	CREATE TABLE IF NOT EXISTS postings (
+    ID BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    entry_id BIGINT NOT NULL REFERENCES journal_entries(ID),
+    account_id INTEGER NOT NULL REFERENCES ledger_accounts(ID),
+    direction VARCHAR(6) NOT NULL CHECK (direction IN ('debit', 'credit')),
+    amount NUMERIC(19, 8) NOT NULL CHECK (amount > 0)
+);
+
+	CREATE INDEX IF NOT EXISTS postings_entry_id_idx ON postings (entry_id);
+	CREATE INDEX IF NOT EXISTS postings_account_id_idx ON postings (account_id);
+
+	CREATE OR REPLACE FUNCTION ledger_forbid_mutation() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger table % is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+	CREATE OR REPLACE FUNCTION ledger_check_balanced() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1
+        FROM postings p
+        JOIN ledger_accounts a ON a.ID = p.account_id
+        WHERE p.entry_id = NEW.entry_id
+        GROUP BY a.currency
+        HAVING SUM(CASE WHEN p.direction = 'debit' THEN p.amount ELSE -p.amount END) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+	DROP TRIGGER IF EXISTS journal_entries_append_only ON journal_entries;
+	CREATE TRIGGER journal_entries_append_only
+    BEFORE UPDATE OR DELETE ON journal_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_forbid_mutation();
+
+	DROP TRIGGER IF EXISTS postings_append_only ON postings;
+	CREATE TRIGGER postings_append_only
+    BEFORE UPDATE OR DELETE ON postings
+    FOR EACH ROW EXECUTE FUNCTION ledger_forbid_mutation();
+
+	DROP TRIGGER IF EXISTS postings_balanced ON postings;
+	CREATE CONSTRAINT TRIGGER postings_balanced
+    AFTER INSERT ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_check_balanced();`
+
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// PostOpeningBalances brings wallets that predate the ledger into it.
+// Every wallet without postings and with a non-zero balance gets an opening entry
+// against the opening balances account, the cached balance itself is left as is.
+func (s *Storage) PostOpeningBalances() error {
+	const op = "storage.postgres.PostOpeningBalances"
+
+	query := `
+	SELECT w.ID, w.currency, w.balance
+	FROM wallets w
+	WHERE w.balance <> 0
+	  AND NOT EXISTS (
+	      SELECT 1 FROM postings p
+	      JOIN ledger_accounts a ON a.ID = p.account_id
+	      WHERE a.wallet_id = w.ID
+	  )`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return fmt.Errorf("%s: failed to query wallets: %w", op, err)
+	}
+
+	type opening struct {
+		walletID int
+		currency string
+		balance  money.Amount
+	}
+	var openings []opening
+	for rows.Next() {
+		var o opening
+		if err := rows.Scan(&o.walletID, &o.currency, &o.balance); err != nil {
+			rows.Close()
+			return fmt.Errorf("%s: failed to scan wallet: %w", op, err)
+		}
+		openings = append(openings, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: failed to iterate wallets: %w", op, err)
+	}
+
+	for _, o := range openings {
+		err := s.withTx(context.Background(), func(tx *sql.Tx) error {
+			walletAccount, err := walletAccountID(tx, o.walletID, o.currency)
+			if err != nil {
+				return err
+			}
+			equityAccount, err := systemAccountID(tx, models.AccountOpeningBalances, o.currency)
+			if err != nil {
+				return err
+			}
+
+			postings := transferPostings(equityAccount, walletAccount, o.currency, o.balance)
+			_, err = insertEntry(tx, models.EntryOpeningBalance, fmt.Sprintf("opening balance of wallet %d", o.walletID), postings)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s: wallet %d: %w", op, o.walletID, err)
+		}
+	}
+
+	if len(openings) > 0 {
+		log.Printf("Posted opening balances for %d wallets\n", len(openings))
+	}
+
+	return nil
+}
+
+// ReconcileWallets compares every cached wallet balance with the balance derived from the ledger.
+func (s *Storage) ReconcileWallets() ([]models.WalletDiscrepancy, error) {
+	const op = "storage.postgres.ReconcileWallets"
+
+	query := `
+	SELECT
+		w.ID,
+		w.currency,
+		w.balance,
+		COALESCE(SUM(CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END), 0)
+	FROM
+		wallets w
+		LEFT JOIN ledger_accounts a ON a.wallet_id = w.ID
+		LEFT JOIN postings p ON p.account_id = a.ID
+	GROUP BY
+		w.ID, w.currency, w.balance
+	HAVING
+		w.balance <> COALESCE(SUM(CASE WHEN p.direction = 'credit' THEN p.amount ELSE -p.amount END), 0)
+	ORDER BY
+		w.ID`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to query ledger: %w", op, err)
+	}
+	defer rows.Close()
+
+	var discrepancies []models.WalletDiscrepancy
+	for rows.Next() {
+		var d models.WalletDiscrepancy
+		if err := rows.Scan(&d.WalletID, &d.Currency, &d.CachedBalance, &d.LedgerBalance); err != nil {
+			return nil, fmt.Errorf("%s: failed to scan row: %w", op, err)
+		}
+		discrepancies = append(discrepancies, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: failed to iterate rows: %w", op, err)
+	}
+
+	return discrepancies, nil
+}
+
+// GetJournalEntry returns a single journal entry with its postings.
+func (s *Storage) GetJournalEntry(id int64) (*models.JournalEntry, error) {
+	const op = "storage.postgres.GetJournalEntry"
+
+	entry := models.JournalEntry{ID: id}
+	var kind string
+	err := s.db.QueryRow(`SELECT kind, description, created_at FROM journal_entries WHERE ID = $1`, id).
+		Scan(&kind, &entry.Description, &entry.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get entry: %w", op, err)
+	}
+	entry.Kind = models.EntryKind(kind)
+
+	rows, err := s.db.Query(`
+	SELECT p.account_id, a.currency, p.direction, p.amount
+	FROM postings p
+	JOIN ledger_accounts a ON a.ID = p.account_id
+	WHERE p.entry_id = $1
+	ORDER BY p.ID`, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get postings: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Posting
+		var direction string
+		if err := rows.Scan(&p.AccountID, &p.Currency, &direction, &p.Amount); err != nil {
+			return nil, fmt.Errorf("%s: failed to scan posting: %w", op, err)
+		}
+		p.Direction = models.Direction(direction)
+		entry.Postings = append(entry.Postings, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: failed to iterate postings: %w", op, err)
+	}
+
+	return &entry, nil
+}
+
+// postEntry writes a balanced journal entry and applies it to the cached wallet balances.
+func postEntry(tx *sql.Tx, kind models.EntryKind, description string, postings []models.Posting) (int64, error) {
+	entryID, err := insertEntry(tx, kind, description, postings)
+	if err != nil {
+		return 0, err
+	}
+
+	queryUpdate := `
+    UPDATE wallets w
+    SET balance = w.balance + $1
+    FROM ledger_accounts a
+    WHERE a.ID = $2 AND a.wallet_id = w.ID
+	`
+	for _, p := range postings {
+		delta := p.Amount
+		if p.Direction == models.Debit {
+			delta = delta.Neg()
+		}
+		if _, err := tx.Exec(queryUpdate, delta, p.AccountID); err != nil {
+			return 0, fmt.Errorf("failed to update cached balance: %w", err)
+		}
+	}
+
+	return entryID, nil
+}
+
+// insertEntry writes a journal entry and its postings without touching wallets.balance.
+func insertEntry(tx *sql.Tx, kind models.EntryKind, description string, postings []models.Posting) (int64, error) {
+	if err := checkBalanced(postings); err != nil {
+		return 0, err
+	}
+
+	var entryID int64
+	err := tx.QueryRow(`INSERT INTO journal_entries (kind, description) VALUES ($1, $2) RETURNING ID`,
+		string(kind), description).Scan(&entryID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert journal entry: %w", err)
+	}
+
+	queryPosting := `
+	INSERT INTO postings (entry_id, account_id, direction, amount)
+	VALUES ($1, $2, $3, $4)`
+	for _, p := range postings {
+		if _, err := tx.Exec(queryPosting, entryID, p.AccountID, string(p.Direction), p.Amount); err != nil {
+			return 0, fmt.Errorf("failed to insert posting: %w", err)
+		}
+	}
+
+	return entryID, nil
+}
+
+func checkBalanced(postings []models.Posting) error {
+	if len(postings) < 2 {
+		return errors.New("journal entry needs at least two postings")
+	}
+
+	sums := make(map[string]money.Amount)
+	for _, p := range postings {
+		if !p.Amount.IsPositive() {
+			return fmt.Errorf("posting amount must be positive, got %s", p.Amount)
+		}
+		switch p.Direction {
+		case models.Debit:
+			sums[p.Currency] = sums[p.Currency].Add(p.Amount)
+		case models.Credit:
+			sums[p.Currency] = sums[p.Currency].Sub(p.Amount)
+		default:
+			return fmt.Errorf("unknown posting direction %q", p.Direction)
+		}
+	}
+	for currency, sum := range sums {
+		if !sum.IsZero() {
+			return fmt.Errorf("journal entry is not balanced in %s", currency)
+		}
+	}
+	return nil
+}
+
+// transferPostings moves amount from one account to another.
+// A negative amount moves it the other way round.
+func transferPostings(from, to int, currency string, amount money.Amount) []models.Posting {
+	if amount.IsNegative() {
+		from, to, amount = to, from, amount.Neg()
+	}
+	return []models.Posting{
+		{AccountID: from, Currency: currency, Direction: models.Debit, Amount: amount},
+		{AccountID: to, Currency: currency, Direction: models.Credit, Amount: amount},
+	}
+}
+
+func walletAccountID(tx *sql.Tx, walletID int, currency string) (int, error) {
+	return accountID(tx, fmt.Sprintf("wallet:%d", walletID), &walletID, currency)
+}
+
+func systemAccountID(tx *sql.Tx, name, currency string) (int, error) {
+	return accountID(tx, fmt.Sprintf("system:%s:%s", name, currency), nil, currency)
+}
+
+func accountID(tx *sql.Tx, code string, walletID *int, currency string) (int, error) {
+	queryInsert := `
+	INSERT INTO ledger_accounts (code, wallet_id, currency)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (code) DO NOTHING`
+	if _, err := tx.Exec(queryInsert, code, walletID, currency); err != nil {
+		return 0, fmt.Errorf("failed to create ledger account %s: %w", code, err)
+	}
+
+	var id int
+	if err := tx.QueryRow(`SELECT ID FROM ledger_accounts WHERE code = $1`, code).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get ledger account %s: %w", code, err)
+	}
+	return id, nil
+}
+
+// lockWallet returns the user's wallet in currency and locks the row until the end of tx.
+func lockWallet(tx *sql.Tx, username, currency string) (models.Wallet, error) {
+	query := `
+	SELECT w.ID, w.currency, w.balance
+	FROM wallets w
+	JOIN users u ON w.user_id = u.ID
+	WHERE u.username = $1 AND w.currency = $2
+	FOR UPDATE OF w`
+
+	var wallet models.Wallet
+	err := tx.QueryRow(query, username, currency).Scan(&wallet.ID, &wallet.Currency, &wallet.Balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		var userExists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)`, username).Scan(&userExists); err != nil {
+			return models.Wallet{}, fmt.Errorf("failed to check if user exists: %w", err)
+		}
+		if !userExists {
+			return models.Wallet{}, fmt.Errorf("user %s does not exist", username)
+		}
+		return models.Wallet{}, fmt.Errorf("No wallet found for user %s and currency %s", username, currency)
+	}
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to get wallet: %w", err)
+	}
+	return wallet, nil
+}
+
+// lockWallets locks the user's wallets in the given currencies in id order, so concurrent
+// operations on the same pair of wallets cannot deadlock, and returns them by currency.
+func lockWallets(tx *sql.Tx, username string, currencies ...string) (map[string]models.Wallet, error) {
+	query := `
+	SELECT w.ID, w.currency, w.balance
+	FROM wallets w
+	JOIN users u ON w.user_id = u.ID
+	WHERE u.username = $1 AND w.currency = ANY($2)
+	ORDER BY w.ID
+	FOR UPDATE OF w`
+
+	rows, err := tx.Query(query, username, pq.Array(currencies))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock wallets: %w", err)
+	}
+	defer rows.Close()
+
+	wallets := make(map[string]models.Wallet, len(currencies))
+	for rows.Next() {
+		var w models.Wallet
+		if err := rows.Scan(&w.ID, &w.Currency, &w.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		wallets[w.Currency] = w
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate wallets: %w", err)
+	}
+
+	for _, currency := range currencies {
+		if _, ok := wallets[currency]; !ok {
+			return nil, fmt.Errorf("No wallet found for user %s and currency %s: %w", username, currency, storage.ErrWalletNotFound)
+		}
+	}
+	return wallets, nil
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+)
+
+func (s *Storage) InitLockoutSchema() error {
+	const op = "storage.postgres.InitLockoutSchema"
+	query := `
+	CREATE TABLE IF NOT EXISTS login_failures (
+    scope VARCHAR(16) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    blocked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, subject)
+);
+	CREATE TABLE IF NOT EXISTS unlock_tokens (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// GetLoginFailures returns the failed logins of subject, a username or an IP by scope.
+func (s *Storage) GetLoginFailures(scope, subject string) (*models.LoginFailures, error) {
+	const op = "storage.postgres.GetLoginFailures"
+
+	failures := &models.LoginFailures{Scope: scope, Subject: subject}
+	var blockedUntil sql.NullTime
+	query := `
+	SELECT failures, last_failure_at, blocked_until
+	FROM login_failures
+	WHERE scope = $1 AND subject = $2`
+	err := s.db.QueryRow(query, scope, subject).Scan(&failures.Failures, &failures.LastFailureAt, &blockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrLoginFailuresNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	failures.BlockedUntil = blockedUntil.Time
+	return failures, nil
+}
+
+// ClaimLoginAttempt counts a login attempt of subject at and returns the number of attempts
+// counted so far. The attempt is refused with storage.ErrLoginBlocked while subject is blocked
+// or has limit attempts counted, so parallel attempts cannot get past the limit.
+// Attempts before since are forgotten, the count starts over and any block is lifted; so does
+// a lockout that has expired. Forgotten counters of other subjects are removed on the way.
+func (s *Storage) ClaimLoginAttempt(scope, subject string, at, since time.Time, limit int) (int, error) {
+	const op = "storage.postgres.ClaimLoginAttempt"
+
+	var failures int
+	query := `
+	INSERT INTO login_failures (scope, subject, failures, last_failure_at)
+	VALUES ($1, $2, 1, $3)
+	ON CONFLICT (scope, subject) DO UPDATE SET
+		failures = CASE
+			WHEN login_failures.last_failure_at < $4
+				OR (login_failures.failures >= $5 AND login_failures.blocked_until <= $3) THEN 1
+			ELSE login_failures.failures + 1 END,
+		blocked_until = CASE
+			WHEN login_failures.last_failure_at < $4
+				OR (login_failures.failures >= $5 AND login_failures.blocked_until <= $3) THEN NULL
+			ELSE login_failures.blocked_until END,
+		last_failure_at = $3
+	WHERE login_failures.last_failure_at < $4
+		OR login_failures.blocked_until <= $3
+		OR (login_failures.blocked_until IS NULL AND login_failures.failures < $5)
+	RETURNING failures`
+	err := s.db.QueryRow(query, scope, subject, at, since, limit).Scan(&failures)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrLoginBlocked)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = s.db.Exec(`
+	DELETE FROM login_failures
+	WHERE last_failure_at < $1 AND (blocked_until IS NULL OR blocked_until <= $2)`, since, at)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to remove forgotten failures: %w", op, err)
+	}
+	return failures, nil
+}
+
+// ReleaseLoginAttempt stops counting one attempt of subject that turned out not to fail.
+func (s *Storage) ReleaseLoginAttempt(scope, subject string) error {
+	const op = "storage.postgres.ReleaseLoginAttempt"
+
+	query := `
+	UPDATE login_failures SET failures = failures - 1
+	WHERE scope = $1 AND subject = $2 AND failures > 0`
+	if _, err := s.db.Exec(query, scope, subject); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// BlockLogin rejects logins of subject until the given time, unless it is blocked for longer.
+func (s *Storage) BlockLogin(scope, subject string, until time.Time) error {
+	const op = "storage.postgres.BlockLogin"
+
+	query := `UPDATE login_failures SET blocked_until = GREATEST(blocked_until, $3) WHERE scope = $1 AND subject = $2`
+	result, err := s.db.Exec(query, scope, subject, until)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrLoginFailuresNotFound)
+	}
+	return nil
+}
+
+// ResetLoginFailures forgets the failed logins of subject and lifts its block.
+func (s *Storage) ResetLoginFailures(scope, subject string) error {
+	const op = "storage.postgres.ResetLoginFailures"
+
+	_, err := s.db.Exec(`DELETE FROM login_failures WHERE scope = $1 AND subject = $2`, scope, subject)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// CreateUnlockToken stores the token of an unlock link. Expired tokens are removed on the way.
+func (s *Storage) CreateUnlockToken(token *models.UnlockToken) error {
+	const op = "storage.postgres.CreateUnlockToken"
+
+	query := `
+	INSERT INTO unlock_tokens (token_hash, user_id, expires_at)
+	SELECT $1, u.ID, $3 FROM users u WHERE u.username = $2`
+	result, err := s.db.Exec(query, token.Hash, token.Username, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: failed to insert unlock token: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	_, err = s.db.Exec(`DELETE FROM unlock_tokens WHERE expires_at <= NOW()`)
+	if err != nil {
+		return fmt.Errorf("%s: failed to remove expired unlock tokens: %w", op, err)
+	}
+	return nil
+}
+
+// UseUnlockToken removes the unexpired token with hash and returns the user it unlocks.
+// Only one caller can use a token, the others get storage.ErrUnlockTokenNotFound.
+func (s *Storage) UseUnlockToken(hash string) (string, error) {
+	const op = "storage.postgres.UseUnlockToken"
+
+	var username string
+	query := `
+	DELETE FROM unlock_tokens t
+	USING users u
+	WHERE t.user_id = u.ID AND t.token_hash = $1 AND t.expires_at > NOW()
+	RETURNING u.username`
+	err := s.db.QueryRow(query, hash).Scan(&username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrUnlockTokenNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return username, nil
+}
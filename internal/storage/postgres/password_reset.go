@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+)
+
+func (s *Storage) InitPasswordResetSchema() error {
+	const op = "storage.postgres.InitPasswordResetSchema"
+	query := `
+	CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// GetUserByEmail returns the user with email.
+func (s *Storage) GetUserByEmail(email string) (*models.User, error) {
+	const op = "storage.postgres.GetUserByEmail"
+
+	user, err := scanUser(s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE email = $1`, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// CreatePasswordResetToken stores the token of a password reset link, unless the user got
+// one after notBefore, in which case it fails with storage.ErrPasswordResetRequestedRecently.
+// The user is locked while checking, so concurrent requests cannot both pass.
+// Expired tokens are removed on the way.
+func (s *Storage) CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken, notBefore time.Time) error {
+	const op = "storage.postgres.CreatePasswordResetToken"
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		err := tx.QueryRow(`SELECT ID FROM users WHERE username = $1 FOR UPDATE`, token.Username).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		var recent bool
+		err = tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM password_reset_tokens WHERE user_id = $1 AND created_at > $2)`,
+			userID, notBefore).Scan(&recent)
+		if err != nil {
+			return fmt.Errorf("failed to check recent password reset tokens: %w", err)
+		}
+		if recent {
+			return storage.ErrPasswordResetRequestedRecently
+		}
+
+		_, err = tx.Exec(`
+		INSERT INTO password_reset_tokens (token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3)`, token.Hash, userID, token.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert password reset token: %w", err)
+		}
+
+		_, err = tx.Exec(`DELETE FROM password_reset_tokens WHERE expires_at <= NOW()`)
+		if err != nil {
+			return fmt.Errorf("failed to remove expired password reset tokens: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ResetPassword uses the unexpired token with hash to replace the password hash of its user
+// and returns the username. All reset tokens and sessions of the user are revoked and
+// failed logins of the username are forgotten, as the user proved to own the email.
+// Only one caller can use a token, the others get storage.ErrPasswordResetTokenNotFound.
+func (s *Storage) ResetPassword(ctx context.Context, hash, passwordHash string) (string, error) {
+	const op = "storage.postgres.ResetPassword"
+
+	var username string
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		err := tx.QueryRow(`
+		DELETE FROM password_reset_tokens
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING user_id`, hash).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrPasswordResetTokenNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to use password reset token: %w", err)
+		}
+
+		err = tx.QueryRow(`
+		UPDATE users SET password_hash = $2 WHERE ID = $1
+		RETURNING username`, userID, passwordHash).Scan(&username)
+		if err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM password_reset_tokens WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to remove password reset tokens: %w", err)
+		}
+
+		if err := revokeUserSessions(tx, userID, ""); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+		DELETE FROM login_failures WHERE scope = $1 AND subject = $2`, models.LoginScopeUser, username)
+		if err != nil {
+			return fmt.Errorf("failed to reset failed logins: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return username, nil
+}
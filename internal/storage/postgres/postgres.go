@@ -1,13 +1,18 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/Foreground-Eclipse/transferer/config"
-	_ "github.com/lib/pq"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/lib/pq"
 )
 
 // docker run --name exchanger -p 5432:5432 -e POSTGRES_USER=postgres -e POSTGRES_PASSWORD=Tatsh -e POSTGRES_DB=exchanger -d postgres
@@ -70,7 +75,15 @@ func (s *Storage) InitWalletSchema() error {
     currency VARCHAR(3) NOT NULL,                  
     balance NUMERIC(19, 8) NOT NULL DEFAULT 0,     
     UNIQUE (user_id, currency)
-);`
+);
+
+	DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'wallets_balance_non_negative') THEN
+        ALTER TABLE wallets ADD CONSTRAINT wallets_balance_non_negative CHECK (balance >= 0) NOT VALID;
+    END IF;
+END;
+$$;`
 	_, err := s.db.Exec(query)
 	if err != nil {
 		return fmt.Errorf("%s : %w", op, err)
@@ -111,6 +124,32 @@ VALUES
 	return nil
 }
 
+// GetRates returns the quotes stored in the currency table keyed by pair, e.g. RUB_USD.
+func (s *Storage) GetRates(ctx context.Context) (map[string]money.Amount, error) {
+	const op = "storage.postgres.GetRates"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT from_currency, to_currency, rate FROM currency`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get rates: %w", op, err)
+	}
+	defer rows.Close()
+
+	quotes := make(map[string]money.Amount)
+	for rows.Next() {
+		var from, to string
+		var rate money.Amount
+		if err := rows.Scan(&from, &to, &rate); err != nil {
+			return nil, fmt.Errorf("%s: failed to scan rate: %w", op, err)
+		}
+		quotes[from+"_"+to] = rate
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: failed to iterate rates: %w", op, err)
+	}
+
+	return quotes, nil
+}
+
 func (s *Storage) RegisterUser(username, passwordHash, email string) error {
 	const op = "storage.postgres.RegisterUser"
 	tx, err := s.db.Begin()
@@ -196,6 +235,34 @@ func (s *Storage) DoesEmailExists(email string) (bool, error) {
 	}
 }
 
+func (s *Storage) GetUser(username string) (*models.User, error) {
+	const op = "storage.postgres.GetUser"
+
+	user, err := scanUser(s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE username = $1`, username))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+// userColumns are the columns of users read by scanUser.
+const userColumns = `ID, username, password_hash, COALESCE(email, ''), email_verified_at IS NOT NULL,
+	roles, frozen_at IS NOT NULL, frozen_reason`
+
+// scanUser reads a row of userColumns from a *sql.Row or *sql.Rows.
+func scanUser(row interface{ Scan(dest ...any) error }) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Email, &user.EmailVerified,
+		pq.Array(&user.Roles), &user.Frozen, &user.FrozenReason)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (s *Storage) GetUsersPassHash(username string) (string, error) {
 	const op = "storage.postgres.GetUsersPassHash"
 
@@ -211,7 +278,50 @@ func (s *Storage) GetUsersPassHash(username string) (string, error) {
 	return passhash, nil
 }
 
-func (s *Storage) GetUserBalance(username string) (map[string]float64, error) {
+// UpdatePasswordHash replaces the password hash of username and revokes all other sessions
+// of the user in the same transaction, so a stolen session does not outlive the change.
+// keepSessionID is the session the change was made from.
+func (s *Storage) UpdatePasswordHash(ctx context.Context, username, passwordHash, keepSessionID string) error {
+	const op = "storage.postgres.UpdatePasswordHash"
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		err := tx.QueryRow(`UPDATE users SET password_hash = $2 WHERE username = $1 RETURNING ID`,
+			username, passwordHash).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		return revokeUserSessions(tx, userID, keepSessionID)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// UpdateEmail replaces the email of username, the new email is not verified.
+// Returns storage.ErrEmailTaken when another user already has email.
+func (s *Storage) UpdateEmail(username, email string) error {
+	const op = "storage.postgres.UpdateEmail"
+
+	result, err := s.db.Exec(`UPDATE users SET email = $2, email_verified_at = NULL WHERE username = $1`, username, email)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("%s: %w", op, storage.ErrEmailTaken)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (s *Storage) GetUserBalance(username string) (map[string]money.Amount, error) {
 	query := `
 		SELECT
 			w.currency,
@@ -231,10 +341,10 @@ func (s *Storage) GetUserBalance(username string) (map[string]float64, error) {
 	}
 	defer rows.Close()
 
-	balances := make(map[string]float64)
+	balances := make(map[string]money.Amount)
 	for rows.Next() {
 		var currency string
-		var balance float64
+		var balance money.Amount
 		err := rows.Scan(&currency, &balance)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning row: %w", err)
@@ -249,22 +359,189 @@ func (s *Storage) GetUserBalance(username string) (map[string]float64, error) {
 	return balances, nil
 }
 
-func (s *Storage) UpdateUsersBalance(username, currency string, amount float64) error {
+// UpdateUsersBalance posts a deposit (positive amount) or a withdrawal (negative amount)
+// to the ledger and updates the cached wallet balance in the same transaction.
+// A withdrawal is checked against the balance under the wallet lock and fails with
+// storage.ErrNotEnoughFunds when it would overdraw the wallet.
+func (s *Storage) UpdateUsersBalance(username, currency string, amount money.Amount) error {
 	const op = "storage.postgres.UpdateUsersBalance"
 
-	queryUserCheck := `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)`
-	var userExists bool
-	err := s.db.QueryRow(queryUserCheck, username).Scan(&userExists)
+	if amount.IsZero() {
+		return fmt.Errorf("%s: amount must not be zero", op)
+	}
+
+	err := s.withTx(context.Background(), func(tx *sql.Tx) error {
+		wallet, err := lockWallet(tx, username, currency)
+		if err != nil {
+			return err
+		}
+		if amount.IsNegative() && wallet.Balance.LessThan(amount.Abs()) {
+			return storage.ErrNotEnoughFunds
+		}
+		return postWalletMovement(tx, wallet, username, amount)
+	})
 	if err != nil {
-		return fmt.Errorf("%s: failed to check if user exists: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
-	if !userExists {
-		return fmt.Errorf("%s: user %s does not exist", op, username)
+
+	log.Printf("Updated wallet for user %s in currency %s by %s\n", username, currency, amount)
+
+	return nil
+}
+
+// WithdrawWithinLimit withdraws amount of currency from the user's wallet unless the user's
+// withdrawals in currency since since and amount add up to more than limit, in which case it
+// fails with storage.ErrWithdrawalLimitExceeded. The total is summed under the wallet lock,
+// so concurrent withdrawals cannot both slip under the limit.
+func (s *Storage) WithdrawWithinLimit(username, currency string, amount, limit money.Amount, since time.Time) error {
+	const op = "storage.postgres.WithdrawWithinLimit"
+
+	if !amount.IsPositive() {
+		return fmt.Errorf("%s: amount must be positive", op)
 	}
 
-	tx, err := s.db.Begin()
+	query := `
+	SELECT COALESCE(SUM(t.amount), 0)
+	FROM transactions t
+	JOIN users u ON t.user_id = u.ID
+	WHERE u.username = $1 AND t.type = $2 AND t.currency = $3 AND t.created_at > $4`
+
+	err := s.withTx(context.Background(), func(tx *sql.Tx) error {
+		wallet, err := lockWallet(tx, username, currency)
+		if err != nil {
+			return err
+		}
+		if wallet.Balance.LessThan(amount) {
+			return storage.ErrNotEnoughFunds
+		}
+
+		var withdrawn money.Amount
+		if err := tx.QueryRow(query, username, string(models.EntryWithdrawal), currency, since).Scan(&withdrawn); err != nil {
+			return fmt.Errorf("failed to get withdrawn total: %w", err)
+		}
+		if withdrawn.Add(amount).GreaterThan(limit) {
+			return storage.ErrWithdrawalLimitExceeded
+		}
+
+		return postWalletMovement(tx, wallet, username, amount.Neg())
+	})
 	if err != nil {
-		return fmt.Errorf("%s: failed to begin transaction: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// postWalletMovement posts a deposit or a withdrawal of amount to the locked wallet.
+func postWalletMovement(tx *sql.Tx, wallet models.Wallet, username string, amount money.Amount) error {
+	kind, clearing := models.EntryDeposit, models.AccountDepositsClearing
+	if amount.IsNegative() {
+		kind, clearing = models.EntryWithdrawal, models.AccountWithdrawalsClearing
+	}
+
+	walletAccount, err := walletAccountID(tx, wallet.ID, wallet.Currency)
+	if err != nil {
+		return err
+	}
+	clearingAccount, err := systemAccountID(tx, clearing, wallet.Currency)
+	if err != nil {
+		return err
+	}
+
+	postings := transferPostings(clearingAccount, walletAccount, wallet.Currency, amount)
+	entryID, err := postEntry(tx, kind, fmt.Sprintf("%s by %s", kind, username), postings)
+	if err != nil {
+		return err
+	}
+
+	return insertTransaction(tx, wallet.ID, entryID, models.Transaction{
+		Type:     kind,
+		Currency: wallet.Currency,
+		Amount:   amount.Abs(),
+	})
+}
+
+// Exchange converts amount of fromCurrency into toCurrency at rate (units of toCurrency per
+// unit of fromCurrency) and returns the credited amount. Both wallets are locked for the whole
+// transaction, so the funds check and the two legs of the exchange are atomic.
+func (s *Storage) Exchange(ctx context.Context, username, fromCurrency, toCurrency string, amount, rate money.Amount) (money.Amount, error) {
+	const op = "storage.postgres.Exchange"
+
+	if fromCurrency == toCurrency {
+		return money.Zero, fmt.Errorf("%s: cannot exchange %s to itself", op, fromCurrency)
+	}
+	if !amount.IsPositive() || !rate.IsPositive() {
+		return money.Zero, fmt.Errorf("%s: amount and rate must be positive", op)
+	}
+
+	converted := amount.Mul(rate).Round()
+	if !converted.IsPositive() {
+		return money.Zero, fmt.Errorf("%s: amount is too small to exchange", op)
+	}
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return exchange(tx, username, fromCurrency, toCurrency, amount, rate, converted)
+	})
+	if err != nil {
+		return money.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return converted, nil
+}
+
+// exchange debits amount of fromCurrency and credits converted of toCurrency through the FX house.
+func exchange(tx *sql.Tx, username, fromCurrency, toCurrency string, amount, rate, converted money.Amount) error {
+	wallets, err := lockWallets(tx, username, fromCurrency, toCurrency)
+	if err != nil {
+		return err
+	}
+	from, to := wallets[fromCurrency], wallets[toCurrency]
+
+	if from.Balance.LessThan(amount) {
+		return storage.ErrNotEnoughFunds
+	}
+
+	fromAccount, err := walletAccountID(tx, from.ID, fromCurrency)
+	if err != nil {
+		return err
+	}
+	toAccount, err := walletAccountID(tx, to.ID, toCurrency)
+	if err != nil {
+		return err
+	}
+	fxFrom, err := systemAccountID(tx, models.AccountFXHouse, fromCurrency)
+	if err != nil {
+		return err
+	}
+	fxTo, err := systemAccountID(tx, models.AccountFXHouse, toCurrency)
+	if err != nil {
+		return err
+	}
+
+	postings := append(
+		transferPostings(fromAccount, fxFrom, fromCurrency, amount),
+		transferPostings(fxTo, toAccount, toCurrency, converted)...,
+	)
+	description := fmt.Sprintf("exchange %s to %s at %s by %s", fromCurrency, toCurrency, rate, username)
+	entryID, err := postEntry(tx, models.EntryExchange, description, postings)
+	if err != nil {
+		return err
+	}
+
+	return insertTransaction(tx, from.ID, entryID, models.Transaction{
+		Type:       models.EntryExchange,
+		Currency:   fromCurrency,
+		Amount:     amount,
+		ToCurrency: toCurrency,
+		ToAmount:   converted,
+		Rate:       rate,
+	})
+}
+
+// withTx runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+func (s *Storage) withTx(ctx context.Context, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
 	defer func() {
@@ -282,33 +559,5 @@ func (s *Storage) UpdateUsersBalance(username, currency string, amount float64)
 		}
 	}()
 
-	var userID int
-	queryGetUserID := `SELECT id FROM users WHERE username = $1`
-	err = tx.QueryRow(queryGetUserID, username).Scan(&userID)
-	if err != nil {
-		return fmt.Errorf("%s: failed to get user_id: %w", op, err)
-	}
-
-	queryUpdate := `
-    UPDATE wallets
-    SET balance = balance + $1
-    WHERE user_id = $2 AND currency = $3
-	`
-	result, err := tx.Exec(queryUpdate, amount, userID, currency)
-	if err != nil {
-		return fmt.Errorf("%s: failed to update wallet: %w", op, err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("%s: failed to get rows affected: %w", op, err)
-	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("%s: No wallet found for user %s and currency %s", op, username, currency)
-	}
-
-	log.Printf("Updated wallet for user %s in currency %s by %f\n", username, currency, amount)
-
-	return nil
+	return fn(tx)
 }
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
+func (s *Storage) InitQuoteSchema() error {
+	const op = "storage.postgres.InitQuoteSchema"
+	query := `
+	CREATE TABLE IF NOT EXISTS exchange_quotes (
+    ID CHAR(32) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    amount NUMERIC(19, 8) NOT NULL,
+    rate NUMERIC(19, 8) NOT NULL,
+    to_amount NUMERIC(19, 8) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ
+);`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// CreateQuote locks rate for exchanging amount of fromCurrency into toCurrency for ttl.
+// The expiry is taken from the database clock, the same one ExchangeQuote checks against.
+func (s *Storage) CreateQuote(username, fromCurrency, toCurrency string, amount, rate money.Amount, ttl time.Duration) (*models.Quote, error) {
+	const op = "storage.postgres.CreateQuote"
+
+	if fromCurrency == toCurrency {
+		return nil, fmt.Errorf("%s: cannot exchange %s to itself", op, fromCurrency)
+	}
+	if !amount.IsPositive() || !rate.IsPositive() {
+		return nil, fmt.Errorf("%s: amount and rate must be positive", op)
+	}
+
+	quote := &models.Quote{
+		FromCurrency: fromCurrency,
+		ToCurrency:   toCurrency,
+		Amount:       amount,
+		Rate:         rate,
+		ToAmount:     amount.Mul(rate).Round(),
+	}
+	if !quote.ToAmount.IsPositive() {
+		return nil, fmt.Errorf("%s: amount is too small to exchange", op)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("%s: failed to generate quote id: %w", op, err)
+	}
+	quote.ID = hex.EncodeToString(id)
+
+	query := `
+	INSERT INTO exchange_quotes (ID, user_id, from_currency, to_currency, amount, rate, to_amount, expires_at)
+	SELECT $2, ID, $3, $4, $5, $6, $7, NOW() + $8 * INTERVAL '1 millisecond' FROM users WHERE username = $1
+	RETURNING expires_at`
+	err := s.db.QueryRow(query, username, quote.ID, fromCurrency, toCurrency,
+		quote.Amount, quote.Rate, quote.ToAmount, ttl.Milliseconds()).Scan(&quote.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to insert quote: %w", op, err)
+	}
+
+	return quote, nil
+}
+
+// ExchangeQuote executes the user's quote at its locked rate and marks it used.
+// A quote that is unknown, expired or already used is never executed.
+func (s *Storage) ExchangeQuote(ctx context.Context, username, quoteID string) (*models.Quote, error) {
+	const op = "storage.postgres.ExchangeQuote"
+
+	quote := &models.Quote{ID: quoteID}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var used, expired bool
+		query := `
+		SELECT q.from_currency, q.to_currency, q.amount, q.rate, q.to_amount, q.expires_at,
+		       q.used_at IS NOT NULL, q.expires_at <= NOW()
+		FROM exchange_quotes q
+		JOIN users u ON q.user_id = u.ID
+		WHERE q.ID = $1 AND u.username = $2
+		FOR UPDATE OF q`
+		err := tx.QueryRow(query, quoteID, username).Scan(&quote.FromCurrency, &quote.ToCurrency,
+			&quote.Amount, &quote.Rate, &quote.ToAmount, &quote.ExpiresAt, &used, &expired)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrQuoteNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get quote: %w", err)
+		}
+
+		if used {
+			return storage.ErrQuoteUsed
+		}
+		if expired {
+			return storage.ErrQuoteExpired
+		}
+
+		if err := exchange(tx, username, quote.FromCurrency, quote.ToCurrency, quote.Amount, quote.Rate, quote.ToAmount); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE exchange_quotes SET used_at = NOW() WHERE ID = $1`, quoteID)
+		if err != nil {
+			return fmt.Errorf("failed to mark quote used: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return quote, nil
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+	"github.com/lib/pq"
+)
+
+func (s *Storage) InitRateHistorySchema() error {
+	const op = "storage.postgres.InitRateHistorySchema"
+	query := `
+	CREATE TABLE IF NOT EXISTS rate_history (
+    ID BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    rate NUMERIC(19, 8) NOT NULL,
+    source VARCHAR(32) NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL
+);
+	CREATE INDEX IF NOT EXISTS rate_history_pair_idx ON rate_history (from_currency, to_currency, fetched_at);`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// SaveRates stores a snapshot of quotes keyed by pair, e.g. RUB_USD, fetched from source at fetchedAt.
+func (s *Storage) SaveRates(ctx context.Context, source string, fetchedAt time.Time, quotes map[string]money.Amount) error {
+	const op = "storage.postgres.SaveRates"
+
+	if len(quotes) == 0 {
+		return nil
+	}
+
+	from := make([]string, 0, len(quotes))
+	to := make([]string, 0, len(quotes))
+	rates := make([]string, 0, len(quotes))
+	for pair, rate := range quotes {
+		fromCurrency, toCurrency, ok := strings.Cut(pair, "_")
+		if !ok {
+			return fmt.Errorf("%s: malformed currency pair %q", op, pair)
+		}
+		from = append(from, fromCurrency)
+		to = append(to, toCurrency)
+		rates = append(rates, rate.String())
+	}
+
+	query := `
+	INSERT INTO rate_history (from_currency, to_currency, rate, source, fetched_at)
+	SELECT f, t, r::NUMERIC, $4, $5
+	FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[]) AS q(f, t, r)`
+	_, err := s.db.ExecContext(ctx, query, pq.Array(from), pq.Array(to), pq.Array(rates), source, fetchedAt)
+	if err != nil {
+		return fmt.Errorf("%s: failed to insert rates: %w", op, err)
+	}
+	return nil
+}
+
+// GetRateHistory returns up to limit quotes of the pair fetched in [start, end), oldest first.
+func (s *Storage) GetRateHistory(fromCurrency, toCurrency string, start, end time.Time, limit int) ([]models.RatePoint, error) {
+	const op = "storage.postgres.GetRateHistory"
+
+	query := `
+	SELECT rate, source, fetched_at
+	FROM rate_history
+	WHERE from_currency = $1 AND to_currency = $2 AND fetched_at >= $3 AND fetched_at < $4
+	ORDER BY fetched_at, ID
+	LIMIT $5`
+	rows, err := s.db.Query(query, fromCurrency, toCurrency, start, end, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get rates: %w", op, err)
+	}
+	defer rows.Close()
+
+	var points []models.RatePoint
+	for rows.Next() {
+		var p models.RatePoint
+		if err := rows.Scan(&p.Rate, &p.Source, &p.FetchedAt); err != nil {
+			return nil, fmt.Errorf("%s: failed to scan rate: %w", op, err)
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: failed to iterate rates: %w", op, err)
+	}
+
+	return points, nil
+}
+
+// GetRateCandles groups the quotes of the pair fetched in [start, end) into buckets of interval,
+// aligned to the Unix epoch, and returns the open, high, low and close rate of every non-empty bucket.
+func (s *Storage) GetRateCandles(fromCurrency, toCurrency string, start, end time.Time, interval time.Duration) ([]models.RateCandle, error) {
+	const op = "storage.postgres.GetRateCandles"
+
+	query := `
+	SELECT
+		to_timestamp(floor(extract(epoch FROM fetched_at) / $5) * $5) AS bucket,
+		(array_agg(rate ORDER BY fetched_at, ID))[1],
+		max(rate),
+		min(rate),
+		(array_agg(rate ORDER BY fetched_at DESC, ID DESC))[1],
+		count(*)
+	FROM rate_history
+	WHERE from_currency = $1 AND to_currency = $2 AND fetched_at >= $3 AND fetched_at < $4
+	GROUP BY bucket
+	ORDER BY bucket`
+	rows, err := s.db.Query(query, fromCurrency, toCurrency, start, end, interval.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get candles: %w", op, err)
+	}
+	defer rows.Close()
+
+	var candles []models.RateCandle
+	for rows.Next() {
+		var c models.RateCandle
+		if err := rows.Scan(&c.Start, &c.Open, &c.High, &c.Low, &c.Close, &c.Count); err != nil {
+			return nil, fmt.Errorf("%s: failed to scan candle: %w", op, err)
+		}
+		c.Start = c.Start.UTC()
+		candles = append(candles, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: failed to iterate candles: %w", op, err)
+	}
+
+	return candles, nil
+}
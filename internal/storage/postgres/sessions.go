@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/lib/pq"
+)
+
+func (s *Storage) InitSessionSchema() error {
+	const op = "storage.postgres.InitSessionSchema"
+	query := `
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash CHAR(64) PRIMARY KEY,
+    family_id CHAR(32) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    access_token_id CHAR(32) NOT NULL,
+    access_expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+	ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMPTZ;
+	ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{}';
+	CREATE INDEX IF NOT EXISTS refresh_tokens_family_idx ON refresh_tokens (family_id);
+	CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    token_id CHAR(32) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// CreateRefreshToken stores a refresh token. A token added to a family that was revoked
+// in the meantime is stored revoked.
+func (s *Storage) CreateRefreshToken(token *models.RefreshToken) error {
+	const op = "storage.postgres.CreateRefreshToken"
+
+	query := `
+	INSERT INTO refresh_tokens (token_hash, family_id, user_id, access_token_id, access_expires_at, expires_at,
+	                            auth_time, amr, revoked_at)
+	SELECT $1, $2, u.ID, $4, $5, $6, $7, $8,
+	       (SELECT MAX(revoked_at) FROM refresh_tokens WHERE family_id = $2)
+	FROM users u WHERE u.username = $3`
+	result, err := s.db.Exec(query, token.Hash, token.FamilyID, token.Username,
+		token.AccessTokenID, token.AccessExpiresAt, token.ExpiresAt, token.AuthTime, pq.Array(token.AuthMethods))
+	if err != nil {
+		return fmt.Errorf("%s: failed to insert refresh token: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// ConsumeRefreshToken marks the refresh token with hash used and returns it, so it can be
+// replaced with a new one. Presenting a used token again revokes its whole family.
+func (s *Storage) ConsumeRefreshToken(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	const op = "storage.postgres.ConsumeRefreshToken"
+
+	token := &models.RefreshToken{Hash: hash}
+	var reused bool
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var used, revoked, expired bool
+		query := `
+		SELECT r.family_id, u.username, r.access_token_id, r.access_expires_at, r.expires_at,
+		       COALESCE(r.auth_time, r.created_at), r.amr,
+		       r.used_at IS NOT NULL, r.revoked_at IS NOT NULL, r.expires_at <= NOW()
+		FROM refresh_tokens r
+		JOIN users u ON r.user_id = u.ID
+		WHERE r.token_hash = $1
+		FOR UPDATE OF r`
+		err := tx.QueryRow(query, hash).Scan(&token.FamilyID, &token.Username, &token.AccessTokenID,
+			&token.AccessExpiresAt, &token.ExpiresAt, &token.AuthTime, pq.Array(&token.AuthMethods),
+			&used, &revoked, &expired)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrRefreshTokenNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get refresh token: %w", err)
+		}
+
+		switch {
+		case revoked:
+			return storage.ErrRefreshTokenRevoked
+		case used:
+			// the revocation has to be committed, so the error is returned after the transaction
+			reused = true
+			return revokeFamily(tx, token.FamilyID)
+		case expired:
+			return storage.ErrRefreshTokenExpired
+		}
+
+		_, err = tx.Exec(`UPDATE refresh_tokens SET used_at = NOW() WHERE token_hash = $1`, hash)
+		if err != nil {
+			return fmt.Errorf("failed to mark refresh token used: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if reused {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrRefreshTokenReused)
+	}
+
+	return token, nil
+}
+
+// RevokeSession revokes every refresh token of the family and the access tokens issued with them.
+func (s *Storage) RevokeSession(ctx context.Context, familyID string) error {
+	const op = "storage.postgres.RevokeSession"
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return revokeFamily(tx, familyID)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func revokeFamily(tx *sql.Tx, familyID string) error {
+	_, err := tx.Exec(`
+	UPDATE refresh_tokens SET revoked_at = NOW()
+	WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	_, err = tx.Exec(`
+	INSERT INTO revoked_access_tokens (token_id, expires_at)
+	SELECT access_token_id, access_expires_at FROM refresh_tokens
+	WHERE family_id = $1 AND access_expires_at > NOW()
+	ON CONFLICT (token_id) DO NOTHING`, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	return nil
+}
+
+// revokeUserSessions revokes every session of the user with userID except the session
+// keepFamilyID, which may be empty to revoke them all.
+func revokeUserSessions(tx *sql.Tx, userID int, keepFamilyID string) error {
+	_, err := tx.Exec(`
+	INSERT INTO revoked_access_tokens (token_id, expires_at)
+	SELECT access_token_id, access_expires_at FROM refresh_tokens
+	WHERE user_id = $1 AND family_id <> $2 AND access_expires_at > NOW()
+	ON CONFLICT (token_id) DO NOTHING`, userID, keepFamilyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+
+	_, err = tx.Exec(`
+	UPDATE refresh_tokens SET revoked_at = NOW()
+	WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL`, userID, keepFamilyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// RevokeAccessToken puts the access token with id on the denylist until it expires.
+// Entries of expired tokens are removed on the way.
+func (s *Storage) RevokeAccessToken(id string, expiresAt time.Time) error {
+	const op = "storage.postgres.RevokeAccessToken"
+
+	_, err := s.db.Exec(`
+	INSERT INTO revoked_access_tokens (token_id, expires_at) VALUES ($1, $2)
+	ON CONFLICT (token_id) DO NOTHING`, id, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: failed to revoke access token: %w", op, err)
+	}
+
+	_, err = s.db.Exec(`DELETE FROM revoked_access_tokens WHERE expires_at <= NOW()`)
+	if err != nil {
+		return fmt.Errorf("%s: failed to remove expired entries: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) IsAccessTokenRevoked(id string) (bool, error) {
+	const op = "storage.postgres.IsAccessTokenRevoked"
+
+	var revoked bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE token_id = $1)`, id).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return revoked, nil
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+)
+
+func (s *Storage) InitTransactionSchema() error {
+	const op = "storage.postgres.InitTransactionSchema"
+	query := `
+	CREATE TABLE IF NOT EXISTS transactions (
+    ID BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    entry_id BIGINT NOT NULL REFERENCES journal_entries(ID),
+    type VARCHAR(16) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount NUMERIC(19, 8) NOT NULL,
+    to_currency VARCHAR(3),
+    to_amount NUMERIC(19, 8),
+    rate NUMERIC(19, 8),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+	ALTER TABLE transactions ADD COLUMN IF NOT EXISTS counterparty_id INTEGER REFERENCES users(ID) ON DELETE SET NULL;
+
+	CREATE INDEX IF NOT EXISTS transactions_user_created_idx ON transactions (user_id, created_at DESC, ID DESC);`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+// GetTransactions returns the user's transactions, newest first.
+// Transactions with the same timestamp are ordered by id so pages never overlap.
+func (s *Storage) GetTransactions(username string, filter models.TransactionFilter) ([]models.Transaction, error) {
+	const op = "storage.postgres.GetTransactions"
+
+	conditions := []string{"u.username = $1"}
+	args := []interface{}{username}
+	addCondition := func(format string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if filter.Type != "" {
+		addCondition("t.type = $%d", string(filter.Type))
+	}
+	if filter.Currency != "" {
+		args = append(args, filter.Currency)
+		conditions = append(conditions, fmt.Sprintf("(t.currency = $%d OR t.to_currency = $%[1]d)", len(args)))
+	}
+	if !filter.From.IsZero() {
+		addCondition("t.created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("t.created_at < $%d", filter.To)
+	}
+	if filter.MinAmount != nil {
+		addCondition("t.amount >= $%d", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		addCondition("t.amount <= $%d", *filter.MaxAmount)
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(t.created_at, t.ID) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf(`
+	SELECT t.ID, t.type, t.currency, t.amount, t.to_currency, t.to_amount, t.rate, cp.username, t.created_at
+	FROM transactions t
+	JOIN users u ON t.user_id = u.ID
+	LEFT JOIN users cp ON t.counterparty_id = cp.ID
+	WHERE %s
+	ORDER BY t.created_at DESC, t.ID DESC
+	LIMIT $%d`, strings.Join(conditions, " AND "), len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to query transactions: %w", op, err)
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		var t models.Transaction
+		var kind string
+		var toCurrency, counterparty sql.NullString
+		var toAmount, rate sql.NullString
+		err := rows.Scan(&t.ID, &kind, &t.Currency, &t.Amount, &toCurrency, &toAmount, &rate, &counterparty, &t.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to scan transaction: %w", op, err)
+		}
+		t.Type = models.EntryKind(kind)
+		t.ToCurrency = toCurrency.String
+		t.Counterparty = counterparty.String
+		if toAmount.Valid {
+			if err := t.ToAmount.Scan(toAmount.String); err != nil {
+				return nil, fmt.Errorf("%s: failed to scan to_amount: %w", op, err)
+			}
+		}
+		if rate.Valid {
+			if err := t.Rate.Scan(rate.String); err != nil {
+				return nil, fmt.Errorf("%s: failed to scan rate: %w", op, err)
+			}
+		}
+		transactions = append(transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: failed to iterate transactions: %w", op, err)
+	}
+
+	return transactions, nil
+}
+
+// insertTransaction records t for the owner of walletID as part of the ledger entry entryID.
+func insertTransaction(tx *sql.Tx, walletID int, entryID int64, t models.Transaction) error {
+	var toCurrency, toAmount, rate, counterparty interface{}
+	if t.ToCurrency != "" {
+		toCurrency, toAmount, rate = t.ToCurrency, t.ToAmount, t.Rate
+	}
+	if t.Counterparty != "" {
+		counterparty = t.Counterparty
+	}
+
+	query := `
+	INSERT INTO transactions (user_id, entry_id, type, currency, amount, to_currency, to_amount, rate, counterparty_id)
+	SELECT user_id, $2, $3, $4, $5, $6, $7, $8, (SELECT ID FROM users WHERE username = $9)
+	FROM wallets WHERE ID = $1`
+	_, err := tx.Exec(query, walletID, entryID, string(t.Type), t.Currency, t.Amount, toCurrency, toAmount, rate, counterparty)
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
+// FindRecipient looks a user up by username or email and returns the username together
+// with the currencies of the user's wallets, oldest wallet first.
+// A username match wins over an email match.
+func (s *Storage) FindRecipient(usernameOrEmail string) (string, []string, error) {
+	const op = "storage.postgres.FindRecipient"
+
+	var username string
+	query := `
+	SELECT username FROM users
+	WHERE username = $1 OR email = $1
+	ORDER BY (username = $1) DESC
+	LIMIT 1`
+	err := s.db.QueryRow(query, usernameOrEmail).Scan(&username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: failed to get user: %w", op, err)
+	}
+
+	rows, err := s.db.Query(`
+	SELECT w.currency FROM wallets w
+	JOIN users u ON w.user_id = u.ID
+	WHERE u.username = $1
+	ORDER BY w.ID`, username)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: failed to get wallets: %w", op, err)
+	}
+	defer rows.Close()
+
+	var currencies []string
+	for rows.Next() {
+		var currency string
+		if err := rows.Scan(&currency); err != nil {
+			return "", nil, fmt.Errorf("%s: failed to scan wallet: %w", op, err)
+		}
+		currencies = append(currencies, currency)
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, fmt.Errorf("%s: failed to iterate wallets: %w", op, err)
+	}
+
+	return username, currencies, nil
+}
+
+// HasTransferredTo reports whether sender has ever sent a transfer to recipient.
+func (s *Storage) HasTransferredTo(sender, recipient string) (bool, error) {
+	const op = "storage.postgres.HasTransferredTo"
+
+	var exists bool
+	query := `
+	SELECT EXISTS (
+		SELECT 1 FROM transactions t
+		JOIN users u ON t.user_id = u.ID
+		JOIN users cp ON t.counterparty_id = cp.ID
+		WHERE u.username = $1 AND cp.username = $2 AND t.type = $3
+	)`
+	err := s.db.QueryRow(query, sender, recipient, string(models.TransferOut)).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exists, nil
+}
+
+// Transfer moves amount of currency from the sender's wallet to the recipient's wallet in
+// toCurrency. If the currencies differ the amount is converted at rate through the FX house.
+// Both wallets are locked, the funds check and both legs happen in one transaction.
+func (s *Storage) Transfer(ctx context.Context, sender, recipient, currency, toCurrency string, amount, rate money.Amount) (*models.Transfer, error) {
+	const op = "storage.postgres.Transfer"
+
+	if sender == recipient {
+		return nil, fmt.Errorf("%s: cannot transfer to yourself", op)
+	}
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("%s: amount must be positive", op)
+	}
+
+	transfer := &models.Transfer{
+		Recipient:        recipient,
+		Currency:         currency,
+		Amount:           amount,
+		CreditedCurrency: toCurrency,
+		CreditedAmount:   amount,
+	}
+	if toCurrency != currency {
+		if !rate.IsPositive() {
+			return nil, fmt.Errorf("%s: rate must be positive", op)
+		}
+		transfer.Rate = rate
+		transfer.CreditedAmount = amount.Mul(rate).Round()
+		if !transfer.CreditedAmount.IsPositive() {
+			return nil, fmt.Errorf("%s: amount is too small to convert", op)
+		}
+	}
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		from, to, err := lockTransferWallets(tx, sender, currency, recipient, toCurrency)
+		if err != nil {
+			return err
+		}
+
+		if from.Balance.LessThan(amount) {
+			return storage.ErrNotEnoughFunds
+		}
+
+		fromAccount, err := walletAccountID(tx, from.ID, currency)
+		if err != nil {
+			return err
+		}
+		toAccount, err := walletAccountID(tx, to.ID, toCurrency)
+		if err != nil {
+			return err
+		}
+
+		var postings []models.Posting
+		if toCurrency == currency {
+			postings = transferPostings(fromAccount, toAccount, currency, amount)
+		} else {
+			fxFrom, err := systemAccountID(tx, models.AccountFXHouse, currency)
+			if err != nil {
+				return err
+			}
+			fxTo, err := systemAccountID(tx, models.AccountFXHouse, toCurrency)
+			if err != nil {
+				return err
+			}
+			postings = append(
+				transferPostings(fromAccount, fxFrom, currency, amount),
+				transferPostings(fxTo, toAccount, toCurrency, transfer.CreditedAmount)...,
+			)
+		}
+
+		description := fmt.Sprintf("transfer %s %s from %s to %s", amount, currency, sender, recipient)
+		transfer.EntryID, err = postEntry(tx, models.EntryTransfer, description, postings)
+		if err != nil {
+			return err
+		}
+
+		outgoing := models.Transaction{
+			Type:         models.TransferOut,
+			Currency:     currency,
+			Amount:       amount,
+			Counterparty: recipient,
+		}
+		if toCurrency != currency {
+			outgoing.ToCurrency, outgoing.ToAmount, outgoing.Rate = toCurrency, transfer.CreditedAmount, rate
+		}
+		if err := insertTransaction(tx, from.ID, transfer.EntryID, outgoing); err != nil {
+			return err
+		}
+
+		return insertTransaction(tx, to.ID, transfer.EntryID, models.Transaction{
+			Type:         models.TransferIn,
+			Currency:     toCurrency,
+			Amount:       transfer.CreditedAmount,
+			Counterparty: sender,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return transfer, nil
+}
+
+// lockTransferWallets locks the sender's and the recipient's wallets in id order.
+func lockTransferWallets(tx *sql.Tx, sender, currency, recipient, toCurrency string) (from, to models.Wallet, err error) {
+	query := `
+	SELECT w.ID, u.username, w.currency, w.balance
+	FROM wallets w
+	JOIN users u ON w.user_id = u.ID
+	WHERE (u.username = $1 AND w.currency = $2) OR (u.username = $3 AND w.currency = $4)
+	ORDER BY w.ID
+	FOR UPDATE OF w`
+
+	rows, err := tx.Query(query, sender, currency, recipient, toCurrency)
+	if err != nil {
+		return from, to, fmt.Errorf("failed to lock wallets: %w", err)
+	}
+	defer rows.Close()
+
+	var foundFrom, foundTo bool
+	for rows.Next() {
+		var w models.Wallet
+		var username string
+		if err := rows.Scan(&w.ID, &username, &w.Currency, &w.Balance); err != nil {
+			return from, to, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		if username == sender {
+			from, foundFrom = w, true
+		} else {
+			to, foundTo = w, true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return from, to, fmt.Errorf("failed to iterate wallets: %w", err)
+	}
+
+	if !foundFrom {
+		return from, to, fmt.Errorf("No wallet found for user %s and currency %s: %w", sender, currency, storage.ErrWalletNotFound)
+	}
+	if !foundTo {
+		return from, to, fmt.Errorf("No wallet found for user %s and currency %s", recipient, toCurrency)
+	}
+	return from, to, nil
+}
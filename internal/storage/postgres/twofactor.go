@@ -0,0 +1,241 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+)
+
+func (s *Storage) InitTwoFactorSchema() error {
+	const op = "storage.postgres.InitTwoFactorSchema"
+	query := `
+	CREATE TABLE IF NOT EXISTS user_totp (
+    user_id INTEGER PRIMARY KEY REFERENCES users(ID) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMPTZ
+);
+	CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    PRIMARY KEY (user_id, code_hash)
+);
+	CREATE TABLE IF NOT EXISTS login_challenges (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);`
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("%s : %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) GetTOTP(username string) (*models.TOTP, error) {
+	const op = "storage.postgres.GetTOTP"
+
+	totp := &models.TOTP{Username: username}
+	query := `
+	SELECT t.secret, t.enabled, t.last_step
+	FROM user_totp t
+	JOIN users u ON t.user_id = u.ID
+	WHERE u.username = $1`
+	err := s.db.QueryRow(query, username).Scan(&totp.Secret, &totp.Enabled, &totp.LastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrTOTPNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return totp, nil
+}
+
+// SaveTOTPSecret stores a new secret of username waiting for confirmation. It replaces
+// a secret that was never confirmed, an enabled one is kept.
+func (s *Storage) SaveTOTPSecret(username, secret string) error {
+	const op = "storage.postgres.SaveTOTPSecret"
+
+	query := `
+	INSERT INTO user_totp (user_id, secret)
+	SELECT u.ID, $2 FROM users u WHERE u.username = $1
+	ON CONFLICT (user_id) DO UPDATE
+	SET secret = EXCLUDED.secret, last_step = 0, created_at = NOW()
+	WHERE user_totp.enabled = FALSE`
+	result, err := s.db.Exec(query, username, secret)
+	if err != nil {
+		return fmt.Errorf("%s: failed to save secret: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		if _, err := s.GetUser(username); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPAlreadyEnabled)
+	}
+	return nil
+}
+
+// EnableTOTP turns on two-factor authentication of username, step is the time step of the code
+// it was confirmed with. The recovery codes replace any earlier ones.
+func (s *Storage) EnableTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error {
+	const op = "storage.postgres.EnableTOTP"
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var userID int
+		query := `
+		UPDATE user_totp t SET enabled = TRUE, last_step = $2, confirmed_at = NOW()
+		FROM users u
+		WHERE t.user_id = u.ID AND u.username = $1 AND t.enabled = FALSE
+		RETURNING t.user_id`
+		err := tx.QueryRow(query, username, step).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrTOTPAlreadyEnabled
+		}
+		if err != nil {
+			return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+		}
+
+		_, err = tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to remove recovery codes: %w", err)
+		}
+		for _, hash := range recoveryCodeHashes {
+			_, err = tx.Exec(`INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+			if err != nil {
+				return fmt.Errorf("failed to insert recovery code: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// UseTOTPStep records that a code of step was accepted. A code of step or an earlier step
+// accepted before fails with storage.ErrTOTPCodeUsed.
+func (s *Storage) UseTOTPStep(username string, step int64) error {
+	const op = "storage.postgres.UseTOTPStep"
+
+	query := `
+	UPDATE user_totp t SET last_step = $2
+	FROM users u
+	WHERE t.user_id = u.ID AND u.username = $1 AND t.last_step < $2`
+	result, err := s.db.Exec(query, username, step)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTOTPCodeUsed)
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the unused recovery code with hash used.
+func (s *Storage) UseRecoveryCode(username, hash string) error {
+	const op = "storage.postgres.UseRecoveryCode"
+
+	query := `
+	UPDATE totp_recovery_codes r SET used_at = NOW()
+	FROM users u
+	WHERE r.user_id = u.ID AND u.username = $1 AND r.code_hash = $2 AND r.used_at IS NULL`
+	result, err := s.db.Exec(query, username, hash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrRecoveryCodeNotFound)
+	}
+	return nil
+}
+
+// CreateLoginChallenge stores a login waiting for the second factor.
+// Expired challenges are removed on the way.
+func (s *Storage) CreateLoginChallenge(challenge *models.LoginChallenge) error {
+	const op = "storage.postgres.CreateLoginChallenge"
+
+	query := `
+	INSERT INTO login_challenges (token_hash, user_id, expires_at)
+	SELECT $1, u.ID, $3 FROM users u WHERE u.username = $2`
+	result, err := s.db.Exec(query, challenge.Hash, challenge.Username, challenge.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: failed to insert login challenge: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	_, err = s.db.Exec(`DELETE FROM login_challenges WHERE expires_at <= NOW()`)
+	if err != nil {
+		return fmt.Errorf("%s: failed to remove expired challenges: %w", op, err)
+	}
+	return nil
+}
+
+// GetLoginChallenge returns the unexpired challenge with hash.
+func (s *Storage) GetLoginChallenge(hash string) (*models.LoginChallenge, error) {
+	const op = "storage.postgres.GetLoginChallenge"
+
+	challenge := &models.LoginChallenge{Hash: hash}
+	query := `
+	SELECT u.username, c.attempts, c.expires_at
+	FROM login_challenges c
+	JOIN users u ON u.ID = c.user_id
+	WHERE c.token_hash = $1 AND c.expires_at > NOW()`
+	err := s.db.QueryRow(query, hash).Scan(&challenge.Username, &challenge.Attempts, &challenge.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrLoginChallengeNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return challenge, nil
+}
+
+// ClaimLoginChallengeAttempt counts an attempt at the challenge with hash and returns it,
+// unless it expired or already had maxAttempts attempts. The check and the count are one
+// statement, so concurrent attempts cannot go over the limit.
+func (s *Storage) ClaimLoginChallengeAttempt(hash string, maxAttempts int) (*models.LoginChallenge, error) {
+	const op = "storage.postgres.ClaimLoginChallengeAttempt"
+
+	challenge := &models.LoginChallenge{Hash: hash}
+	query := `
+	UPDATE login_challenges c
+	SET attempts = c.attempts + 1
+	FROM users u
+	WHERE c.token_hash = $1 AND c.expires_at > NOW() AND c.attempts < $2 AND u.ID = c.user_id
+	RETURNING u.username, c.attempts, c.expires_at`
+	err := s.db.QueryRow(query, hash, maxAttempts).Scan(&challenge.Username, &challenge.Attempts, &challenge.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrLoginChallengeNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return challenge, nil
+}
+
+// DeleteLoginChallenge removes the challenge with hash. Only one caller can delete it,
+// the others get storage.ErrLoginChallengeNotFound.
+func (s *Storage) DeleteLoginChallenge(hash string) error {
+	const op = "storage.postgres.DeleteLoginChallenge"
+
+	result, err := s.db.Exec(`DELETE FROM login_challenges WHERE token_hash = $1`, hash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrLoginChallengeNotFound)
+	}
+	return nil
+}
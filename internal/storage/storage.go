@@ -0,0 +1,51 @@
+package storage
+
+import "errors"
+
+var (
+	ErrNotEnoughFunds = errors.New("not enough money")
+	ErrUserNotFound   = errors.New("user not found")
+	ErrWalletNotFound = errors.New("wallet not found")
+	ErrEmailTaken     = errors.New("email is taken")
+	ErrQuoteNotFound  = errors.New("quote not found")
+	ErrQuoteExpired   = errors.New("quote expired")
+	ErrQuoteUsed      = errors.New("quote already used")
+
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	ErrRefreshTokenRevoked  = errors.New("refresh token revoked")
+	// ErrRefreshTokenReused means an already rotated refresh token was presented again,
+	// so it may have been stolen. Its whole family is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+
+	ErrTOTPNotFound       = errors.New("two-factor authentication not set up")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	// ErrTOTPCodeUsed means the code of this or a later time step was already accepted.
+	ErrTOTPCodeUsed           = errors.New("code already used")
+	ErrRecoveryCodeNotFound   = errors.New("recovery code not found")
+	ErrLoginChallengeNotFound = errors.New("login challenge not found")
+
+	ErrLoginFailuresNotFound = errors.New("no failed logins")
+	ErrLoginBlocked          = errors.New("login blocked")
+	ErrUnlockTokenNotFound   = errors.New("unlock token not found")
+
+	ErrPasswordResetTokenNotFound     = errors.New("password reset token not found")
+	ErrEmailVerificationTokenNotFound = errors.New("email verification token not found")
+	// ErrDepositLimitExceeded means a deposit would take an unverified user over their deposit limit.
+	ErrDepositLimitExceeded = errors.New("deposit limit exceeded")
+	// ErrWithdrawalLimitExceeded means recent withdrawals and the new one add up to more than
+	// the caller allowed without a fresh authentication.
+	ErrWithdrawalLimitExceeded = errors.New("withdrawal limit exceeded")
+	// ErrPasswordResetRequestedRecently means the user was mailed a password reset link too recently.
+	ErrPasswordResetRequestedRecently = errors.New("password reset requested recently")
+	// ErrEmailVerificationSentRecently means the user was mailed a verification link too recently.
+	ErrEmailVerificationSentRecently = errors.New("email verification sent recently")
+
+	ErrAdjustmentNotFound   = errors.New("adjustment not found")
+	ErrAdjustmentNotPending = errors.New("adjustment is not pending")
+	ErrAdjustmentExpired    = errors.New("adjustment expired")
+	// ErrSelfApproval means an admin tried to approve an adjustment they proposed.
+	ErrSelfApproval = errors.New("adjustment cannot be approved by its proposer")
+	// ErrSelfAdjustment means an admin tried to propose or approve an adjustment of their own balance.
+	ErrSelfAdjustment = errors.New("admins cannot adjust their own balance")
+)
@@ -0,0 +1,261 @@
+// Package tlsconfig builds TLS configurations from certificate files and reloads the
+// certificates when the files change, so they can be rotated without a restart.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// checkInterval is how often the certificate files are checked for changes.
+const checkInterval = 10 * time.Second
+
+// ParseVersion parses a TLS version such as "1.2". An empty string means TLS 1.2.
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version %q, use 1.2 or 1.3", version)
+	}
+}
+
+// Server returns the configuration of a TLS listener serving the certificate in certFile and keyFile.
+func Server(logger *zap.Logger, certFile, keyFile, minVersion string) (*tls.Config, error) {
+	const op = "tlsconfig.Server"
+
+	version, err := ParseVersion(minVersion)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	reloader, err := NewKeyPairReloader(logger, certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &tls.Config{
+		MinVersion:     version,
+		GetCertificate: reloader.GetCertificate,
+	}, nil
+}
+
+// Client returns the configuration of a TLS connection to serverName.
+// The server is verified against the CA bundle in caFile, reloaded when the file changes,
+// or the system roots if caFile is empty.
+// If certFile and keyFile are set, the client presents that certificate (mutual TLS).
+func Client(logger *zap.Logger, caFile, certFile, keyFile, serverName, minVersion string) (*tls.Config, error) {
+	const op = "tlsconfig.Client"
+
+	version, err := ParseVersion(minVersion)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cfg := &tls.Config{
+		MinVersion: version,
+		ServerName: serverName,
+	}
+
+	if caFile != "" {
+		roots, err := NewCAPoolReloader(logger, caFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		// The default verification only knows a fixed RootCAs, so it is replaced by
+		// VerifyConnection, which checks the chain against the current bundle.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			// no name is sent in SNI for an IP address
+			if cs.ServerName == "" {
+				cs.ServerName = serverName
+			}
+			return roots.VerifyConnection(cs)
+		}
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("%s: client certificate needs both a cert and a key file", op)
+		}
+		reloader, err := NewKeyPairReloader(logger, certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		cfg.GetClientCertificate = reloader.GetClientCertificate
+	}
+
+	return cfg, nil
+}
+
+// CAPoolReloader serves a CA pool loaded from a PEM bundle and reloads it when the file changes.
+// If a changed bundle cannot be loaded, the previous pool keeps being served.
+type CAPoolReloader struct {
+	caFile string
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu        sync.Mutex
+	pool      *x509.CertPool
+	mod       time.Time
+	lastCheck time.Time
+}
+
+func NewCAPoolReloader(logger *zap.Logger, caFile string) (*CAPoolReloader, error) {
+	r := &CAPoolReloader{
+		caFile: caFile,
+		logger: logger,
+		now:    time.Now,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Pool returns the current CA pool.
+func (r *CAPoolReloader) Pool() *x509.CertPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.now().Sub(r.lastCheck) >= checkInterval {
+		if err := r.reload(); err != nil {
+			r.logger.Error("failed to reload CA bundle, keeping the previous one",
+				zap.String("ca", r.caFile), zap.Error(err))
+		}
+	}
+	return r.pool
+}
+
+// VerifyConnection verifies the server certificate chain and name against the current pool,
+// as crypto/tls does with RootCAs.
+func (r *CAPoolReloader) VerifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tlsconfig: server presented no certificate")
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         r.Pool(),
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// reload loads the bundle if the file changed since the last load. r.mu must be held
+// unless r is not shared yet.
+func (r *CAPoolReloader) reload() error {
+	r.lastCheck = r.now()
+
+	info, err := os.Stat(r.caFile)
+	if err != nil {
+		return err
+	}
+	if r.pool != nil && info.ModTime().Equal(r.mod) {
+		return nil
+	}
+
+	pem, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in %s", r.caFile)
+	}
+
+	r.pool = pool
+	r.mod = info.ModTime()
+	r.logger.Info("loaded CA bundle", zap.String("ca", r.caFile))
+	return nil
+}
+
+// KeyPairReloader serves a certificate loaded from files and reloads it when either file changes.
+// If a changed pair cannot be loaded, e.g. because only one of the files was replaced yet,
+// the previous certificate keeps being served.
+type KeyPairReloader struct {
+	certFile string
+	keyFile  string
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+func NewKeyPairReloader(logger *zap.Logger, certFile, keyFile string) (*KeyPairReloader, error) {
+	r := &KeyPairReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   logger,
+		now:      time.Now,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *KeyPairReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate(), nil
+}
+
+func (r *KeyPairReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.certificate(), nil
+}
+
+func (r *KeyPairReloader) certificate() *tls.Certificate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.now().Sub(r.lastCheck) >= checkInterval {
+		if err := r.reload(); err != nil {
+			r.logger.Error("failed to reload certificate, keeping the previous one",
+				zap.String("cert", r.certFile), zap.Error(err))
+		}
+	}
+	return r.cert
+}
+
+// reload loads the key pair if the files changed since the last load. r.mu must be held
+// unless r is not shared yet.
+func (r *KeyPairReloader) reload() error {
+	r.lastCheck = r.now()
+
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil && certInfo.ModTime().Equal(r.certMod) && keyInfo.ModTime().Equal(r.keyMod) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	r.cert = &cert
+	r.certMod, r.keyMod = certInfo.ModTime(), keyInfo.ModTime()
+	r.logger.Info("loaded certificate", zap.String("cert", r.certFile))
+	return nil
+}
@@ -0,0 +1,265 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writeKeyPair writes a self-signed certificate for commonName and its key to dir.
+func writeKeyPair(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              []string{commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+// touch moves the modification time of the files forward, as a rewrite a second later would.
+func touch(t *testing.T, at time.Time, files ...string) {
+	t.Helper()
+
+	for _, file := range files {
+		require.NoError(t, os.Chtimes(file, at, at))
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    uint16
+		wantErr bool
+	}{
+		{version: "", want: tls.VersionTLS12},
+		{version: "1.2", want: tls.VersionTLS12},
+		{version: "1.3", want: tls.VersionTLS13},
+		{version: "1.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := ParseVersion(tt.version)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestKeyPairReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "first")
+
+	r, err := NewKeyPairReloader(zap.NewNop(), certFile, keyFile)
+	require.NoError(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "first", commonName(t, cert))
+
+	writeKeyPair(t, dir, "second")
+	touch(t, now.Add(time.Second), certFile, keyFile)
+
+	cert, _ = r.GetCertificate(nil)
+	assert.Equal(t, "first", commonName(t, cert), "files are not checked before checkInterval")
+
+	now = now.Add(checkInterval)
+	cert, _ = r.GetCertificate(nil)
+	assert.Equal(t, "second", commonName(t, cert))
+
+	// a half-written pair keeps the previous certificate
+	require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0o600))
+	touch(t, now.Add(2*time.Second), keyFile)
+	now = now.Add(checkInterval)
+	cert, _ = r.GetClientCertificate(nil)
+	assert.Equal(t, "second", commonName(t, cert))
+}
+
+// readCertificate parses the certificate written by writeKeyPair.
+func readCertificate(t *testing.T, certFile string) *x509.Certificate {
+	t.Helper()
+
+	data, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	block, _ := pem.Decode(data)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestCAPoolReloader(t *testing.T) {
+	dir := t.TempDir()
+	caFile, _ := writeKeyPair(t, dir, "exchanger")
+	first := readCertificate(t, caFile)
+
+	r, err := NewCAPoolReloader(zap.NewNop(), caFile)
+	require.NoError(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	verify := func(cert *x509.Certificate) error {
+		return r.VerifyConnection(tls.ConnectionState{ServerName: "exchanger", PeerCertificates: []*x509.Certificate{cert}})
+	}
+	assert.NoError(t, verify(first))
+	assert.Error(t, r.VerifyConnection(tls.ConnectionState{ServerName: "other", PeerCertificates: []*x509.Certificate{first}}))
+
+	writeKeyPair(t, dir, "exchanger")
+	touch(t, now.Add(time.Second), caFile)
+	second := readCertificate(t, caFile)
+
+	assert.Error(t, verify(second), "the bundle is not checked before checkInterval")
+
+	now = now.Add(checkInterval)
+	assert.NoError(t, verify(second))
+	assert.Error(t, verify(first))
+
+	// a broken bundle keeps the previous pool
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+	touch(t, now.Add(2*time.Second), caFile)
+	now = now.Add(checkInterval)
+	assert.NoError(t, verify(second))
+}
+
+func TestNewKeyPairReloader_Error(t *testing.T) {
+	dir := t.TempDir()
+	certFile, _ := writeKeyPair(t, dir, "first")
+
+	_, err := NewKeyPairReloader(zap.NewNop(), certFile, filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
+
+func TestServer(t *testing.T) {
+	certFile, keyFile := writeKeyPair(t, t.TempDir(), "server")
+
+	cfg, err := Server(zap.NewNop(), certFile, keyFile, "1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+
+	_, err = Server(zap.NewNop(), certFile, keyFile, "1.0")
+	assert.Error(t, err)
+}
+
+func TestClient(t *testing.T) {
+	dir := t.TempDir()
+	caFile, keyFile := writeKeyPair(t, dir, "exchanger")
+
+	tests := []struct {
+		name       string
+		caFile     string
+		certFile   string
+		keyFile    string
+		wantErr    bool
+		wantRoots  bool
+		wantClient bool
+	}{
+		{name: "System_Roots"},
+		{name: "CA_Bundle", caFile: caFile, wantRoots: true},
+		{name: "Mutual_TLS", caFile: caFile, certFile: caFile, keyFile: keyFile, wantRoots: true, wantClient: true},
+		{name: "Missing_Key", caFile: caFile, certFile: caFile, wantErr: true},
+		{name: "Missing_CA", caFile: filepath.Join(dir, "missing.pem"), wantErr: true},
+		{name: "Bad_CA", caFile: keyFile, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Client(zap.NewNop(), tt.caFile, tt.certFile, tt.keyFile, "exchanger", "")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "exchanger", cfg.ServerName)
+			assert.Equal(t, tt.wantRoots, cfg.VerifyConnection != nil)
+			assert.Equal(t, tt.wantClient, cfg.GetClientCertificate != nil)
+		})
+	}
+}
+
+func TestMutualTLSHandshake(t *testing.T) {
+	serverCert, serverKey := writeKeyPair(t, t.TempDir(), "exchanger")
+	clientCert, clientKey := writeKeyPair(t, t.TempDir(), "transferer")
+
+	serverCfg, err := Server(zap.NewNop(), serverCert, serverKey, "")
+	require.NoError(t, err)
+	clientCAs, err := NewCAPoolReloader(zap.NewNop(), clientCert)
+	require.NoError(t, err)
+	serverCfg.ClientCAs = clientCAs.Pool()
+	serverCfg.ClientAuth = tls.RequireAndVerifyClientCert
+
+	clientCfg, err := Client(zap.NewNop(), serverCert, clientCert, clientKey, "exchanger", "")
+	require.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverCfg)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	peer := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			peer <- ""
+			return
+		}
+		defer conn.Close()
+		tlsConn := conn.(*tls.Conn)
+		if err := tlsConn.Handshake(); err != nil {
+			peer <- ""
+			return
+		}
+		peer <- tlsConn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientCfg)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, "transferer", <-peer)
+}
@@ -0,0 +1,343 @@
+// Package twofactor enrols users in TOTP two-factor authentication (RFC 6238) and checks
+// the second factor of a login.
+package twofactor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	period = 30
+	// skew is the number of time steps a code may be off, to allow for clock drift.
+	skew = 1
+
+	recoveryCodeCount = 10
+	// maxChallengeAttempts is how many codes can be entered for a login challenge.
+	maxChallengeAttempts = 5
+	qrCodeSize           = 256
+)
+
+var (
+	ErrAlreadyEnabled   = errors.New("two-factor authentication already enabled")
+	ErrNotEnrolled      = errors.New("two-factor authentication not set up")
+	ErrInvalidCode      = errors.New("invalid code")
+	ErrInvalidChallenge = errors.New("invalid or expired login challenge")
+)
+
+type Store interface {
+	GetTOTP(username string) (*models.TOTP, error)
+	SaveTOTPSecret(username, secret string) error
+	EnableTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(username string, step int64) error
+	UseRecoveryCode(username, hash string) error
+	CreateLoginChallenge(challenge *models.LoginChallenge) error
+	GetLoginChallenge(hash string) (*models.LoginChallenge, error)
+	ClaimLoginChallengeAttempt(hash string, maxAttempts int) (*models.LoginChallenge, error)
+	DeleteLoginChallenge(hash string) error
+}
+
+// Enrollment is a new TOTP secret to be added to an authenticator app, by the otpauth URI
+// or by scanning the QR code PNG of it.
+type Enrollment struct {
+	Secret string
+	URI    string
+	QRCode []byte
+}
+
+// Challenge is a login that passed the password check and waits for the second factor.
+type Challenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+type Manager struct {
+	store        Store
+	issuer       string
+	challengeTTL time.Duration
+	now          func() time.Time
+}
+
+func NewManager(store Store, issuer string, challengeTTL time.Duration) *Manager {
+	return &Manager{
+		store:        store,
+		issuer:       issuer,
+		challengeTTL: challengeTTL,
+		now:          time.Now,
+	}
+}
+
+// Setup generates a new secret of username. It is not used for logins until the user
+// confirms it, setting up again before that replaces it.
+func (m *Manager) Setup(username string) (Enrollment, error) {
+	const op = "twofactor.Manager.Setup"
+
+	current, err := m.store.GetTOTP(username)
+	if err != nil && !errors.Is(err, storage.ErrTOTPNotFound) {
+		return Enrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if current != nil && current.Enabled {
+		return Enrollment{}, fmt.Errorf("%s: %w", op, ErrAlreadyEnabled)
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      m.issuer,
+		AccountName: username,
+		Period:      period,
+	})
+	if err != nil {
+		return Enrollment{}, fmt.Errorf("%s: failed to generate secret: %w", op, err)
+	}
+
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return Enrollment{}, fmt.Errorf("%s: failed to render QR code: %w", op, err)
+	}
+	var qrCode bytes.Buffer
+	if err := png.Encode(&qrCode, img); err != nil {
+		return Enrollment{}, fmt.Errorf("%s: failed to encode QR code: %w", op, err)
+	}
+
+	err = m.store.SaveTOTPSecret(username, key.Secret())
+	if errors.Is(err, storage.ErrTOTPAlreadyEnabled) {
+		return Enrollment{}, fmt.Errorf("%s: %w", op, ErrAlreadyEnabled)
+	}
+	if err != nil {
+		return Enrollment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return Enrollment{Secret: key.Secret(), URI: key.URL(), QRCode: qrCode.Bytes()}, nil
+}
+
+// Confirm enables two-factor authentication of username once code shows the authenticator app
+// has the secret. It returns the recovery codes, each of them replaces a TOTP code once.
+// They are not stored in plain text and cannot be shown again.
+func (m *Manager) Confirm(ctx context.Context, username, code string) ([]string, error) {
+	const op = "twofactor.Manager.Confirm"
+
+	current, err := m.store.GetTOTP(username)
+	if errors.Is(err, storage.ErrTOTPNotFound) {
+		return nil, fmt.Errorf("%s: %w", op, ErrNotEnrolled)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if current.Enabled {
+		return nil, fmt.Errorf("%s: %w", op, ErrAlreadyEnabled)
+	}
+
+	step, ok := m.matchStep(current.Secret, code)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+
+	err = m.store.EnableTOTP(ctx, username, step, hashes)
+	if errors.Is(err, storage.ErrTOTPAlreadyEnabled) {
+		return nil, fmt.Errorf("%s: %w", op, ErrAlreadyEnabled)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return codes, nil
+}
+
+// Enabled tells whether a login of username needs the second factor.
+func (m *Manager) Enabled(username string) (bool, error) {
+	const op = "twofactor.Manager.Enabled"
+
+	current, err := m.store.GetTOTP(username)
+	if errors.Is(err, storage.ErrTOTPNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return current.Enabled, nil
+}
+
+// StartChallenge starts the second step of a login of username.
+func (m *Manager) StartChallenge(username string) (Challenge, error) {
+	const op = "twofactor.Manager.StartChallenge"
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Challenge{}, fmt.Errorf("%s: failed to generate challenge token: %w", op, err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	challenge := &models.LoginChallenge{
+		Hash:      hashToken(token),
+		Username:  username,
+		ExpiresAt: m.now().Add(m.challengeTTL),
+	}
+	if err := m.store.CreateLoginChallenge(challenge); err != nil {
+		return Challenge{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return Challenge{Token: token, ExpiresAt: challenge.ExpiresAt}, nil
+}
+
+// ChallengeUsername returns the user logging in with the challenge token, so the attempt
+// can be throttled before the code is checked.
+func (m *Manager) ChallengeUsername(token string) (string, error) {
+	const op = "twofactor.Manager.ChallengeUsername"
+
+	challenge, err := m.store.GetLoginChallenge(hashToken(token))
+	if errors.Is(err, storage.ErrLoginChallengeNotFound) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return challenge.Username, nil
+}
+
+// CompleteChallenge checks code, a TOTP code or an unused recovery code, for the login
+// challenge token and returns the user to start the session of. A challenge can be
+// completed once and is refused after maxChallengeAttempts attempts.
+func (m *Manager) CompleteChallenge(token, code string) (string, error) {
+	const op = "twofactor.Manager.CompleteChallenge"
+
+	// The attempt is counted before the code is checked, so parallel guesses cannot all
+	// get through while the counter is still below the limit.
+	hash := hashToken(token)
+	challenge, err := m.store.ClaimLoginChallengeAttempt(hash, maxChallengeAttempts)
+	if errors.Is(err, storage.ErrLoginChallengeNotFound) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = m.verify(challenge.Username, code)
+	if errors.Is(err, ErrInvalidCode) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCode)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = m.store.DeleteLoginChallenge(hash)
+	if errors.Is(err, storage.ErrLoginChallengeNotFound) {
+		// completed by a concurrent request
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidChallenge)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return challenge.Username, nil
+}
+
+// Verify checks code, a TOTP code or an unused recovery code, of a user who already
+// has a session, for example to confirm a sensitive operation.
+func (m *Manager) Verify(username, code string) error {
+	const op = "twofactor.Manager.Verify"
+
+	if err := m.verify(username, code); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// verify accepts a TOTP code of username once, or one of the recovery codes.
+func (m *Manager) verify(username, code string) error {
+	current, err := m.store.GetTOTP(username)
+	if errors.Is(err, storage.ErrTOTPNotFound) {
+		return ErrInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+	if !current.Enabled {
+		return ErrInvalidCode
+	}
+
+	if step, ok := m.matchStep(current.Secret, code); ok {
+		err = m.store.UseTOTPStep(username, step)
+		if errors.Is(err, storage.ErrTOTPCodeUsed) {
+			return ErrInvalidCode
+		}
+		return err
+	}
+
+	recoveryCode := normalizeRecoveryCode(code)
+	if recoveryCode == "" {
+		return ErrInvalidCode
+	}
+	err = m.store.UseRecoveryCode(username, hashToken(recoveryCode))
+	if errors.Is(err, storage.ErrRecoveryCodeNotFound) {
+		return ErrInvalidCode
+	}
+	return err
+}
+
+// matchStep returns the time step code was generated for, if it is within the allowed skew.
+func (m *Manager) matchStep(secret, code string) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != otp.DigitsSix.Length() {
+		return 0, false
+	}
+
+	now := m.now()
+	current := now.Unix() / period
+	for offset := -skew; offset <= skew; offset++ {
+		step := current + int64(offset)
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*period, 0), totp.ValidateOpts{
+			Period:    period,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCode returns a code like "k7xq2-m4pd9" of 50 random bits.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode drops separators and case, so the code can be typed either way.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
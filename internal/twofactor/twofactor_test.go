@@ -0,0 +1,273 @@
+package twofactor
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps secrets, recovery codes and challenges the way the postgres storage does.
+type memoryStore struct {
+	secrets       map[string]*models.TOTP
+	recoveryCodes map[string]map[string]bool
+	challenges    map[string]*models.LoginChallenge
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		secrets:       map[string]*models.TOTP{},
+		recoveryCodes: map[string]map[string]bool{},
+		challenges:    map[string]*models.LoginChallenge{},
+	}
+}
+
+func (s *memoryStore) GetTOTP(username string) (*models.TOTP, error) {
+	secret, ok := s.secrets[username]
+	if !ok {
+		return nil, storage.ErrTOTPNotFound
+	}
+	copied := *secret
+	return &copied, nil
+}
+
+func (s *memoryStore) SaveTOTPSecret(username, secret string) error {
+	if current, ok := s.secrets[username]; ok && current.Enabled {
+		return storage.ErrTOTPAlreadyEnabled
+	}
+	s.secrets[username] = &models.TOTP{Username: username, Secret: secret}
+	return nil
+}
+
+func (s *memoryStore) EnableTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error {
+	current := s.secrets[username]
+	if current.Enabled {
+		return storage.ErrTOTPAlreadyEnabled
+	}
+	current.Enabled, current.LastStep = true, step
+	s.recoveryCodes[username] = map[string]bool{}
+	for _, hash := range recoveryCodeHashes {
+		s.recoveryCodes[username][hash] = true
+	}
+	return nil
+}
+
+func (s *memoryStore) UseTOTPStep(username string, step int64) error {
+	current := s.secrets[username]
+	if current.LastStep >= step {
+		return storage.ErrTOTPCodeUsed
+	}
+	current.LastStep = step
+	return nil
+}
+
+func (s *memoryStore) UseRecoveryCode(username, hash string) error {
+	if !s.recoveryCodes[username][hash] {
+		return storage.ErrRecoveryCodeNotFound
+	}
+	delete(s.recoveryCodes[username], hash)
+	return nil
+}
+
+func (s *memoryStore) CreateLoginChallenge(challenge *models.LoginChallenge) error {
+	copied := *challenge
+	s.challenges[challenge.Hash] = &copied
+	return nil
+}
+
+func (s *memoryStore) GetLoginChallenge(hash string) (*models.LoginChallenge, error) {
+	challenge, ok := s.challenges[hash]
+	if !ok || !challenge.ExpiresAt.After(time.Now()) {
+		return nil, storage.ErrLoginChallengeNotFound
+	}
+	copied := *challenge
+	return &copied, nil
+}
+
+func (s *memoryStore) ClaimLoginChallengeAttempt(hash string, maxAttempts int) (*models.LoginChallenge, error) {
+	challenge, ok := s.challenges[hash]
+	if !ok || !challenge.ExpiresAt.After(time.Now()) || challenge.Attempts >= maxAttempts {
+		return nil, storage.ErrLoginChallengeNotFound
+	}
+	challenge.Attempts++
+	copied := *challenge
+	return &copied, nil
+}
+
+func (s *memoryStore) DeleteLoginChallenge(hash string) error {
+	if _, ok := s.challenges[hash]; !ok {
+		return storage.ErrLoginChallengeNotFound
+	}
+	delete(s.challenges, hash)
+	return nil
+}
+
+func code(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	code, err := totp.GenerateCode(secret, at)
+	require.NoError(t, err)
+	return code
+}
+
+// enrol sets up and confirms two-factor authentication of alice and returns the secret
+// and the recovery codes.
+func enrol(t *testing.T, manager *Manager) (string, []string) {
+	t.Helper()
+
+	enrollment, err := manager.Setup("alice")
+	require.NoError(t, err)
+	codes, err := manager.Confirm(context.Background(), "alice", code(t, enrollment.Secret, manager.now()))
+	require.NoError(t, err)
+	return enrollment.Secret, codes
+}
+
+func TestManager_Setup(t *testing.T) {
+	manager := NewManager(newMemoryStore(), "Transferer", time.Minute)
+
+	enrollment, err := manager.Setup("alice")
+	require.NoError(t, err)
+
+	uri, err := url.Parse(enrollment.URI)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Transferer:alice", uri.Path)
+	assert.Equal(t, enrollment.Secret, uri.Query().Get("secret"))
+	assert.Equal(t, "Transferer", uri.Query().Get("issuer"))
+
+	_, err = png.Decode(bytes.NewReader(enrollment.QRCode))
+	assert.NoError(t, err)
+
+	enabled, err := manager.Enabled("alice")
+	require.NoError(t, err)
+	assert.False(t, enabled, "not enabled before confirmation")
+}
+
+func TestManager_Confirm(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager(newMemoryStore(), "Transferer", time.Minute)
+
+	_, err := manager.Confirm(ctx, "alice", "123456")
+	assert.ErrorIs(t, err, ErrNotEnrolled)
+
+	enrollment, err := manager.Setup("alice")
+	require.NoError(t, err)
+	_, err = manager.Confirm(ctx, "alice", "000000")
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	codes, err := manager.Confirm(ctx, "alice", code(t, enrollment.Secret, time.Now()))
+	require.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, codes[0])
+
+	enabled, err := manager.Enabled("alice")
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	_, err = manager.Setup("alice")
+	assert.ErrorIs(t, err, ErrAlreadyEnabled)
+}
+
+func TestManager_CompleteChallenge(t *testing.T) {
+	now := time.Now()
+	manager := NewManager(newMemoryStore(), "Transferer", time.Hour)
+	manager.now = func() time.Time { return now }
+	secret, recoveryCodes := enrol(t, manager)
+
+	// the code the setup was confirmed with cannot be used again
+	challenge, err := manager.StartChallenge("alice")
+	require.NoError(t, err)
+	username, err := manager.ChallengeUsername(challenge.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", username)
+	_, err = manager.CompleteChallenge(challenge.Token, code(t, secret, now))
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	now = now.Add(period * time.Second)
+	username, err = manager.CompleteChallenge(challenge.Token, code(t, secret, now))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", username)
+
+	// a challenge is completed once
+	_, err = manager.CompleteChallenge(challenge.Token, code(t, secret, now.Add(period*time.Second)))
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+	_, err = manager.ChallengeUsername(challenge.Token)
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+
+	// a recovery code works once, typed in any case and with or without the dash
+	challenge, err = manager.StartChallenge("alice")
+	require.NoError(t, err)
+	_, err = manager.CompleteChallenge(challenge.Token, recoveryCodes[0])
+	require.NoError(t, err)
+	challenge, err = manager.StartChallenge("alice")
+	require.NoError(t, err)
+	_, err = manager.CompleteChallenge(challenge.Token, recoveryCodes[0])
+	assert.ErrorIs(t, err, ErrInvalidCode)
+	_, err = manager.CompleteChallenge(challenge.Token, strings.ToUpper(strings.ReplaceAll(recoveryCodes[1], "-", "")))
+	assert.NoError(t, err)
+}
+
+func TestManager_CompleteChallenge_TooManyAttempts(t *testing.T) {
+	manager := NewManager(newMemoryStore(), "Transferer", time.Hour)
+	secret, _ := enrol(t, manager)
+
+	challenge, err := manager.StartChallenge("alice")
+	require.NoError(t, err)
+	for i := 0; i < maxChallengeAttempts-1; i++ {
+		_, err = manager.CompleteChallenge(challenge.Token, "000000")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	}
+	// the last attempt still accepts a right code
+	username, err := manager.CompleteChallenge(challenge.Token, code(t, secret, time.Now().Add(period*time.Second)))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", username)
+
+	challenge, err = manager.StartChallenge("alice")
+	require.NoError(t, err)
+	for i := 0; i < maxChallengeAttempts; i++ {
+		_, err = manager.CompleteChallenge(challenge.Token, "000000")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	_, err = manager.CompleteChallenge(challenge.Token, code(t, secret, time.Now().Add(period*time.Second)))
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+}
+
+func TestManager_CompleteChallenge_Expired(t *testing.T) {
+	manager := NewManager(newMemoryStore(), "Transferer", -time.Minute)
+	secret, _ := enrol(t, manager)
+
+	challenge, err := manager.StartChallenge("alice")
+	require.NoError(t, err)
+	_, err = manager.ChallengeUsername(challenge.Token)
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+	_, err = manager.CompleteChallenge(challenge.Token, code(t, secret, time.Now().Add(period*time.Second)))
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+}
+
+func TestManager_Verify(t *testing.T) {
+	now := time.Now()
+	manager := NewManager(newMemoryStore(), "Transferer", time.Minute)
+	manager.now = func() time.Time { return now }
+
+	err := manager.Verify("alice", "123456")
+	assert.ErrorIs(t, err, ErrInvalidCode, "not enrolled")
+
+	secret, recoveryCodes := enrol(t, manager)
+
+	now = now.Add(period * time.Second)
+	require.NoError(t, manager.Verify("alice", code(t, secret, now)))
+	assert.ErrorIs(t, manager.Verify("alice", code(t, secret, now)), ErrInvalidCode, "a code is used once")
+	require.NoError(t, manager.Verify("alice", recoveryCodes[0]))
+	assert.ErrorIs(t, manager.Verify("alice", "000000"), ErrInvalidCode)
+}
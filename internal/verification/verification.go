@@ -0,0 +1,183 @@
+// Package verification confirms that users own the email of their account. New users and
+// users who changed their email are mailed a link with a one-time token, until they follow
+// it moving money out of the account is restricted.
+package verification
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/mail"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+)
+
+var (
+	ErrInvalidToken    = errors.New("invalid or expired email verification token")
+	ErrAlreadyVerified = errors.New("email is already verified")
+	ErrNoEmail         = errors.New("account has no email")
+)
+
+// ResendTooSoonError is returned by Resend when the previous email was sent less than
+// the resend interval ago.
+type ResendTooSoonError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ResendTooSoonError) Error() string {
+	return "verification email was sent recently"
+}
+
+type Store interface {
+	GetUser(username string) (*models.User, error)
+	CreateEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken, notBefore time.Time) error
+	LastEmailVerificationSentAt(username string) (time.Time, error)
+	VerifyEmail(ctx context.Context, hash string) (string, error)
+}
+
+// Options are the settings of a Manager, see config.VerificationConfig.
+type Options struct {
+	TokenTTL       time.Duration
+	ResendInterval time.Duration
+	// URL is the page the verification link points to, the token is added as the token
+	// query parameter. Without it the token itself is mailed.
+	URL string
+}
+
+type Manager struct {
+	store  Store
+	mailer mail.Mailer
+	opts   Options
+	now    func() time.Time
+}
+
+func NewManager(store Store, mailer mail.Mailer, opts Options) *Manager {
+	return &Manager{
+		store:  store,
+		mailer: mailer,
+		opts:   opts,
+		now:    time.Now,
+	}
+}
+
+// Send mails a verification link to the email of username, unless it is already verified.
+func (m *Manager) Send(ctx context.Context, username string) error {
+	const op = "verification.Manager.Send"
+
+	user, err := m.store.GetUser(username)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user.EmailVerified {
+		return nil
+	}
+	// nothing was sent after now, so the first link is never refused
+	if err := m.send(ctx, user, m.now()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Resend mails a new verification link to the email of username. Returns ErrAlreadyVerified
+// when there is nothing to verify and a *ResendTooSoonError when the previous link is too recent.
+func (m *Manager) Resend(ctx context.Context, username string) error {
+	const op = "verification.Manager.Resend"
+
+	user, err := m.store.GetUser(username)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user.EmailVerified {
+		return fmt.Errorf("%s: %w", op, ErrAlreadyVerified)
+	}
+
+	now := m.now()
+	err = m.send(ctx, user, now.Add(-m.opts.ResendInterval))
+	if errors.Is(err, storage.ErrEmailVerificationSentRecently) {
+		return fmt.Errorf("%s: %w", op, m.resendTooSoon(username, now))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// resendTooSoon tells how long username has to wait for the next link.
+func (m *Manager) resendTooSoon(username string, now time.Time) error {
+	sentAt, err := m.store.LastEmailVerificationSentAt(username)
+	if err != nil {
+		return err
+	}
+	wait := sentAt.Add(m.opts.ResendInterval).Sub(now)
+	if wait <= 0 {
+		// the link was refused a moment ago, only the clocks disagree
+		wait = time.Second
+	}
+	return &ResendTooSoonError{RetryAfter: wait}
+}
+
+// Verify marks the email token was sent to as verified and returns the username.
+// A token can be used once.
+func (m *Manager) Verify(ctx context.Context, token string) (string, error) {
+	const op = "verification.Manager.Verify"
+
+	username, err := m.store.VerifyEmail(ctx, hashToken(token))
+	if errors.Is(err, storage.ErrEmailVerificationTokenNotFound) {
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidToken)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return username, nil
+}
+
+// send mails a new link to user, unless a link was sent after notBefore.
+func (m *Manager) send(ctx context.Context, user *models.User, notBefore time.Time) error {
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate email verification token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	err := m.store.CreateEmailVerificationToken(ctx, &models.EmailVerificationToken{
+		Hash:      hashToken(token),
+		Username:  user.Username,
+		Email:     user.Email,
+		ExpiresAt: m.now().Add(m.opts.TokenTTL),
+	}, notBefore)
+	if err != nil {
+		return err
+	}
+
+	return m.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your Transferer email",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"confirm that this is your email with %s\n"+
+			"It is valid for %s. Until then withdrawals and transfers are disabled and deposits are limited.\n\n"+
+			"If you did not create an account, ignore this email.\n",
+			user.Username, m.link(token), m.opts.TokenTTL),
+	})
+}
+
+func (m *Manager) link(token string) string {
+	if m.opts.URL == "" {
+		return "the verification token " + token
+	}
+	return "the link " + mail.WithToken(m.opts.URL, token)
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,188 @@
+package verification
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/internal/mail"
+	"github.com/Foreground-Eclipse/transferer/internal/storage"
+	"github.com/Foreground-Eclipse/transferer/internal/storage/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type storedToken struct {
+	models.EmailVerificationToken
+	createdAt time.Time
+}
+
+// memoryStore keeps users and verification tokens the way the postgres storage does.
+type memoryStore struct {
+	now    func() time.Time
+	users  map[string]*models.User
+	tokens map[string]storedToken
+}
+
+func newMemoryStore(now func() time.Time) *memoryStore {
+	return &memoryStore{
+		now: now,
+		users: map[string]*models.User{
+			"alice": {Username: "alice", Email: "alice@example.com"},
+		},
+		tokens: map[string]storedToken{},
+	}
+}
+
+func (s *memoryStore) GetUser(username string) (*models.User, error) {
+	user, ok := s.users[username]
+	if !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (s *memoryStore) CreateEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken, notBefore time.Time) error {
+	user, ok := s.users[token.Username]
+	if !ok || user.Email != token.Email {
+		return storage.ErrUserNotFound
+	}
+	for _, other := range s.tokens {
+		if other.Username == token.Username && other.createdAt.After(notBefore) {
+			return storage.ErrEmailVerificationSentRecently
+		}
+	}
+	s.tokens[token.Hash] = storedToken{EmailVerificationToken: *token, createdAt: s.now()}
+	return nil
+}
+
+func (s *memoryStore) LastEmailVerificationSentAt(username string) (time.Time, error) {
+	var last time.Time
+	for _, token := range s.tokens {
+		if token.Username == username && token.createdAt.After(last) {
+			last = token.createdAt
+		}
+	}
+	return last, nil
+}
+
+func (s *memoryStore) VerifyEmail(ctx context.Context, hash string) (string, error) {
+	token, ok := s.tokens[hash]
+	if !ok || !token.ExpiresAt.After(s.now()) {
+		return "", storage.ErrEmailVerificationTokenNotFound
+	}
+	user := s.users[token.Username]
+	if user.Email != token.Email {
+		return "", storage.ErrEmailVerificationTokenNotFound
+	}
+	user.EmailVerified = true
+	for h, other := range s.tokens {
+		if other.Username == token.Username {
+			delete(s.tokens, h)
+		}
+	}
+	return user.Username, nil
+}
+
+type memoryMailer struct {
+	sent []mail.Message
+}
+
+func (m *memoryMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var linkPattern = regexp.MustCompile(`https://transferer\.example/verify\?token=([A-Za-z0-9_-]+)`)
+
+func tokenFrom(t *testing.T, msg mail.Message) string {
+	t.Helper()
+	match := linkPattern.FindStringSubmatch(msg.Body)
+	require.Len(t, match, 2, "the mail contains the verification link")
+	return match[1]
+}
+
+func newTestManager() (*Manager, *memoryStore, *memoryMailer, *time.Time) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store, mailer := newMemoryStore(clock), &memoryMailer{}
+	manager := NewManager(store, mailer, Options{
+		TokenTTL:       48 * time.Hour,
+		ResendInterval: time.Minute,
+		URL:            "https://transferer.example/verify",
+	})
+	manager.now = clock
+	return manager, store, mailer, &now
+}
+
+func TestManager_Verify(t *testing.T) {
+	ctx := context.Background()
+	manager, store, mailer, _ := newTestManager()
+
+	require.NoError(t, manager.Send(ctx, "alice"))
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "alice@example.com", mailer.sent[0].To)
+	token := tokenFrom(t, mailer.sent[0])
+
+	_, err := manager.Verify(ctx, "wrong")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	username, err := manager.Verify(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", username)
+	assert.True(t, store.users["alice"].EmailVerified)
+
+	_, err = manager.Verify(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidToken, "a token is used once")
+
+	require.NoError(t, manager.Send(ctx, "alice"))
+	assert.Len(t, mailer.sent, 1, "verified emails are not mailed again")
+	assert.ErrorIs(t, manager.Resend(ctx, "alice"), ErrAlreadyVerified)
+}
+
+func TestManager_VerifyChangedEmail(t *testing.T) {
+	ctx := context.Background()
+	manager, store, mailer, _ := newTestManager()
+
+	require.NoError(t, manager.Send(ctx, "alice"))
+	store.users["alice"].Email = "alice@example.org"
+
+	_, err := manager.Verify(ctx, tokenFrom(t, mailer.sent[0]))
+	assert.ErrorIs(t, err, ErrInvalidToken, "a token only verifies the email it was sent to")
+	assert.False(t, store.users["alice"].EmailVerified)
+}
+
+func TestManager_VerifyExpired(t *testing.T) {
+	ctx := context.Background()
+	manager, store, mailer, now := newTestManager()
+
+	require.NoError(t, manager.Send(ctx, "alice"))
+	*now = now.Add(49 * time.Hour)
+
+	_, err := manager.Verify(ctx, tokenFrom(t, mailer.sent[0]))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.False(t, store.users["alice"].EmailVerified)
+}
+
+func TestManager_Resend(t *testing.T) {
+	ctx := context.Background()
+	manager, _, mailer, now := newTestManager()
+
+	require.NoError(t, manager.Send(ctx, "alice"))
+
+	*now = now.Add(20 * time.Second)
+	err := manager.Resend(ctx, "alice")
+	var tooSoon *ResendTooSoonError
+	require.ErrorAs(t, err, &tooSoon)
+	assert.Equal(t, 40*time.Second, tooSoon.RetryAfter)
+	assert.Len(t, mailer.sent, 1)
+
+	*now = now.Add(40 * time.Second)
+	require.NoError(t, manager.Resend(ctx, "alice"))
+	require.Len(t, mailer.sent, 2)
+
+	_, err = manager.Verify(ctx, tokenFrom(t, mailer.sent[0]))
+	require.NoError(t, err, "earlier links stay valid")
+}
@@ -1,52 +1,256 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/Foreground-Eclipse/transferer/config"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtKey = []byte("supersecretkey")
+// defaultKeyID identifies JWT_SECRET in the key set.
+const defaultKeyID = "default"
 
+// Roles of the roles claim. RoleUser is the role of every registered user, support staff
+// looks into accounts and freezes them, auditors only look, admins do everything.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+	RoleAuditor = "auditor"
+)
+
+// IsRole tells whether role is one of the known roles.
+func IsRole(role string) bool {
+	switch role {
+	case RoleUser, RoleSupport, RoleAdmin, RoleAuditor:
+		return true
+	}
+	return false
+}
+
+// Authentication methods of the amr claim (RFC 8176).
+const (
+	MethodPassword = "pwd"
+	MethodOTP      = "otp"
+)
+
+var (
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenRevoked = errors.New("token revoked")
+	// ErrRevocationCheck means the denylist could not be read, the token itself may be valid.
+	ErrRevocationCheck = errors.New("failed to check token revocation")
+)
+
+// JWTClaim is the payload of an access token. Subject (sub) is the user ID, Id (jti) identifies
+// the token for revocation, SessionID (sid) is the refresh token family the token was issued in.
+// AuthTime (auth_time) is when the user last entered a password or a code and AuthMethods (amr)
+// how, refreshed tokens keep both.
 type JWTClaim struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	Username    string   `json:"username"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
+	AuthTime    int64    `json:"auth_time,omitempty"`
+	AuthMethods []string `json:"amr,omitempty"`
 	jwt.StandardClaims
 }
 
-func GenerateJWT(username string, cfg *config.Config) (tokenString string, err error) {
-	expirationTime := time.Now().Add(time.Duration(cfg.JWT.JWTExpirationTimeHours) * time.Hour)
+// Subject is the user an access token is issued to and how they authenticated.
+type Subject struct {
+	UserID      int64
+	Username    string
+	Roles       []string
+	AuthTime    time.Time
+	AuthMethods []string
+}
+
+// Denylist tells whether an access token was revoked before it expired.
+type Denylist interface {
+	IsAccessTokenRevoked(jti string) (bool, error)
+}
+
+type AccessToken struct {
+	Token     string
+	ID        string
+	ExpiresAt time.Time
+}
+
+// KeySet signs tokens with one key and validates them with any key of the set,
+// picked by the kid header of the token. Keys are HS256 secrets or RS256 and EdDSA key pairs.
+//
+// To rotate keys, add the new key to every instance, then make it the signing key,
+// and remove the old key once the tokens it signed have expired.
+type KeySet struct {
+	keys       map[string]key
+	signingKID string
+	expiration time.Duration
+	denylist   Denylist
+}
+
+// NewKeySet builds the key set from the HMAC secrets in JWT_KEYS, the PEM files in JWT_KEY_FILES
+// and JWT_SECRET, the latter under the "default" key id.
+// The signing key is JWT_SIGNING_KEY_ID, or the only key if there is just one.
+// Tokens are checked against denylist unless it is nil.
+func NewKeySet(cfg config.JWTConfig, denylist Denylist) (*KeySet, error) {
+	const op = "auth.NewKeySet"
+
+	keys := make(map[string]key, len(cfg.Keys)+len(cfg.KeyFiles)+1)
+	for kid, secret := range cfg.Keys {
+		if kid == "" || secret == "" {
+			return nil, fmt.Errorf("%s: key id and secret must not be empty", op)
+		}
+		keys[kid] = hmacKey(secret)
+	}
+	for kid, path := range cfg.KeyFiles {
+		if kid == "" {
+			return nil, fmt.Errorf("%s: key id must not be empty", op)
+		}
+		if _, ok := keys[kid]; ok {
+			return nil, fmt.Errorf("%s: duplicate key id %q", op, kid)
+		}
+		k, err := loadKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to load key %q: %w", op, kid, err)
+		}
+		keys[kid] = k
+	}
+	if cfg.JWTSecret != "" {
+		if _, ok := keys[defaultKeyID]; ok {
+			return nil, fmt.Errorf("%s: key id %q is reserved for JWT_SECRET", op, defaultKeyID)
+		}
+		keys[defaultKeyID] = hmacKey(cfg.JWTSecret)
+	}
+
+	signingKID := cfg.SigningKeyID
+	switch {
+	case len(keys) == 0:
+		return nil, fmt.Errorf("%s: no signing keys configured", op)
+	case signingKID == "" && len(keys) == 1:
+		for kid := range keys {
+			signingKID = kid
+		}
+	case signingKID == "":
+		return nil, fmt.Errorf("%s: several keys configured, signing key id must be set", op)
+	}
+	signingKey, ok := keys[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("%s: signing key %q not found", op, signingKID)
+	}
+	if signingKey.private == nil {
+		return nil, fmt.Errorf("%s: signing key %q is a public key", op, signingKID)
+	}
+
+	return &KeySet{
+		keys:       keys,
+		signingKID: signingKID,
+		expiration: cfg.AccessTokenTTL,
+		denylist:   denylist,
+	}, nil
+}
+
+// IssueAccessToken signs a short-lived access token of subject within the session sessionID.
+func (s *KeySet) IssueAccessToken(subject Subject, sessionID string) (AccessToken, error) {
+	const op = "auth.KeySet.IssueAccessToken"
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return AccessToken{}, fmt.Errorf("%s: failed to generate token id: %w", op, err)
+	}
+
+	now := time.Now()
+	accessToken := AccessToken{
+		ID:        hex.EncodeToString(id),
+		ExpiresAt: now.Add(s.expiration),
+	}
 	claims := &JWTClaim{
-		Username: username,
+		Username:    subject.Username,
+		Roles:       subject.Roles,
+		SessionID:   sessionID,
+		AuthMethods: subject.AuthMethods,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
+			Subject:   strconv.FormatInt(subject.UserID, 10),
+			Id:        accessToken.ID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: accessToken.ExpiresAt.Unix(),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err = token.SignedString(jwtKey)
-	return
+	if !subject.AuthTime.IsZero() {
+		claims.AuthTime = subject.AuthTime.Unix()
+	}
+	signingKey := s.keys[s.signingKID]
+	token := jwt.NewWithClaims(signingKey.method, claims)
+	token.Header["kid"] = s.signingKID
+
+	var err error
+	accessToken.Token, err = token.SignedString(signingKey.private)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return accessToken, nil
 }
-func ValidateToken(signedToken string) (string, error) {
+
+// ParseToken checks the signature, expiry and revocation of an access token and returns its claims.
+func (s *KeySet) ParseToken(signedToken string) (*JWTClaim, error) {
 	token, err := jwt.ParseWithClaims(
 		signedToken,
 		&JWTClaim{},
-		func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtKey), nil
-		},
+		s.key,
 	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	claims, ok := token.Claims.(*JWTClaim)
 	if !ok {
 		err = errors.New("couldn't parse claims")
-		return "", err
+		return nil, err
 	}
 	if claims.ExpiresAt < time.Now().Local().Unix() {
-		err = errors.New("token expired")
+		return nil, ErrTokenExpired
+	}
+
+	if s.denylist != nil && claims.Id != "" {
+		revoked, err := s.denylist.IsAccessTokenRevoked(claims.Id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRevocationCheck, err)
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+	return claims, nil
+}
+
+func (s *KeySet) ValidateToken(signedToken string) (string, error) {
+	claims, err := s.ParseToken(signedToken)
+	if err != nil {
 		return "", err
 	}
 	return claims.Username, nil
 }
+
+// key returns the key a token is validated with. Tokens issued before key ids were
+// introduced have no kid and are validated with the signing key.
+func (s *KeySet) key(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"]
+	if !ok {
+		kid = s.signingKID
+	}
+	k, ok := s.keys[fmt.Sprint(kid)]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %v", kid)
+	}
+
+	// the algorithm is fixed by the key, not taken from the token
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+	return k.public, nil
+}
@@ -0,0 +1,184 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/config"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeySet(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.JWTConfig
+		wantKID    string
+		wantErrMsg string
+	}{
+		{
+			name:    "Secret_Only",
+			cfg:     config.JWTConfig{JWTSecret: "secret"},
+			wantKID: "default",
+		},
+		{
+			name:    "Single_Key",
+			cfg:     config.JWTConfig{Keys: map[string]string{"2025-01": "one"}},
+			wantKID: "2025-01",
+		},
+		{
+			name:    "Signing_Key_Chosen",
+			cfg:     config.JWTConfig{JWTSecret: "secret", Keys: map[string]string{"2025-01": "one"}, SigningKeyID: "2025-01"},
+			wantKID: "2025-01",
+		},
+		{
+			name:       "No_Keys",
+			cfg:        config.JWTConfig{},
+			wantErrMsg: "auth.NewKeySet: no signing keys configured",
+		},
+		{
+			name:       "Signing_Key_Not_Set",
+			cfg:        config.JWTConfig{Keys: map[string]string{"2025-01": "one", "2025-06": "two"}},
+			wantErrMsg: "auth.NewKeySet: several keys configured, signing key id must be set",
+		},
+		{
+			name:       "Signing_Key_Unknown",
+			cfg:        config.JWTConfig{JWTSecret: "secret", SigningKeyID: "2025-01"},
+			wantErrMsg: `auth.NewKeySet: signing key "2025-01" not found`,
+		},
+		{
+			name:       "Reserved_Key_ID",
+			cfg:        config.JWTConfig{JWTSecret: "secret", Keys: map[string]string{"default": "one"}},
+			wantErrMsg: `auth.NewKeySet: key id "default" is reserved for JWT_SECRET`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keySet, err := NewKeySet(tt.cfg, nil)
+			if tt.wantErrMsg != "" {
+				assert.EqualError(t, err, tt.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKID, keySet.signingKID)
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	oldKeys, err := NewKeySet(config.JWTConfig{
+		Keys:           map[string]string{"old": "one"},
+		AccessTokenTTL: time.Hour,
+	}, nil)
+	require.NoError(t, err)
+	oldToken, err := oldKeys.IssueAccessToken(Subject{UserID: 1, Username: "alice"}, "")
+	require.NoError(t, err)
+
+	// the new key is added and made the signing key, the old one is kept for validation
+	rotated, err := NewKeySet(config.JWTConfig{
+		Keys:           map[string]string{"old": "one", "new": "two"},
+		SigningKeyID:   "new",
+		AccessTokenTTL: time.Hour,
+	}, nil)
+	require.NoError(t, err)
+	newToken, err := rotated.IssueAccessToken(Subject{UserID: 2, Username: "bob"}, "")
+	require.NoError(t, err)
+
+	username, err := rotated.ValidateToken(oldToken.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", username)
+	username, err = rotated.ValidateToken(newToken.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", username)
+
+	// the old key is retired
+	retired, err := NewKeySet(config.JWTConfig{Keys: map[string]string{"new": "two"}}, nil)
+	require.NoError(t, err)
+	_, err = retired.ValidateToken(oldToken.Token)
+	assert.ErrorContains(t, err, "unknown key id old")
+	_, err = retired.ValidateToken(newToken.Token)
+	assert.NoError(t, err)
+}
+
+func TestKeySet_ValidateToken(t *testing.T) {
+	keySet, err := NewKeySet(config.JWTConfig{JWTSecret: "secret", AccessTokenTTL: time.Hour}, nil)
+	require.NoError(t, err)
+
+	sign := func(method jwt.SigningMethod, key interface{}, kid string, expiresAt time.Time) string {
+		token := jwt.NewWithClaims(method, &JWTClaim{
+			Username:       "alice",
+			StandardClaims: jwt.StandardClaims{ExpiresAt: expiresAt.Unix()},
+		})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+	valid := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "With_Key_ID", token: sign(jwt.SigningMethodHS256, []byte("secret"), "default", valid)},
+		{name: "Without_Key_ID", token: sign(jwt.SigningMethodHS256, []byte("secret"), "", valid)},
+		{name: "Wrong_Secret", token: sign(jwt.SigningMethodHS256, []byte("other"), "default", valid), wantErr: true},
+		{name: "Other_Algorithm", token: sign(jwt.SigningMethodHS512, []byte("secret"), "default", valid), wantErr: true},
+		{name: "Expired", token: sign(jwt.SigningMethodHS256, []byte("secret"), "default", time.Now().Add(-time.Minute)), wantErr: true},
+		{name: "Malformed", token: "not a token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username, err := keySet.ValidateToken(tt.token)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "alice", username)
+		})
+	}
+}
+
+type denylist map[string]bool
+
+func (d denylist) IsAccessTokenRevoked(jti string) (bool, error) {
+	return d[jti], nil
+}
+
+func TestKeySet_Denylist(t *testing.T) {
+	revoked := denylist{}
+	keySet, err := NewKeySet(config.JWTConfig{JWTSecret: "secret", AccessTokenTTL: time.Hour}, revoked)
+	require.NoError(t, err)
+
+	authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+	accessToken, err := keySet.IssueAccessToken(Subject{
+		UserID:      7,
+		Username:    "alice",
+		Roles:       []string{RoleUser},
+		AuthTime:    authTime,
+		AuthMethods: []string{MethodPassword, MethodOTP},
+	}, "session")
+	require.NoError(t, err)
+
+	claims, err := keySet.ParseToken(accessToken.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+	assert.Equal(t, "7", claims.Subject)
+	assert.Equal(t, []string{RoleUser}, claims.Roles)
+	assert.Equal(t, "session", claims.SessionID)
+	assert.Equal(t, authTime.Unix(), claims.AuthTime)
+	assert.Equal(t, []string{MethodPassword, MethodOTP}, claims.AuthMethods)
+	assert.Equal(t, accessToken.ID, claims.Id)
+	assert.Equal(t, accessToken.ExpiresAt.Unix(), claims.ExpiresAt)
+
+	revoked[accessToken.ID] = true
+	_, err = keySet.ValidateToken(accessToken.Token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
@@ -0,0 +1,97 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// minRSABits is the smallest RSA key accepted for RS256.
+const minRSABits = 2048
+
+// key is one key of a KeySet. HMAC keys sign and verify with the same secret, asymmetric keys
+// verify with public and sign with private, which is nil for a key kept only for verification.
+type key struct {
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+func hmacKey(secret string) key {
+	return key{method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+}
+
+// loadKeyFile loads an RSA or Ed25519 key from a PEM file. A private key signs with RS256
+// or EdDSA, a public key only verifies tokens, e.g. those signed with a retired key.
+func loadKeyFile(path string) (key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return key{}, err
+	}
+	return parseKeyPEM(data)
+}
+
+func parseKeyPEM(data []byte) (key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return key{}, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return key{}, err
+		}
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return key{}, fmt.Errorf("unsupported private key type %T", private)
+		}
+		k, err := publicKey(signer.Public())
+		if err != nil {
+			return key{}, err
+		}
+		k.private = private
+		return k, nil
+	case "RSA PRIVATE KEY":
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return key{}, err
+		}
+		k, err := publicKey(&private.PublicKey)
+		if err != nil {
+			return key{}, err
+		}
+		k.private = private
+		return k, nil
+	case "PUBLIC KEY":
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return key{}, err
+		}
+		return publicKey(public)
+	default:
+		return key{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+func publicKey(public crypto.PublicKey) (key, error) {
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSABits {
+			return key{}, fmt.Errorf("RSA key must have at least %d bits", minRSABits)
+		}
+		return key{method: jwt.SigningMethodRS256, public: public}, nil
+	case ed25519.PublicKey:
+		return key{method: jwt.SigningMethodEdDSA, public: public}, nil
+	default:
+		return key{}, fmt.Errorf("unsupported public key type %T, use RSA or Ed25519", public)
+	}
+}
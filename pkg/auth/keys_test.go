@@ -0,0 +1,171 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Foreground-Eclipse/transferer/config"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+func writePKCS8(t *testing.T, private interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	return writePEM(t, "PRIVATE KEY", der)
+}
+
+func writePublic(t *testing.T, public interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	return writePEM(t, "PUBLIC KEY", der)
+}
+
+func TestKeySet_Asymmetric(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		keyFile  string
+		wantAlg  string
+		wantKind string
+	}{
+		{name: "RSA_PKCS8", keyFile: writePKCS8(t, rsaKey), wantAlg: "RS256", wantKind: "RSA"},
+		{name: "RSA_PKCS1", keyFile: writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), wantAlg: "RS256", wantKind: "RSA"},
+		{name: "Ed25519", keyFile: writePKCS8(t, edKey), wantAlg: "EdDSA", wantKind: "OKP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keySet, err := NewKeySet(config.JWTConfig{
+				KeyFiles:       map[string]string{"signing": tt.keyFile},
+				AccessTokenTTL: time.Hour,
+			}, nil)
+			require.NoError(t, err)
+
+			accessToken, err := keySet.IssueAccessToken(Subject{Username: "alice"}, "")
+			require.NoError(t, err)
+
+			token, _, err := new(jwt.Parser).ParseUnverified(accessToken.Token, &JWTClaim{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAlg, token.Header["alg"])
+			assert.Equal(t, "signing", token.Header["kid"])
+
+			username, err := keySet.ValidateToken(accessToken.Token)
+			require.NoError(t, err)
+			assert.Equal(t, "alice", username)
+
+			jwks := keySet.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, "signing", jwks.Keys[0].KeyID)
+			assert.Equal(t, tt.wantAlg, jwks.Keys[0].Alg)
+			assert.Equal(t, tt.wantKind, jwks.Keys[0].KeyType)
+			assert.Equal(t, "sig", jwks.Keys[0].Use)
+		})
+	}
+}
+
+func TestKeySet_PublicKeyOnly(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateFile := writePKCS8(t, edKey)
+	publicFile := writePublic(t, edKey.Public())
+
+	retiring, err := NewKeySet(config.JWTConfig{KeyFiles: map[string]string{"old": privateFile}, AccessTokenTTL: time.Hour}, nil)
+	require.NoError(t, err)
+	oldToken, err := retiring.IssueAccessToken(Subject{Username: "alice"}, "")
+	require.NoError(t, err)
+
+	// the old private key is gone, its public key still verifies the tokens it signed
+	keySet, err := NewKeySet(config.JWTConfig{
+		JWTSecret:    "secret",
+		KeyFiles:     map[string]string{"old": publicFile},
+		SigningKeyID: "default",
+	}, nil)
+	require.NoError(t, err)
+	username, err := keySet.ValidateToken(oldToken.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", username)
+
+	// the HMAC secret is not published
+	jwks := keySet.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "old", jwks.Keys[0].KeyID)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey)), jwks.Keys[0].X)
+
+	_, err = NewKeySet(config.JWTConfig{KeyFiles: map[string]string{"old": publicFile}}, nil)
+	assert.EqualError(t, err, `auth.NewKeySet: signing key "old" is a public key`)
+}
+
+func TestKeySet_AlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicFile := writePublic(t, &rsaKey.PublicKey)
+	publicPEM, err := os.ReadFile(publicFile)
+	require.NoError(t, err)
+
+	keySet, err := NewKeySet(config.JWTConfig{KeyFiles: map[string]string{"rsa": writePKCS8(t, rsaKey)}}, nil)
+	require.NoError(t, err)
+
+	// an HS256 token "signed" with the published public key must not pass as the RSA key
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &JWTClaim{
+		Username:       "mallory",
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	})
+	token.Header["kid"] = "rsa"
+	forged, err := token.SignedString(publicPEM)
+	require.NoError(t, err)
+
+	_, err = keySet.ValidateToken(forged)
+	assert.ErrorContains(t, err, "unexpected signing method HS256")
+}
+
+func TestKeySet_RSAKeyTooSmall(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	_, err = NewKeySet(config.JWTConfig{KeyFiles: map[string]string{"rsa": writePKCS8(t, rsaKey)}}, nil)
+	assert.ErrorContains(t, err, "RSA key must have at least 2048 bits")
+}
+
+func TestJWKS_RSA(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keySet, err := NewKeySet(config.JWTConfig{KeyFiles: map[string]string{"rsa": writePKCS8(t, rsaKey)}}, nil)
+	require.NoError(t, err)
+
+	jwk := keySet.JWKS().Keys[0]
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	require.NoError(t, err)
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	require.NoError(t, err)
+	assert.Equal(t, rsaKey.N, new(big.Int).SetBytes(n))
+	assert.Equal(t, "AQAB", jwk.E)
+	assert.Equal(t, int64(rsaKey.E), new(big.Int).SetBytes(e).Int64())
+}
@@ -0,0 +1,149 @@
+package money
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Scale is the number of fractional digits we keep, it matches NUMERIC(19, 8) in the database.
+const Scale = 8
+
+// Amount is an exact decimal number used for balances, amounts and exchange rates.
+// It is encoded as a JSON string and can be scanned from and written to NUMERIC columns.
+type Amount struct {
+	d decimal.Decimal
+}
+
+// Zero is the zero amount, same as Amount{}.
+var Zero = Amount{}
+
+func NewFromInt(value int64) Amount {
+	return Amount{d: decimal.NewFromInt(value)}
+}
+
+// NewFromFloat32 converts a float32 using its shortest decimal representation,
+// so the 0.012 rate received over gRPC becomes exactly 0.012.
+func NewFromFloat32(value float32) Amount {
+	return Amount{d: decimal.NewFromFloat32(value)}
+}
+
+func NewFromFloat(value float64) Amount {
+	return Amount{d: decimal.NewFromFloat(value)}
+}
+
+func Parse(value string) (Amount, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return Zero, fmt.Errorf("invalid amount %q: %w", value, err)
+	}
+	return Amount{d: d}, nil
+}
+
+// MustParse is like Parse but panics on invalid input, it is meant for constants and tests.
+func MustParse(value string) Amount {
+	a, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func (a Amount) Add(b Amount) Amount {
+	return Amount{d: a.d.Add(b.d)}
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{d: a.d.Sub(b.d)}
+}
+
+// Mul returns the exact product, call Round before storing it as a balance.
+func (a Amount) Mul(b Amount) Amount {
+	return Amount{d: a.d.Mul(b.d)}
+}
+
+// Div divides and rounds the result half to even to Scale digits.
+// It panics if b is zero.
+func (a Amount) Div(b Amount) Amount {
+	return Amount{d: a.d.DivRound(b.d, Scale+1).RoundBank(Scale)}
+}
+
+// Round rounds half to even to Scale digits.
+func (a Amount) Round() Amount {
+	return Amount{d: a.d.RoundBank(Scale)}
+}
+
+func (a Amount) Neg() Amount {
+	return Amount{d: a.d.Neg()}
+}
+
+func (a Amount) Abs() Amount {
+	return Amount{d: a.d.Abs()}
+}
+
+// Cmp returns -1 if a < b, 0 if a == b and 1 if a > b.
+func (a Amount) Cmp(b Amount) int {
+	return a.d.Cmp(b.d)
+}
+
+func (a Amount) Equal(b Amount) bool {
+	return a.d.Equal(b.d)
+}
+
+func (a Amount) LessThan(b Amount) bool {
+	return a.d.LessThan(b.d)
+}
+
+func (a Amount) GreaterThan(b Amount) bool {
+	return a.d.GreaterThan(b.d)
+}
+
+func (a Amount) IsZero() bool {
+	return a.d.IsZero()
+}
+
+func (a Amount) IsPositive() bool {
+	return a.d.IsPositive()
+}
+
+func (a Amount) IsNegative() bool {
+	return a.d.IsNegative()
+}
+
+// FitsScale reports whether a has at most Scale fractional digits, so it is stored as is.
+func (a Amount) FitsScale() bool {
+	return a.d.Equal(a.d.Truncate(Scale))
+}
+
+// Float64 returns the closest float64, it must only be used for display and metrics.
+func (a Amount) Float64() float64 {
+	f, _ := a.d.Float64()
+	return f
+}
+
+func (a Amount) String() string {
+	return a.d.String()
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + a.d.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts both "12.34" and 12.34.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return fmt.Errorf("amount must not be null")
+	}
+	return a.d.UnmarshalJSON(data)
+}
+
+// Scan implements sql.Scanner for NUMERIC columns.
+func (a *Amount) Scan(value interface{}) error {
+	return a.d.Scan(value)
+}
+
+// Value implements driver.Valuer.
+func (a Amount) Value() (driver.Value, error) {
+	return a.d.String(), nil
+}
@@ -0,0 +1,66 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmountArithmetic(t *testing.T) {
+	sum := MustParse("0.1").Add(MustParse("0.2"))
+	assert.True(t, sum.Equal(MustParse("0.3")))
+
+	large := MustParse("12345678901.12345678")
+	assert.Equal(t, "12345678902.12345678", large.Add(NewFromInt(1)).String())
+
+	assert.Equal(t, "91.66666667", NewFromInt(100).Mul(MustParse("0.011")).Div(MustParse("0.012")).String())
+	assert.Equal(t, "0.012", NewFromFloat32(0.012).String())
+	assert.Equal(t, "0.00000002", MustParse("0.000000015").Round().String())
+
+	assert.True(t, MustParse("0.00000001").FitsScale())
+	assert.True(t, MustParse("1.1000000000").FitsScale(), "trailing zeros do not count")
+	assert.False(t, MustParse("0.000000001").FitsScale())
+	assert.False(t, MustParse("-5.123456789").FitsScale())
+}
+
+func TestAmountJSON(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{name: "String", input: `"50.10"`, expected: "50.1"},
+		{name: "Number", input: `100`, expected: "100"},
+		{name: "Null", input: `null`, expectErr: true},
+		{name: "Garbage", input: `"string"`, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var a Amount
+			err := json.Unmarshal([]byte(tc.input), &a)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, a.String())
+		})
+	}
+
+	out, err := json.Marshal(map[string]Amount{"USD": MustParse("200.00000000")})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"USD":"200"}`, string(out))
+}
+
+func TestAmountScan(t *testing.T) {
+	var a Amount
+	assert.NoError(t, a.Scan([]byte("150.50000000")))
+	assert.Equal(t, "150.5", a.String())
+
+	v, err := a.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "150.5", v)
+}
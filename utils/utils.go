@@ -1,9 +1,13 @@
 package utils
 
-import "errors"
+import (
+	"errors"
 
-func VerifyWithdrawalAmount(amount float32, balance float64) error {
-	if float64(amount) > balance {
+	"github.com/Foreground-Eclipse/transferer/pkg/money"
+)
+
+func VerifyWithdrawalAmount(amount, balance money.Amount) error {
+	if amount.GreaterThan(balance) {
 		return errors.New("not enough money to withdraw")
 	}
 	return nil